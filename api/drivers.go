@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+
+	"food-telegram/services"
+)
+
+type driverJSON struct {
+	ID                 string `json:"id"`
+	TgUserID           int64  `json:"tg_user_id"`
+	FullName           string `json:"full_name"`
+	Phone              string `json:"phone"`
+	CarPlate           string `json:"car_plate"`
+	CarModel           string `json:"car_model"`
+	CarColor           string `json:"car_color"`
+	Status             string `json:"status"`
+	IsOnline           bool   `json:"is_online"`
+	VerificationStatus string `json:"verification_status"`
+}
+
+func toDriverJSON(d services.Driver) driverJSON {
+	return driverJSON{
+		ID: d.ID, TgUserID: d.TgUserID, FullName: d.FullName, Phone: d.Phone, CarPlate: d.CarPlate,
+		CarModel: d.CarModel, CarColor: d.CarColor, Status: d.Status, IsOnline: d.IsOnline,
+		VerificationStatus: d.VerificationStatus,
+	}
+}
+
+// Drivers work for every branch, so only global keys see them.
+func requireGlobal(w http.ResponseWriter, r *http.Request) bool {
+	if !keyFrom(r).Global() {
+		writeError(w, http.StatusForbidden, "drivers are only available to global API keys")
+		return false
+	}
+	return true
+}
+
+// GET /api/v1/drivers?status=online|offline&limit=&offset=
+func (s *Server) listDrivers(w http.ResponseWriter, r *http.Request) {
+	if !requireGlobal(w, r) {
+		return
+	}
+	q := r.URL.Query()
+	limit, offset, err := parsePage(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	status := q.Get("status")
+	if status != "" && status != services.DriverStatusOnline && status != services.DriverStatusOffline {
+		writeError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+	drivers, err := services.ListDrivers(r.Context(), status, limit+1, offset)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	p := page{Limit: limit, Offset: offset}
+	if len(drivers) > limit {
+		drivers, p.HasMore = drivers[:limit], true
+	}
+	out := make([]driverJSON, 0, len(drivers))
+	for _, d := range drivers {
+		out = append(out, toDriverJSON(d))
+	}
+	p.Data = out
+	writeJSON(w, http.StatusOK, p)
+}
+
+// GET /api/v1/drivers/{uuid}
+func (s *Server) getDriver(w http.ResponseWriter, r *http.Request, id string) {
+	if !requireGlobal(w, r) {
+		return
+	}
+	if !isUUID(id) {
+		writeError(w, http.StatusBadRequest, "invalid driver id")
+		return
+	}
+	d, err := services.GetDriverByID(r.Context(), id)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	if d == nil {
+		writeError(w, http.StatusNotFound, "driver not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, toDriverJSON(*d))
+}
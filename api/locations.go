@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+
+	"food-telegram/models"
+	"food-telegram/services"
+)
+
+type locationJSON struct {
+	ID   int64   `json:"id"`
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+}
+
+func toLocationJSON(l models.Location) locationJSON {
+	return locationJSON{ID: l.ID, Name: l.Name, Lat: l.Lat, Lon: l.Lon}
+}
+
+// liveLocationFor returns the live branch, or nil if it does not exist or is deleted.
+func (s *Server) liveLocationFor(r *http.Request, id int64) (*models.Location, error) {
+	return s.liveLocation(r.Context(), id)
+}
+
+// GET /api/v1/locations: every branch for a global key, only its own for a branch key.
+func (s *Server) listLocations(w http.ResponseWriter, r *http.Request) {
+	locs, err := services.ListLocations(r.Context())
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	key := keyFrom(r)
+	out := make([]locationJSON, 0, len(locs))
+	for _, l := range locs {
+		if key.CanAccessLocation(l.ID) {
+			out = append(out, toLocationJSON(l))
+		}
+	}
+	writeJSON(w, http.StatusOK, list{Data: out})
+}
+
+// GET /api/v1/locations/{id}
+func (s *Server) getLocation(w http.ResponseWriter, r *http.Request, id int64) {
+	if !keyFrom(r).CanAccessLocation(id) {
+		writeError(w, http.StatusNotFound, "location not found")
+		return
+	}
+	l, err := s.liveLocationFor(r, id)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	if l == nil {
+		writeError(w, http.StatusNotFound, "location not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, toLocationJSON(*l))
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+
+	"food-telegram/models"
+	"food-telegram/services"
+)
+
+type menuItemJSON struct {
+	ID         int64  `json:"id"`
+	LocationID *int64 `json:"location_id"`
+	Category   string `json:"category"`
+	Name       string `json:"name"`
+	Price      int64  `json:"price"`
+}
+
+func toMenuItemJSON(it services.BranchMenuItem) menuItemJSON {
+	return menuItemJSON{ID: it.ID, LocationID: it.LocationID, Category: it.Category, Name: it.Name, Price: it.Price}
+}
+
+func validCategory(c string) bool {
+	return c == models.CategoryFood || c == models.CategoryDrink || c == models.CategoryDessert
+}
+
+// GET /api/v1/menu?location_id=&category=
+func (s *Server) listMenu(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var requested int64
+	if v := q.Get("location_id"); v != "" {
+		id, err := parseID(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid location_id")
+			return
+		}
+		requested = id
+	}
+	category := q.Get("category")
+	if category != "" && !validCategory(category) {
+		writeError(w, http.StatusBadRequest, "invalid category")
+		return
+	}
+	locationID, err := scopeLocation(keyFrom(r), requested)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	items, err := services.ListBranchMenu(r.Context(), locationID, category)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	out := make([]menuItemJSON, 0, len(items))
+	for _, it := range items {
+		out = append(out, toMenuItemJSON(it))
+	}
+	writeJSON(w, http.StatusOK, list{Data: out})
+}
+
+type menuItemRequest struct {
+	LocationID int64  `json:"location_id"`
+	Category   string `json:"category"`
+	Name       string `json:"name"`
+	Price      int64  `json:"price"`
+}
+
+// POST /api/v1/menu {"location_id": 3, "category": "food", "name": "Lavash", "price": 28000}
+// A branch key may leave location_id out; a global key must name the branch.
+func (s *Server) addMenuItem(w http.ResponseWriter, r *http.Request) {
+	var req menuItemRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	locationID, err := scopeLocation(keyFrom(r), req.LocationID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if locationID <= 0 {
+		writeError(w, http.StatusBadRequest, "location_id is required")
+		return
+	}
+	if !validCategory(req.Category) || req.Name == "" || req.Price < 0 {
+		writeError(w, http.StatusBadRequest, "category (food, drink, dessert), name and a non-negative price are required")
+		return
+	}
+	l, err := s.liveLocationFor(r, locationID)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	if l == nil {
+		writeError(w, http.StatusNotFound, "location not found")
+		return
+	}
+	id, err := services.AddMenuItemForLocation(r.Context(), req.Category, req.Name, req.Price, locationID)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, menuItemJSON{ID: id, LocationID: &locationID, Category: req.Category, Name: req.Name, Price: req.Price})
+}
+
+// DELETE /api/v1/menu/{id} soft-deletes the item (the superadmin can restore it).
+func (s *Server) deleteMenuItem(w http.ResponseWriter, r *http.Request, id int64) {
+	it, err := services.GetBranchMenuItem(r.Context(), id)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	key := keyFrom(r)
+	if it == nil || (!key.Global() && (it.LocationID == nil || !key.CanAccessLocation(*it.LocationID))) {
+		writeError(w, http.StatusNotFound, "menu item not found")
+		return
+	}
+	if err := services.DeleteMenuItem(r.Context(), id); err != nil {
+		internalError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"food-telegram/services"
+)
+
+type orderJSON struct {
+	ID           int64     `json:"id"`
+	LocationID   int64     `json:"location_id"`
+	Status       string    `json:"status"`
+	Phone        string    `json:"phone,omitempty"`
+	Lat          float64   `json:"lat"`
+	Lon          float64   `json:"lon"`
+	DistanceKm   float64   `json:"distance_km"`
+	ItemsTotal   int64     `json:"items_total"`
+	DeliveryFee  int64     `json:"delivery_fee"`
+	GrandTotal   int64     `json:"grand_total"`
+	DeliveryType *string   `json:"delivery_type"`
+	DriverID     *string   `json:"driver_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func toOrderJSON(o services.OrderRecord) orderJSON {
+	return orderJSON{
+		ID: o.ID, LocationID: o.LocationID, Status: o.Status, Phone: o.Phone, Lat: o.Lat, Lon: o.Lon,
+		DistanceKm: o.DistanceKm, ItemsTotal: o.ItemsTotal, DeliveryFee: o.DeliveryFee, GrandTotal: o.GrandTotal,
+		DeliveryType: o.DeliveryType, DriverID: o.DriverID, CreatedAt: o.CreatedAt, UpdatedAt: o.UpdatedAt,
+	}
+}
+
+// GET /api/v1/orders?status=&from=&to=&location_id=&limit=&offset=
+func (s *Server) listOrders(w http.ResponseWriter, r *http.Request) {
+	f, err := orderFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if f.LocationID, err = scopeLocation(keyFrom(r), f.LocationID); err != nil {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	limit := f.Limit
+	f.Limit++ // one extra row tells whether there is a next page
+	orders, err := services.ListOrders(r.Context(), f)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	p := page{Limit: limit, Offset: f.Offset}
+	if len(orders) > limit {
+		orders, p.HasMore = orders[:limit], true
+	}
+	out := make([]orderJSON, 0, len(orders))
+	for _, o := range orders {
+		out = append(out, toOrderJSON(o))
+	}
+	p.Data = out
+	writeJSON(w, http.StatusOK, p)
+}
+
+// loadOrder returns the order if the key may see it; otherwise it answers 404 (another branch's orders do not exist
+// for a branch key) and returns nil.
+func (s *Server) loadOrder(w http.ResponseWriter, r *http.Request, id int64) *services.OrderRecord {
+	o, err := services.GetOrderRecord(r.Context(), id)
+	if err != nil {
+		internalError(w, r, err)
+		return nil
+	}
+	if o == nil || !keyFrom(r).CanAccessLocation(o.LocationID) {
+		writeError(w, http.StatusNotFound, "order not found")
+		return nil
+	}
+	return o
+}
+
+// GET /api/v1/orders/{id}
+func (s *Server) getOrder(w http.ResponseWriter, r *http.Request, id int64) {
+	if o := s.loadOrder(w, r, id); o != nil {
+		writeJSON(w, http.StatusOK, toOrderJSON(*o))
+	}
+}
+
+type statusRequest struct {
+	Status string `json:"status"`
+}
+
+// statusChangeError answers a failed status change: 409 with the reason when the state machine refused it, 404 when
+// the order is gone (or, for the key, belongs to another branch), 500 otherwise.
+func statusChangeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrderTransition):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrOrderOtherBranch):
+		writeError(w, http.StatusNotFound, "order not found")
+	default:
+		internalError(w, r, err)
+	}
+}
+
+// POST /api/v1/orders/{id}/status {"status": "preparing"}
+// The change goes through the order state machine as the branch, exactly like the buttons on the order card.
+func (s *Server) updateOrderStatus(w http.ResponseWriter, r *http.Request, id int64) {
+	var req statusRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !services.IsOrderStatus(req.Status) {
+		writeError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+	o := s.loadOrder(w, r, id)
+	if o == nil {
+		return
+	}
+	key := keyFrom(r)
+	locationID := o.LocationID
+	if !key.Global() {
+		locationID = *key.LocationID
+	}
+	if _, err := services.UpdateOrderStatus(r.Context(), id, req.Status, locationID, key.CreatedBy); err != nil {
+		statusChangeError(w, r, err)
+		return
+	}
+	if s.onOrderUpdated != nil {
+		s.onOrderUpdated(id)
+	}
+	if o = s.loadOrder(w, r, id); o != nil {
+		writeJSON(w, http.StatusOK, toOrderJSON(*o))
+	}
+}
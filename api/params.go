@@ -0,0 +1,129 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"food-telegram/services"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+func parseID(raw string) (int64, error) {
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid id %q", raw)
+	}
+	return id, nil
+}
+
+// parsePage reads limit (default 50, at most 200) and offset.
+func parsePage(q url.Values) (limit, offset int, err error) {
+	limit = defaultPageLimit
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return 0, 0, fmt.Errorf("invalid limit %q", v)
+		}
+		if limit > maxPageLimit {
+			limit = maxPageLimit
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q", v)
+		}
+	}
+	return limit, offset, nil
+}
+
+// parseTime reads an RFC 3339 timestamp or a date (YYYY-MM-DD, Tashkent time). A date as the upper bound
+// ("to") covers the whole day, so from=2024-05-01&to=2024-05-01 is that one day.
+func parseTime(q url.Values, name string, upper bool) (time.Time, error) {
+	v := strings.TrimSpace(q.Get(name))
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseInLocation("2006-01-02", v, services.BranchTimezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: want YYYY-MM-DD or RFC 3339", name, v)
+	}
+	if upper {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d, nil
+}
+
+// orderFilter builds the ListOrders filter from the query: status, from, to, location_id, limit, offset.
+func orderFilter(q url.Values) (services.OrderFilter, error) {
+	var f services.OrderFilter
+	var err error
+	if f.Limit, f.Offset, err = parsePage(q); err != nil {
+		return f, err
+	}
+	if v := q.Get("status"); v != "" {
+		if !services.IsOrderStatus(v) {
+			return f, fmt.Errorf("invalid status %q", v)
+		}
+		f.Status = v
+	}
+	if f.From, err = parseTime(q, "from", false); err != nil {
+		return f, err
+	}
+	if f.To, err = parseTime(q, "to", true); err != nil {
+		return f, err
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return f, fmt.Errorf("from must be before to")
+	}
+	if v := q.Get("location_id"); v != "" {
+		if f.LocationID, err = parseID(v); err != nil {
+			return f, fmt.Errorf("invalid location_id %q", v)
+		}
+	}
+	return f, nil
+}
+
+// errForeignBranch is returned when a branch key asks for another branch.
+var errForeignBranch = fmt.Errorf("this API key is limited to its own branch")
+
+// scopeLocation returns the branch a request may act on: a branch key always gets its own (asking for another
+// one is an error), a global key gets the requested one (0 = every branch).
+func scopeLocation(key *services.APIKey, requested int64) (int64, error) {
+	if key.Global() {
+		return requested, nil
+	}
+	if requested != 0 && requested != *key.LocationID {
+		return 0, errForeignBranch
+	}
+	return *key.LocationID, nil
+}
+
+// isUUID checks the textual form of a UUID (drivers.id) so a malformed ID is a 400, not a database error.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if c != '-' {
+				return false
+			}
+		case '0' <= c && c <= '9', 'a' <= c && c <= 'f', 'A' <= c && c <= 'F':
+		default:
+			return false
+		}
+	}
+	return true
+}
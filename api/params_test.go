@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"food-telegram/services"
+)
+
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantLimit  int
+		wantOffset int
+		wantErr    bool
+	}{
+		{"", defaultPageLimit, 0, false},
+		{"limit=10&offset=20", 10, 20, false},
+		{"limit=1000", maxPageLimit, 0, false},
+		{"limit=0", 0, 0, true},
+		{"limit=abc", 0, 0, true},
+		{"offset=-1", 0, 0, true},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		limit, offset, err := parsePage(q)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if limit != tt.wantLimit || offset != tt.wantOffset {
+			t.Errorf("%q: got %d/%d, want %d/%d", tt.query, limit, offset, tt.wantLimit, tt.wantOffset)
+		}
+	}
+}
+
+func TestOrderFilterDates(t *testing.T) {
+	q, _ := url.ParseQuery("from=2024-05-01&to=2024-05-01&status=ready&location_id=3")
+	f, err := orderFilter(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFrom := time.Date(2024, 5, 1, 0, 0, 0, 0, services.BranchTimezone)
+	if !f.From.Equal(wantFrom) || !f.To.Equal(wantFrom.AddDate(0, 0, 1)) {
+		t.Errorf("range = %v .. %v, want the whole of 2024-05-01", f.From, f.To)
+	}
+	if f.Status != services.OrderStatusReady || f.LocationID != 3 {
+		t.Errorf("filter = %+v", f)
+	}
+
+	q, _ = url.ParseQuery("from=2024-05-01T10:00:00Z")
+	if f, err = orderFilter(q); err != nil || !f.From.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) || !f.To.IsZero() {
+		t.Errorf("RFC 3339 from: %+v, %v", f, err)
+	}
+
+	for _, bad := range []string{"status=cooking", "from=01.05.2024", "from=2024-05-02&to=2024-05-01", "location_id=x"} {
+		q, _ := url.ParseQuery(bad)
+		if _, err := orderFilter(q); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestScopeLocation(t *testing.T) {
+	branch := int64(3)
+	global := &services.APIKey{}
+	scoped := &services.APIKey{LocationID: &branch}
+
+	if got, err := scopeLocation(global, 0); err != nil || got != 0 {
+		t.Errorf("global, any = %d, %v", got, err)
+	}
+	if got, err := scopeLocation(global, 5); err != nil || got != 5 {
+		t.Errorf("global, 5 = %d, %v", got, err)
+	}
+	if got, err := scopeLocation(scoped, 0); err != nil || got != 3 {
+		t.Errorf("branch, any = %d, %v", got, err)
+	}
+	if got, err := scopeLocation(scoped, 3); err != nil || got != 3 {
+		t.Errorf("branch, own = %d, %v", got, err)
+	}
+	if _, err := scopeLocation(scoped, 5); err == nil {
+		t.Error("branch key reached another branch")
+	}
+}
+
+func TestIsUUID(t *testing.T) {
+	if !isUUID("0b7e6f2a-3c1d-4e5f-8a9b-0c1d2e3f4a5b") {
+		t.Error("valid UUID rejected")
+	}
+	for _, s := range []string{"", "1", "0b7e6f2a3c1d4e5f8a9b0c1d2e3f4a5b", "0b7e6f2a-3c1d-4e5f-8a9b-0c1d2e3f4a5g"} {
+		if isUUID(s) {
+			t.Errorf("%q accepted", s)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package api serves the authenticated JSON API (/api/v1) for POS systems, aggregators and custom dashboards.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"food-telegram/models"
+	"food-telegram/services"
+)
+
+// Server routes /api/v1 requests. Every request needs an API key (see services.CreateAPIKey); a branch key only
+// sees and changes its own branch, the same isolation UpdateOrderStatus applies to branch admins.
+type Server struct {
+	authenticate   func(ctx context.Context, plain string) (*services.APIKey, error)
+	liveLocation   func(ctx context.Context, id int64) (*models.Location, error)
+	onOrderUpdated func(orderID int64)
+}
+
+// New returns a server that checks keys against the api_keys table.
+func New() *Server {
+	return &Server{authenticate: services.AuthenticateAPIKey, liveLocation: services.GetLiveLocation}
+}
+
+// SetOnOrderUpdated is called after an order status change so the caller can deliver the queued notifications.
+func (s *Server) SetOnOrderUpdated(fn func(orderID int64)) {
+	s.onOrderUpdated = fn
+}
+
+// ListenAndServe serves the API on addr until the listener fails.
+func (s *Server) ListenAndServe(addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
+
+// Handler returns the API's http.Handler.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/", s.serveV1)
+	return mux
+}
+
+type apiKeyCtxKey struct{}
+
+func keyFrom(r *http.Request) *services.APIKey {
+	k, _ := r.Context().Value(apiKeyCtxKey{}).(*services.APIKey)
+	return k
+}
+
+// plainKey reads the key from "Authorization: Bearer <key>" or "X-API-Key: <key>".
+func plainKey(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+			return strings.TrimSpace(h[7:])
+		}
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+func (s *Server) serveV1(w http.ResponseWriter, r *http.Request) {
+	plain := plainKey(r)
+	if plain == "" {
+		writeError(w, http.StatusUnauthorized, "missing API key")
+		return
+	}
+	key, err := s.authenticate(r.Context(), plain)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	if key == nil {
+		writeError(w, http.StatusUnauthorized, "invalid API key")
+		return
+	}
+	// Changes made through the API are audited as the superadmin who issued the key
+	ctx := services.WithAuditActor(r.Context(), key.CreatedBy, services.AuditRoleAPI)
+	ctx = context.WithValue(ctx, apiKeyCtxKey{}, key)
+	s.route(w, r.WithContext(ctx))
+}
+
+// route dispatches on the path below /api/v1/ (ServeMux in Go 1.21 has no method or wildcard patterns).
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "orders":
+		s.allow(w, r, http.MethodGet, s.listOrders)
+	case len(parts) == 2 && parts[0] == "orders":
+		s.withID(w, r, parts[1], http.MethodGet, s.getOrder)
+	case len(parts) == 3 && parts[0] == "orders" && parts[2] == "status":
+		s.withID(w, r, parts[1], http.MethodPost, s.updateOrderStatus)
+	case len(parts) == 1 && parts[0] == "menu":
+		switch r.Method {
+		case http.MethodGet:
+			s.listMenu(w, r)
+		case http.MethodPost:
+			s.addMenuItem(w, r)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+	case len(parts) == 2 && parts[0] == "menu":
+		s.withID(w, r, parts[1], http.MethodDelete, s.deleteMenuItem)
+	case len(parts) == 1 && parts[0] == "locations":
+		s.allow(w, r, http.MethodGet, s.listLocations)
+	case len(parts) == 2 && parts[0] == "locations":
+		s.withID(w, r, parts[1], http.MethodGet, s.getLocation)
+	case len(parts) == 1 && parts[0] == "drivers":
+		s.allow(w, r, http.MethodGet, s.listDrivers)
+	case len(parts) == 2 && parts[0] == "drivers":
+		s.allow(w, r, http.MethodGet, func(w http.ResponseWriter, r *http.Request) { s.getDriver(w, r, parts[1]) })
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, method string, h http.HandlerFunc) {
+	if r.Method != method {
+		methodNotAllowed(w, method)
+		return
+	}
+	h(w, r)
+}
+
+func (s *Server) withID(w http.ResponseWriter, r *http.Request, raw, method string, h func(http.ResponseWriter, *http.Request, int64)) {
+	if r.Method != method {
+		methodNotAllowed(w, method)
+		return
+	}
+	id, err := parseID(raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h(w, r, id)
+}
+
+// page is the envelope of list responses. HasMore tells the client to ask again with offset+limit.
+type page struct {
+	Data    interface{} `json:"data"`
+	Limit   int         `json:"limit"`
+	Offset  int         `json:"offset"`
+	HasMore bool        `json:"has_more"`
+}
+
+// list is the envelope of short, unpaged lists (menu, locations).
+type list struct {
+	Data interface{} `json:"data"`
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorBody{Error: msg})
+}
+
+func methodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+// internalError logs err and answers 500 without leaking details to the client.
+func internalError(w http.ResponseWriter, r *http.Request, err error) {
+	fmt.Fprintf(os.Stderr, "api %s %s: %v\n", r.Method, r.URL.Path, err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON body: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"food-telegram/models"
+	"food-telegram/services"
+)
+
+// testServer accepts "global" and "branch" (location 3) as keys; location 4 is deleted. The requests below are all
+// answered before any database access.
+func testServer() *Server {
+	branch := int64(3)
+	keys := map[string]*services.APIKey{
+		"global": {ID: 1, CreatedBy: 42},
+		"branch": {ID: 2, CreatedBy: 42, LocationID: &branch},
+	}
+	return &Server{
+		authenticate: func(_ context.Context, plain string) (*services.APIKey, error) {
+			return keys[plain], nil
+		},
+		liveLocation: func(_ context.Context, id int64) (*models.Location, error) {
+			if id == branch {
+				return &models.Location{ID: branch, Name: "Chilonzor"}, nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+func TestServerRejects(t *testing.T) {
+	h := testServer().Handler()
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"no key", "GET", "/api/v1/orders", "", "", http.StatusUnauthorized},
+		{"unknown key", "GET", "/api/v1/orders", "X-API-Key", "nope", http.StatusUnauthorized},
+		{"basic auth", "GET", "/api/v1/orders", "Authorization", "Basic Z2xvYmFs", http.StatusUnauthorized},
+		{"unknown route", "GET", "/api/v1/payments", "Authorization", "Bearer global", http.StatusNotFound},
+		{"wrong method", "DELETE", "/api/v1/orders", "Authorization", "Bearer global", http.StatusMethodNotAllowed},
+		{"bad id", "GET", "/api/v1/orders/abc", "Authorization", "Bearer global", http.StatusBadRequest},
+		{"bad filter", "GET", "/api/v1/orders?status=cooking", "Authorization", "Bearer global", http.StatusBadRequest},
+		{"other branch orders", "GET", "/api/v1/orders?location_id=5", "X-API-Key", "branch", http.StatusForbidden},
+		{"other branch menu", "GET", "/api/v1/menu?location_id=5", "X-API-Key", "branch", http.StatusForbidden},
+		{"other branch location", "GET", "/api/v1/locations/5", "X-API-Key", "branch", http.StatusNotFound},
+		{"drivers with branch key", "GET", "/api/v1/drivers", "X-API-Key", "branch", http.StatusForbidden},
+		{"driver with branch key", "GET", "/api/v1/drivers/0b7e6f2a-3c1d-4e5f-8a9b-0c1d2e3f4a5b", "X-API-Key", "branch", http.StatusForbidden},
+		{"bad driver id", "GET", "/api/v1/drivers/42", "X-API-Key", "global", http.StatusBadRequest},
+		{"deleted location", "GET", "/api/v1/locations/4", "X-API-Key", "global", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+			var body errorBody
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+				t.Errorf("body = %q, want a JSON error", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAddMenuItemDeletedLocation(t *testing.T) {
+	h := testServer().Handler()
+	body := `{"location_id": 4, "category": "food", "name": "Lavash", "price": 28000}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/menu", strings.NewReader(body))
+	req.Header.Set("X-API-Key", "global")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("menu item for a deleted location: status = %d, want 404 (%s)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGetLiveLocation(t *testing.T) {
+	h := testServer().Handler()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/locations/3", nil)
+	req.Header.Set("X-API-Key", "branch")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var got locationJSON
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &got) != nil || got.Name != "Chilonzor" {
+		t.Fatalf("live location: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStatusChangeError(t *testing.T) {
+	_, refused := services.CheckOrderTransition(services.OrderStatusNew, services.OrderStatusCompleted, services.OrderActorBranch, services.OrderFacts{})
+	tests := []struct {
+		name string
+		err  error
+		want int
+		body string
+	}{
+		{"refused transition", refused, http.StatusConflict, refused.Error()},
+		{"missing order", services.ErrOrderNotFound, http.StatusNotFound, "order not found"},
+		{"other branch", services.ErrOrderOtherBranch, http.StatusNotFound, "order not found"},
+		{"database", errors.New("conn refused on 10.0.0.5"), http.StatusInternalServerError, "internal error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			statusChangeError(rec, httptest.NewRequest(http.MethodPost, "/api/v1/orders/7/status", nil), tt.err)
+			var body errorBody
+			if rec.Code != tt.want || json.Unmarshal(rec.Body.Bytes(), &body) != nil || body.Error != tt.body {
+				t.Errorf("got %d %s, want %d %q", rec.Code, rec.Body.String(), tt.want, tt.body)
+			}
+		})
+	}
+}
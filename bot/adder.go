@@ -2,13 +2,18 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"food-telegram/config"
+	"food-telegram/lang"
 	"food-telegram/models"
 	"food-telegram/services"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -47,26 +52,31 @@ const (
 
 // AdderBot is the admin bot: password login and admin panel only. No application form; no password generation/sending.
 type AdderBot struct {
-	api               *tgbotapi.BotAPI
-	zayafkaAPI        *tgbotapi.BotAPI // optional: send password/rejection to applicant via Zayafka (they applied there)
+	api               Messenger
+	client            *tgbotapi.BotAPI // polls updates for api
+	zayafkaAPI        Messenger        // optional: send password/rejection to applicant via Zayafka (they applied there)
+	driverAPI         Messenger        // optional: send payout statements to drivers
+	driverClient      *tgbotapi.BotAPI // downloads files received by the driver bot
 	cfg               *config.Config
 	login             string
 	superAdminID      int64
 	state             map[int64]*adderState
 	locState          map[int64]*locationAdderState
 	addBranchAdmin  map[int64]*addBranchAdminState
+	pendingTOTP     map[int64]int64 // branch staff whose password was accepted -> location, until the authenticator code is sent
+	totpSecretMsg   map[int64]int   // branch staff -> /2fa_on message holding the secret, deleted once the first code verifies
 	activeLocation  map[int64]int64 // per-admin selected location for menu items
 	expiredNotified      map[string]bool // "tg_user_id:role" -> already sent superadmin expiry notification
 	onSubscriptionRenewed func(tgUserID int64, role string) // clear background-job "already notified" so next expiry can notify again
 	stateMu              sync.RWMutex
 }
 
-// NewAdderBot creates an adder bot using ADDER_TOKEN. superAdminID is the big admin (ADMIN_ID); they use LOGIN. Branch admins log in with their unique password.
+// NewAdderBot creates an adder bot using ADDER_TOKEN. superAdminID is the big admin (ADMIN_ID); they use LOGIN. Branch staff log in with their own password (and 2FA code if enabled).
 func NewAdderBot(cfg *config.Config, superAdminID int64) (*AdderBot, error) {
 	if cfg.Telegram.AdderToken == "" {
 		return nil, fmt.Errorf("ADDER_TOKEN not set")
 	}
-	api, err := tgbotapi.NewBotAPI(cfg.Telegram.AdderToken)
+	api, err := newBotAPI(cfg, cfg.Telegram.AdderToken)
 	if err != nil {
 		return nil, err
 	}
@@ -75,13 +85,16 @@ func NewAdderBot(cfg *config.Config, superAdminID int64) (*AdderBot, error) {
 		sid = superAdminID
 	}
 	return &AdderBot{
-		api:               api,
+		api:               messenger(api),
+		client:            api,
 		cfg:               cfg,
 		login:             strings.TrimSpace(cfg.Telegram.Login),
 		superAdminID:      sid,
 		state:             make(map[int64]*adderState),
 		locState:          make(map[int64]*locationAdderState),
 		addBranchAdmin: make(map[int64]*addBranchAdminState),
+		pendingTOTP:    make(map[int64]int64),
+		totpSecretMsg:  make(map[int64]int),
 		activeLocation: make(map[int64]int64),
 		expiredNotified:   make(map[string]bool),
 	}, nil
@@ -90,7 +103,7 @@ func NewAdderBot(cfg *config.Config, superAdminID int64) (*AdderBot, error) {
 func (a *AdderBot) Start() {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
-	updates := a.api.GetUpdatesChan(u)
+	updates := a.client.GetUpdatesChan(u)
 
 	for update := range updates {
 		if update.CallbackQuery != nil {
@@ -122,6 +135,13 @@ func (a *AdderBot) Start() {
 			continue
 		}
 		if a.superAdminID != 0 && userID == a.superAdminID {
+			if a.handleDriverRejectReasonFlow(msg.Chat.ID, userID, text) {
+				continue
+			}
+			if text == "/driver_reviews" {
+				a.handleDriverReviews(msg.Chat.ID)
+				continue
+			}
 			if text == "/subs_pending" {
 				a.handleSubsPending(msg.Chat.ID)
 				continue
@@ -150,15 +170,134 @@ func (a *AdderBot) Start() {
 				a.handleAddDriver(msg.Chat.ID, strings.TrimSpace(text[11:]))
 				continue
 			}
+			if text == "/cash_due" {
+				a.handleCashDue(msg.Chat.ID)
+				continue
+			}
+			if text == "/payouts_due" {
+				a.handlePayoutsDue(msg.Chat.ID)
+				continue
+			}
+			if text == "/audit" || strings.HasPrefix(text, "/audit ") {
+				a.handleAudit(msg.Chat.ID, strings.TrimSpace(strings.TrimPrefix(text, "/audit")))
+				continue
+			}
+			if text == "/deleted" {
+				a.handleDeleted(msg.Chat.ID)
+				continue
+			}
+			if strings.HasPrefix(text, "/restore_location ") {
+				a.handleRestoreLocation(msg.Chat.ID, userID, strings.TrimSpace(strings.TrimPrefix(text, "/restore_location")))
+				continue
+			}
+			if strings.HasPrefix(text, "/restore_item ") {
+				a.handleRestoreItem(msg.Chat.ID, userID, strings.TrimSpace(strings.TrimPrefix(text, "/restore_item")))
+				continue
+			}
+			if text == "/send_stats" {
+				a.handleSendStats(msg.Chat.ID)
+				continue
+			}
+			if text == "/outbox" {
+				a.handleOutbox(msg.Chat.ID)
+				continue
+			}
+			if strings.HasPrefix(text, "/outbox_retry ") {
+				a.handleOutboxRetry(msg.Chat.ID, strings.TrimSpace(strings.TrimPrefix(text, "/outbox_retry")))
+				continue
+			}
+			if text == "/sessions" || strings.HasPrefix(text, "/sessions ") {
+				a.handleSessions(msg.Chat.ID, strings.TrimSpace(strings.TrimPrefix(text, "/sessions")))
+				continue
+			}
+			if text == "/api_keys" {
+				a.handleAPIKeys(msg.Chat.ID)
+				continue
+			}
+			if text == "/api_key_new" || strings.HasPrefix(text, "/api_key_new ") {
+				a.handleAPIKeyNew(msg.Chat.ID, userID, strings.TrimSpace(strings.TrimPrefix(text, "/api_key_new")))
+				continue
+			}
+			if strings.HasPrefix(text, "/api_key_revoke ") {
+				a.handleAPIKeyRevoke(msg.Chat.ID, userID, strings.TrimSpace(strings.TrimPrefix(text, "/api_key_revoke")))
+				continue
+			}
+			if text == "/webhooks" || strings.HasPrefix(text, "/webhooks ") {
+				a.handleWebhooks(msg.Chat.ID, strings.TrimSpace(strings.TrimPrefix(text, "/webhooks")))
+				continue
+			}
+			if text == "/webhook_add" || strings.HasPrefix(text, "/webhook_add ") {
+				a.handleWebhookAdd(msg.Chat.ID, userID, strings.TrimSpace(strings.TrimPrefix(text, "/webhook_add")))
+				continue
+			}
+			if strings.HasPrefix(text, "/webhook_remove ") {
+				a.handleWebhookRemove(msg.Chat.ID, userID, strings.TrimSpace(strings.TrimPrefix(text, "/webhook_remove")))
+				continue
+			}
+			if text == "/webhook_failed" || strings.HasPrefix(text, "/webhook_failed ") {
+				a.handleWebhookFailed(msg.Chat.ID, strings.TrimSpace(strings.TrimPrefix(text, "/webhook_failed")))
+				continue
+			}
+			if strings.HasPrefix(text, "/webhook_replay ") {
+				a.handleWebhookReplay(msg.Chat.ID, strings.TrimSpace(strings.TrimPrefix(text, "/webhook_replay")))
+				continue
+			}
+			if text == "/export" || strings.HasPrefix(text, "/export ") {
+				a.handleExport(msg.Chat.ID, userID, strings.TrimSpace(strings.TrimPrefix(text, "/export")))
+				continue
+			}
+			if text == "/report" || strings.HasPrefix(text, "/report ") {
+				a.handleReport(msg.Chat.ID, userID, strings.TrimSpace(strings.TrimPrefix(text, "/report")))
+				continue
+			}
+			if text == "/summary_off" || text == "/summary_on" {
+				a.handleDailySummaryToggle(msg.Chat.ID, userID, text == "/summary_off")
+				continue
+			}
+			if text == "/ratings" {
+				a.handleRatings(msg.Chat.ID, userID)
+				continue
+			}
+			if text == "/online_hours" || strings.HasPrefix(text, "/online_hours ") {
+				a.handleOnlineHours(msg.Chat.ID, strings.TrimSpace(strings.TrimPrefix(text, "/online_hours")))
+				continue
+			}
+			if text == "/payout" || strings.HasPrefix(text, "/payout ") {
+				a.handlePayout(msg.Chat.ID, userID, strings.TrimSpace(strings.TrimPrefix(text, "/payout")))
+				continue
+			}
+			if text == "/commission" || strings.HasPrefix(text, "/commission ") {
+				a.handleCommission(msg.Chat.ID, userID, strings.TrimSpace(strings.TrimPrefix(text, "/commission")))
+				continue
+			}
 		}
 		if text == "/login" {
 			a.send(msg.Chat.ID, adderLoginPrompt)
 			continue
 		}
+		// Session timed out on this message: say so instead of treating the text as a password
+		if event := a.expireSessionIfDue(userID); event != "" {
+			a.send(msg.Chat.ID, sessionEndedText(event))
+			continue
+		}
+		if text == "/logout" {
+			if a.isLoggedIn(userID) {
+				a.clearFlows(userID)
+				a.clearLoggedIn(userID)
+				a.send(msg.Chat.ID, "👋 Siz tizimdan chiqdingiz. "+adderLoginPrompt)
+			} else {
+				a.send(msg.Chat.ID, adderRequireLoginMsg)
+			}
+			continue
+		}
 
 		// Not logged in: only password entry (no application flow; that is in Zayavka only).
 		if !a.isLoggedIn(userID) {
 			ctx := context.Background()
+			// Branch staff with 2FA: password accepted, waiting for the authenticator code
+			if a.handlePendingTOTP(msg.Chat.ID, userID, text) {
+				continue
+			}
 			// Superadmin can always log in with LOGIN password first
 			if a.superAdminID != 0 && userID == a.superAdminID && a.login != "" {
 				if wait, _ := services.LoginThrottleWaitSeconds(ctx, userID, services.ThrottleRoleSuperadmin); wait > 0 {
@@ -167,7 +306,7 @@ func (a *AdderBot) Start() {
 				}
 				if text == a.login {
 					_ = services.RecordLoginSuccess(ctx, userID, services.ThrottleRoleSuperadmin)
-					a.setLoggedIn(userID, "super")
+					a.setLoggedIn(userID, "super", 0)
 					a.sendAdminPanel(msg.Chat.ID, userID)
 					continue
 				}
@@ -189,11 +328,10 @@ func (a *AdderBot) Start() {
 						a.sendExpiredUserAndNotifySuperadmin(msg.Chat.ID, userID, services.UserRoleRestaurantAdmin, subMsg)
 						continue
 					}
-					a.setLoggedIn(userID, "branch")
 					locID, _ := services.GetAdminLocationID(ctx, userID)
-					a.stateMu.Lock()
-					a.activeLocation[userID] = locID
-					a.stateMu.Unlock()
+					if !a.beginBranchLogin(msg.Chat.ID, userID, locID) {
+						continue
+					}
 					a.send(msg.Chat.ID, "✅ Kirish muvaffaqiyatli.")
 					if within, warn := services.SubscriptionExpiresWithinDays(ctx, userID, services.UserRoleRestaurantAdmin, 3); within && warn != "" {
 						a.send(msg.Chat.ID, warn)
@@ -222,7 +360,7 @@ func (a *AdderBot) Start() {
 					}
 					if text == a.login {
 						_ = services.RecordLoginSuccess(ctx, userID, services.ThrottleRoleSuperadmin)
-						a.setLoggedIn(userID, "super")
+						a.setLoggedIn(userID, "super", 0)
 						a.sendAdminPanel(msg.Chat.ID, userID)
 						continue
 					}
@@ -233,10 +371,9 @@ func (a *AdderBot) Start() {
 				}
 				if locID, ok, err := services.AuthenticateBranchAdmin(ctx, userID, text); err == nil && ok {
 					_ = services.RecordLoginSuccess(ctx, userID, services.ThrottleRoleRestaurantAdmin)
-					a.setLoggedIn(userID, "branch")
-					a.stateMu.Lock()
-					a.activeLocation[userID] = locID
-					a.stateMu.Unlock()
+					if !a.beginBranchLogin(msg.Chat.ID, userID, locID) {
+						continue
+					}
 					locName, _ := services.GetLocationName(ctx, locID)
 					if locName != "" {
 						a.send(msg.Chat.ID, "✅ Logged in to «"+locName+"». You can add or edit menu items for your place.")
@@ -254,7 +391,7 @@ func (a *AdderBot) Start() {
 					a.send(msg.Chat.ID, fmt.Sprintf("⏳ Iltimos %d soniya kutib qayta urinib ko'ring.", wait))
 				} else if text == a.login {
 					_ = services.RecordLoginSuccess(ctx, userID, services.ThrottleRoleSuperadmin)
-					a.setLoggedIn(userID, "super")
+					a.setLoggedIn(userID, "super", 0)
 					a.sendAdminPanel(msg.Chat.ID, userID)
 				} else {
 					_ = services.RecordLoginFailed(ctx, userID, services.ThrottleRoleSuperadmin)
@@ -265,10 +402,9 @@ func (a *AdderBot) Start() {
 					a.send(msg.Chat.ID, fmt.Sprintf("⏳ Iltimos %d soniya kutib qayta urinib ko'ring.", wait))
 				} else if locID, ok, err := services.AuthenticateBranchAdmin(ctx, userID, text); err == nil && ok {
 					_ = services.RecordLoginSuccess(ctx, userID, services.ThrottleRoleRestaurantAdmin)
-					a.setLoggedIn(userID, "branch")
-					a.stateMu.Lock()
-					a.activeLocation[userID] = locID
-					a.stateMu.Unlock()
+					if !a.beginBranchLogin(msg.Chat.ID, userID, locID) {
+						continue
+					}
 					locName, _ := services.GetLocationName(ctx, locID)
 					if locName != "" {
 						a.send(msg.Chat.ID, "✅ Logged in to «"+locName+"». You can add or edit menu items for your place.")
@@ -298,6 +434,37 @@ func (a *AdderBot) Start() {
 			}
 		}
 
+		// Branch staff: owner manages staff, everyone toggles their own shift
+		if a.getRole(userID) == "branch" && a.handleBranchStaffCommand(msg.Chat.ID, userID, text) {
+			continue
+		}
+
+		// Branch staff: reports of their own branch
+		if a.getRole(userID) == "branch" && (text == "/report" || strings.HasPrefix(text, "/report ")) {
+			a.handleReport(msg.Chat.ID, userID, strings.TrimSpace(strings.TrimPrefix(text, "/report")))
+			continue
+		}
+
+		if a.getRole(userID) == "branch" && (text == "/export" || strings.HasPrefix(text, "/export ")) {
+			a.handleExport(msg.Chat.ID, userID, strings.TrimSpace(strings.TrimPrefix(text, "/export")))
+			continue
+		}
+
+		if a.getRole(userID) == "branch" && (text == "/summary_off" || text == "/summary_on") {
+			a.handleDailySummaryToggle(msg.Chat.ID, userID, text == "/summary_off")
+			continue
+		}
+
+		if a.getRole(userID) == "branch" && text == "/ratings" {
+			a.handleRatings(msg.Chat.ID, userID)
+			continue
+		}
+
+		// Branch staff: own password and 2FA
+		if a.getRole(userID) == "branch" && a.handleBranchSecurityCommand(msg, userID, text) {
+			continue
+		}
+
 		// Handle add menu item flow (name -> price)
 		if a.handleMenuAddFlow(msg, userID, text) {
 			continue
@@ -332,15 +499,61 @@ func (a *AdderBot) Start() {
 	}
 }
 
-var adderLoggedIn = make(map[int64]bool)
-var adderRole     = make(map[int64]string) // "super" or "branch"
-var adderLoggedInMu sync.RWMutex
-
+// isLoggedIn reports whether the user has a valid adder session (admin_sessions) and marks it as active now.
 func (a *AdderBot) isLoggedIn(userID int64) bool {
-	adderLoggedInMu.RLock()
-	ok := adderLoggedIn[userID]
-	adderLoggedInMu.RUnlock()
-	return ok
+	idle, absolute := a.sessionTimeouts()
+	sess, _, err := services.TouchAdminSession(context.Background(), userID, idle, absolute)
+	if err != nil {
+		log.Printf("adder: session user=%d: %v", userID, err)
+		return false
+	}
+	return sess != nil
+}
+
+// sessionTimeouts returns the idle and absolute session limits (ADMIN_SESSION_IDLE_MIN, ADMIN_SESSION_MAX_HOURS).
+func (a *AdderBot) sessionTimeouts() (idle, absolute time.Duration) {
+	return time.Duration(a.cfg.Telegram.AdminSessionIdleMin) * time.Minute, time.Duration(a.cfg.Telegram.AdminSessionMaxHours) * time.Hour
+}
+
+// deletedRetention is how long soft-deleted locations and menu items can be restored before the purge job removes them.
+func (a *AdderBot) deletedRetention() time.Duration {
+	return time.Duration(a.cfg.Data.DeletedRetentionDays) * 24 * time.Hour
+}
+
+// expireSessionIfDue ends the user's session if it has timed out and returns the timeout event ("" if still valid or not logged in).
+func (a *AdderBot) expireSessionIfDue(userID int64) string {
+	idle, absolute := a.sessionTimeouts()
+	_, event, err := services.TouchAdminSession(context.Background(), userID, idle, absolute)
+	if err != nil {
+		log.Printf("adder: session user=%d: %v", userID, err)
+		return ""
+	}
+	return event
+}
+
+func sessionEndedText(event string) string {
+	switch event {
+	case services.AdminSessionEventIdleTimeout:
+		return "⌛ Faolsizlik sababli sessiya tugadi. " + adderLoginPrompt
+	case services.AdminSessionEventAbsoluteTimeout:
+		return "⌛ Sessiya muddati tugadi, qayta kiring. " + adderLoginPrompt
+	case services.AdminSessionEventRevoked:
+		return "🔒 Sessiyangiz superadmin tomonidan yakunlandi. " + adderLoginPrompt
+	}
+	return adderRequireLoginMsg
+}
+
+// ExpireSessions ends timed-out adder sessions and tells their users. Called periodically from main.
+func (a *AdderBot) ExpireSessions() {
+	idle, absolute := a.sessionTimeouts()
+	expired, err := services.ExpireAdminSessions(context.Background(), idle, absolute)
+	if err != nil {
+		log.Printf("adder: expire sessions: %v", err)
+	}
+	for _, e := range expired {
+		a.clearFlows(e.TgUserID)
+		a.send(e.TgUserID, sessionEndedText(e.Event))
+	}
 }
 
 // requireAdminLogin returns (true, "") if user may access admin panel; (false, msg) if not logged in or subscription expired. For branch, checks by branch subscription (primary's), not current user.
@@ -365,12 +578,18 @@ func (a *AdderBot) requireAdminLogin(chatID int64, userID int64) (allowed bool,
 
 // GetAPI returns the adder bot API.
 func (a *AdderBot) GetAPI() *tgbotapi.BotAPI {
-	return a.api
+	return a.client
 }
 
 // SetZayafkaAPI sets the Zayafka bot API so adder can send password/rejection to applicants in the bot they used.
 func (a *AdderBot) SetZayafkaAPI(api *tgbotapi.BotAPI) {
-	a.zayafkaAPI = api
+	a.zayafkaAPI = messenger(api)
+}
+
+// SetDriverBotAPI sets the driver bot API so payout statements reach the driver.
+func (a *AdderBot) SetDriverBotAPI(api *tgbotapi.BotAPI) {
+	a.driverAPI = messenger(api)
+	a.driverClient = api
 }
 
 // SetOnSubscriptionRenewed sets the callback when a subscription is renewed (so background job can clear "already notified" for next expiry).
@@ -385,7 +604,7 @@ func (a *AdderBot) sendToSuperadminViaZayafka(text string, kb tgbotapi.InlineKey
 	}
 	msg := tgbotapi.NewMessage(a.superAdminID, text)
 	msg.ReplyMarkup = kb
-	if _, err := a.zayafkaAPI.Send(msg); err != nil {
+	if _, err := a.zayafkaAPI.SendMessage(msg); err != nil {
 		log.Printf("adder: send to superadmin via zayafka: %v", err)
 	}
 }
@@ -394,7 +613,7 @@ func (a *AdderBot) sendToSuperadminViaZayafka(text string, kb tgbotapi.InlineKey
 func (a *AdderBot) sendToApplicant(chatID int64, text string) {
 	if a.zayafkaAPI != nil {
 		msg := tgbotapi.NewMessage(chatID, text)
-		if _, err := a.zayafkaAPI.Send(msg); err != nil {
+		if _, err := a.zayafkaAPI.SendMessage(msg); err != nil {
 			log.Printf("adder: send to applicant via zayafka: %v", err)
 		}
 		return
@@ -406,7 +625,7 @@ func (a *AdderBot) sendToApplicantWithInline(chatID int64, text string, kb tgbot
 	if a.zayafkaAPI != nil {
 		msg := tgbotapi.NewMessage(chatID, text)
 		msg.ReplyMarkup = kb
-		if _, err := a.zayafkaAPI.Send(msg); err != nil {
+		if _, err := a.zayafkaAPI.SendMessage(msg); err != nil {
 			log.Printf("adder: send to applicant via zayafka: %v", err)
 		}
 		return
@@ -414,33 +633,57 @@ func (a *AdderBot) sendToApplicantWithInline(chatID int64, text string, kb tgbot
 	a.sendWithInline(chatID, text, kb)
 }
 
+// auditCtx attributes the audited service calls made with the returned context to the user.
+func (a *AdderBot) auditCtx(userID int64) context.Context {
+	return auditContext(a.superAdminID, userID)
+}
+
+// auditContext returns a context carrying the audit actor: superadmin, else the user's branch role.
+func auditContext(superAdminID, userID int64) context.Context {
+	ctx := context.Background()
+	role := services.AuditRoleSuperadmin
+	if superAdminID == 0 || userID != superAdminID {
+		_, role, _ = services.GetBranchRole(ctx, userID)
+	}
+	return services.WithAuditActor(ctx, userID, role)
+}
+
+// getRole returns the role of the user's session: "super" or "branch" (defaults to "super" without a session).
 func (a *AdderBot) getRole(userID int64) string {
-	adderLoggedInMu.RLock()
-	r := adderRole[userID]
-	adderLoggedInMu.RUnlock()
-	if r == "" {
+	sess, err := services.GetAdminSession(context.Background(), userID)
+	if err != nil {
+		log.Printf("adder: session role user=%d: %v", userID, err)
+	}
+	if sess == nil || sess.Role == "" {
 		return "super"
 	}
-	return r
+	return sess.Role
 }
 
-func (a *AdderBot) setLoggedIn(userID int64, role string) {
-	adderLoggedInMu.Lock()
-	adderLoggedIn[userID] = true
-	adderRole[userID] = role
-	adderLoggedInMu.Unlock()
+// setLoggedIn starts the user's persistent session; locID is the branch for "branch" sessions (0 for the superadmin).
+func (a *AdderBot) setLoggedIn(userID int64, role string, locID int64) {
+	if err := services.StartAdminSession(context.Background(), userID, role, locID); err != nil {
+		log.Printf("adder: start session user=%d: %v", userID, err)
+	}
 }
 
+// clearLoggedIn logs the user out (session event "logout").
 func (a *AdderBot) clearLoggedIn(userID int64) {
-	adderLoggedInMu.Lock()
-	delete(adderLoggedIn, userID)
-	delete(adderRole, userID)
-	adderLoggedInMu.Unlock()
+	a.endSession(userID, services.AdminSessionEventLogout, 0)
+}
+
+// endSession ends the user's session with event; actorID is who revoked it (0 otherwise).
+func (a *AdderBot) endSession(userID int64, event string, actorID int64) bool {
+	ended, err := services.EndAdminSession(context.Background(), userID, event, actorID)
+	if err != nil {
+		log.Printf("adder: end session user=%d: %v", userID, err)
+	}
+	return ended
 }
 
 func (a *AdderBot) send(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
-	if _, err := a.api.Send(msg); err != nil {
+	if _, err := a.api.SendMessage(msg); err != nil {
 		log.Printf("adder send error: %v", err)
 	}
 }
@@ -448,7 +691,7 @@ func (a *AdderBot) send(chatID int64, text string) {
 func (a *AdderBot) sendWithInline(chatID int64, text string, kb tgbotapi.InlineKeyboardMarkup) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = kb
-	if _, err := a.api.Send(msg); err != nil {
+	if _, err := a.api.SendMessage(msg); err != nil {
 		log.Printf("adder send error: %v", err)
 	}
 }
@@ -456,7 +699,7 @@ func (a *AdderBot) sendWithInline(chatID int64, text string, kb tgbotapi.InlineK
 func (a *AdderBot) sendWithReplyKeyboard(chatID int64, text string, kb tgbotapi.ReplyKeyboardMarkup) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = kb
-	if _, err := a.api.Send(msg); err != nil {
+	if _, err := a.api.SendMessage(msg); err != nil {
 		log.Printf("adder send error: %v", err)
 	}
 }
@@ -464,7 +707,7 @@ func (a *AdderBot) sendWithReplyKeyboard(chatID int64, text string, kb tgbotapi.
 func (a *AdderBot) sendRemoveKeyboard(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
-	if _, err := a.api.Send(msg); err != nil {
+	if _, err := a.api.SendMessage(msg); err != nil {
 		log.Printf("adder send error: %v", err)
 	}
 }
@@ -510,7 +753,7 @@ func (a *AdderBot) SendRenewalRequestToSuperadmin(tgUserID int64, role string) {
 	)
 	msg := tgbotapi.NewMessage(adminChatID, superMsg)
 	msg.ReplyMarkup = superKb
-	if _, err := a.api.Send(msg); err != nil {
+	if _, err := a.api.SendMessage(msg); err != nil {
 		log.Printf("adder: failed to send renewal request to superadmin (chat_id=%d): %v — ensure superadmin has started the Qo'shuvchi (adder) bot", adminChatID, err)
 		return
 	}
@@ -540,76 +783,975 @@ func (a *AdderBot) SendExpiredNotificationToSuperadmin(tgUserID int64, role stri
 	if !already {
 		a.expiredNotified[key] = true
 	}
-	a.stateMu.Unlock()
-	if already {
+	a.stateMu.Unlock()
+	if already {
+		return
+	}
+	superMsg := fmt.Sprintf("❌ Abonement tugadi: tg_user_id=%d role=%s\n\nYangi parol berish va abonement yangilash uchun quyidagi tugmani bosing.", tgUserID, role)
+	superKb := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("✅ Yangilash (parol aylantirish)", fmt.Sprintf("exp_renew:%d:%s", tgUserID, role))),
+	)
+	a.sendWithInline(a.superAdminID, superMsg, superKb)
+	a.sendToSuperadminViaZayafka(superMsg, superKb)
+}
+
+// HandleExpRenewFromZayafka runs renewal when superadmin taps the renew button in Zayafka; replyChatID is where to send confirmation (via Zayafka). For restaurant_admin only reactivates (no new password).
+func (a *AdderBot) HandleExpRenewFromZayafka(tgUserID int64, role string, replyChatID int64) {
+	ctx := a.auditCtx(a.superAdminID)
+	newPass, err := services.RenewSubscription(ctx, tgUserID, role, 1, a.superAdminID, nil, "")
+	if err != nil {
+		a.sendToApplicant(replyChatID, "❌ "+err.Error())
+		return
+	}
+	a.stateMu.Lock()
+	delete(a.expiredNotified, fmt.Sprintf("%d:%s", tgUserID, role))
+	a.stateMu.Unlock()
+	if a.onSubscriptionRenewed != nil {
+		a.onSubscriptionRenewed(tgUserID, role)
+	}
+	if role == services.UserRoleRestaurantAdmin {
+		a.sendToApplicant(replyChatID, "✅ Abonement yangilandi. Parol o'zgarmadi.")
+	} else {
+		subChatID, _ := services.GetChatIDForSubscriber(ctx, tgUserID, role)
+		if subChatID != 0 {
+			a.sendToApplicant(subChatID, newPass)
+		}
+		if subChatID != 0 {
+			a.sendToApplicant(replyChatID, "✅ Yangilandi. Yangi parol Zayafka orqali foydalanuvchiga yuborildi.")
+		} else {
+			a.sendToApplicant(replyChatID, fmt.Sprintf("✅ Yangilandi. Yangi parol (qo'lda yuboring): %s", newPass))
+		}
+	}
+}
+
+func (a *AdderBot) handleApplyCommand(chatID int64, userID int64) {
+	a.send(chatID, "📋 Ariza yuborish uchun Zayavka botidan foydalaning.")
+}
+
+func (a *AdderBot) handleApplicationsCommand(chatID int64, userID int64) {
+	if a.superAdminID == 0 || userID != a.superAdminID {
+		return
+	}
+	a.send(chatID, "📋 Arizalarni Zayavka botda ko'ring.")
+}
+
+func (a *AdderBot) handleAddDriver(chatID int64, args string) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		a.send(chatID, "Ishlatish: /add_driver <tg_user_id>\nHaydovchini qo'shadi, parol yuboradi. Haydovchi driver botda /login qiladi.")
+		return
+	}
+	var tgUserID int64
+	if _, err := fmt.Sscanf(args, "%d", &tgUserID); err != nil || tgUserID <= 0 {
+		a.send(chatID, "❌ tg_user_id raqam bo'lishi kerak.")
+		return
+	}
+	ctx := a.auditCtx(a.superAdminID)
+	plainPass, err := services.AddDriverDirect(ctx, tgUserID)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	a.send(chatID, fmt.Sprintf("✅ Haydovchi qo'shildi (tg_user_id=%d).\n\n🔑 Parol: %s\n\nBu parolni haydovchiga yuboring. U driver botda /login qiladi.", tgUserID, plainPass))
+}
+
+func (a *AdderBot) handlePayoutsDue(chatID int64) {
+	ctx := context.Background()
+	list, err := services.ListUnsettledDriverBalances(ctx)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	if len(list) == 0 {
+		a.send(chatID, "📭 To'lanmagan haydovchi daromadi yo'q.")
+		return
+	}
+	var b strings.Builder
+	b.WriteString("💰 To'lanmagan haydovchi daromadlari:\n\n")
+	for _, r := range list {
+		b.WriteString(fmt.Sprintf("• %s (tg_user_id=%d): %d ta buyurtma, %d so'm (+ choy puli %d, to'lovga kirmaydi), %s dan\n",
+			r.FullName, r.TgUserID, r.Orders, r.DriverShare, r.Tips, r.Since.Format("2006-01-02")))
+	}
+	b.WriteString("\nHisob-kitob: /payout <tg_user_id> <YYYY-MM-DD> <YYYY-MM-DD>")
+	a.send(chatID, b.String())
+}
+
+func (a *AdderBot) handleCashDue(chatID int64) {
+	ctx := context.Background()
+	list, err := services.ListOutstandingCashBalances(ctx)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	if len(list) == 0 {
+		a.send(chatID, "✅ Haydovchilarda topshirilmagan naqd pul yo'q.")
+		return
+	}
+	var b strings.Builder
+	b.WriteString("💵 Haydovchilardagi naqd pul (filial bo'yicha):\n")
+	var lastLoc, total int64
+	for _, r := range list {
+		if r.LocationID != lastLoc {
+			b.WriteString(fmt.Sprintf("\n🏪 %s (id=%d)\n", r.LocationName, r.LocationID))
+			lastLoc = r.LocationID
+		}
+		mark := ""
+		if r.Pending {
+			mark = " ⏳"
+		}
+		b.WriteString(fmt.Sprintf("• %s (tg_user_id=%d): %d so'm, %d ta buyurtma%s\n", r.DriverName, r.DriverTgID, r.Amount, r.Orders, mark))
+		total += r.Amount
+	}
+	b.WriteString(fmt.Sprintf("\nJami: %d so'm\n⏳ = filial tasdig'ini kutmoqda", total))
+	a.send(chatID, b.String())
+}
+
+const commissionUsage = "Ishlatish:\n/commission <foiz> — butun platforma uchun\n/commission <location_id> <foiz> — bitta filial uchun\n/commission <location_id> off — filial platforma qoidasiga qaytadi"
+
+// handleCommission lists the driver commission rules (no args) or changes one (see services.ParseCommissionArgs).
+// New deliveries use the branch rule, else the platform-wide rule, else DRIVER_COMMISSION_PCT.
+func (a *AdderBot) handleCommission(chatID int64, superadminID int64, args string) {
+	ctx := a.auditCtx(superadminID)
+	if args == "" {
+		rules, err := services.ListCommissionRules(ctx)
+		if err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return
+		}
+		var b strings.Builder
+		b.WriteString("💼 Haydovchi komissiyasi (yetkazish to'lovidan):\n\n")
+		platformSet := false
+		for _, r := range rules {
+			if r.LocationID == services.PlatformCommissionLocation {
+				platformSet = true
+				b.WriteString(fmt.Sprintf("• Platforma: %g%%\n", r.Pct))
+				continue
+			}
+			b.WriteString(fmt.Sprintf("• %s (id=%d): %g%%\n", r.LocationName, r.LocationID, r.Pct))
+		}
+		if !platformSet {
+			b.WriteString(fmt.Sprintf("• Platforma: %g%% (DRIVER_COMMISSION_PCT)\n", a.cfg.Delivery.DriverCommissionPct))
+		}
+		b.WriteString("\n" + commissionUsage)
+		a.send(chatID, b.String())
+		return
+	}
+	c, err := services.ParseCommissionArgs(args)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error()+"\n\n"+commissionUsage)
+		return
+	}
+	if c.Clear {
+		if err := services.DeleteCommissionRule(ctx, c.LocationID); err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return
+		}
+		a.send(chatID, fmt.Sprintf("✅ Filial #%d endi platforma komissiyasini ishlatadi.", c.LocationID))
+		return
+	}
+	if err := services.SetCommissionRule(ctx, c.LocationID, c.Pct, superadminID); err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	if c.LocationID == services.PlatformCommissionLocation {
+		a.send(chatID, fmt.Sprintf("✅ Platforma komissiyasi: %g%%. Yangi yetkazishlarga qo'llanadi.", c.Pct))
+		return
+	}
+	a.send(chatID, fmt.Sprintf("✅ Filial #%d komissiyasi: %g%%. Yangi yetkazishlarga qo'llanadi.", c.LocationID, c.Pct))
+}
+
+func (a *AdderBot) handlePayout(chatID int64, superadminID int64, args string) {
+	parts := strings.Fields(args)
+	if len(parts) != 3 {
+		a.send(chatID, "Ishlatish: /payout <tg_user_id> <dan YYYY-MM-DD> <gacha YYYY-MM-DD>\nMisol: /payout 123456789 2026-03-01 2026-03-07")
+		return
+	}
+	var tgUserID int64
+	if _, err := fmt.Sscanf(parts[0], "%d", &tgUserID); err != nil || tgUserID <= 0 {
+		a.send(chatID, "❌ tg_user_id raqam bo'lishi kerak.")
+		return
+	}
+	from, err1 := time.ParseInLocation("2006-01-02", parts[1], time.Local)
+	to, err2 := time.ParseInLocation("2006-01-02", parts[2], time.Local)
+	if err1 != nil || err2 != nil {
+		a.send(chatID, "❌ Sana formati: YYYY-MM-DD")
+		return
+	}
+	ctx := a.auditCtx(superadminID)
+	driver, err := services.GetDriverByTgUserID(ctx, tgUserID)
+	if err != nil || driver == nil {
+		a.send(chatID, "❌ Haydovchi topilmadi.")
+		return
+	}
+	payout, err := services.SettleDriverPayout(ctx, driver.ID, from, to, superadminID)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	statement := services.FormatPayoutStatement(payout, driver.FullName)
+	a.send(chatID, "✅ Hisob-kitob qilindi.\n\n"+statement)
+	if a.driverAPI != nil && driver.ChatID != 0 {
+		if _, err := a.driverAPI.SendMessage(tgbotapi.NewMessage(driver.ChatID, statement)); err != nil {
+			log.Printf("adder: send payout statement to driver: %v", err)
+		}
+	}
+}
+
+// NotifyDriverVerificationPending tells the superadmin a driver submitted documents (called from the driver bot).
+func (a *AdderBot) NotifyDriverVerificationPending(driverID string) {
+	if a.superAdminID == 0 {
+		return
+	}
+	driver, err := services.GetDriverByID(context.Background(), driverID)
+	if err != nil || driver == nil {
+		return
+	}
+	kb := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("👀 Ko'rish", "drv_review:"+driver.ID),
+	))
+	a.sendWithInline(a.superAdminID, fmt.Sprintf("🆕 Haydovchi hujjatlari tekshiruvga yuborildi: %s (tg_user_id=%d)\n\nNavbat: /driver_reviews", driver.FullName, driver.TgUserID), kb)
+}
+
+// handleDriverReviews lists drivers waiting for document review.
+func (a *AdderBot) handleDriverReviews(chatID int64) {
+	list, err := services.ListDriversPendingVerification(context.Background(), 20)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	if len(list) == 0 {
+		a.send(chatID, "📭 Tekshiruv kutayotgan haydovchi yo'q.")
+		return
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, d := range list {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("👀 %s (%s)", d.FullName, d.CarPlate), "drv_review:"+d.ID),
+		))
+	}
+	a.sendWithInline(chatID, fmt.Sprintf("🪪 Hujjatlari tekshiruv kutayotgan haydovchilar: %d", len(list)), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// handleDriverReviewCallback shows a driver's documents (drv_review) or approves/rejects them (drv_approve / drv_reject).
+func (a *AdderBot) handleDriverReviewCallback(chatID int64, superadminID int64, action, driverID string) {
+	ctx := a.auditCtx(superadminID)
+	driver, err := services.GetDriverByID(ctx, driverID)
+	if err != nil || driver == nil {
+		a.send(chatID, "❌ Haydovchi topilmadi.")
+		return
+	}
+	if driver.VerificationStatus != services.DriverVerificationPending {
+		a.send(chatID, "Bu haydovchi allaqachon ko'rib chiqilgan.")
+		return
+	}
+	switch action {
+	case "drv_review":
+		docs, err := services.GetDriverDocuments(ctx, driver.ID)
+		if err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return
+		}
+		for _, doc := range docs {
+			caption := doc.DocType
+			if doc.ExpiresAt != nil {
+				caption += " — " + doc.ExpiresAt.Format("2006-01-02") + " gacha"
+			}
+			a.sendDriverDocumentPhoto(chatID, doc.FileID, caption)
+		}
+		text := fmt.Sprintf("🪪 Haydovchi: %s\nTel: %s\nMashina: %s %s (%s)\ntg_user_id=%d",
+			driver.FullName, driver.Phone, driver.CarModel, driver.CarColor, driver.CarPlate, driver.TgUserID)
+		kb := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Tasdiqlash", "drv_approve:"+driver.ID),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Rad etish", "drv_reject:"+driver.ID),
+		))
+		a.sendWithInline(chatID, text, kb)
+	case "drv_approve":
+		if err := services.ApproveDriverVerification(ctx, driver.ID, superadminID); err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return
+		}
+		a.sendToDriver(driver.ChatID, lang.T(lang.Uz, "dr_verif_approved"))
+		a.send(chatID, "✅ Haydovchi tasdiqlandi.")
+	case "drv_reject":
+		ok, err := services.SetDriverReviewRejectInProgress(ctx, driver.ID, superadminID)
+		if err != nil || !ok {
+			a.send(chatID, "Bu haydovchi allaqachon ko'rib chiqilgan.")
+			return
+		}
+		a.send(chatID, "Sabab yuboring (yoki /skip standart sabab uchun):")
+	}
+}
+
+// handleDriverRejectReasonFlow treats the superadmin's next message as the reject reason after drv_reject. Returns true if consumed.
+func (a *AdderBot) handleDriverRejectReasonFlow(chatID int64, superadminID int64, text string) bool {
+	if strings.HasPrefix(text, "/") && text != "/skip" {
+		return false
+	}
+	ctx := a.auditCtx(superadminID)
+	driverID, err := services.GetDriverIDByReviewRejectInProgressBy(ctx, superadminID)
+	if err != nil || driverID == "" {
+		return false
+	}
+	reason := strings.TrimSpace(text)
+	if reason == "/skip" || reason == "" {
+		reason = "Hujjatlar aniq emas yoki talablarga mos emas."
+	}
+	if err := services.RejectDriverVerification(ctx, driverID, superadminID, reason); err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return true
+	}
+	if driver, _ := services.GetDriverByID(ctx, driverID); driver != nil {
+		a.sendToDriver(driver.ChatID, fmt.Sprintf(lang.T(lang.Uz, "dr_verif_rejected"), reason))
+	}
+	a.send(chatID, "✅ Haydovchi hujjatlari rad etildi.")
+	return true
+}
+
+// sendToDriver sends text to a driver chat via the driver bot (no-op if not wired or chat unknown).
+func (a *AdderBot) sendToDriver(driverChatID int64, text string) {
+	if a.driverAPI == nil || driverChatID == 0 {
+		return
+	}
+	if _, err := a.driverAPI.SendMessage(tgbotapi.NewMessage(driverChatID, text)); err != nil {
+		log.Printf("adder: send to driver: %v", err)
+	}
+}
+
+// sendDriverDocumentPhoto re-uploads a photo received by the driver bot (file_ids are per bot) to chatID.
+func (a *AdderBot) sendDriverDocumentPhoto(chatID int64, fileID, caption string) {
+	if a.driverClient == nil {
+		a.send(chatID, "📎 "+caption+" (driver bot ulanmagan, rasmni ko'rsatib bo'lmaydi)")
+		return
+	}
+	url, err := a.driverClient.GetFileDirectURL(fileID)
+	if err != nil {
+		a.send(chatID, "❌ "+caption+": "+err.Error())
+		return
+	}
+	resp, err := http.Get(url)
+	if err != nil {
+		a.send(chatID, "❌ "+caption+": "+err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		a.send(chatID, "❌ "+caption+": rasmni yuklab bo'lmadi")
+		return
+	}
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: "document.jpg", Bytes: data})
+	photo.Caption = caption
+	if err := a.api.SendPhoto(photo); err != nil {
+		log.Printf("adder: send driver document: %v", err)
+	}
+}
+
+// handleAudit shows recent audit events: /audit [actor <tg_user_id> | <target_type> [target_id]] [limit].
+func (a *AdderBot) handleAudit(chatID int64, args string) {
+	f, err := services.ParseAuditQuery(args)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error()+"\nIshlatish: /audit [actor <tg_user_id> | <obyekt_turi> [id]] [limit]\nObyekt turlari: menu_item, location, branch_staff, credential, subscription, application, driver, cash_handover, order, api_key, webhook")
+		return
+	}
+	list, err := services.ListAuditEvents(context.Background(), f)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	if len(list) == 0 {
+		a.send(chatID, "📭 Audit yozuvlari topilmadi.")
+		return
+	}
+	var b strings.Builder
+	b.WriteString("🧾 Audit (yangilari birinchi):\n")
+	for i, e := range list {
+		line := "\n" + services.FormatAuditEvent(e) + "\n"
+		if b.Len()+len(line) > 3900 {
+			b.WriteString(fmt.Sprintf("\n… yana %d ta yozuv. Filtr yoki kichikroq limit ishlating.", len(list)-i))
+			break
+		}
+		b.WriteString(line)
+	}
+	a.send(chatID, b.String())
+}
+
+// handleDeleted lists soft-deleted locations and menu items that can still be restored.
+func (a *AdderBot) handleDeleted(chatID int64) {
+	ctx := context.Background()
+	locs, err := services.ListDeletedLocations(ctx)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	items, err := services.ListDeletedMenuItems(ctx)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	if len(locs) == 0 && len(items) == 0 {
+		a.send(chatID, "📭 O'chirilgan filial yoki mahsulot yo'q.")
+		return
+	}
+	retention := a.deletedRetention()
+	var b strings.Builder
+	if len(locs) > 0 {
+		b.WriteString("🗑 O'chirilgan filiallar (/restore_location <id>):\n")
+		for _, l := range locs {
+			b.WriteString(fmt.Sprintf("\n#%d %s — %d ta mahsulot, %s gacha tiklash mumkin", l.ID, l.Name, l.MenuItems,
+				services.RestoreDeadline(l.DeletedAt, retention).Local().Format("02.01.2006 15:04")))
+		}
+		b.WriteString("\n\n")
+	}
+	if len(items) > 0 {
+		b.WriteString("🗑 O'chirilgan mahsulotlar (/restore_item <id>):\n")
+		for i, m := range items {
+			line := fmt.Sprintf("\n#%d %s (%d so'm) — %s, %s gacha", m.ID, m.Name, m.Price, m.LocationName,
+				services.RestoreDeadline(m.DeletedAt, retention).Local().Format("02.01.2006 15:04"))
+			if b.Len()+len(line) > 3900 {
+				b.WriteString(fmt.Sprintf("\n… yana %d ta mahsulot.", len(items)-i))
+				break
+			}
+			b.WriteString(line)
+		}
+	}
+	a.send(chatID, strings.TrimSpace(b.String()))
+}
+
+// handleRestoreLocation restores a soft-deleted location with its menu: /restore_location <id>.
+func (a *AdderBot) handleRestoreLocation(chatID, userID int64, args string) {
+	id, err := strconv.ParseInt(args, 10, 64)
+	if err != nil || id <= 0 {
+		a.send(chatID, "Ishlatish: /restore_location <location_id>")
+		return
+	}
+	if err := services.RestoreLocation(a.auditCtx(userID), id, a.deletedRetention()); err != nil {
+		a.send(chatID, "❌ Filialni tiklab bo'lmadi: "+err.Error())
+		return
+	}
+	a.send(chatID, fmt.Sprintf("✅ Filial #%d va uning menyusi tiklandi. Xodimlar qayta parol bilan kirishadi.", id))
+}
+
+// handleRestoreItem restores a menu item deleted on its own: /restore_item <id>.
+func (a *AdderBot) handleRestoreItem(chatID, userID int64, args string) {
+	id, err := strconv.ParseInt(args, 10, 64)
+	if err != nil || id <= 0 {
+		a.send(chatID, "Ishlatish: /restore_item <menu_item_id>")
+		return
+	}
+	if err := services.RestoreMenuItem(a.auditCtx(userID), id, a.deletedRetention()); err != nil {
+		a.send(chatID, "❌ Mahsulotni tiklab bo'lmadi: "+err.Error())
+		return
+	}
+	a.send(chatID, fmt.Sprintf("✅ Mahsulot #%d menyuga qaytarildi.", id))
+}
+
+// handleOutbox shows how many notifications are queued and the ones that failed for good.
+func (a *AdderBot) handleOutbox(chatID int64) {
+	ctx := context.Background()
+	pending, failed, err := services.CountOutbox(ctx)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("📬 Navbatda: %d\n❌ Yuborilmagan: %d", pending, failed))
+	if failed > 0 {
+		list, err := services.ListFailedOutbox(ctx, 30)
+		if err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return
+		}
+		b.WriteString("\n\nQayta yuborish: /outbox_retry <id>\n")
+		for i, it := range list {
+			line := fmt.Sprintf("\n#%d %s buyurtma #%d → %s %d (%d urinish): %s", it.ID, it.Kind, it.OrderID, it.Audience, it.ChatID, it.Attempts, it.LastError)
+			if b.Len()+len(line) > 3900 {
+				b.WriteString(fmt.Sprintf("\n… yana %d ta.", len(list)-i))
+				break
+			}
+			b.WriteString(line)
+		}
+	}
+	a.send(chatID, b.String())
+}
+
+// handleSendStats shows the Telegram send limiter per lane: queue depth, throttled sends and their waits.
+func (a *AdderBot) handleSendStats(chatID int64) {
+	st := sharedSendLimiter(a.cfg).Stats()
+	names := [...]string{"Buyurtmalar", "Javoblar", "Fon xabarlari"}
+	var b strings.Builder
+	b.WriteString("📶 Telegram yuborish navbati\n")
+	for i, l := range st.Lanes {
+		var avg time.Duration
+		if l.Throttled > 0 {
+			avg = l.TotalWait / time.Duration(l.Throttled)
+		}
+		b.WriteString(fmt.Sprintf("\n%s: navbatda %d, yuborilgan %d, kutgan %d (o'rtacha %s, eng ko'p %s)",
+			names[i], l.Queued, l.Sent, l.Throttled, avg.Round(time.Millisecond), l.MaxWait.Round(time.Millisecond)))
+	}
+	b.WriteString(fmt.Sprintf("\n\n429 javoblari: %d", st.TooManyRequests))
+	a.send(chatID, b.String())
+}
+
+// handleOutboxRetry queues a failed notification again: /outbox_retry <id>.
+func (a *AdderBot) handleOutboxRetry(chatID int64, args string) {
+	id, err := strconv.ParseInt(args, 10, 64)
+	if err != nil || id <= 0 {
+		a.send(chatID, "Ishlatish: /outbox_retry <id>")
+		return
+	}
+	if err := services.RetryFailedOutbox(context.Background(), id); err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	a.send(chatID, fmt.Sprintf("✅ Xabar #%d qayta navbatga qo'yildi.", id))
+}
+
+// handleSessions lists active adder sessions grouped by branch with a revoke button each: /sessions [location_id].
+func (a *AdderBot) handleSessions(chatID int64, args string) {
+	var locID int64
+	if args != "" {
+		id, err := strconv.ParseInt(args, 10, 64)
+		if err != nil || id <= 0 {
+			a.send(chatID, "Ishlatish: /sessions [location_id]")
+			return
+		}
+		locID = id
+	}
+	a.ExpireSessions()
+	list, err := services.ListAdminSessions(context.Background(), locID)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	if len(list) == 0 {
+		a.send(chatID, "📭 Faol sessiya yo'q.")
+		return
+	}
+	var b strings.Builder
+	var rows [][]tgbotapi.InlineKeyboardButton
+	b.WriteString(fmt.Sprintf("🔐 Faol sessiyalar (%d):\n", len(list)))
+	group := int64(-1)
+	for _, s := range list {
+		if s.BranchLocationID != group {
+			group = s.BranchLocationID
+			if group == 0 {
+				b.WriteString("\n👑 Superadmin\n")
+			} else {
+				b.WriteString(fmt.Sprintf("\n📍 %s (id %d)\n", s.LocationName, group))
+			}
+		}
+		b.WriteString(fmt.Sprintf("• %d — kirgan %s, oxirgi faollik %s\n", s.TgUserID, s.CreatedAt.Local().Format("02.01 15:04"), s.LastSeenAt.Local().Format("02.01 15:04")))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🚪 Yakunlash: %d", s.TgUserID), fmt.Sprintf("sess_revoke:%d", s.TgUserID)),
+		))
+	}
+	a.sendWithInline(chatID, b.String(), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// handleAPIKeys lists the HTTP API keys with their scope and last use.
+func (a *AdderBot) handleAPIKeys(chatID int64) {
+	list, err := services.ListAPIKeys(context.Background())
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	if len(list) == 0 {
+		a.send(chatID, "🔑 API kalitlari yo'q.\nYaratish: /api_key_new <nomi> [location_id]")
+		return
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("🔑 API kalitlari (%d):\n", len(list)))
+	for _, k := range list {
+		scope := "barcha filiallar"
+		if k.LocationID != nil {
+			scope = fmt.Sprintf("filial #%d", *k.LocationID)
+		}
+		used := "ishlatilmagan"
+		if k.LastUsedAt != nil {
+			used = "oxirgi so'rov " + k.LastUsedAt.Local().Format("02.01 15:04")
+		}
+		line := fmt.Sprintf("\n#%d %s — %s, %s", k.ID, k.Name, scope, used)
+		if k.RevokedAt != nil {
+			line = fmt.Sprintf("\n#%d %s — bekor qilingan %s", k.ID, k.Name, k.RevokedAt.Local().Format("02.01.2006"))
+		}
+		if b.Len()+len(line) > 3900 {
+			break
+		}
+		b.WriteString(line)
+	}
+	b.WriteString("\n\nYaratish: /api_key_new <nomi> [location_id]\nBekor qilish: /api_key_revoke <id>")
+	a.send(chatID, b.String())
+}
+
+// handleAPIKeyNew issues a key: /api_key_new <name> [location_id]. Without location_id the key is global.
+// The plain key is shown only in this reply.
+func (a *AdderBot) handleAPIKeyNew(chatID, userID int64, args string) {
+	parts := strings.Fields(args)
+	if len(parts) == 0 {
+		a.send(chatID, "Ishlatish: /api_key_new <nomi> [location_id]")
+		return
+	}
+	var locationID *int64
+	if len(parts) > 1 {
+		if id, err := strconv.ParseInt(parts[len(parts)-1], 10, 64); err == nil && id > 0 {
+			locationID = &id
+			parts = parts[:len(parts)-1]
+		}
+	}
+	plain, k, err := services.CreateAPIKey(a.auditCtx(userID), strings.Join(parts, " "), locationID, userID)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	scope := "barcha filiallar (global)"
+	if k.LocationID != nil {
+		scope = fmt.Sprintf("faqat filial #%d", *k.LocationID)
+	}
+	a.send(chatID, fmt.Sprintf("✅ API kaliti #%d yaratildi: %s\nDoira: %s\n\n%s\n\n⚠️ Kalit faqat hozir ko'rsatiladi, saqlab qo'ying. So'rovlarda: Authorization: Bearer <kalit>", k.ID, k.Name, scope, plain))
+}
+
+// handleAPIKeyRevoke disables a key: /api_key_revoke <id>.
+func (a *AdderBot) handleAPIKeyRevoke(chatID, userID int64, args string) {
+	id, err := strconv.ParseInt(args, 10, 64)
+	if err != nil || id <= 0 {
+		a.send(chatID, "Ishlatish: /api_key_revoke <id>")
+		return
+	}
+	if err := services.RevokeAPIKey(a.auditCtx(userID), id); err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	a.send(chatID, fmt.Sprintf("✅ API kaliti #%d bekor qilindi.", id))
+}
+
+// handleWebhooks lists webhooks with their events and failed deliveries: /webhooks [location_id].
+func (a *AdderBot) handleWebhooks(chatID int64, args string) {
+	var locID int64
+	if args != "" {
+		id, err := strconv.ParseInt(args, 10, 64)
+		if err != nil || id <= 0 {
+			a.send(chatID, "Ishlatish: /webhooks [location_id]")
+			return
+		}
+		locID = id
+	}
+	list, err := services.ListWebhooks(context.Background(), locID)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	if len(list) == 0 {
+		a.send(chatID, "🪝 Webhook yo'q.\nQo'shish: /webhook_add <location_id> <url> [hodisalar]")
+		return
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("🪝 Webhooklar (%d):\n", len(list)))
+	for _, w := range list {
+		events := "barcha hodisalar"
+		if len(w.Events) > 0 {
+			events = strings.Join(w.Events, ", ")
+		}
+		line := fmt.Sprintf("\n#%d filial #%d → %s\n   %s", w.ID, w.LocationID, w.URL, events)
+		if w.DisabledAt != nil {
+			line += " — o'chirilgan"
+		} else if w.Failed > 0 {
+			line += fmt.Sprintf(" — ❌ %d ta yetkazilmagan", w.Failed)
+		}
+		if b.Len()+len(line) > 3900 {
+			break
+		}
+		b.WriteString(line)
+	}
+	b.WriteString("\n\nYetkazilmaganlar: /webhook_failed [webhook_id]\nO'chirish: /webhook_remove <id>")
+	a.send(chatID, b.String())
+}
+
+// handleWebhookAdd subscribes a branch: /webhook_add <location_id> <url> [event,event,...]. The signing secret
+// is shown only in this reply.
+func (a *AdderBot) handleWebhookAdd(chatID, userID int64, args string) {
+	const usage = "Ishlatish: /webhook_add <location_id> <url> [hodisalar]\nHodisalar (vergul bilan, bo'sh = hammasi): "
+	parts := strings.Fields(args)
+	if len(parts) < 2 || len(parts) > 3 {
+		a.send(chatID, usage+strings.Join(services.WebhookEvents, ", "))
+		return
+	}
+	locID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || locID <= 0 {
+		a.send(chatID, usage+strings.Join(services.WebhookEvents, ", "))
+		return
+	}
+	var events []string
+	if len(parts) == 3 {
+		if events, err = services.ParseWebhookEvents(parts[2]); err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return
+		}
+	}
+	w, err := services.CreateWebhook(a.auditCtx(userID), locID, parts[1], events, userID)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	a.send(chatID, fmt.Sprintf("✅ Webhook #%d qo'shildi: filial #%d → %s\n\nImzo kaliti (faqat hozir ko'rsatiladi):\n%s\n\nHar bir so'rovda %s: t=<vaqt>,v1=<HMAC-SHA256(kalit, \"<vaqt>.<body>\")>",
+		w.ID, w.LocationID, w.URL, w.Secret, services.WebhookHeaderSignature))
+}
+
+// handleWebhookRemove disables a webhook: /webhook_remove <id>.
+func (a *AdderBot) handleWebhookRemove(chatID, userID int64, args string) {
+	id, err := strconv.ParseInt(args, 10, 64)
+	if err != nil || id <= 0 {
+		a.send(chatID, "Ishlatish: /webhook_remove <id>")
+		return
+	}
+	if err := services.DisableWebhook(a.auditCtx(userID), id); err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	a.send(chatID, fmt.Sprintf("✅ Webhook #%d o'chirildi.", id))
+}
+
+// handleWebhookFailed lists failed deliveries: /webhook_failed [webhook_id].
+func (a *AdderBot) handleWebhookFailed(chatID int64, args string) {
+	var hookID int64
+	if args != "" {
+		id, err := strconv.ParseInt(args, 10, 64)
+		if err != nil || id <= 0 {
+			a.send(chatID, "Ishlatish: /webhook_failed [webhook_id]")
+			return
+		}
+		hookID = id
+	}
+	list, err := services.ListFailedWebhookDeliveries(context.Background(), hookID, 30)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	if len(list) == 0 {
+		a.send(chatID, "✅ Yetkazilmagan webhook yo'q.")
+		return
+	}
+	var b strings.Builder
+	b.WriteString("❌ Yetkazilmagan webhooklar\nQayta yuborish: /webhook_replay <id> yoki /webhook_replay all <webhook_id>\n")
+	for i, d := range list {
+		line := fmt.Sprintf("\n#%d webhook #%d, %s buyurtma #%d (%d urinish): %s", d.ID, d.WebhookID, d.Event, d.OrderID, d.Attempts, d.LastError)
+		if b.Len()+len(line) > 3900 {
+			b.WriteString(fmt.Sprintf("\n… yana %d ta.", len(list)-i))
+			break
+		}
+		b.WriteString(line)
+	}
+	a.send(chatID, b.String())
+}
+
+// handleWebhookReplay queues failed deliveries again: /webhook_replay <delivery_id> or /webhook_replay all <webhook_id>.
+func (a *AdderBot) handleWebhookReplay(chatID int64, args string) {
+	const usage = "Ishlatish: /webhook_replay <id> yoki /webhook_replay all <webhook_id>"
+	parts := strings.Fields(args)
+	var deliveryID, hookID int64
+	var err error
+	switch {
+	case len(parts) == 1:
+		deliveryID, err = strconv.ParseInt(parts[0], 10, 64)
+	case len(parts) == 2 && parts[0] == "all":
+		hookID, err = strconv.ParseInt(parts[1], 10, 64)
+	default:
+		a.send(chatID, usage)
+		return
+	}
+	if err != nil || deliveryID < 0 || hookID < 0 || deliveryID+hookID == 0 {
+		a.send(chatID, usage)
+		return
+	}
+	n, err := services.ReplayWebhookDeliveries(context.Background(), deliveryID, hookID)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	if n == 0 {
+		a.send(chatID, "❌ Faol webhookning yetkazilmagan so'rovi topilmadi")
+		return
+	}
+	a.send(chatID, fmt.Sprintf("✅ %d ta so'rov qayta navbatga qo'yildi.", n))
+}
+
+// handleReport sends the orders report for a date range: the superadmin for every branch or one location_id,
+// branch staff for their own branch only.
+func (a *AdderBot) handleReport(chatID int64, userID int64, args string) {
+	from, to, locationID, err := parseReportArgs(args, time.Now())
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	ctx := context.Background()
+	if a.superAdminID == 0 || userID != a.superAdminID {
+		locID, _, err := services.GetBranchRole(ctx, userID)
+		if err != nil || locID == 0 {
+			a.send(chatID, "❌ Filial topilmadi.")
+			return
+		}
+		if locationID != 0 && locationID != locID {
+			a.send(chatID, "❌ Faqat o'z filialingiz hisobotini ko'rishingiz mumkin.")
+			return
+		}
+		locationID = locID
+	}
+	title := "Barcha filiallar"
+	if locationID != 0 {
+		name, err := services.GetLocationName(ctx, locationID)
+		if err != nil || name == "" {
+			a.send(chatID, "❌ Filial topilmadi.")
+			return
+		}
+		title = name
+	}
+	r, err := services.GetOrdersReport(ctx, locationID, from, to)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	a.send(chatID, formatOrdersReport(r, title))
+}
+
+// handleDailySummaryToggle turns the end-of-day summary (sent by the message bot) off or back on for the user.
+func (a *AdderBot) handleDailySummaryToggle(chatID int64, userID int64, off bool) {
+	if err := services.SetDailySummaryOptOut(context.Background(), userID, off); err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	if off {
+		a.send(chatID, "🔕 Kun yakuni xabari o'chirildi. Qayta yoqish: /summary_on")
 		return
 	}
-	superMsg := fmt.Sprintf("❌ Abonement tugadi: tg_user_id=%d role=%s\n\nYangi parol berish va abonement yangilash uchun quyidagi tugmani bosing.", tgUserID, role)
-	superKb := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("✅ Yangilash (parol aylantirish)", fmt.Sprintf("exp_renew:%d:%s", tgUserID, role))),
-	)
-	a.sendWithInline(a.superAdminID, superMsg, superKb)
-	a.sendToSuperadminViaZayafka(superMsg, superKb)
+	a.send(chatID, "🔔 Kun yakuni xabari yoqildi. O'chirish: /summary_off")
 }
 
-// HandleExpRenewFromZayafka runs renewal when superadmin taps the renew button in Zayafka; replyChatID is where to send confirmation (via Zayafka). For restaurant_admin only reactivates (no new password).
-func (a *AdderBot) HandleExpRenewFromZayafka(tgUserID int64, role string, replyChatID int64) {
+// handleRatings shows customer ratings: the superadmin gets every branch's and driver's score, branch staff their
+// branch's score and latest ratings.
+func (a *AdderBot) handleRatings(chatID int64, userID int64) {
 	ctx := context.Background()
-	newPass, err := services.RenewSubscription(ctx, tgUserID, role, 1, a.superAdminID, nil, "")
-	if err != nil {
-		a.sendToApplicant(replyChatID, "❌ "+err.Error())
+	if a.superAdminID != 0 && userID == a.superAdminID {
+		branches, err := services.ListBranchRatingScores(ctx)
+		if err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return
+		}
+		drivers, err := services.ListDriverRatingScores(ctx)
+		if err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return
+		}
+		a.send(chatID, formatRatingScores(branches, drivers))
 		return
 	}
-	a.stateMu.Lock()
-	delete(a.expiredNotified, fmt.Sprintf("%d:%s", tgUserID, role))
-	a.stateMu.Unlock()
-	if a.onSubscriptionRenewed != nil {
-		a.onSubscriptionRenewed(tgUserID, role)
+	locID, _, err := services.GetBranchRole(ctx, userID)
+	if err != nil || locID == 0 {
+		a.send(chatID, "❌ Filial topilmadi.")
+		return
 	}
-	if role == services.UserRoleRestaurantAdmin {
-		a.sendToApplicant(replyChatID, "✅ Abonement yangilandi. Parol o'zgarmadi.")
-	} else {
-		subChatID, _ := services.GetChatIDForSubscriber(ctx, tgUserID, role)
-		if subChatID != 0 {
-			a.sendToApplicant(subChatID, newPass)
-		}
-		if subChatID != 0 {
-			a.sendToApplicant(replyChatID, "✅ Yangilandi. Yangi parol Zayafka orqali foydalanuvchiga yuborildi.")
-		} else {
-			a.sendToApplicant(replyChatID, fmt.Sprintf("✅ Yangilandi. Yangi parol (qo'lda yuboring): %s", newPass))
-		}
+	name, _ := services.GetLocationName(ctx, locID)
+	scores, err := services.ListLocationRatings(ctx, []int64{locID})
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	recent, err := services.ListRecentRatings(ctx, locID, recentRatingsShown)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
 	}
+	a.send(chatID, formatBranchRatings(name, scores[locID], recent))
 }
 
-func (a *AdderBot) handleApplyCommand(chatID int64, userID int64) {
-	a.send(chatID, "📋 Ariza yuborish uchun Zayavka botidan foydalaning.")
-}
+const exportUsage = "Ishlatish: /export <orders|items|deliveries|payments> [csv|xlsx] [dan YYYY-MM-DD] [gacha YYYY-MM-DD] [location_id]"
 
-func (a *AdderBot) handleApplicationsCommand(chatID int64, userID int64) {
+// handleExport sends a spreadsheet as a Telegram document: the superadmin for every branch or one location_id,
+// branch owners and managers for their own branch only.
+func (a *AdderBot) handleExport(chatID int64, userID int64, args string) {
+	req, err := services.ParseExportArgs(strings.Fields(args), time.Now())
+	if errors.Is(err, services.ErrReportArgs) {
+		a.send(chatID, exportUsage)
+		return
+	}
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	ctx := context.Background()
 	if a.superAdminID == 0 || userID != a.superAdminID {
+		locID, role, err := services.GetBranchRole(ctx, userID)
+		if err != nil || locID == 0 {
+			a.send(chatID, "❌ Filial topilmadi.")
+			return
+		}
+		if !services.BranchRoleCan(role, services.BranchPermExport) {
+			a.send(chatID, "❌ Eksport faqat filial egasi va menejeri uchun.")
+			return
+		}
+		if req.LocationID != 0 && req.LocationID != locID {
+			a.send(chatID, "❌ Faqat o'z filialingiz ma'lumotlarini yuklab olishingiz mumkin.")
+			return
+		}
+		req.LocationID = locID
+	}
+	name, data, rows, err := services.ExportFile(ctx, req)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
 		return
 	}
-	a.send(chatID, "📋 Arizalarni Zayavka botda ko'ring.")
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: name, Bytes: data})
+	doc.Caption = fmt.Sprintf("📄 %s: %d qator", name, rows)
+	if err := a.api.SendDocument(doc); err != nil {
+		log.Printf("adder export send: %v", err)
+		a.send(chatID, "❌ Faylni yuborib bo'lmadi: "+err.Error())
+	}
 }
 
-func (a *AdderBot) handleAddDriver(chatID int64, args string) {
-	args = strings.TrimSpace(args)
-	if args == "" {
-		a.send(chatID, "Ishlatish: /add_driver <tg_user_id>\nHaydovchini qo'shadi, parol yuboradi. Haydovchi driver botda /login qiladi.")
+// handleOnlineHours reports online time per driver: /online_hours [from YYYY-MM-DD] [to YYYY-MM-DD] (default: this week).
+func (a *AdderBot) handleOnlineHours(chatID int64, args string) {
+	parts := strings.Fields(args)
+	now := time.Now()
+	from := services.StartOfWeek(now)
+	to, toLabel := now, now.Format("2006-01-02")
+	if len(parts) > 2 {
+		a.send(chatID, "Ishlatish: /online_hours [dan YYYY-MM-DD] [gacha YYYY-MM-DD]")
 		return
 	}
-	var tgUserID int64
-	if _, err := fmt.Sscanf(args, "%d", &tgUserID); err != nil || tgUserID <= 0 {
-		a.send(chatID, "❌ tg_user_id raqam bo'lishi kerak.")
+	if len(parts) >= 1 {
+		d, err := time.ParseInLocation("2006-01-02", parts[0], time.Local)
+		if err != nil {
+			a.send(chatID, "❌ Sana formati: YYYY-MM-DD")
+			return
+		}
+		from = d
+	}
+	if len(parts) == 2 {
+		d, err := time.ParseInLocation("2006-01-02", parts[1], time.Local)
+		if err != nil {
+			a.send(chatID, "❌ Sana formati: YYYY-MM-DD")
+			return
+		}
+		to, toLabel = d.AddDate(0, 0, 1), parts[1]
+	}
+	if !to.After(from) {
+		a.send(chatID, "❌ Davr noto'g'ri: boshlanish sanasi tugash sanasidan keyin.")
 		return
 	}
-	ctx := context.Background()
-	plainPass, err := services.AddDriverDirect(ctx, tgUserID)
+	list, err := services.GetDriverOnlineHours(context.Background(), from, to)
 	if err != nil {
 		a.send(chatID, "❌ "+err.Error())
 		return
 	}
-	a.send(chatID, fmt.Sprintf("✅ Haydovchi qo'shildi (tg_user_id=%d).\n\n🔑 Parol: %s\n\nBu parolni haydovchiga yuboring. U driver botda /login qiladi.", tgUserID, plainPass))
+	if len(list) == 0 {
+		a.send(chatID, "📭 Bu davrda onlayn bo'lgan haydovchi yo'q.")
+		return
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("🕘 Haydovchilar onlayn vaqti (%s — %s):\n\n", from.Format("2006-01-02"), toLabel))
+	for _, r := range list {
+		mins := int(r.Online.Minutes())
+		b.WriteString(fmt.Sprintf("• %s (tg_user_id=%d): %d soat %d daqiqa\n", r.FullName, r.TgUserID, mins/60, mins%60))
+	}
+	a.send(chatID, b.String())
 }
 
 func (a *AdderBot) handleSubsPending(chatID int64) {
@@ -661,7 +1803,7 @@ func (a *AdderBot) handleRenew(chatID int64, superadminID int64, args string) {
 			amount = &v
 		}
 	}
-	ctx := context.Background()
+	ctx := a.auditCtx(superadminID)
 	newPass, err := services.RenewSubscription(ctx, tgUserID, role, days, superadminID, amount, "")
 	if err != nil {
 		a.send(chatID, "❌ "+err.Error())
@@ -695,7 +1837,7 @@ func (a *AdderBot) handleResetPassword(chatID int64, args string) {
 		a.send(chatID, "❌ restaurant_id musbat raqam bo'lishi kerak.")
 		return
 	}
-	ctx := context.Background()
+	ctx := a.auditCtx(a.superAdminID)
 	newPass, primaryTgUserID, err := services.ResetBranchAdminPassword(ctx, branchLocationID)
 	if err != nil {
 		a.send(chatID, "❌ "+err.Error())
@@ -756,7 +1898,7 @@ func (a *AdderBot) handlePause(chatID int64, args string) {
 		a.send(chatID, "❌ role: restaurant_admin yoki driver")
 		return
 	}
-	ctx := context.Background()
+	ctx := a.auditCtx(a.superAdminID)
 	if err := services.PauseSubscription(ctx, tgUserID, role); err != nil {
 		a.send(chatID, "❌ "+err.Error())
 		return
@@ -780,7 +1922,7 @@ func (a *AdderBot) handleUnpause(chatID int64, args string) {
 		a.send(chatID, "❌ role: restaurant_admin yoki driver")
 		return
 	}
-	ctx := context.Background()
+	ctx := a.auditCtx(a.superAdminID)
 	if err := services.UnpauseSubscription(ctx, tgUserID, role); err != nil {
 		a.send(chatID, "❌ "+err.Error())
 		return
@@ -788,6 +1930,258 @@ func (a *AdderBot) handleUnpause(chatID int64, args string) {
 	a.send(chatID, fmt.Sprintf("✅ Abonement davom ettirildi (tg_user_id=%d, role=%s).", tgUserID, role))
 }
 
+// branchCan reports whether a logged-in branch user's role grants perm (superadmin never manages a branch menu).
+func (a *AdderBot) branchCan(userID int64, perm string) bool {
+	if a.getRole(userID) != "branch" {
+		return false
+	}
+	_, role, err := services.GetBranchRole(context.Background(), userID)
+	if err != nil {
+		log.Printf("adder: branch role user=%d: %v", userID, err)
+		return false
+	}
+	return services.BranchRoleCan(role, perm)
+}
+
+// handleBranchStaffCommand handles /staff, /staff_add, /staff_remove (owner) and /on_shift, /off_shift (any staff). Returns true if handled.
+func (a *AdderBot) handleBranchStaffCommand(chatID int64, userID int64, text string) bool {
+	cmd := strings.Fields(text)
+	if len(cmd) == 0 {
+		return false
+	}
+	switch cmd[0] {
+	case "/staff", "/staff_add", "/staff_remove", "/on_shift", "/off_shift":
+	default:
+		return false
+	}
+	ctx := a.auditCtx(userID)
+	locID, role, err := services.GetBranchRole(ctx, userID)
+	if err != nil || locID == 0 {
+		a.send(chatID, "❌ Filial topilmadi.")
+		return true
+	}
+	if cmd[0] == "/on_shift" || cmd[0] == "/off_shift" {
+		onShift := cmd[0] == "/on_shift"
+		if err := services.SetBranchStaffOnShift(ctx, locID, userID, onShift); err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return true
+		}
+		if onShift {
+			a.send(chatID, "✅ Smenadasiz: yangi buyurtmalar sizga keladi.")
+		} else {
+			a.send(chatID, "✅ Smena tugadi: yangi buyurtmalar sizga kelmaydi.")
+		}
+		return true
+	}
+	if !services.BranchRoleCan(role, services.BranchPermManageStaff) {
+		a.send(chatID, "❌ Xodimlarni faqat filial egasi boshqaradi.")
+		return true
+	}
+	switch cmd[0] {
+	case "/staff":
+		a.sendBranchStaffList(chatID, locID)
+	case "/staff_add":
+		if len(cmd) < 3 {
+			a.send(chatID, "Ishlatish: /staff_add <tg_user_id> <manager|cashier> [uz|ru]")
+			return true
+		}
+		staffID, err := strconv.ParseInt(cmd[1], 10, 64)
+		if err != nil || staffID <= 0 {
+			a.send(chatID, "❌ tg_user_id musbat raqam bo'lishi kerak.")
+			return true
+		}
+		staffRole := strings.ToLower(cmd[2])
+		orderLang := "uz"
+		if len(cmd) > 3 {
+			orderLang = strings.ToLower(cmd[3])
+		}
+		plain, err := services.AddBranchStaff(ctx, locID, staffID, userID, staffRole, orderLang)
+		if err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return true
+		}
+		if plain == "" {
+			a.send(chatID, fmt.Sprintf("✅ Xodim qo'shildi (tg_user_id=%d, rol=%s).\nU o'zining mavjud paroli bilan kiradi.", staffID, staffRole))
+			return true
+		}
+		a.send(chatID, fmt.Sprintf("✅ Xodim qo'shildi (tg_user_id=%d, rol=%s).\n🔑 Parol: %s\nParolni xodimga bering: u shu bot orqali o'z Telegram akkauntidan kiradi va /password bilan o'zgartiradi.", staffID, staffRole, plain))
+	case "/staff_remove":
+		if len(cmd) < 2 {
+			a.send(chatID, "Ishlatish: /staff_remove <tg_user_id>")
+			return true
+		}
+		staffID, err := strconv.ParseInt(cmd[1], 10, 64)
+		if err != nil || staffID <= 0 {
+			a.send(chatID, "❌ tg_user_id musbat raqam bo'lishi kerak.")
+			return true
+		}
+		if err := services.RemoveBranchStaff(ctx, locID, staffID); err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return true
+		}
+		a.endSession(staffID, services.AdminSessionEventRevoked, userID)
+		a.send(chatID, fmt.Sprintf("✅ Xodim o'chirildi (tg_user_id=%d).", staffID))
+	}
+	return true
+}
+
+func (a *AdderBot) sendBranchStaffList(chatID int64, locID int64) {
+	staff, err := services.ListBranchStaff(context.Background(), locID)
+	if err != nil {
+		a.send(chatID, "❌ "+err.Error())
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("👥 Filial xodimlari:\n")
+	for _, s := range staff {
+		shift := "🟢 smenada"
+		if !s.OnShift {
+			shift = "⚪️ smenada emas"
+		}
+		sb.WriteString(fmt.Sprintf("\n• %d — %s, %s, %s", s.AdminUserID, s.Role, shift, s.OrderLang))
+	}
+	sb.WriteString("\n\nQo'shish: /staff_add <tg_user_id> <manager|cashier> [uz|ru]\nO'chirish: /staff_remove <tg_user_id>")
+	a.send(chatID, sb.String())
+}
+
+// beginBranchLogin finishes a branch password login, or asks for the authenticator code first when the user enabled 2FA.
+// Returns true if the user is now logged in.
+func (a *AdderBot) beginBranchLogin(chatID int64, userID int64, locID int64) bool {
+	ctx := context.Background()
+	enabled, err := services.BranchAdminTOTPEnabled(ctx, userID)
+	if err != nil {
+		log.Printf("adder: totp status user=%d: %v", userID, err)
+		a.send(chatID, "❌ Xatolik yuz berdi. Qayta urinib ko'ring.")
+		return false
+	}
+	if enabled {
+		a.stateMu.Lock()
+		a.pendingTOTP[userID] = locID
+		a.stateMu.Unlock()
+		a.send(chatID, "🔐 Authenticator ilovasidagi 6 xonali kodni yuboring. Bekor qilish: /cancel")
+		return false
+	}
+	a.finishBranchLogin(userID, locID)
+	return true
+}
+
+func (a *AdderBot) finishBranchLogin(userID int64, locID int64) {
+	if err := services.RecordBranchAdminLogin(context.Background(), userID, locID); err != nil {
+		log.Printf("adder: record branch login user=%d: %v", userID, err)
+	}
+	a.setLoggedIn(userID, "branch", locID)
+	a.stateMu.Lock()
+	a.activeLocation[userID] = locID
+	a.stateMu.Unlock()
+}
+
+// handlePendingTOTP checks the authenticator code of a user whose password was already accepted. Returns true if handled.
+func (a *AdderBot) handlePendingTOTP(chatID int64, userID int64, text string) bool {
+	a.stateMu.RLock()
+	locID, pending := a.pendingTOTP[userID]
+	a.stateMu.RUnlock()
+	if !pending {
+		return false
+	}
+	ctx := context.Background()
+	if wait, _ := services.LoginThrottleWaitSeconds(ctx, userID, services.ThrottleRoleRestaurantAdmin); wait > 0 {
+		a.send(chatID, fmt.Sprintf("⏳ Iltimos %d soniya kutib qayta urinib ko'ring.", wait))
+		return true
+	}
+	ok, err := services.VerifyBranchAdminTOTP(ctx, userID, text)
+	if err != nil || !ok {
+		_ = services.RecordLoginFailed(ctx, userID, services.ThrottleRoleRestaurantAdmin)
+		a.send(chatID, "❌ Kod noto'g'ri yoki ishlatilgan. Yangi kodni yuboring. Bekor qilish: /cancel")
+		return true
+	}
+	a.stateMu.Lock()
+	delete(a.pendingTOTP, userID)
+	a.stateMu.Unlock()
+	a.finishBranchLogin(userID, locID)
+	a.send(chatID, "✅ Kirish muvaffaqiyatli.")
+	a.sendAdminPanel(chatID, userID)
+	return true
+}
+
+// deleteTOTPSecretMessage removes the /2fa_on message with the user's authenticator secret, if one is still in the chat.
+func (a *AdderBot) deleteTOTPSecretMessage(chatID int64, userID int64) {
+	a.stateMu.Lock()
+	msgID, ok := a.totpSecretMsg[userID]
+	delete(a.totpSecretMsg, userID)
+	a.stateMu.Unlock()
+	if !ok {
+		return
+	}
+	if err := a.api.DeleteMessage(chatID, msgID); err != nil {
+		log.Printf("adder delete 2fa secret message: %v", err)
+	}
+}
+
+// handleBranchSecurityCommand handles /password, /2fa_on, /2fa_confirm and /2fa_off for logged-in branch staff. Returns true if handled.
+func (a *AdderBot) handleBranchSecurityCommand(msg *tgbotapi.Message, userID int64, text string) bool {
+	cmd := strings.Fields(text)
+	if len(cmd) == 0 {
+		return false
+	}
+	chatID := msg.Chat.ID
+	ctx := a.auditCtx(userID)
+	switch cmd[0] {
+	case "/password":
+		// The message holds passwords: remove it from the chat whatever the outcome.
+		_ = a.api.DeleteMessage(chatID, msg.MessageID)
+		if len(cmd) != 3 {
+			a.send(chatID, "Ishlatish: /password <joriy_parol> <yangi_parol>\nYangi parol: kamida 8 belgi, harf va raqam.")
+			return true
+		}
+		if err := services.ChangeBranchAdminPassword(ctx, userID, cmd[1], cmd[2]); err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return true
+		}
+		a.send(chatID, "✅ Parol o'zgartirildi.")
+	case "/2fa_on":
+		secret, err := services.BeginTOTPEnrollment(ctx, userID)
+		if err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return true
+		}
+		uri := services.TOTPProvisioningURI(secret, a.client.Self.UserName, strconv.FormatInt(userID, 10))
+		a.deleteTOTPSecretMessage(chatID, userID) // a repeated /2fa_on replaces the secret
+		sent, err := a.api.SendMessage(tgbotapi.NewMessage(chatID, "🔐 Authenticator ilovasiga (Google Authenticator, Authy...) kalitni qo'shing:\n\n"+secret+"\n\n"+uri+
+			"\n\nSo'ng ilovadagi kodni yuboring: /2fa_confirm <kod>\n\n⚠️ Bu xabar kod tasdiqlangach o'chiriladi. Tasdiqlamasangiz, uni o'zingiz o'chiring."))
+		if err != nil {
+			log.Printf("adder send 2fa secret: %v", err)
+			return true
+		}
+		a.stateMu.Lock()
+		a.totpSecretMsg[userID] = sent.MessageID
+		a.stateMu.Unlock()
+	case "/2fa_confirm":
+		if len(cmd) != 2 {
+			a.send(chatID, "Ishlatish: /2fa_confirm <kod>")
+			return true
+		}
+		if err := services.ConfirmTOTPEnrollment(ctx, userID, cmd[1]); err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return true
+		}
+		a.deleteTOTPSecretMessage(chatID, userID)
+		a.send(chatID, "✅ 2FA yoqildi. Endi kirishda paroldan keyin kod so'raladi. Kalit yozilgan xabar o'chirildi.")
+	case "/2fa_off":
+		if len(cmd) != 2 {
+			a.send(chatID, "Ishlatish: /2fa_off <kod>")
+			return true
+		}
+		if err := services.DisableBranchAdminTOTP(ctx, userID, cmd[1]); err != nil {
+			a.send(chatID, "❌ "+err.Error())
+			return true
+		}
+		a.send(chatID, "✅ 2FA o'chirildi.")
+	default:
+		return false
+	}
+	return true
+}
+
 func (a *AdderBot) handleStart(chatID int64, userID int64) {
 	// If already logged in, show the panel instead of asking for password again.
 	if a.isLoggedIn(userID) {
@@ -799,7 +2193,7 @@ func (a *AdderBot) handleStart(chatID int64, userID int64) {
 			if locFlow.Step == "admin_id" {
 				a.send(chatID, "ℹ️ Siz hozir filial qo'shish jarayonidasiz.\n\n👤 Iltimos, *branch admin* ning Telegram user ID raqamini yuboring.\nAgar bekor qilmoqchi bo'lsangiz: /cancel")
 			} else {
-				a.send(chatID, "🔑 Send the unique password for this branch admin (must not be used by any other branch admin).\nCancel: /cancel")
+				a.send(chatID, "🔑 Send the password for this branch owner.\nCancel: /cancel")
 			}
 			return
 		}
@@ -807,7 +2201,7 @@ func (a *AdderBot) handleStart(chatID int64, userID int64) {
 			if addFlow.Step == "admin_id" {
 				a.send(chatID, "👤 Send the Telegram user ID of the new branch admin.")
 			} else if addFlow.Step == "password" {
-				a.send(chatID, "🔑 Send the unique password for this branch admin.")
+				a.send(chatID, "🔑 Send the password for this branch owner.")
 			} else if addFlow.Step == "order_lang" {
 				kb := tgbotapi.NewInlineKeyboardMarkup(
 					tgbotapi.NewInlineKeyboardRow(
@@ -833,8 +2227,11 @@ func (a *AdderBot) adminKeyboard(userID int64) tgbotapi.InlineKeyboardMarkup {
 	a.stateMu.RUnlock()
 	role := a.getRole(userID)
 
-	// Branch admin: only their place — add/list/delete menu items (no location switch, no add location).
+	// Branch admin: only their place — add/list/delete menu items (no location switch, no add location). Cashiers only handle orders.
 	if role == "branch" {
+		if !a.branchCan(userID, services.BranchPermManageMenu) {
+			return tgbotapi.NewInlineKeyboardMarkup()
+		}
 		rows := [][]tgbotapi.InlineKeyboardButton{
 			{
 				tgbotapi.NewInlineKeyboardButtonData("🍽 Add Food", "adder:add:food"),
@@ -925,7 +2322,18 @@ func (a *AdderBot) sendAdminPanel(chatID int64, userID int64) {
 		if name, err := services.GetLocationName(context.Background(), locID); err == nil && name != "" {
 			locLabel = name
 		}
+		if !a.branchCan(userID, services.BranchPermManageMenu) {
+			a.send(chatID, fmt.Sprintf("📋 %s\n\nBuyurtmalar xabar botiga keladi.\nSmena: /on_shift yoki /off_shift\n📊 Hisobot: /report · Kun yakuni: /summary_off · ⭐ Baholar: /ratings\n🔐 Parol: /password · 2FA: /2fa_on", locLabel))
+			return
+		}
 		text := fmt.Sprintf("📋 Admin — %s\n\nAdd or delete menu items for your place. Choose an action below:", locLabel)
+		if a.branchCan(userID, services.BranchPermManageStaff) {
+			text += "\n\n👥 Xodimlar: /staff"
+		}
+		text += "\n📊 Hisobot: /report [dan] [gacha] · Eksport: /export <orders|items|deliveries|payments>"
+		text += "\n🌙 Kun yakuni xabari: /summary_off · /summary_on"
+		text += "\n⭐ Mijoz baholari: /ratings"
+		text += "\n🔐 Parol: /password · 2FA: /2fa_on"
 		a.sendWithInline(chatID, text, a.adminKeyboard(userID))
 		return
 	}
@@ -984,7 +2392,7 @@ func (a *AdderBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 	userID := cq.From.ID
 	data := cq.Data
 
-	a.api.Request(tgbotapi.NewCallback(cq.ID, ""))
+	a.api.AnswerCallback(tgbotapi.NewCallback(cq.ID, ""))
 
 	// Expired subscription: user sends renewal request to superadmin
 	if data == "exp_contact" {
@@ -1010,7 +2418,7 @@ func (a *AdderBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 			a.send(chatID, "❌ Noto'g'ri role.")
 			return
 		}
-		ctx := context.Background()
+		ctx := a.auditCtx(userID)
 		newPass, err := services.RenewSubscription(ctx, tgUserID, role, 1, userID, nil, "")
 		if err != nil {
 			a.send(chatID, "❌ "+err.Error())
@@ -1045,6 +2453,38 @@ func (a *AdderBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 		return
 	}
 
+	// Driver document review (superadmin only)
+	if strings.HasPrefix(data, "drv_review:") || strings.HasPrefix(data, "drv_approve:") || strings.HasPrefix(data, "drv_reject:") {
+		if a.superAdminID == 0 || userID != a.superAdminID {
+			return
+		}
+		action, driverID, _ := strings.Cut(data, ":")
+		a.handleDriverReviewCallback(chatID, userID, action, driverID)
+		return
+	}
+
+	// Revoke an adder session (superadmin only)
+	if strings.HasPrefix(data, "sess_revoke:") {
+		if a.superAdminID == 0 || userID != a.superAdminID {
+			return
+		}
+		target, err := strconv.ParseInt(strings.TrimPrefix(data, "sess_revoke:"), 10, 64)
+		if err != nil || target <= 0 {
+			a.send(chatID, "❌ Noto'g'ri format.")
+			return
+		}
+		if !a.endSession(target, services.AdminSessionEventRevoked, userID) {
+			a.send(chatID, "ℹ️ Bu sessiya allaqachon tugagan.")
+			return
+		}
+		a.clearFlows(target)
+		if target != userID {
+			a.send(target, sessionEndedText(services.AdminSessionEventRevoked))
+		}
+		a.send(chatID, fmt.Sprintf("✅ Sessiya yakunlandi (tg_user_id=%d).", target))
+		return
+	}
+
 	if data == "apply_start" {
 		a.send(chatID, "📋 Ariza yuborish uchun Zayavka botidan foydalaning.")
 		return
@@ -1093,8 +2533,8 @@ func (a *AdderBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 			a.send(chatID, "Please select a location first (📍 Select Location).")
 			return
 		}
-		ctx := context.Background()
-		if err := services.RemoveAllBranchAdminsForLocation(ctx, locID); err != nil {
+		ctx := a.auditCtx(userID)
+		if err := services.RemoveBranchOwners(ctx, locID); err != nil {
 			a.send(chatID, "❌ Failed to remove previous admin(s): "+err.Error())
 			return
 		}
@@ -1116,7 +2556,7 @@ func (a *AdderBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 			a.send(chatID, "❌ Session expired. Please start again from Add Branch Admin.")
 			return
 		}
-		ctx := context.Background()
+		ctx := a.auditCtx(userID)
 		if err := services.AddBranchAdmin(ctx, ab.LocationID, ab.PendingAdminID, userID, ab.PendingPasswordHash, langCode); err != nil {
 			a.send(chatID, "❌ Failed to add branch admin: "+err.Error())
 		} else {
@@ -1127,8 +2567,8 @@ func (a *AdderBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 		a.stateMu.Unlock()
 		return
 	case strings.HasPrefix(data, "adder:list:"):
-		if a.getRole(userID) != "branch" {
-			a.send(chatID, "Only branch admins can manage menu items. Big admin only manages locations.")
+		if !a.branchCan(userID, services.BranchPermManageMenu) {
+			a.send(chatID, "Only branch owners and managers can manage menu items. Big admin only manages locations.")
 			return
 		}
 		cat := strings.TrimPrefix(data, "adder:list:")
@@ -1137,8 +2577,8 @@ func (a *AdderBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 		}
 		return
 	case strings.HasPrefix(data, "adder:del:"):
-		if a.getRole(userID) != "branch" {
-			a.send(chatID, "Only branch admins can manage menu items.")
+		if !a.branchCan(userID, services.BranchPermManageMenu) {
+			a.send(chatID, "Only branch owners and managers can manage menu items.")
 			return
 		}
 		idStr := strings.TrimPrefix(data, "adder:del:")
@@ -1146,7 +2586,7 @@ func (a *AdderBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 		if err != nil {
 			return
 		}
-		ctx := context.Background()
+		ctx := a.auditCtx(userID)
 		if err := services.DeleteMenuItem(ctx, id); err != nil {
 			a.send(chatID, "Failed to delete: "+err.Error())
 			return
@@ -1193,7 +2633,7 @@ func (a *AdderBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 			a.send(chatID, "❌ Session expired. Please start again from Add Fast Food Location.")
 			return
 		}
-		ctx := context.Background()
+		ctx := a.auditCtx(userID)
 		locID, err := services.CreateLocationWithAdmin(ctx, st.Name, st.Lat, st.Lon, st.PendingAdminID, userID, st.PendingPasswordHash, langCode)
 		if err != nil {
 			a.send(chatID, "Failed to save location + admin: "+err.Error())
@@ -1221,7 +2661,7 @@ func (a *AdderBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 		a.clearLoggedIn(userID)
 		return
 	case data == "adder:del_location":
-		// Delete the currently active location (and its menu items & user bindings)
+		// Soft-delete the currently active location and its menu; the superadmin can /restore_location within the retention window
 		a.stateMu.RLock()
 		locID := a.activeLocation[userID]
 		a.stateMu.RUnlock()
@@ -1229,7 +2669,7 @@ func (a *AdderBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 			a.send(chatID, "Hech qanday faol filial tanlanmagan.")
 			return
 		}
-		ctx := context.Background()
+		ctx := a.auditCtx(userID)
 		if err := services.DeleteLocation(ctx, locID); err != nil {
 			a.send(chatID, "Filialni o'chirishda xatolik yuz berdi: "+err.Error())
 			return
@@ -1238,11 +2678,11 @@ func (a *AdderBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 		delete(a.activeLocation, userID)
 		a.stateMu.Unlock()
 		a.clearLoggedIn(userID)
-		a.send(chatID, "✅ Filial va uning menyusi o'chirildi. Yangi operatsiya uchun qayta parol kiriting.")
+		a.send(chatID, fmt.Sprintf("✅ Filial va uning menyusi o'chirildi. %d kun ichida /restore_location %d bilan tiklash mumkin. Yangi operatsiya uchun qayta parol kiriting.", a.cfg.Data.DeletedRetentionDays, locID))
 		return
 	case strings.HasPrefix(data, "adder:add:"):
-		if a.getRole(userID) != "branch" {
-			a.send(chatID, "Only branch admins can add menu items. Big admin only manages locations.")
+		if !a.branchCan(userID, services.BranchPermManageMenu) {
+			a.send(chatID, "Only branch owners and managers can add menu items. Big admin only manages locations.")
 			return
 		}
 		cat := strings.TrimPrefix(data, "adder:add:")
@@ -1273,11 +2713,11 @@ func (a *AdderBot) handleMenuAddFlow(msg *tgbotapi.Message, userID int64, text s
 	a.stateMu.RLock()
 	st := a.state[userID]
 	a.stateMu.RUnlock()
-	if st != nil && a.getRole(userID) != "branch" {
+	if st != nil && !a.branchCan(userID, services.BranchPermManageMenu) {
 		a.stateMu.Lock()
 		delete(a.state, userID)
 		a.stateMu.Unlock()
-		a.send(msg.Chat.ID, "Only branch admins can add menu items.")
+		a.send(msg.Chat.ID, "Only branch owners and managers can add menu items.")
 		return true
 	}
 
@@ -1296,7 +2736,7 @@ func (a *AdderBot) handleMenuAddFlow(msg *tgbotapi.Message, userID int64, text s
 			a.send(msg.Chat.ID, "Invalid price. Send a number (e.g. 15000).")
 			return true
 		}
-		ctx := context.Background()
+		ctx := a.auditCtx(userID)
 		// Must have a location; global items are not allowed
 		if st.LocationID <= 0 {
 			a.send(msg.Chat.ID, "Iltimos, avval menyu uchun filialni tanlang (\"📍 Select Location for Menu\").")
@@ -1338,7 +2778,7 @@ func (a *AdderBot) handleAddBranchAdminFlow(msg *tgbotapi.Message, userID int64,
 		a.stateMu.Lock()
 		a.addBranchAdmin[userID] = &addBranchAdminState{LocationID: ab.LocationID, PendingAdminID: adminID, Step: "password"}
 		a.stateMu.Unlock()
-		a.send(msg.Chat.ID, "🔑 Send the unique password for this branch admin (must not be used by any other branch admin). Cancel: /cancel")
+		a.send(msg.Chat.ID, "🔑 Send the password for this branch owner. They can change it later with /password. Cancel: /cancel")
 		return true
 	case "password":
 		password := strings.TrimSpace(text)
@@ -1414,7 +2854,7 @@ func (a *AdderBot) handleLocationAddFlow(msg *tgbotapi.Message, userID int64, te
 
 		resp := tgbotapi.NewMessage(msg.Chat.ID, "Now send the location of this fast food branch by pressing the button below.")
 		resp.ReplyMarkup = kb
-		if _, err := a.api.Send(resp); err != nil {
+		if _, err := a.api.SendMessage(resp); err != nil {
 			log.Printf("adder send error: %v", err)
 		}
 		return true
@@ -1438,7 +2878,7 @@ func (a *AdderBot) handleLocationAddFlow(msg *tgbotapi.Message, userID int64, te
 		// Remove reply keyboard, then show inline actions to assign admin.
 		removeKb := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("📍 Location received for \"%s\".\n\n⚠️ This branch will be saved *only after* you assign a branch admin.", st.Name))
 		removeKb.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
-		if _, err := a.api.Send(removeKb); err != nil {
+		if _, err := a.api.SendMessage(removeKb); err != nil {
 			log.Printf("adder send error: %v", err)
 		}
 
@@ -1451,7 +2891,7 @@ func (a *AdderBot) handleLocationAddFlow(msg *tgbotapi.Message, userID int64, te
 		a.sendWithInline(msg.Chat.ID, "Assign a branch admin to complete saving this location.", kb)
 		return true
 	case "admin_id":
-		// Expect numeric Telegram user ID for branch admin; then we ask for unique password.
+		// Expect numeric Telegram user ID for branch admin; then we ask for their password.
 		adminID, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
 		if err != nil || adminID <= 0 {
 			a.send(msg.Chat.ID, "❌ Invalid user ID. This step expects a *numeric Telegram user ID* (e.g. 123456789), not the admin password.\n\n💡 Ask the user to use @userinfobot to get their ID. Cancel: /cancel")
@@ -1462,12 +2902,12 @@ func (a *AdderBot) handleLocationAddFlow(msg *tgbotapi.Message, userID int64, te
 		a.stateMu.Lock()
 		a.locState[userID] = st
 		a.stateMu.Unlock()
-		a.send(msg.Chat.ID, "🔑 Send the unique password for this branch admin (must not be used by any other branch admin). They will use it to log in to the adder bot. Cancel: /cancel")
+		a.send(msg.Chat.ID, "🔑 Send the password for this branch owner. They will use it to log in to the adder bot and can change it with /password. Cancel: /cancel")
 		return true
 	case "password":
 		password := strings.TrimSpace(text)
 		if password == "" {
-			a.send(msg.Chat.ID, "❌ Password cannot be empty. Send a unique password for this branch admin.")
+			a.send(msg.Chat.ID, "❌ Password cannot be empty. Send a password for this branch owner.")
 			return true
 		}
 		passwordHash, err := services.HashBranchAdminPassword(password)
@@ -1494,11 +2934,10 @@ func (a *AdderBot) handleLocationAddFlow(msg *tgbotapi.Message, userID int64, te
 }
 
 func (a *AdderBot) cancelFlows(chatID int64, userID int64) {
-	a.stateMu.Lock()
-	delete(a.state, userID)
-	delete(a.locState, userID)
-	delete(a.addBranchAdmin, userID)
-	a.stateMu.Unlock()
+	a.clearFlows(userID)
+	if a.superAdminID != 0 && userID == a.superAdminID {
+		_ = services.ClearDriverReviewRejectInProgress(context.Background(), userID)
+	}
 
 	if a.isLoggedIn(userID) {
 		a.send(chatID, "✅ Cancelled. Admin panel opened.")
@@ -1509,6 +2948,16 @@ func (a *AdderBot) cancelFlows(chatID int64, userID int64) {
 	a.handleStart(chatID, userID)
 }
 
+// clearFlows drops any in-progress add/edit flow of the user.
+func (a *AdderBot) clearFlows(userID int64) {
+	a.stateMu.Lock()
+	delete(a.state, userID)
+	delete(a.locState, userID)
+	delete(a.addBranchAdmin, userID)
+	delete(a.pendingTOTP, userID)
+	a.stateMu.Unlock()
+}
+
 // sendSelectLocationList shows all locations so admin can pick an active one for menu items.
 func (a *AdderBot) sendSelectLocationList(chatID int64, userID int64) {
 	ctx := context.Background()
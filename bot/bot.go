@@ -96,11 +96,13 @@ type checkoutState struct {
 }
 
 type Bot struct {
-	api          *tgbotapi.BotAPI
-	messageBot   *tgbotapi.BotAPI // bot for sending order notifications (MESSAGE_TOKEN)
-	driverBotAPI *tgbotapi.BotAPI // for pushing READY orders to nearby drivers (DRIVER_BOT_TOKEN)
-	cfg          *config.Config
-	admin        int64
+	api           Messenger
+	client        *tgbotapi.BotAPI // polls updates for api
+	messageBot    Messenger        // bot for sending order notifications (MESSAGE_TOKEN)
+	messageClient *tgbotapi.BotAPI
+	driverBotAPI  Messenger // for pushing READY orders to nearby drivers (DRIVER_BOT_TOKEN)
+	cfg           *config.Config
+	admin         int64
 
 	locSuggestions   map[int64][]services.LocationWithDistance
 	locSuggestionsMu sync.RWMutex
@@ -111,29 +113,38 @@ type Bot struct {
 	userLang   map[int64]string // "uz" or "ru"
 	userLangMu sync.RWMutex
 
-	orderLocks sync.Map // map[orderID]*sync.Mutex, for per-order locking in RefreshOrderCards
+	orderLocks sync.Map // map[orderID]*sync.Mutex, so two outbox rows never edit the same card at once
+
+	ratingComments   map[int64]int64 // userID -> order the next message comments on
+	ratingCommentsMu sync.Mutex
+
+	outboxWake chan struct{} // KickOutbox -> notification outbox worker
 }
 
 func New(cfg *config.Config, adminUserID int64) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(cfg.Telegram.Token)
+	api, err := newBotAPI(cfg, cfg.Telegram.Token)
 	if err != nil {
 		return nil, err
 	}
 	bot := &Bot{
-		api:              api,
+		api:              messenger(api),
+		client:           api,
 		cfg:              cfg,
 		admin:            adminUserID,
 		locSuggestions:   make(map[int64][]services.LocationWithDistance),
 		userSharedCoords: make(map[int64]struct{ Lat, Lon float64 }),
 		userLang:         make(map[int64]string),
+		ratingComments:   make(map[int64]int64),
+		outboxWake:       make(chan struct{}, 1),
 	}
 	// Initialize message bot if MESSAGE_TOKEN is set
 	if cfg.Telegram.MessageToken != "" {
-		messageBot, err := tgbotapi.NewBotAPI(cfg.Telegram.MessageToken)
+		messageBot, err := newBotAPI(cfg, cfg.Telegram.MessageToken)
 		if err != nil {
 			log.Printf("warning: failed to initialize message bot: %v", err)
 		} else {
-			bot.messageBot = messageBot
+			bot.messageBot = messenger(messageBot)
+			bot.messageClient = messageBot
 		}
 	}
 	return bot, nil
@@ -141,30 +152,31 @@ func New(cfg *config.Config, adminUserID int64) (*Bot, error) {
 
 // GetAPI returns the main bot API (for driver bot to send customer notifications).
 func (b *Bot) GetAPI() *tgbotapi.BotAPI {
-	return b.api
+	return b.client
 }
 
 // GetMessageBot returns the message bot API (for driver bot to send admin notifications).
 func (b *Bot) GetMessageBot() *tgbotapi.BotAPI {
-	return b.messageBot
+	return b.messageClient
 }
 
 // SetDriverBotAPI sets the driver bot API so this bot can push READY orders to nearby drivers.
 func (b *Bot) SetDriverBotAPI(api *tgbotapi.BotAPI) {
-	b.driverBotAPI = api
+	b.driverBotAPI = messenger(api)
 }
 
-// apiForAudience returns the bot API used to send/edit messages for the given audience.
-func (b *Bot) apiForAudience(audience string) *tgbotapi.BotAPI {
+// apiForAudience returns the bot API used to send/edit order notifications for the given audience,
+// on the high send lane so order cards are not held up by other traffic.
+func (b *Bot) apiForAudience(audience string) Messenger {
 	switch audience {
 	case "admin":
-		return b.messageBot
+		return withSendPriority(b.messageBot, SendPriorityHigh)
 	case "customer":
-		return b.api
+		return withSendPriority(b.api, SendPriorityHigh)
 	case "driver":
-		return b.driverBotAPI
+		return withSendPriority(b.driverBotAPI, SendPriorityHigh)
 	default:
-		return b.api
+		return withSendPriority(b.api, SendPriorityHigh)
 	}
 }
 
@@ -189,21 +201,20 @@ func cardMarkup(c services.OrderCardContent) *tgbotapi.InlineKeyboardMarkup {
 	return &kb
 }
 
-// UpsertOrderCard edits the existing order card message if we have a pointer; otherwise sends new and saves pointer.
+// UpsertOrderCard edits the existing order card message in chatID if we have a pointer; otherwise sends new and saves pointer.
 // On "message not found" (e.g. deleted): send new message and upsert pointer.
-// On "message is not modified": ignore.
-func (b *Bot) UpsertOrderCard(ctx context.Context, audience string, orderID int64, chatID int64, content services.OrderCardContent) {
+// On "message is not modified": nothing to do. Other send errors are returned for the outbox to retry.
+func (b *Bot) UpsertOrderCard(ctx context.Context, audience string, orderID int64, chatID int64, content services.OrderCardContent) error {
 	api := b.apiForAudience(audience)
 	if api == nil {
-		return
+		return fmt.Errorf("%s bot is not configured", audience)
 	}
-	chatIDPtr, messageID, ok, err := services.GetOrderMessagePointer(ctx, orderID, audience)
+	messageID, ok, err := services.GetOrderMessagePointer(ctx, orderID, audience, chatID)
 	if err != nil {
-		log.Printf("UpsertOrderCard get pointer order_id=%d audience=%s: %v", orderID, audience, err)
-		return
+		return fmt.Errorf("get card pointer: %w", err)
 	}
 	if ok {
-		edit := tgbotapi.NewEditMessageText(chatIDPtr, messageID, content.Text)
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, content.Text)
 		edit.ParseMode = ""
 		if kb := cardMarkup(content); kb != nil {
 			edit.ReplyMarkup = kb
@@ -211,49 +222,34 @@ func (b *Bot) UpsertOrderCard(ctx context.Context, audience string, orderID int6
 			emptyKb := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
 			edit.ReplyMarkup = &emptyKb
 		}
-		_, err = api.Send(edit)
-		if err != nil {
-			errStr := err.Error()
-			if strings.Contains(errStr, "not found") || strings.Contains(errStr, "message to edit not found") {
-				// Fallback: send new and update pointer
-				msg := tgbotapi.NewMessage(chatIDPtr, content.Text)
-				if kb := cardMarkup(content); kb != nil {
-					msg.ReplyMarkup = *kb
-				}
-				sent, sendErr := api.Send(msg)
-				if sendErr != nil {
-					log.Printf("UpsertOrderCard fallback send order_id=%d audience=%s: %v", orderID, audience, sendErr)
-					return
-				}
-				_ = services.UpsertOrderMessagePointer(ctx, orderID, audience, chatIDPtr, sent.MessageID)
-				return
-			}
-			if strings.Contains(errStr, "not modified") {
-				return
-			}
-			log.Printf("UpsertOrderCard edit order_id=%d audience=%s: %v", orderID, audience, err)
-			return
+		err = api.EditMessage(edit)
+		if err == nil {
+			return services.UpsertOrderMessagePointer(ctx, orderID, audience, chatID, messageID)
 		}
-		_ = services.UpsertOrderMessagePointer(ctx, orderID, audience, chatIDPtr, messageID)
-		return
+		errStr := err.Error()
+		if strings.Contains(errStr, "not modified") {
+			return nil
+		}
+		if !strings.Contains(errStr, "not found") {
+			return err
+		}
+		// Fallback: the card was deleted, send a new one and update the pointer
 	}
-	// No pointer: send new and save
 	msg := tgbotapi.NewMessage(chatID, content.Text)
 	if kb := cardMarkup(content); kb != nil {
 		msg.ReplyMarkup = *kb
 	}
-	sent, err := api.Send(msg)
+	sent, err := api.SendMessage(msg)
 	if err != nil {
-		log.Printf("UpsertOrderCard send order_id=%d audience=%s: %v", orderID, audience, err)
-		return
+		return err
 	}
-	_ = services.UpsertOrderMessagePointer(ctx, orderID, audience, chatID, sent.MessageID)
+	return services.UpsertOrderMessagePointer(ctx, orderID, audience, chatID, sent.MessageID)
 }
 
 // AnswerCallbackQuery sends a short toast for the callback (no new message).
 func (b *Bot) AnswerCallbackQuery(callbackQueryID, text string) {
 	if b.messageBot != nil {
-		b.messageBot.Request(tgbotapi.NewCallback(callbackQueryID, text))
+		b.messageBot.AnswerCallback(tgbotapi.NewCallback(callbackQueryID, text))
 	}
 }
 
@@ -265,70 +261,12 @@ func (b *Bot) lockOrder(orderID int64) func() {
 	return mu.Unlock
 }
 
-// RefreshOrderCards updates the order card for admin, customer, and (if assigned) driver. Call after any order change (e.g. from driver bot).
-func (b *Bot) RefreshOrderCards(ctx context.Context, orderID int64) {
-	unlock := b.lockOrder(orderID)
-	defer unlock()
-
-	o, err := services.GetOrder(ctx, orderID)
-	if err != nil || o == nil {
-		return
-	}
-	var driver *services.Driver
-	if o.DriverID != nil && *o.DriverID != "" {
-		driver, _ = services.GetDriverByID(ctx, *o.DriverID)
-	}
-
-	// Admin card: chat from pointer or first branch admin
-	adminChatID, _, ok, _ := services.GetOrderMessagePointer(ctx, orderID, "admin")
-	if !ok {
-		admins, _ := services.GetBranchAdmins(ctx, o.LocationID)
-		if len(admins) > 0 {
-			adminChatID = admins[0]
-		}
-	}
-	if adminChatID != 0 {
-		adminLang, _ := services.GetAdminOrderLang(ctx, adminChatID)
-		if adminLang == "" {
-			adminLang = lang.Uz
-		}
-		content := services.BuildAdminCard(o, driver, adminLang)
-		b.UpsertOrderCard(ctx, "admin", orderID, adminChatID, content)
-	}
-
-	// Customer card
-	if o.ChatID != "" {
-		if customerChatID, parseErr := strconv.ParseInt(o.ChatID, 10, 64); parseErr == nil {
-			var trackURL string
-			if o.Status == services.OrderStatusDelivering && driver != nil {
-				loc, _ := services.GetDriverLocation(ctx, driver.ID)
-				if loc != nil {
-					trackURL = fmt.Sprintf("https://www.google.com/maps?q=%f,%f", loc.Lat, loc.Lon)
-				}
-			}
-			content := services.BuildCustomerCard(o, driver, trackURL)
-			b.UpsertOrderCard(ctx, "customer", orderID, customerChatID, content)
-		}
-	}
-
-	// Driver card (only when driver assigned)
-	if driver != nil {
-		driverLang := lang.Uz
-		content := services.BuildDriverCard(o, driverLang)
-		b.UpsertOrderCard(ctx, "driver", orderID, driver.ChatID, content)
-	}
-}
-
 func (b *Bot) setBotCommands() error {
-	cfg := tgbotapi.SetMyCommandsConfig{
-		Commands: []tgbotapi.BotCommand{
-			{Command: "start", Description: "Bosh sahifa"},
-			{Command: "language", Description: "Tilni o'zgartirish"},
-			{Command: "orders", Description: "Buyurtmalarim"},
-		},
-	}
-	_, err := b.api.Request(cfg)
-	return err
+	return b.api.SetCommands(
+		tgbotapi.BotCommand{Command: "start", Description: "Bosh sahifa"},
+		tgbotapi.BotCommand{Command: "language", Description: "Tilni o'zgartirish"},
+		tgbotapi.BotCommand{Command: "orders", Description: "Buyurtmalarim"},
+	)
 }
 
 func (b *Bot) Start() {
@@ -339,66 +277,75 @@ func (b *Bot) Start() {
 	}
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
-	updates := b.api.GetUpdatesChan(u)
+	updates := b.client.GetUpdatesChan(u)
 
 	for update := range updates {
-		if update.CallbackQuery != nil {
-			b.handleCallback(update.CallbackQuery)
-			continue
-		}
-		if update.Message == nil {
-			continue
-		}
-		msg := update.Message
-		userID := msg.From.ID
-		text := strings.TrimSpace(msg.Text)
+		b.HandleUpdate(update)
+	}
+}
 
-		// Handle shared location (from user flow, not admin adder)
-		if msg.Location != nil {
-			b.handleUserLocation(msg.Chat.ID, userID, msg.Location.Latitude, msg.Location.Longitude)
-			continue
-		}
+// HandleUpdate handles one update of the customer bot (Start feeds it the polled updates).
+func (b *Bot) HandleUpdate(update tgbotapi.Update) {
+	if update.CallbackQuery != nil {
+		b.handleCallback(update.CallbackQuery)
+		return
+	}
+	if update.Message == nil {
+		return
+	}
+	msg := update.Message
+	userID := msg.From.ID
+	text := strings.TrimSpace(msg.Text)
 
-		switch {
-		case text == "/start":
-			b.handleStart(msg.Chat.ID, userID)
-		case text == "/language":
-			b.handleLanguage(msg.Chat.ID, userID)
-		case text == "/orders":
-			b.handleOrders(msg.Chat.ID, userID)
-		case text == "/menu":
-			b.userSharedCoordsMu.RLock()
-			_, hasLocation := b.userSharedCoords[userID]
-			b.userSharedCoordsMu.RUnlock()
-			if !hasLocation {
-				b.sendLang(msg.Chat.ID, userID, "please_share_loc")
-				b.showWelcomeWithLocation(msg.Chat.ID, userID, b.getLang(userID))
-			} else {
-				b.sendMenu(msg.Chat.ID, userID)
-			}
-		case msg.Contact != nil:
-			username := ""
-			if msg.From != nil && msg.From.UserName != "" {
-				username = msg.From.UserName
-			}
-			b.handleContact(msg.Chat.ID, userID, msg.Contact.PhoneNumber, username)
-		case strings.HasPrefix(text, "/override"):
-			b.handleOverride(msg.Chat.ID, userID, text)
-		case strings.HasPrefix(text, "/stats"):
-			b.handleStats(msg.Chat.ID, userID, text)
-		case strings.HasPrefix(text, "/promote"):
-			b.handlePromote(msg.Chat.ID, userID, text)
-		case strings.HasPrefix(text, "/list_admins"):
-			b.handleListAdmins(msg.Chat.ID, userID, text)
-		case strings.HasPrefix(text, "/remove_admin"):
-			b.handleRemoveAdmin(msg.Chat.ID, userID, text)
+	if b.handleRatingComment(msg) {
+		return
+	}
+
+	// Handle shared location (from user flow, not admin adder)
+	if msg.Location != nil {
+		b.handleUserLocation(msg.Chat.ID, userID, msg.Location.Latitude, msg.Location.Longitude)
+		return
+	}
+
+	switch {
+	case text == "/start":
+		b.handleStart(msg.Chat.ID, userID)
+	case text == "/language":
+		b.handleLanguage(msg.Chat.ID, userID)
+	case text == "/orders":
+		b.handleOrders(msg.Chat.ID, userID)
+	case text == "/menu":
+		b.userSharedCoordsMu.RLock()
+		_, hasLocation := b.userSharedCoords[userID]
+		b.userSharedCoordsMu.RUnlock()
+		if !hasLocation {
+			b.sendLang(msg.Chat.ID, userID, "please_share_loc")
+			b.showWelcomeWithLocation(msg.Chat.ID, userID, b.getLang(userID))
+		} else {
+			b.sendMenu(msg.Chat.ID, userID)
 		}
+	case msg.Contact != nil:
+		username := ""
+		if msg.From != nil && msg.From.UserName != "" {
+			username = msg.From.UserName
+		}
+		b.handleContact(msg.Chat.ID, userID, msg.Contact.PhoneNumber, username)
+	case strings.HasPrefix(text, "/override"):
+		b.handleOverride(msg.Chat.ID, userID, text)
+	case strings.HasPrefix(text, "/stats"):
+		b.handleStats(msg.Chat.ID, userID, text)
+	case strings.HasPrefix(text, "/promote"):
+		b.handlePromote(msg.Chat.ID, userID, text)
+	case strings.HasPrefix(text, "/list_admins"):
+		b.handleListAdmins(msg.Chat.ID, userID, text)
+	case strings.HasPrefix(text, "/remove_admin"):
+		b.handleRemoveAdmin(msg.Chat.ID, userID, text)
 	}
 }
 
 func (b *Bot) send(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.api.SendMessage(msg); err != nil {
 		log.Printf("send error: %v", err)
 	}
 }
@@ -438,7 +385,7 @@ func (b *Bot) sendLang(chatID int64, userID int64, key string, args ...interface
 func (b *Bot) sendWithInline(chatID int64, text string, kb tgbotapi.InlineKeyboardMarkup) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = kb
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.api.SendMessage(msg); err != nil {
 		log.Printf("send error: %v", err)
 	}
 }
@@ -456,7 +403,7 @@ func (b *Bot) handleStart(chatID int64, userID int64) {
 		)
 		msg := tgbotapi.NewMessage(chatID, lang.T(lang.Uz, "choose_lang_both"))
 		msg.ReplyMarkup = kb
-		if _, err := b.api.Send(msg); err != nil {
+		if _, err := b.api.SendMessage(msg); err != nil {
 			log.Printf("send error: %v", err)
 		}
 		return
@@ -481,7 +428,7 @@ func (b *Bot) requestLocationOnly(chatID int64, userID int64) {
 	kb.ResizeKeyboard = true
 	msg := tgbotapi.NewMessage(chatID, lang.T(l, "request_location_only"))
 	msg.ReplyMarkup = kb
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.api.SendMessage(msg); err != nil {
 		log.Printf("send error: %v", err)
 	}
 }
@@ -499,7 +446,7 @@ func (b *Bot) handleLanguage(chatID int64, userID int64) {
 	)
 	msg := tgbotapi.NewMessage(chatID, lang.T(l, "choose_lang_both"))
 	msg.ReplyMarkup = kb
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.api.SendMessage(msg); err != nil {
 		log.Printf("send error: %v", err)
 	}
 }
@@ -545,7 +492,7 @@ func (b *Bot) showWelcomeWithLocation(chatID int64, userID int64, langCode strin
 	kb.ResizeKeyboard = true
 	msg := tgbotapi.NewMessage(chatID, lang.T(l, "welcome"))
 	msg.ReplyMarkup = kb
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.api.SendMessage(msg); err != nil {
 		log.Printf("send error: %v", err)
 	}
 }
@@ -634,7 +581,7 @@ func (b *Bot) sendMenu(chatID int64, userID int64) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
 	msg.ReplyMarkup = b.categoryKeyboard(l)
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.api.SendMessage(msg); err != nil {
 		log.Printf("send error: %v", err)
 	}
 }
@@ -665,7 +612,7 @@ func (b *Bot) sendCategoryMenu(chatID int64, userID int64, category string) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
 	msg.ReplyMarkup = b.menuKeyboard(userID, category, l)
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.api.SendMessage(msg); err != nil {
 		log.Printf("send error: %v", err)
 	}
 }
@@ -675,7 +622,7 @@ func (b *Bot) handleCallback(cq *tgbotapi.CallbackQuery) {
 	userID := cq.From.ID
 	data := cq.Data
 
-	b.api.Request(tgbotapi.NewCallback(cq.ID, ""))
+	b.api.AnswerCallback(tgbotapi.NewCallback(cq.ID, ""))
 
 	switch {
 	case data == "lang:uz" || data == "lang:ru":
@@ -709,6 +656,10 @@ func (b *Bot) handleCallback(cq *tgbotapi.CallbackQuery) {
 		} else {
 			b.sendMenu(chatID, userID)
 		}
+	case strings.HasPrefix(data, "rate:"):
+		b.handleRateCallback(chatID, userID, data)
+	case strings.HasPrefix(data, "rate_comment:"):
+		b.handleRateCommentCallback(chatID, userID, data)
 	case strings.HasPrefix(data, "locsel:"):
 		// User selected a specific fast food location
 		idStr := strings.TrimPrefix(data, "locsel:")
@@ -853,7 +804,7 @@ func (b *Bot) addToCart(chatID int64, userID int64, itemID string, category stri
 	edit := tgbotapi.NewEditMessageText(chatID, editMsgID, text)
 	edit.ParseMode = "Markdown"
 	edit.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: b.menuKeyboard(userID, category, l).InlineKeyboard}
-	if _, err := b.api.Send(edit); err != nil {
+	if err := b.api.EditMessage(edit); err != nil {
 		log.Printf("edit error: %v", err)
 	}
 }
@@ -929,7 +880,7 @@ func (b *Bot) sendSuggestionScreen(chatID int64, userID int64) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
 	msg.ReplyMarkup = kb
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.api.SendMessage(msg); err != nil {
 		log.Printf("send error: %v", err)
 	}
 }
@@ -975,7 +926,7 @@ func (b *Bot) requestPhone(chatID int64, userID int64) {
 
 	msg := tgbotapi.NewMessage(chatID, lang.T(l, "request_phone", checkout.ItemsTotal))
 	msg.ReplyMarkup = kb
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.api.SendMessage(msg); err != nil {
 		log.Printf("send error: %v", err)
 	}
 }
@@ -1039,15 +990,25 @@ func (b *Bot) sendLocationSuggestions(chatID int64, userID int64, page int, from
 		end = len(list)
 	}
 
+	ids := make([]int64, 0, end-start)
+	for _, loc := range list[start:end] {
+		ids = append(ids, loc.Location.ID)
+	}
+	scores, err := services.ListLocationRatings(context.Background(), ids)
+	if err != nil {
+		log.Printf("location ratings: %v", err)
+	}
+
 	langCode := b.getLang(userID)
 	text := lang.T(langCode, "nearest_locations")
 	var buttons [][]tgbotapi.InlineKeyboardButton
 	for i := start; i < end; i++ {
 		loc := list[i]
-		text += fmt.Sprintf("%d) %s — %.1f km\n", i+1, loc.Location.Name, loc.Distance)
+		rating := locationRatingLabel(scores, loc.Location.ID)
+		text += fmt.Sprintf("%d) %s — %.1f km%s\n", i+1, loc.Location.Name, loc.Distance, rating)
 		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
-				fmt.Sprintf("%d) %s", i+1, loc.Location.Name),
+				fmt.Sprintf("%d) %s%s", i+1, loc.Location.Name, rating),
 				fmt.Sprintf("locsel:%d", loc.Location.ID),
 			),
 		))
@@ -1072,7 +1033,7 @@ func (b *Bot) sendLocationSuggestions(chatID int64, userID int64, page int, from
 	// that removes it, to avoid duplicate texts.)
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = kb
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.api.SendMessage(msg); err != nil {
 		log.Printf("send error: %v", err)
 	}
 }
@@ -1136,7 +1097,7 @@ func (b *Bot) sendLocationSuggestionsManual(chatID int64, userID int64, page int
 	// Remove the reply keyboard from start screen
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = kb
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.api.SendMessage(msg); err != nil {
 		log.Printf("send error: %v", err)
 	}
 }
@@ -1212,15 +1173,15 @@ func (b *Bot) handleCheckoutDeliveryCallback(cq *tgbotapi.CallbackQuery) {
 	userID := cq.From.ID
 	parts := strings.SplitN(cq.Data, ":", 2)
 	if len(parts) != 2 {
-		b.api.Request(tgbotapi.NewCallback(cq.ID, ""))
+		b.api.AnswerCallback(tgbotapi.NewCallback(cq.ID, ""))
 		return
 	}
 	deliveryType := parts[1]
 	if deliveryType != "delivery" && deliveryType != "pickup" {
-		b.api.Request(tgbotapi.NewCallback(cq.ID, ""))
+		b.api.AnswerCallback(tgbotapi.NewCallback(cq.ID, ""))
 		return
 	}
-	b.api.Request(tgbotapi.NewCallback(cq.ID, "✅"))
+	b.api.AnswerCallback(tgbotapi.NewCallback(cq.ID, "✅"))
 
 	ctx := context.Background()
 	checkout, err := services.GetCheckout(ctx, userID)
@@ -1272,6 +1233,7 @@ func (b *Bot) handleCheckoutDeliveryCallback(cq *tgbotapi.CallbackQuery) {
 		ItemsTotal:   itemsTotal,
 		LocationID:   locationID,
 		DeliveryType: deliveryType,
+		Items:        orderItemsFromCart(checkout.CartItems),
 	})
 	if err != nil {
 		b.sendLang(chatID, userID, "order_failed", err.Error())
@@ -1283,49 +1245,21 @@ func (b *Bot) handleCheckoutDeliveryCallback(cq *tgbotapi.CallbackQuery) {
 	confirmMsg += lang.T(l, "order_total", itemsTotal+deliveryFee)
 	b.send(chatID, confirmMsg)
 
-	o, _ := services.GetOrder(ctx, id)
-	if o != nil {
-		b.UpsertOrderCard(ctx, "customer", id, chatID, services.BuildCustomerCard(o, nil, ""))
-	}
-	hasUserLocation := customerLat != 0 || customerLon != 0
-	b.notifyAdmin(ctx, id, branch, customerLat, customerLon, hasUserLocation)
+	// Customer and staff cards (and the delivery pin for staff) were queued with the order
+	b.KickOutbox()
 }
 
-func (b *Bot) notifyAdmin(ctx context.Context, orderID int64, location *models.Location, userLat, userLon float64, hasUserLocation bool) {
-	if b.messageBot == nil {
-		return
-	}
-	o, _ := services.GetOrder(ctx, orderID)
-	if o == nil {
-		return
-	}
-	var admins []services.BranchAdminWithLang
-	if location != nil {
-		var err error
-		admins, err = services.GetBranchAdminsWithLang(ctx, location.ID)
-		if err != nil {
-			log.Printf("failed to get branch admins for location %d: %v", location.ID, err)
+// orderItemsFromCart copies the checkout cart into order lines.
+func orderItemsFromCart(items []services.CartItem) []models.OrderItem {
+	out := make([]models.OrderItem, 0, len(items))
+	for _, it := range items {
+		if it.Qty <= 0 {
+			continue
 		}
+		menuID, _ := strconv.ParseInt(it.ID, 10, 64)
+		out = append(out, models.OrderItem{MenuItemID: menuID, Category: it.Category, Name: it.Name, Price: it.Price, Qty: it.Qty})
 	}
-	if len(admins) == 0 && b.admin != 0 {
-		orderLang, _ := services.GetAdminOrderLang(ctx, b.admin)
-		admins = []services.BranchAdminWithLang{{AdminUserID: b.admin, OrderLang: orderLang}}
-	}
-	if len(admins) == 0 {
-		log.Printf("warning: no branch admins for order #%d", orderID)
-		return
-	}
-	first := admins[0]
-	adminLang := first.OrderLang
-	if adminLang == "" {
-		adminLang = lang.Uz
-	}
-	content := services.BuildAdminCard(o, nil, adminLang)
-	b.UpsertOrderCard(ctx, "admin", orderID, first.AdminUserID, content)
-	if hasUserLocation {
-		locMsg := tgbotapi.NewLocation(first.AdminUserID, userLat, userLon)
-		_, _ = b.messageBot.Send(locMsg)
-	}
+	return out
 }
 
 // orderStatusKeyboard returns inline buttons for the given order status, localized to adminLang ("uz" or "ru").
@@ -1380,28 +1314,35 @@ func (b *Bot) orderStatusKeyboard(orderID int64, status string, deliveryType *st
 func (b *Bot) startOrderStatusCallbacks() {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
-	updates := b.messageBot.GetUpdatesChan(u)
+	updates := b.messageClient.GetUpdatesChan(u)
 	for update := range updates {
-		if update.CallbackQuery == nil {
-			continue
-		}
-		cq := update.CallbackQuery
-		data := cq.Data
-		if strings.HasPrefix(data, "order_status:") {
-			b.handleOrderStatusCallback(cq)
-		}
+		b.HandleMessageBotUpdate(update)
+	}
+}
+
+// HandleMessageBotUpdate handles one update of the message bot: order status and cash hand-over buttons.
+func (b *Bot) HandleMessageBotUpdate(update tgbotapi.Update) {
+	if update.CallbackQuery == nil {
+		return
+	}
+	cq := update.CallbackQuery
+	data := cq.Data
+	if strings.HasPrefix(data, "order_status:") {
+		b.handleOrderStatusCallback(cq)
+	} else if strings.HasPrefix(data, "cash_handover:") {
+		b.handleCashHandoverCallback(cq)
 	}
 }
 
 func (b *Bot) handleOrderStatusCallback(cq *tgbotapi.CallbackQuery) {
 	parts := strings.SplitN(cq.Data, ":", 3)
 	if len(parts) != 3 {
-		b.messageBot.Request(tgbotapi.NewCallback(cq.ID, "Invalid callback."))
+		b.messageBot.AnswerCallback(tgbotapi.NewCallback(cq.ID, "Invalid callback."))
 		return
 	}
 	orderID, err := strconv.ParseInt(parts[1], 10, 64)
 	if err != nil || orderID <= 0 {
-		b.messageBot.Request(tgbotapi.NewCallback(cq.ID, "Invalid order."))
+		b.messageBot.AnswerCallback(tgbotapi.NewCallback(cq.ID, "Invalid order."))
 		return
 	}
 	newStatus := parts[2]
@@ -1410,85 +1351,67 @@ func (b *Bot) handleOrderStatusCallback(cq *tgbotapi.CallbackQuery) {
 	ctx := context.Background()
 	adminLocID, err := services.GetAdminLocationID(ctx, adminUserID)
 	if err != nil || adminLocID == 0 {
-		b.messageBot.Request(tgbotapi.NewCallback(cq.ID, "Unauthorized."))
+		b.messageBot.AnswerCallback(tgbotapi.NewCallback(cq.ID, "Unauthorized."))
 		return
 	}
 
-	err = services.UpdateOrderStatus(ctx, orderID, newStatus, adminLocID, adminUserID)
-	if err != nil {
-		b.messageBot.Request(tgbotapi.NewCallback(cq.ID, err.Error()))
+	if _, err := services.UpdateOrderStatus(ctx, orderID, newStatus, adminLocID, adminUserID); err != nil {
+		b.messageBot.AnswerCallback(tgbotapi.NewCallback(cq.ID, err.Error()))
 		log.Printf("order status update failed: order=%d status=%s admin=%d: %v", orderID, newStatus, adminUserID, err)
 		return
 	}
 	b.AnswerCallbackQuery(cq.ID, "✅ Status updated.")
-	b.RefreshOrderCards(ctx, orderID)
-	if newStatus == services.OrderStatusReady {
-		o, _ := services.GetOrder(ctx, orderID)
-		if o != nil && o.DeliveryType != nil && *o.DeliveryType == "delivery" {
-			go b.pushReadyOrderToDrivers(context.Background(), orderID)
-		}
-	}
+	b.KickOutbox()
 }
 
-// pushReadyOrderToDrivers finds nearby online drivers and sends them a Telegram message with an Accept button.
-// Uses a 10s timeout. Claims pushed_at once; skips if already pushed within 60s. Aborts loop if order no longer available.
-func (b *Bot) pushReadyOrderToDrivers(ctx context.Context, orderID int64) {
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
-	claimed, err := services.TrySetOrderPushedAt(ctx, orderID)
-	if err != nil {
-		log.Printf("push order to drivers: claim pushed_at failed order_id=%d: %v", orderID, err)
-		return
-	}
-	if !claimed {
-		within, _ := services.OrderPushedWithinSeconds(ctx, orderID, 60)
-		if within {
-			log.Printf("push order to drivers: order_id=%d skipped due to pushed_at (already pushed within 60s)", orderID)
-		}
-		return
-	}
-
-	o, err := services.GetOrder(ctx, orderID)
-	if err != nil || o == nil || o.Status != services.OrderStatusReady {
+// handleCashHandoverCallback handles cash_handover:{id}:confirm|reject from a branch admin in the message bot.
+func (b *Bot) handleCashHandoverCallback(cq *tgbotapi.CallbackQuery) {
+	parts := strings.SplitN(cq.Data, ":", 3)
+	if len(parts) != 3 || (parts[2] != "confirm" && parts[2] != "reject") {
+		b.messageBot.AnswerCallback(tgbotapi.NewCallback(cq.ID, "Invalid callback."))
 		return
 	}
-	orderLat, orderLon, err := services.GetOrderCoordinates(ctx, orderID)
-	if err != nil || (orderLat == 0 && orderLon == 0) {
+	handoverID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || handoverID <= 0 {
+		b.messageBot.AnswerCallback(tgbotapi.NewCallback(cq.ID, "Invalid callback."))
 		return
 	}
-	if b.driverBotAPI == nil {
+	adminUserID := cq.From.ID
+	ctx := auditContext(b.admin, adminUserID)
+	adminLocID, err := services.GetAdminLocationID(ctx, adminUserID)
+	if err != nil || adminLocID == 0 {
+		b.messageBot.AnswerCallback(tgbotapi.NewCallback(cq.ID, "Unauthorized."))
 		return
 	}
-	radiusKm := b.cfg.Delivery.DriverPushRadiusKm
-	if radiusKm <= 0 {
-		radiusKm = 5
-	}
-	drivers, err := services.GetNearbyOnlineDriversForOrder(ctx, orderLat, orderLon, radiusKm, 10)
+	confirm := parts[2] == "confirm"
+	h, err := services.DecideCashHandover(ctx, handoverID, confirm, adminLocID, adminUserID)
 	if err != nil {
-		log.Printf("push order to drivers: get nearby drivers failed order_id=%d: %v", orderID, err)
-		return
-	}
-	acceptData := "driver_accept:" + strconv.FormatInt(orderID, 10)
-	// Include items total, delivery fee, and grand total so driver sees full price
-	msgText := "📦 Yangi buyurtma yaqin atrofda!\n\nMasofa: %.2f km\nBuyurtma: %d so'm\nYetkazib berish: %d so'm\nJami: %d so'm\n\nQabul qilasizmi?"
-	for _, d := range drivers {
-		ok, err := services.OrderAvailableForPush(ctx, orderID)
-		if err != nil || !ok {
-			log.Printf("push order to drivers: order_id=%d skipped due to no longer available (status/assigned)", orderID)
-			return
-		}
-		text := fmt.Sprintf(msgText, d.DistanceKm, o.ItemsTotal, o.DeliveryFee, o.GrandTotal)
-		msg := tgbotapi.NewMessage(d.ChatID, text)
-		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData("Accept Order #"+strconv.FormatInt(orderID, 10), acceptData),
-			),
-		)
-		if _, sendErr := b.driverBotAPI.Send(msg); sendErr != nil {
-			log.Printf("push order to driver: send failed order_id=%d driver_chat_id=%d: %v", orderID, d.ChatID, sendErr)
-		} else {
-			log.Printf("push order to driver: order_id=%d driver_chat_id=%d distance_km=%.2f", orderID, d.ChatID, d.DistanceKm)
+		b.messageBot.AnswerCallback(tgbotapi.NewCallback(cq.ID, err.Error()))
+		return
+	}
+
+	adminLang, _ := services.GetAdminOrderLang(ctx, adminUserID)
+	resultKey, driverKey := "adm_cash_rejected", "dr_cash_rejected"
+	if confirm {
+		resultKey, driverKey = "adm_cash_confirmed", "dr_cash_confirmed"
+	}
+	b.AnswerCallbackQuery(cq.ID, lang.T(adminLang, resultKey))
+	if cq.Message != nil {
+		edit := tgbotapi.NewEditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, cq.Message.Text+"\n\n"+lang.T(adminLang, resultKey))
+		_ = b.messageBot.EditMessage(edit)
+	}
+	if b.driverBotAPI != nil {
+		driver, _ := services.GetDriverByID(ctx, h.DriverID)
+		if driver != nil && driver.ChatID != 0 {
+			locName, _ := services.GetLocationName(ctx, h.LocationID)
+			if err := services.EnqueueOutboxMessages(ctx, services.OutboxItem{
+				Audience: "driver",
+				ChatID:   driver.ChatID,
+				Payload:  services.OutboxPayload{Text: lang.T(lang.Uz, driverKey, locName, h.Amount)},
+			}); err != nil {
+				log.Printf("cash handover %d: queue driver notice: %v", h.ID, err)
+			}
+			b.KickOutbox()
 		}
 	}
 }
@@ -1509,7 +1432,7 @@ func replaceOrderStatusInMessage(text, newStatusLabel string) string {
 func (b *Bot) removeKeyboard(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.api.SendMessage(msg); err != nil {
 		log.Printf("send error: %v", err)
 	}
 }
@@ -1535,7 +1458,7 @@ func (b *Bot) handleOverride(chatID int64, userID int64, text string) {
 		note = strings.Join(parts[3:], " ")
 	}
 
-	err = services.OverrideDeliveryFee(context.Background(), models.OverrideDeliveryFeeInput{
+	err = services.OverrideDeliveryFee(auditContext(b.admin, b.admin), models.OverrideDeliveryFeeInput{
 		OrderID:    orderID,
 		NewFee:     newFee,
 		OverrideBy: b.admin,
@@ -1585,7 +1508,7 @@ func (b *Bot) handlePromote(chatID int64, userID int64, text string) {
 	if len(parts) < 4 {
 		b.send(chatID, "📝 Usage: /promote <branch_location_id> <new_admin_user_id> <password> [uz|ru]\n\n"+
 			"Example: /promote 1 123456789 MyUniquePass123 uz\n\n"+
-			"The password must be unique. Last arg is the language for order notifications: uz (Uzbek) or ru (Russian). Default: uz.\n\n"+
+			"The password becomes the owner's own login (they can change it with /password in the adder bot). Last arg is the language for order notifications: uz (Uzbek) or ru (Russian). Default: uz.\n\n"+
 			"💡 To get a user's ID, ask them to use @userinfobot on Telegram.")
 		return
 	}
@@ -1627,7 +1550,7 @@ func (b *Bot) handlePromote(chatID int64, userID int64, text string) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := auditContext(b.admin, userID)
 	err = services.AddBranchAdmin(ctx, branchLocationID, newAdminID, userID, passwordHash, orderLang)
 	if err != nil {
 		b.send(chatID, "❌ Failed to promote admin: "+err.Error())
@@ -1737,7 +1660,7 @@ func (b *Bot) handleRemoveAdmin(chatID int64, userID int64, text string) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := auditContext(b.admin, userID)
 	// Safety net: if migrations weren't applied, create the table on-demand.
 	if err := services.EnsureBranchAdminsTable(ctx); err != nil {
 		b.send(chatID, "❌ DB error: "+err.Error())
@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"food-telegram/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const dailySummaryFooter = "\n\n🔕 O'chirish: qo'shuvchi botda /summary_off"
+
+// dailyRollupBranches caps the branch lines of the roll-up so it stays one Telegram message.
+const dailyRollupBranches = 40
+
+// formatDailySummary renders a branch's end-of-day summary.
+func formatDailySummary(s *services.DailySummary) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("🌙 Kun yakuni — %s\n%s\n\n", s.Name, s.Day.Format("2006-01-02")))
+	if s.Orders == 0 {
+		b.WriteString("📭 Bu kunda buyurtmalar bo'lmadi.")
+	} else {
+		b.WriteString(fmt.Sprintf("Buyurtmalar: %d\n  • yakunlangan: %d\n  • rad etilgan: %d\n", s.Orders, s.Completed, s.Rejected))
+		b.WriteString(fmt.Sprintf("💰 Tushum: %d so'm", s.Revenue))
+		if len(s.Slowest) > 0 {
+			b.WriteString("\n\n🐢 Eng sekin buyurtmalar:")
+			for _, o := range s.Slowest {
+				b.WriteString(fmt.Sprintf("\n  • #%d — %s", o.ID, formatDuration(o.Took)))
+			}
+		}
+	}
+	return b.String()
+}
+
+// formatDailyRollup renders the superadmin's roll-up of every branch.
+func formatDailyRollup(s *services.DailySummary) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("🌙 Kun yakuni — barcha filiallar\n%s\n\n", s.Day.Format("2006-01-02")))
+	if s.Orders == 0 {
+		b.WriteString("📭 Bu kunda buyurtmalar bo'lmadi.")
+		return b.String()
+	}
+	b.WriteString(fmt.Sprintf("Buyurtmalar: %d (yakunlangan %d, rad etilgan %d)\n💰 Tushum: %d so'm\n",
+		s.Orders, s.Completed, s.Rejected, s.Revenue))
+	b.WriteString("\n🏪 Filiallar:")
+	for i, br := range s.Branches {
+		if i == dailyRollupBranches {
+			b.WriteString(fmt.Sprintf("\n  … va yana %d ta filial", len(s.Branches)-i))
+			break
+		}
+		name := br.Name
+		if name == "" {
+			name = fmt.Sprintf("ID %d", br.LocationID)
+		}
+		b.WriteString(fmt.Sprintf("\n  • %s — %d ta, %d so'm", name, br.Orders, br.Revenue))
+		if br.Rejected > 0 {
+			b.WriteString(fmt.Sprintf(", rad %d", br.Rejected))
+		}
+	}
+	if len(s.Slowest) > 0 {
+		b.WriteString("\n\n🐢 Eng sekin buyurtmalar:")
+		for _, o := range s.Slowest {
+			b.WriteString(fmt.Sprintf("\n  • #%d (filial %d) — %s", o.ID, o.LocationID, formatDuration(o.Took)))
+		}
+	}
+	return b.String()
+}
+
+// SendDueDailySummaries sends, through the message bot, every end-of-day summary that is due at now: each branch's
+// to its staff when the branch closes (hour o'clock on days without opening hours), and the platform roll-up to
+// the superadmin at hour o'clock. Users who turned summaries off are skipped; each summary is claimed before it is
+// sent, so it goes out at most once.
+func (b *Bot) SendDueDailySummaries(ctx context.Context, now time.Time, hour int, superadminID int64) error {
+	if b.messageBot == nil {
+		return nil
+	}
+	locs, err := services.ListLocationsForCustomer(ctx) // branches with an active subscription
+	if err != nil {
+		return err
+	}
+	for _, l := range locs {
+		week, err := services.GetLocationHours(ctx, l.ID)
+		if err != nil {
+			return err
+		}
+		for _, s := range services.DueDailySummaryDays(week, now, hour) {
+			s.LocationID, s.Name = l.ID, l.Name
+			if err := b.sendDailySummary(ctx, &s); err != nil {
+				log.Printf("daily summary %d %s: %v", l.ID, s.Day.Format("2006-01-02"), err)
+			}
+		}
+	}
+	if superadminID == 0 {
+		return nil
+	}
+	s, ok := services.DueRollupDay(now, hour)
+	if !ok {
+		return nil
+	}
+	return b.sendDailySummary(ctx, &s, superadminID)
+}
+
+// sendDailySummary claims, fills and sends one summary: a branch's to its staff, the roll-up to recipients.
+func (b *Bot) sendDailySummary(ctx context.Context, s *services.DailySummary, recipients ...int64) error {
+	if s.LocationID != 0 {
+		staff, err := services.ListBranchStaff(ctx, s.LocationID)
+		if err != nil {
+			return err
+		}
+		for _, st := range staff {
+			recipients = append(recipients, st.AdminUserID)
+		}
+	}
+	optedOut, err := services.DailySummaryOptedOut(ctx, recipients)
+	if err != nil {
+		return err
+	}
+	var to []int64
+	for _, id := range recipients {
+		if !optedOut[id] {
+			to = append(to, id)
+		}
+	}
+	if len(to) == 0 {
+		return nil
+	}
+	claimed, err := services.ClaimDailySummary(ctx, s.LocationID, s.Day)
+	if err != nil || !claimed {
+		return err
+	}
+	if err := services.FillDailySummary(ctx, s); err != nil {
+		return err
+	}
+	text := formatDailyRollup(s)
+	if s.LocationID != 0 {
+		text = formatDailySummary(s)
+	}
+	text += dailySummaryFooter
+	api := withSendPriority(b.messageBot, SendPriorityLow)
+	for _, chatID := range to {
+		if _, err := api.SendMessage(tgbotapi.NewMessage(chatID, text)); err != nil {
+			log.Printf("daily summary to %d: %v", chatID, err)
+		}
+	}
+	return nil
+}
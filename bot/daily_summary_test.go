@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"food-telegram/services"
+)
+
+func TestFormatDailySummary(t *testing.T) {
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, services.BranchTimezone)
+	s := &services.DailySummary{
+		LocationID: 3, Name: "Chilonzor", Day: day,
+		Orders: 12, Completed: 10, Rejected: 2, Revenue: 560000,
+		Slowest: []services.SlowOrder{{ID: 41, Took: 95 * time.Minute}, {ID: 38, Took: 52 * time.Minute}},
+	}
+	out := formatDailySummary(s)
+	for _, want := range []string{"Chilonzor", "2024-03-04", "Buyurtmalar: 12", "rad etilgan: 2", "560000 so'm",
+		"#41 — 1 soat 35 daqiqa", "#38 — 52 daqiqa"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary misses %q:\n%s", want, out)
+		}
+	}
+
+	empty := formatDailySummary(&services.DailySummary{Name: "Yunusobod", Day: day})
+	if !strings.Contains(empty, "buyurtmalar bo'lmadi") {
+		t.Errorf("empty day summary:\n%s", empty)
+	}
+}
+
+func TestFormatDailyRollup(t *testing.T) {
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, services.BranchTimezone)
+	s := &services.DailySummary{
+		Day: day, Orders: 20, Completed: 17, Rejected: 3, Revenue: 900000,
+		Branches: []services.BranchDayTotal{
+			{LocationID: 3, Name: "Chilonzor", Orders: 12, Rejected: 2, Revenue: 560000},
+			{LocationID: 5, Orders: 8, Rejected: 1, Revenue: 340000},
+		},
+	}
+	out := formatDailyRollup(s)
+	for _, want := range []string{"barcha filiallar", "Buyurtmalar: 20", "Chilonzor — 12 ta, 560000 so'm, rad 2",
+		"ID 5 — 8 ta, 340000 so'm, rad 1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("roll-up misses %q:\n%s", want, out)
+		}
+	}
+	for i := 0; i < dailyRollupBranches+2; i++ {
+		s.Branches = append(s.Branches, services.BranchDayTotal{LocationID: int64(100 + i), Orders: 1})
+	}
+	if out := formatDailyRollup(s); !strings.Contains(out, "va yana 4 ta filial") {
+		t.Errorf("long roll-up not capped:\n%s", out)
+	}
+}
@@ -2,11 +2,13 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"food-telegram/config"
 	"food-telegram/lang"
@@ -26,19 +28,30 @@ type driverRegState struct {
 	Lon      *float64
 }
 
+// driverDocState holds the document upload step (DocType being asked; AwaitExpiry after its photo).
+type driverDocState struct {
+	DriverID    string
+	DocType     string
+	FileID      string
+	AwaitExpiry bool
+}
+
 // DriverBot handles driver interactions (uses DRIVER_BOT_TOKEN). Auth by Telegram ID (driver row exists). No password.
 type DriverBot struct {
-	api                   *tgbotapi.BotAPI
-	mainBot                *tgbotapi.BotAPI
-	messageBot             *tgbotapi.BotAPI
+	api                   Messenger
+	client                *tgbotapi.BotAPI // polls updates for api
+	mainBot                Messenger
+	messageBot             Messenger
 	config                 *config.Config
 	stateMu                sync.RWMutex
 	driverLang             map[int64]string
 	driverLangMu           sync.RWMutex
 	driverReg              map[int64]*driverRegState
+	driverDocs             map[int64]*driverDocState
 	onOrderUpdated         func(orderID int64)
 	onSubscriptionExpired   func(tgUserID int64, role string)
 	onRenewalRequest       func(tgUserID int64, role string)
+	onVerificationSubmitted func(driverID string)
 }
 
 // NewDriverBot creates a driver bot using DRIVER_BOT_TOKEN.
@@ -46,26 +59,28 @@ func NewDriverBot(cfg *config.Config, mainBotAPI *tgbotapi.BotAPI, messageBotAPI
 	if cfg.Telegram.DriverToken == "" {
 		return nil, fmt.Errorf("DRIVER_BOT_TOKEN not set")
 	}
-	api, err := tgbotapi.NewBotAPI(cfg.Telegram.DriverToken)
+	api, err := newBotAPI(cfg, cfg.Telegram.DriverToken)
 	if err != nil {
 		return nil, err
 	}
 	return &DriverBot{
-		api:           api,
-		mainBot:       mainBotAPI,
-		messageBot:    messageBotAPI,
+		api:           messenger(api),
+		client:        api,
+		mainBot:       messenger(mainBotAPI),
+		messageBot:    messenger(messageBotAPI),
 		config:        cfg,
 		driverLang: make(map[int64]string),
 		driverReg:  make(map[int64]*driverRegState),
+		driverDocs: make(map[int64]*driverDocState),
 	}, nil
 }
 
 // GetAPI returns the driver bot API (for customer bot to push orders to drivers).
 func (d *DriverBot) GetAPI() *tgbotapi.BotAPI {
-	return d.api
+	return d.client
 }
 
-// SetOnOrderUpdated sets the callback invoked after an order is updated (accept/status/complete) so main bot can refresh order cards.
+// SetOnOrderUpdated sets the callback invoked after an order is updated (accept/status/complete) so main bot can deliver the queued order cards.
 func (d *DriverBot) SetOnOrderUpdated(f func(orderID int64)) {
 	d.onOrderUpdated = f
 }
@@ -80,6 +95,11 @@ func (d *DriverBot) SetOnRenewalRequest(f func(tgUserID int64, role string)) {
 	d.onRenewalRequest = f
 }
 
+// SetOnVerificationSubmitted sets the callback when a driver submits documents for review (adder notifies superadmin).
+func (d *DriverBot) SetOnVerificationSubmitted(f func(driverID string)) {
+	d.onVerificationSubmitted = f
+}
+
 func (d *DriverBot) getLang(userID int64) string {
 	d.driverLangMu.RLock()
 	defer d.driverLangMu.RUnlock()
@@ -111,70 +131,88 @@ func (d *DriverBot) sendLang(chatID int64, userID int64, key string, args ...int
 func (d *DriverBot) Start() {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
-	updates := d.api.GetUpdatesChan(u)
+	updates := d.client.GetUpdatesChan(u)
 
 	for update := range updates {
-		if update.CallbackQuery != nil {
-			d.handleCallback(update.CallbackQuery)
-			continue
-		}
-		if update.Message == nil {
-			continue
-		}
-		msg := update.Message
-		userID := msg.From.ID
-		text := strings.TrimSpace(msg.Text)
+		d.HandleUpdate(update)
+	}
+}
 
-		if text == "/start" {
-			d.handleStart(msg.Chat.ID, userID)
-			continue
-		}
+// HandleUpdate handles one update of the driver bot (Start feeds it the polled updates).
+func (d *DriverBot) HandleUpdate(update tgbotapi.Update) {
+	if update.CallbackQuery != nil {
+		d.handleCallback(update.CallbackQuery)
+		return
+	}
+	if update.Message == nil {
+		return
+	}
+	msg := update.Message
+	userID := msg.From.ID
+	text := strings.TrimSpace(msg.Text)
 
-		ctx := context.Background()
-		driver, _ := services.GetDriverByTgUserID(ctx, userID)
-		if driver != nil && driver.ChatID == 0 {
-			_ = services.UpdateDriverChatID(ctx, driver.ID, msg.Chat.ID)
-			driver.ChatID = msg.Chat.ID
-		}
+	if text == "/start" {
+		d.handleStart(msg.Chat.ID, userID)
+		return
+	}
 
-		// Registration flow (driver does not exist yet)
-		if driver == nil {
-			if d.handleRegFlow(msg, userID, text, nil, nil) {
-				continue
-			}
-			// Contact share for phone step
-			if msg.Contact != nil {
-				phone := msg.Contact.PhoneNumber
-				if msg.Contact.UserID != 0 && msg.Contact.UserID != userID {
-					phone = ""
-				}
-				if d.handleRegFlow(msg, userID, "", &phone, nil) {
-					continue
-				}
+	ctx := context.Background()
+	driver, _ := services.GetDriverByTgUserID(ctx, userID)
+	if driver != nil && driver.ChatID == 0 {
+		_ = services.UpdateDriverChatID(ctx, driver.ID, msg.Chat.ID)
+		driver.ChatID = msg.Chat.ID
+	}
+
+	// Registration flow (driver does not exist yet)
+	if driver == nil {
+		if d.handleRegFlow(msg, userID, text, nil, nil) {
+			return
+		}
+		// Contact share for phone step
+		if msg.Contact != nil {
+			phone := msg.Contact.PhoneNumber
+			if msg.Contact.UserID != 0 && msg.Contact.UserID != userID {
+				phone = ""
 			}
-			if msg.Location != nil {
-				lat, lon := msg.Location.Latitude, msg.Location.Longitude
-				if d.handleRegFlow(msg, userID, "", nil, &[]float64{lat, lon}) {
-					continue
-				}
+			if d.handleRegFlow(msg, userID, "", &phone, nil) {
+				return
 			}
-			d.send(msg.Chat.ID, "Ro'yxatdan o'tish uchun /start bosing.")
-			continue
 		}
-
-		// Driver exists → auth by Telegram ID. Handle location for online drivers.
 		if msg.Location != nil {
-			d.handleLocation(msg.Chat.ID, userID, msg.Location.Latitude, msg.Location.Longitude)
-			continue
+			lat, lon := msg.Location.Latitude, msg.Location.Longitude
+			if d.handleRegFlow(msg, userID, "", nil, &[]float64{lat, lon}) {
+				return
+			}
 		}
+		d.send(msg.Chat.ID, "Ro'yxatdan o'tish uchun /start bosing.")
+		return
+	}
+
+	if d.handleDocsFlow(msg, driver, text) {
+		return
+	}
 
-		d.sendLang(msg.Chat.ID, userID, "dr_please_use_buttons")
+	if strings.HasPrefix(text, "/tip") {
+		d.handleTip(msg.Chat.ID, driver, text)
+		return
+	}
+	if strings.HasPrefix(text, "/shift") {
+		d.handleShift(msg.Chat.ID, driver, text)
+		return
+	}
+
+	// Driver exists → auth by Telegram ID. Handle location for online drivers.
+	if msg.Location != nil {
+		d.handleLocation(msg.Chat.ID, userID, msg.Location.Latitude, msg.Location.Longitude)
+		return
 	}
+
+	d.sendLang(msg.Chat.ID, userID, "dr_please_use_buttons")
 }
 
 func (d *DriverBot) send(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
-	if _, err := d.api.Send(msg); err != nil {
+	if _, err := d.api.SendMessage(msg); err != nil {
 		log.Printf("driver bot send error: %v", err)
 	}
 }
@@ -182,7 +220,7 @@ func (d *DriverBot) send(chatID int64, text string) {
 func (d *DriverBot) sendWithInline(chatID int64, text string, kb tgbotapi.InlineKeyboardMarkup) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = kb
-	if _, err := d.api.Send(msg); err != nil {
+	if _, err := d.api.SendMessage(msg); err != nil {
 		log.Printf("driver bot send error: %v", err)
 	}
 }
@@ -190,7 +228,7 @@ func (d *DriverBot) sendWithInline(chatID int64, text string, kb tgbotapi.Inline
 func (d *DriverBot) sendWithReplyKeyboard(chatID int64, text string, kb tgbotapi.ReplyKeyboardMarkup) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = kb
-	if _, err := d.api.Send(msg); err != nil {
+	if _, err := d.api.SendMessage(msg); err != nil {
 		log.Printf("driver bot send error: %v", err)
 	}
 }
@@ -198,7 +236,7 @@ func (d *DriverBot) sendWithReplyKeyboard(chatID int64, text string, kb tgbotapi
 func (d *DriverBot) sendRemoveKeyboard(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
-	if _, err := d.api.Send(msg); err != nil {
+	if _, err := d.api.SendMessage(msg); err != nil {
 		log.Printf("driver bot send error: %v", err)
 	}
 }
@@ -314,8 +352,8 @@ func (d *DriverBot) handleRegFlow(msg *tgbotapi.Message, userID int64, text stri
 			d.send(chatID, "❌ "+err.Error())
 			return true
 		}
-		d.send(chatID, "✅ Ro'yxatdan o'tdingiz. Endi Go Online qilishingiz mumkin.")
-		d.sendDriverPanel(chatID, driver)
+		d.send(chatID, "✅ Ro'yxatdan o'tdingiz.")
+		d.startDocsFlow(chatID, driver)
 		return true
 	}
 	return false
@@ -354,6 +392,12 @@ func (d *DriverBot) sendDriverPanel(chatID int64, driver *services.Driver) {
 }
 
 func (d *DriverBot) sendDriverPanelWithLocation(chatID int64, driver *services.Driver, knownLocation *services.DriverLocation) {
+	text, kb := d.driverPanel(driver, knownLocation)
+	d.sendWithInline(chatID, text, kb)
+}
+
+// driverPanel renders the driver panel text and its buttons.
+func (d *DriverBot) driverPanel(driver *services.Driver, knownLocation *services.DriverLocation) (string, tgbotapi.InlineKeyboardMarkup) {
 	ctx := context.Background()
 	l := d.getLang(driver.TgUserID)
 	if l == "" {
@@ -364,6 +408,14 @@ func (d *DriverBot) sendDriverPanelWithLocation(chatID int64, driver *services.D
 		statusEmoji = "🔴"
 	}
 	text := fmt.Sprintf(lang.T(l, "dr_panel"), statusEmoji, driver.Status)
+	switch driver.VerificationStatus {
+	case services.DriverVerificationPending:
+		text += "\n" + lang.T(l, "dr_verif_pending")
+	case services.DriverVerificationRejected:
+		text += "\n" + fmt.Sprintf(lang.T(l, "dr_verif_rejected"), driver.VerificationRejectReason)
+	case services.DriverVerificationPendingDocuments:
+		text += "\n" + lang.T(l, "dr_verif_need_docs")
+	}
 
 	hasLocation := false
 	var loc *services.DriverLocation
@@ -383,7 +435,33 @@ func (d *DriverBot) sendDriverPanelWithLocation(chatID int64, driver *services.D
 	}
 
 	kb := d.driverKeyboard(driver.TgUserID, driver.Status, hasLocation)
-	d.sendWithInline(chatID, text, kb)
+	if driver.VerificationStatus == services.DriverVerificationPendingDocuments || driver.VerificationStatus == services.DriverVerificationRejected {
+		kb.InlineKeyboard = append(kb.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(lang.T(l, "dr_docs_btn"), "driver:docs"),
+		))
+	}
+	return text, kb
+}
+
+// queueNotice queues a background notice to the driver through the outbox so it is retried until Telegram takes it.
+// removeKeyboard hides the location keyboard; with panel set the notice is followed by a fresh panel.
+func (d *DriverBot) queueNotice(chatID int64, text string, removeKeyboard bool, panel *services.Driver) {
+	items := []services.OutboxItem{{
+		Audience: "driver",
+		ChatID:   chatID,
+		Payload:  services.OutboxPayload{Text: text, RemoveKeyboard: removeKeyboard, Bulk: true},
+	}}
+	if panel != nil {
+		panelText, kb := d.driverPanel(panel, nil)
+		items = append(items, services.OutboxItem{
+			Audience: "driver",
+			ChatID:   chatID,
+			Payload:  services.OutboxPayload{Text: panelText, Buttons: outboxButtons(kb), Bulk: true},
+		})
+	}
+	if err := services.EnqueueOutboxMessages(context.Background(), items...); err != nil {
+		log.Printf("driver bot: queue notice for %d: %v", chatID, err)
+	}
 }
 
 func (d *DriverBot) driverKeyboard(userID int64, status string, hasLocation bool) tgbotapi.InlineKeyboardMarkup {
@@ -403,6 +481,10 @@ func (d *DriverBot) driverKeyboard(userID int64, status string, hasLocation bool
 			tgbotapi.NewInlineKeyboardButtonData(lang.T(l, "dr_active_order"), "driver:active"),
 		})
 	}
+	rows = append(rows, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(lang.T(l, "dr_earnings"), "driver:earnings"),
+		tgbotapi.NewInlineKeyboardButtonData(lang.T(l, "dr_cash"), "driver:cash"),
+	})
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
@@ -423,6 +505,17 @@ func (d *DriverBot) handleLocation(chatID int64, userID int64, lat, lon float64)
 		return
 	}
 	log.Printf("driver location saved: driver_id=%s lat=%.6f lon=%.6f", driver.ID, lat, lon)
+	// New position changes the customer ETA: refresh cards of the active orders.
+	active, _ := services.GetDriverActiveOrders(ctx, driver.ID)
+	for _, o := range active {
+		if err := services.EnqueueOrderCards(ctx, o.ID); err != nil {
+			log.Printf("queue order cards order_id=%d: %v", o.ID, err)
+			continue
+		}
+		if d.onOrderUpdated != nil {
+			d.onOrderUpdated(o.ID)
+		}
+	}
 	driver, _ = services.GetDriverByTgUserID(ctx, userID)
 	if driver != nil {
 		loc := &services.DriverLocation{
@@ -443,7 +536,7 @@ func (d *DriverBot) handleLocation(chatID int64, userID int64, lat, lon float64)
 		kb.ResizeKeyboard = true
 		msg := tgbotapi.NewMessage(chatID, lang.T(l, "dr_location_updated"))
 		msg.ReplyMarkup = kb
-		d.api.Send(msg)
+		d.api.SendMessage(msg)
 		d.sendDriverPanelWithLocation(chatID, driver, loc)
 	}
 }
@@ -453,7 +546,7 @@ func (d *DriverBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 	userID := cq.From.ID
 	data := cq.Data
 
-	d.api.Request(tgbotapi.NewCallback(cq.ID, ""))
+	d.api.AnswerCallback(tgbotapi.NewCallback(cq.ID, ""))
 
 	if data == "driver_reg_skip_loc" {
 		d.stateMu.Lock()
@@ -469,8 +562,8 @@ func (d *DriverBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 				d.send(chatID, "❌ "+err.Error())
 				return
 			}
-			d.send(chatID, "✅ Ro'yxatdan o'tdingiz. Endi Go Online qilishingiz mumkin.")
-			d.sendDriverPanel(chatID, driver)
+			d.send(chatID, "✅ Ro'yxatdan o'tdingiz.")
+			d.startDocsFlow(chatID, driver)
 		}
 		return
 	}
@@ -507,6 +600,10 @@ func (d *DriverBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 	switch {
 	case data == "driver:online":
 		if err := services.UpdateDriverStatus(ctx, driver.ID, services.DriverStatusOnline); err != nil {
+			if errors.Is(err, services.ErrDriverNotVerified) {
+				d.sendDriverPanel(chatID, driver)
+				return
+			}
 			d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
 			return
 		}
@@ -523,7 +620,7 @@ func (d *DriverBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 		kb.ResizeKeyboard = true
 		msg := tgbotapi.NewMessage(chatID, lang.T(l, "dr_online_success"))
 		msg.ReplyMarkup = kb
-		d.api.Send(msg)
+		d.api.SendMessage(msg)
 	case data == "driver:offline":
 		if err := services.UpdateDriverStatus(ctx, driver.ID, services.DriverStatusOffline); err != nil {
 			d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
@@ -531,13 +628,27 @@ func (d *DriverBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 		}
 		removeKb := tgbotapi.NewMessage(chatID, lang.T(d.getLang(driver.TgUserID), "dr_offline_success"))
 		removeKb.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
-		d.api.Send(removeKb)
+		d.api.SendMessage(removeKb)
 		driver.Status = services.DriverStatusOffline
 		d.sendDriverPanel(chatID, driver)
+	case data == "driver:docs":
+		if driver.VerificationStatus == services.DriverVerificationPendingDocuments || driver.VerificationStatus == services.DriverVerificationRejected {
+			d.startDocsFlow(chatID, driver)
+		}
 	case data == "driver:jobs":
 		d.handleJobsNearMe(chatID, driver)
 	case data == "driver:active":
 		d.handleActiveOrder(chatID, driver)
+	case data == "driver:earnings":
+		d.handleEarnings(chatID, driver)
+	case data == "driver:cash":
+		d.handleCashBalances(chatID, driver)
+	case strings.HasPrefix(data, "driver_cash:"):
+		locationID, err := strconv.ParseInt(strings.TrimPrefix(data, "driver_cash:"), 10, 64)
+		if err != nil || locationID <= 0 {
+			return
+		}
+		d.handleCashHandover(chatID, driver, locationID)
 	case strings.HasPrefix(data, "driver_accept:"):
 		orderIDStr := strings.TrimPrefix(data, "driver_accept:")
 		orderID, err := strconv.ParseInt(orderIDStr, 10, 64)
@@ -549,12 +660,12 @@ func (d *DriverBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 	case strings.HasPrefix(data, "driver_status:"):
 		parts := strings.SplitN(data, ":", 3)
 		if len(parts) != 3 {
-			d.api.Request(tgbotapi.NewCallback(cq.ID, lang.T(d.getLang(driver.TgUserID), "dr_invalid_order_id")))
+			d.api.AnswerCallback(tgbotapi.NewCallback(cq.ID, lang.T(d.getLang(driver.TgUserID), "dr_invalid_order_id")))
 			return
 		}
 		orderID, err := strconv.ParseInt(parts[1], 10, 64)
 		if err != nil || orderID <= 0 {
-			d.api.Request(tgbotapi.NewCallback(cq.ID, lang.T(d.getLang(driver.TgUserID), "dr_invalid_order_id")))
+			d.api.AnswerCallback(tgbotapi.NewCallback(cq.ID, lang.T(d.getLang(driver.TgUserID), "dr_invalid_order_id")))
 			return
 		}
 		newStatus := parts[2]
@@ -563,7 +674,7 @@ func (d *DriverBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 		} else {
 			d.handleDriverStatusUpdate(chatID, driver, orderID, newStatus, cq.Message.MessageID)
 		}
-		d.api.Request(tgbotapi.NewCallback(cq.ID, lang.T(d.getLang(driver.TgUserID), "dr_status_updated")))
+		d.api.AnswerCallback(tgbotapi.NewCallback(cq.ID, lang.T(d.getLang(driver.TgUserID), "dr_status_updated")))
 	case strings.HasPrefix(data, "driver_done:"):
 		orderIDStr := strings.TrimPrefix(data, "driver_done:")
 		orderID, err := strconv.ParseInt(orderIDStr, 10, 64)
@@ -603,7 +714,7 @@ func (d *DriverBot) handleJobsNearMe(chatID int64, driver *services.Driver) {
 			kb.ResizeKeyboard = true
 			msg := tgbotapi.NewMessage(chatID, lang.T(l, "dr_location_stale"))
 			msg.ReplyMarkup = kb
-			d.api.Send(msg)
+			d.api.SendMessage(msg)
 			return
 		}
 		d.sendLang(chatID, driver.TgUserID, "dr_location_stale")
@@ -618,6 +729,29 @@ func (d *DriverBot) handleJobsNearMe(chatID int64, driver *services.Driver) {
 		return
 	}
 
+	// Holding orders already: only offer orders that can be batched with them.
+	active, err := services.GetDriverActiveOrders(ctx, driver.ID)
+	if err != nil {
+		d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
+		return
+	}
+	maxActive := d.config.Delivery.DriverMaxActiveOrders
+	header := lang.T(l, "dr_jobs_header")
+	if len(active) > 0 {
+		if len(active) >= maxActive {
+			d.sendLang(chatID, driver.TgUserID, "dr_max_active", maxActive)
+			return
+		}
+		batchable := orders[:0]
+		for _, o := range orders {
+			if services.CanTakeAnotherOrder(active, o.LocationID, maxActive) == nil {
+				batchable = append(batchable, o)
+			}
+		}
+		orders = batchable
+		header = lang.T(l, "dr_jobs_batch_note") + header
+	}
+
 	if len(orders) == 0 {
 		d.sendLang(chatID, driver.TgUserID, "dr_no_jobs")
 		return
@@ -627,7 +761,7 @@ func (d *DriverBot) handleJobsNearMe(chatID int64, driver *services.Driver) {
 	if len(orders) < displayLimit {
 		displayLimit = len(orders)
 	}
-	text := lang.T(l, "dr_jobs_header")
+	text := header
 	var rows [][]tgbotapi.InlineKeyboardButton
 	for i := 0; i < displayLimit; i++ {
 		o := orders[i]
@@ -672,44 +806,43 @@ func (d *DriverBot) handleActiveOrder(chatID int64, driver *services.Driver) {
 			kb.ResizeKeyboard = true
 			msg := tgbotapi.NewMessage(chatID, lang.T(l, "dr_location_stale"))
 			msg.ReplyMarkup = kb
-			d.api.Send(msg)
+			d.api.SendMessage(msg)
 			return
 		}
 	}
-	order, err := services.GetDriverActiveOrder(ctx, driver.ID)
+	orders, err := services.GetDriverActiveOrders(ctx, driver.ID)
 	if err != nil {
 		d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
 		return
 	}
-	if order == nil {
+	if len(orders) == 0 {
 		d.sendLang(chatID, driver.TgUserID, "dr_no_active")
 		return
 	}
+	text := fmt.Sprintf(lang.T(l, "dr_active_list_header"), len(orders), d.config.Delivery.DriverMaxActiveOrders)
 	var rows [][]tgbotapi.InlineKeyboardButton
-	var statusText string
-	switch order.Status {
-	case services.OrderStatusAssigned:
-		statusText = lang.T(l, "dr_status_accepted")
-		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(lang.T(l, "dr_mark_collected"), fmt.Sprintf("driver_status:%d:%s", order.ID, services.OrderStatusPickedUp)),
-		))
-	case services.OrderStatusPickedUp:
-		statusText = lang.T(l, "dr_status_picked")
-		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(lang.T(l, "dr_start_delivering"), fmt.Sprintf("driver_status:%d:%s", order.ID, services.OrderStatusDelivering)),
-		))
-	case services.OrderStatusDelivering:
-		statusText = lang.T(l, "dr_status_delivering")
-		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(lang.T(l, "dr_order_completed_btn"), fmt.Sprintf("driver_status:%d:%s", order.ID, services.OrderStatusCompleted)),
-		))
-	case services.OrderStatusCompleted:
-		statusText = lang.T(l, "dr_status_completed")
+	for _, order := range orders {
+		var statusText, btnText, nextStatus string
+		switch order.Status {
+		case services.OrderStatusAssigned:
+			statusText, btnText, nextStatus = lang.T(l, "dr_status_accepted"), lang.T(l, "dr_mark_collected"), services.OrderStatusPickedUp
+		case services.OrderStatusPickedUp:
+			statusText, btnText, nextStatus = lang.T(l, "dr_status_picked"), lang.T(l, "dr_start_delivering"), services.OrderStatusDelivering
+		case services.OrderStatusDelivering:
+			statusText, btnText, nextStatus = lang.T(l, "dr_status_delivering"), lang.T(l, "dr_order_completed_btn"), services.OrderStatusCompleted
+		default:
+			statusText = order.Status
+		}
+		text += fmt.Sprintf(lang.T(l, "dr_active_list_line"), order.ID, statusText, order.ItemsTotal, order.DeliveryFee, order.GrandTotal)
+		if nextStatus != "" {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("#%d: %s", order.ID, btnText), fmt.Sprintf("driver_status:%d:%s", order.ID, nextStatus)),
+			))
+		}
 	}
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData(lang.T(l, "dr_back"), "driver:back"),
 	))
-	text := fmt.Sprintf(lang.T(l, "dr_active_header"), order.ID, order.ItemsTotal, order.DeliveryFee, order.GrandTotal, statusText)
 	kb := tgbotapi.NewInlineKeyboardMarkup(rows...)
 	d.sendWithInline(chatID, text, kb)
 }
@@ -720,10 +853,12 @@ func (d *DriverBot) handleAcceptOrder(chatID int64, driver *services.Driver, ord
 		return
 	}
 	ctx := context.Background()
-	order, err := services.AcceptOrder(ctx, orderID, driver.ID, driver.TgUserID)
+	order, err := services.AcceptOrder(ctx, orderID, driver.ID, driver.TgUserID, d.config.Delivery.DriverMaxActiveOrders)
 	if err != nil {
 		if err.Error() == "bu buyurtma allaqachon olingan" {
 			d.sendLang(chatID, driver.TgUserID, "dr_order_already_taken")
+		} else if errors.Is(err, services.ErrDriverAtCapacity) {
+			d.sendLang(chatID, driver.TgUserID, "dr_max_active", d.config.Delivery.DriverMaxActiveOrders)
 		} else {
 			d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
 		}
@@ -738,7 +873,7 @@ func (d *DriverBot) handleAcceptOrder(chatID int64, driver *services.Driver, ord
 		if err == nil && restaurantLoc != nil {
 			d.send(chatID, fmt.Sprintf(lang.T(l, "dr_restaurant_location"), restaurantLoc.Name))
 			locationMsg := tgbotapi.NewLocation(chatID, restaurantLoc.Lat, restaurantLoc.Lon)
-			if _, err := d.api.Send(locationMsg); err != nil {
+			if err := d.api.SendLocation(locationMsg); err != nil {
 				log.Printf("send restaurant location to driver: %v", err)
 			}
 		}
@@ -770,7 +905,7 @@ func (d *DriverBot) handleDriverStatusUpdate(chatID int64, driver *services.Driv
 			d.sendLang(chatID, driver.TgUserID, "dr_customer_address")
 			// Send location
 			locationMsg := tgbotapi.NewLocation(chatID, customerLat, customerLon)
-			if _, err := d.api.Send(locationMsg); err != nil {
+			if err := d.api.SendLocation(locationMsg); err != nil {
 				log.Printf("send customer location to driver: %v", err)
 			}
 		}
@@ -783,7 +918,7 @@ func (d *DriverBot) handleDriverStatusUpdate(chatID int64, driver *services.Driv
 
 func (d *DriverBot) handleCompleteDelivery(chatID int64, driver *services.Driver, orderID int64, messageID int) {
 	ctx := context.Background()
-	err := services.CompleteDeliveryByDriver(ctx, orderID, driver.ID, driver.TgUserID)
+	err := services.CompleteDeliveryByDriver(ctx, orderID, driver.ID, driver.TgUserID, d.config.Delivery.DriverCommissionPct)
 	if err != nil {
 		d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
 		return
@@ -795,9 +930,308 @@ func (d *DriverBot) handleCompleteDelivery(chatID int64, driver *services.Driver
 	}
 
 	d.sendLang(chatID, driver.TgUserID, "dr_delivery_completed", orderID)
+	d.sendLang(chatID, driver.TgUserID, "dr_tip_hint", orderID)
 	d.sendDriverPanel(chatID, driver)
 
 	if d.onOrderUpdated != nil {
 		d.onOrderUpdated(orderID)
 	}
 }
+
+// handleEarnings shows today's and this week's ledger totals for the driver.
+func (d *DriverBot) handleEarnings(chatID int64, driver *services.Driver) {
+	ctx := context.Background()
+	now := time.Now()
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	today, err := services.GetDriverEarningsSummary(ctx, driver.ID, todayStart, now)
+	if err != nil {
+		d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
+		return
+	}
+	week, err := services.GetDriverEarningsSummary(ctx, driver.ID, services.StartOfWeek(now), now)
+	if err != nil {
+		d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
+		return
+	}
+	l := d.getLang(driver.TgUserID)
+	if l == "" {
+		l = lang.Uz
+	}
+	pct, err := services.DriverCommissionPct(ctx, services.PlatformCommissionLocation, d.config.Delivery.DriverCommissionPct)
+	if err != nil {
+		log.Printf("driver commission: %v", err)
+		pct = d.config.Delivery.DriverCommissionPct
+	}
+	text := lang.T(l, "dr_earnings_summary",
+		today.Orders, today.DriverShare, today.Tips, today.CashCollected,
+		week.Orders, week.DriverShare, week.Tips, week.CashCollected,
+		pct)
+	if score, err := services.GetDriverRating(ctx, driver.ID); err != nil {
+		log.Printf("driver rating %s: %v", driver.ID, err)
+	} else if score.Count > 0 {
+		text += lang.T(l, "dr_rating_line", score.String())
+	}
+	kb := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(lang.T(l, "dr_back"), "driver:back"),
+	))
+	d.sendWithInline(chatID, text, kb)
+}
+
+// handleTip records a tip: /tip <order_id> <amount>.
+func (d *DriverBot) handleTip(chatID int64, driver *services.Driver, text string) {
+	parts := strings.Fields(text)
+	if len(parts) != 3 {
+		d.sendLang(chatID, driver.TgUserID, "dr_tip_usage")
+		return
+	}
+	orderID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || orderID <= 0 {
+		d.sendLang(chatID, driver.TgUserID, "dr_invalid_order_id")
+		return
+	}
+	amount, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || amount < 0 {
+		d.sendLang(chatID, driver.TgUserID, "dr_tip_usage")
+		return
+	}
+	if err := services.AddDriverTip(context.Background(), orderID, driver.ID, amount); err != nil {
+		d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
+		return
+	}
+	d.sendLang(chatID, driver.TgUserID, "dr_tip_saved", orderID, amount)
+}
+
+// startDocsFlow asks for the first required document (license → tech passport → selfie).
+func (d *DriverBot) startDocsFlow(chatID int64, driver *services.Driver) {
+	d.stateMu.Lock()
+	d.driverDocs[driver.TgUserID] = &driverDocState{DriverID: driver.ID, DocType: services.DriverRequiredDocuments[0]}
+	d.stateMu.Unlock()
+	d.sendLang(chatID, driver.TgUserID, "dr_docs_intro")
+	d.sendLang(chatID, driver.TgUserID, "dr_docs_ask_"+services.DriverRequiredDocuments[0])
+}
+
+// handleDocsFlow handles document photos and expiry dates. Returns true if message was consumed.
+func (d *DriverBot) handleDocsFlow(msg *tgbotapi.Message, driver *services.Driver, text string) bool {
+	d.stateMu.Lock()
+	st := d.driverDocs[driver.TgUserID]
+	d.stateMu.Unlock()
+	if st == nil {
+		return false
+	}
+	if text == "/start" || strings.HasPrefix(text, "/tip") || strings.HasPrefix(text, "/shift") {
+		return false
+	}
+	chatID := msg.Chat.ID
+	ctx := context.Background()
+
+	if !st.AwaitExpiry {
+		if len(msg.Photo) == 0 {
+			d.sendLang(chatID, driver.TgUserID, "dr_docs_send_photo")
+			return true
+		}
+		st.FileID = msg.Photo[len(msg.Photo)-1].FileID // largest size
+		if services.DriverDocumentHasExpiry(st.DocType) {
+			st.AwaitExpiry = true
+			d.sendLang(chatID, driver.TgUserID, "dr_docs_ask_expiry")
+			return true
+		}
+		if err := services.SaveDriverDocument(ctx, st.DriverID, st.DocType, st.FileID, nil); err != nil {
+			d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
+			return true
+		}
+	} else {
+		expires, err := services.ParseDocumentExpiry(text, time.Now())
+		if err != nil {
+			d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
+			return true
+		}
+		if err := services.SaveDriverDocument(ctx, st.DriverID, st.DocType, st.FileID, &expires); err != nil {
+			d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
+			return true
+		}
+	}
+
+	// Next document or submit for review.
+	next := ""
+	for i, t := range services.DriverRequiredDocuments {
+		if t == st.DocType && i+1 < len(services.DriverRequiredDocuments) {
+			next = services.DriverRequiredDocuments[i+1]
+		}
+	}
+	if next != "" {
+		d.stateMu.Lock()
+		d.driverDocs[driver.TgUserID] = &driverDocState{DriverID: st.DriverID, DocType: next}
+		d.stateMu.Unlock()
+		d.sendLang(chatID, driver.TgUserID, "dr_docs_ask_"+next)
+		return true
+	}
+	d.stateMu.Lock()
+	delete(d.driverDocs, driver.TgUserID)
+	d.stateMu.Unlock()
+	if err := services.SubmitDriverVerification(ctx, st.DriverID); err != nil {
+		d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
+		return true
+	}
+	d.sendLang(chatID, driver.TgUserID, "dr_docs_submitted")
+	if d.onVerificationSubmitted != nil {
+		d.onVerificationSubmitted(st.DriverID)
+	}
+	return true
+}
+
+// NotifyDocumentsExpired tells a driver their documents expired and asks for new ones (used by background job).
+func (d *DriverBot) NotifyDocumentsExpired(driver services.Driver) {
+	if driver.ChatID == 0 {
+		return
+	}
+	l := d.getLang(driver.TgUserID)
+	if l == "" {
+		l = lang.Uz
+	}
+	full, _ := services.GetDriverByID(context.Background(), driver.ID)
+	d.queueNotice(driver.ChatID, lang.T(l, "dr_docs_expired"), true, full)
+}
+
+// handleShift shows the current/next shift (/shift) or sets it (/shift HH:MM-HH:MM).
+func (d *DriverBot) handleShift(chatID int64, driver *services.Driver, text string) {
+	ctx := context.Background()
+	arg := strings.TrimSpace(strings.TrimPrefix(text, "/shift"))
+	if arg == "" {
+		sh, err := services.GetDriverUpcomingShift(ctx, driver.ID)
+		if err != nil {
+			d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
+			return
+		}
+		if sh == nil {
+			d.sendLang(chatID, driver.TgUserID, "dr_shift_none")
+			return
+		}
+		d.sendLang(chatID, driver.TgUserID, "dr_shift_current", sh.StartsAt.Format("02.01 15:04"), sh.EndsAt.Format("02.01 15:04"))
+		return
+	}
+	start, end, err := services.ParseShiftRange(arg, time.Now())
+	if err != nil {
+		d.sendLang(chatID, driver.TgUserID, "dr_shift_usage")
+		return
+	}
+	sh, err := services.SetDriverShift(ctx, driver.ID, start, end)
+	if err != nil {
+		d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
+		return
+	}
+	d.sendLang(chatID, driver.TgUserID, "dr_shift_set", sh.StartsAt.Format("02.01 15:04"), sh.EndsAt.Format("02.01 15:04"))
+}
+
+// SendOfflineReminder warns an online driver that they will be taken offline in about minutes (used by background job).
+func (d *DriverBot) SendOfflineReminder(n services.DriverOfflineNotice, minutes int) {
+	if n.ChatID == 0 {
+		return
+	}
+	key := "dr_reminder_stale"
+	if n.Reason == services.DriverStatusReasonShiftEnd {
+		key = "dr_reminder_shift_end"
+	}
+	l := d.getLang(n.TgUserID)
+	if l == "" {
+		l = lang.Uz
+	}
+	d.queueNotice(n.ChatID, lang.T(l, key, minutes), false, nil)
+}
+
+// NotifyAutoOffline tells the driver they were taken offline, removes the location keyboard and shows the panel (used by background job).
+func (d *DriverBot) NotifyAutoOffline(n services.DriverOfflineNotice) {
+	if n.ChatID == 0 {
+		return
+	}
+	l := d.getLang(n.TgUserID)
+	if l == "" {
+		l = lang.Uz
+	}
+	key := "dr_auto_offline_stale"
+	if n.Reason == services.DriverStatusReasonShiftEnd {
+		key = "dr_auto_offline_shift_end"
+	}
+	driver, _ := services.GetDriverByTgUserID(context.Background(), n.TgUserID)
+	d.queueNotice(n.ChatID, lang.T(l, key), true, driver)
+}
+
+// handleCashBalances lists cash the driver holds per branch with a hand-over button for each.
+func (d *DriverBot) handleCashBalances(chatID int64, driver *services.Driver) {
+	ctx := context.Background()
+	balances, err := services.GetDriverCashBalances(ctx, driver.ID)
+	if err != nil {
+		d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
+		return
+	}
+	l := d.getLang(driver.TgUserID)
+	if l == "" {
+		l = lang.Uz
+	}
+	if len(balances) == 0 {
+		d.sendLang(chatID, driver.TgUserID, "dr_cash_none")
+		return
+	}
+	text := lang.T(l, "dr_cash_header")
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, b := range balances {
+		mark := ""
+		if b.Pending {
+			mark = lang.T(l, "dr_cash_pending_mark")
+		} else {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(lang.T(l, "dr_cash_handover_btn"), b.LocationName), fmt.Sprintf("driver_cash:%d", b.LocationID)),
+			))
+		}
+		text += fmt.Sprintf(lang.T(l, "dr_cash_line"), b.LocationName, b.Amount, b.Orders, mark)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(lang.T(l, "dr_back"), "driver:back"),
+	))
+	d.sendWithInline(chatID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// handleCashHandover opens a hand-over for the branch and asks its admins (via message bot) to confirm.
+func (d *DriverBot) handleCashHandover(chatID int64, driver *services.Driver, locationID int64) {
+	ctx := context.Background()
+	h, err := services.RequestCashHandover(ctx, driver.ID, locationID)
+	if err != nil {
+		d.sendLang(chatID, driver.TgUserID, "dr_error", err.Error())
+		return
+	}
+	locName, _ := services.GetLocationName(ctx, locationID)
+	d.sendLang(chatID, driver.TgUserID, "dr_cash_handover_sent", locName, h.Amount)
+
+	if d.messageBot == nil {
+		return
+	}
+	admins, err := services.GetBranchAdminsWithLang(ctx, locationID)
+	if err != nil {
+		log.Printf("cash handover %d: branch admins: %v", h.ID, err)
+		return
+	}
+	driverName := driver.FullName
+	if driver.Phone != "" {
+		driverName += " (" + driver.Phone + ")"
+	}
+	var items []services.OutboxItem
+	for _, a := range admins {
+		al := a.OrderLang
+		if al == "" {
+			al = lang.Uz
+		}
+		items = append(items, services.OutboxItem{
+			Audience: "admin",
+			ChatID:   a.AdminUserID,
+			Payload: services.OutboxPayload{
+				Text: lang.T(al, "adm_cash_request", h.ID, driverName, h.Amount, h.Orders),
+				Buttons: [][]services.OrderCardButton{{
+					{Text: lang.T(al, "adm_cash_confirm"), CallbackData: fmt.Sprintf("cash_handover:%d:confirm", h.ID)},
+					{Text: lang.T(al, "adm_cash_reject"), CallbackData: fmt.Sprintf("cash_handover:%d:reject", h.ID)},
+				}},
+			},
+		})
+	}
+	if err := services.EnqueueOutboxMessages(ctx, items...); err != nil {
+		log.Printf("cash handover %d: queue admin requests: %v", h.ID, err)
+	}
+}
@@ -0,0 +1,72 @@
+package bot
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Messenger is the part of the Telegram Bot API the handlers use: sending messages, locations, photos and
+// documents, editing cards, answering callbacks, deleting messages and setting the command menu. botMessenger
+// implements it over *tgbotapi.BotAPI; tests use a fake that records the calls. Polling and file downloads stay
+// on the concrete client.
+type Messenger interface {
+	SendMessage(msg tgbotapi.MessageConfig) (tgbotapi.Message, error)
+	SendLocation(loc tgbotapi.LocationConfig) error
+	SendPhoto(photo tgbotapi.PhotoConfig) error
+	SendDocument(doc tgbotapi.DocumentConfig) error
+	EditMessage(edit tgbotapi.EditMessageTextConfig) error
+	AnswerCallback(cb tgbotapi.CallbackConfig) error
+	DeleteMessage(chatID int64, messageID int) error
+	SetCommands(commands ...tgbotapi.BotCommand) error
+}
+
+// messenger returns api as a Messenger, keeping a nil client nil (a nil *botMessenger in an interface is not == nil).
+func messenger(api *tgbotapi.BotAPI) Messenger {
+	if api == nil {
+		return nil
+	}
+	return &botMessenger{api: api}
+}
+
+// botMessenger is the Messenger of a real bot.
+type botMessenger struct {
+	api *tgbotapi.BotAPI
+}
+
+func (m *botMessenger) SendMessage(msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
+	return m.api.Send(msg)
+}
+
+func (m *botMessenger) SendLocation(loc tgbotapi.LocationConfig) error {
+	_, err := m.api.Send(loc)
+	return err
+}
+
+func (m *botMessenger) SendPhoto(photo tgbotapi.PhotoConfig) error {
+	_, err := m.api.Send(photo)
+	return err
+}
+
+func (m *botMessenger) SendDocument(doc tgbotapi.DocumentConfig) error {
+	_, err := m.api.Send(doc)
+	return err
+}
+
+func (m *botMessenger) EditMessage(edit tgbotapi.EditMessageTextConfig) error {
+	_, err := m.api.Send(edit)
+	return err
+}
+
+func (m *botMessenger) AnswerCallback(cb tgbotapi.CallbackConfig) error {
+	_, err := m.api.Request(cb)
+	return err
+}
+
+func (m *botMessenger) DeleteMessage(chatID int64, messageID int) error {
+	_, err := m.api.Request(tgbotapi.NewDeleteMessage(chatID, messageID))
+	return err
+}
+
+func (m *botMessenger) SetCommands(commands ...tgbotapi.BotCommand) error {
+	_, err := m.api.Request(tgbotapi.NewSetMyCommands(commands...))
+	return err
+}
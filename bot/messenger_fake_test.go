@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// FakeMessage is one call recorded by FakeMessenger.
+type FakeMessage struct {
+	Kind      string // "text", "location", "photo", "document", "edit", "callback", "delete" or "commands"
+	ChatID    int64
+	MessageID int // message ID given to a sent message, or the edited one
+	Text      string
+	Buttons   []string // callback data of the inline buttons, row by row
+	// RemoveKeyboard is set when the message hides the reply keyboard.
+	RemoveKeyboard bool
+	Lat, Lon       float64
+}
+
+// FakeMessenger is an in-memory Messenger: it records every call and hands out increasing message IDs.
+// Fail makes the next sends return an error (e.g. a *tgbotapi.Error to simulate Telegram refusing).
+type FakeMessenger struct {
+	mu       sync.Mutex
+	nextID   int
+	messages []FakeMessage
+	fail     []error
+}
+
+// NewFakeMessenger returns an empty fake.
+func NewFakeMessenger() *FakeMessenger {
+	return &FakeMessenger{nextID: 100}
+}
+
+// Fail queues errors returned by the next calls, one per call.
+func (f *FakeMessenger) Fail(errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail = append(f.fail, errs...)
+}
+
+func (f *FakeMessenger) SendMessage(msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
+	m := FakeMessage{Kind: "text", ChatID: msg.ChatID, Text: msg.Text, Buttons: fakeButtons(msg.ReplyMarkup)}
+	_, m.RemoveKeyboard = msg.ReplyMarkup.(tgbotapi.ReplyKeyboardRemove)
+	if err := f.record(&m, true); err != nil {
+		return tgbotapi.Message{}, err
+	}
+	return tgbotapi.Message{MessageID: m.MessageID, Chat: &tgbotapi.Chat{ID: m.ChatID}, Text: m.Text}, nil
+}
+
+func (f *FakeMessenger) SendLocation(loc tgbotapi.LocationConfig) error {
+	return f.record(&FakeMessage{Kind: "location", ChatID: loc.ChatID, Lat: loc.Latitude, Lon: loc.Longitude}, true)
+}
+
+func (f *FakeMessenger) SendPhoto(photo tgbotapi.PhotoConfig) error {
+	return f.record(&FakeMessage{Kind: "photo", ChatID: photo.ChatID, Text: photo.Caption, Buttons: fakeButtons(photo.ReplyMarkup)}, true)
+}
+
+func (f *FakeMessenger) SendDocument(doc tgbotapi.DocumentConfig) error {
+	return f.record(&FakeMessage{Kind: "document", ChatID: doc.ChatID, Text: doc.Caption}, true)
+}
+
+func (f *FakeMessenger) EditMessage(edit tgbotapi.EditMessageTextConfig) error {
+	return f.record(&FakeMessage{Kind: "edit", ChatID: edit.ChatID, MessageID: edit.MessageID, Text: edit.Text, Buttons: fakeButtons(edit.ReplyMarkup)}, false)
+}
+
+func (f *FakeMessenger) AnswerCallback(cb tgbotapi.CallbackConfig) error {
+	return f.record(&FakeMessage{Kind: "callback", Text: cb.Text}, false)
+}
+
+func (f *FakeMessenger) DeleteMessage(chatID int64, messageID int) error {
+	return f.record(&FakeMessage{Kind: "delete", ChatID: chatID, MessageID: messageID}, false)
+}
+
+func (f *FakeMessenger) SetCommands(commands ...tgbotapi.BotCommand) error {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.Command)
+	}
+	return f.record(&FakeMessage{Kind: "commands", Text: strings.Join(names, " ")}, false)
+}
+
+// record stores m, giving it the next message ID if it is a new message, or returns the next queued failure.
+func (f *FakeMessenger) record(m *FakeMessage, newMessage bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.popFail(); err != nil {
+		return err
+	}
+	if newMessage {
+		f.nextID++
+		m.MessageID = f.nextID
+	}
+	f.messages = append(f.messages, *m)
+	return nil
+}
+
+func (f *FakeMessenger) popFail() error {
+	if len(f.fail) == 0 {
+		return nil
+	}
+	err := f.fail[0]
+	f.fail = f.fail[1:]
+	return err
+}
+
+// Messages returns everything recorded so far.
+func (f *FakeMessenger) Messages() []FakeMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeMessage(nil), f.messages...)
+}
+
+// To returns the recorded calls for one chat.
+func (f *FakeMessenger) To(chatID int64) []FakeMessage {
+	var list []FakeMessage
+	for _, m := range f.Messages() {
+		if m.ChatID == chatID {
+			list = append(list, m)
+		}
+	}
+	return list
+}
+
+// Last returns the last recorded call for chatID of kind (any kind if ""), and false if there is none.
+func (f *FakeMessenger) Last(chatID int64, kind string) (FakeMessage, bool) {
+	list := f.To(chatID)
+	for i := len(list) - 1; i >= 0; i-- {
+		if kind == "" || list[i].Kind == kind {
+			return list[i], true
+		}
+	}
+	return FakeMessage{}, false
+}
+
+// Reset forgets the recorded calls.
+func (f *FakeMessenger) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = nil
+}
+
+func fakeButtons(markup interface{}) []string {
+	var kb *tgbotapi.InlineKeyboardMarkup
+	switch v := markup.(type) {
+	case tgbotapi.InlineKeyboardMarkup:
+		kb = &v
+	case *tgbotapi.InlineKeyboardMarkup:
+		kb = v
+	}
+	if kb == nil {
+		return nil
+	}
+	var data []string
+	for _, row := range kb.InlineKeyboard {
+		for _, btn := range row {
+			if btn.CallbackData != nil {
+				data = append(data, *btn.CallbackData)
+			}
+		}
+	}
+	return data
+}
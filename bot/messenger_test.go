@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestFakeMessengerRecords(t *testing.T) {
+	f := NewFakeMessenger()
+	msg := tgbotapi.NewMessage(1, "card")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("OK", "order_status:7:preparing"),
+	))
+	sent, err := f.SendMessage(msg)
+	if err != nil || sent.MessageID == 0 {
+		t.Fatalf("Send = %+v, %v", sent, err)
+	}
+	if err := f.EditMessage(tgbotapi.NewEditMessageText(1, sent.MessageID, "card v2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SendLocation(tgbotapi.NewLocation(1, 41.3, 69.2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AnswerCallback(tgbotapi.NewCallback("cb", "done")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := f.To(1)
+	if len(got) != 3 || got[0].Kind != "text" || got[1].Kind != "edit" || got[2].Kind != "location" {
+		t.Fatalf("To(1) = %+v", got)
+	}
+	if len(got[0].Buttons) != 1 || got[0].Buttons[0] != "order_status:7:preparing" {
+		t.Errorf("buttons = %v", got[0].Buttons)
+	}
+	if got[1].MessageID != sent.MessageID || got[1].Text != "card v2" {
+		t.Errorf("edit = %+v, want message %d edited", got[1], sent.MessageID)
+	}
+	if m, ok := f.Last(0, "callback"); !ok || m.Text != "done" {
+		t.Errorf("callback = %+v, %v", m, ok)
+	}
+
+	blocked := &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}
+	f.Fail(blocked)
+	if _, err := f.SendMessage(tgbotapi.NewMessage(2, "x")); !errors.Is(err, blocked) {
+		t.Errorf("SendMessage after Fail = %v, want %v", err, blocked)
+	}
+	if _, err := f.SendMessage(tgbotapi.NewMessage(2, "x")); err != nil {
+		t.Errorf("Fail applies to one call only, got %v", err)
+	}
+}
+
+func TestMessengerKeepsNil(t *testing.T) {
+	if m := messenger(nil); m != nil {
+		t.Errorf("messenger(nil) = %#v, want nil interface", m)
+	}
+	if m := withSendPriority(nil, SendPriorityHigh); m != nil {
+		t.Errorf("withSendPriority(nil) = %#v, want nil", m)
+	}
+}
+
+func TestWithSendPriorityKeepsLimiter(t *testing.T) {
+	limiter := NewSendLimiter(25, time.Second)
+	api := &tgbotapi.BotAPI{Client: &limitedClient{limiter: limiter, next: http.DefaultClient, priority: SendPriorityNormal}}
+	m := withSendPriority(messenger(api), SendPriorityLow)
+	bm, ok := m.(*botMessenger)
+	if !ok {
+		t.Fatalf("withSendPriority = %T, want *botMessenger", m)
+	}
+	c, ok := bm.api.Client.(*limitedClient)
+	if !ok || c.limiter != limiter || c.priority != SendPriorityLow {
+		t.Errorf("client = %#v, want the same limiter on the low lane", bm.api.Client)
+	}
+	if api.Client.(*limitedClient).priority != SendPriorityNormal {
+		t.Error("withSendPriority changed the original client")
+	}
+}
@@ -0,0 +1,344 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"food-telegram/lang"
+	"food-telegram/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	outboxBatchSize = 20
+	// outboxLease is how long a claimed row is left alone before another pass may try it again (worker crashed mid-send).
+	outboxLease = time.Minute
+)
+
+// outboxPermanentError marks a delivery that cannot succeed on retry (e.g. the bot for the audience is not configured).
+type outboxPermanentError struct{ msg string }
+
+func (e outboxPermanentError) Error() string { return e.msg }
+
+// KickOutbox wakes the outbox worker after a state change queued notifications.
+func (b *Bot) KickOutbox() {
+	select {
+	case b.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// OutboxWake is signalled by KickOutbox; the worker loop delivers on it as well as on its ticker.
+func (b *Bot) OutboxWake() <-chan struct{} {
+	return b.outboxWake
+}
+
+// DeliverOutbox delivers the due notification_outbox rows. Event rows fan out into delivery rows, which are
+// picked up in the same pass. Failed sends are retried with backoff; permanent failures are marked failed.
+func (b *Bot) DeliverOutbox(ctx context.Context) {
+	for {
+		items, err := services.ClaimOutbox(ctx, outboxBatchSize, outboxLease)
+		if err != nil {
+			log.Printf("outbox: claim: %v", err)
+			return
+		}
+		if len(items) == 0 {
+			return
+		}
+		for _, it := range items {
+			b.deliverOutboxItem(ctx, it)
+		}
+	}
+}
+
+func (b *Bot) deliverOutboxItem(ctx context.Context, it services.OutboxItem) {
+	var err error
+	switch it.Kind {
+	case services.OutboxKindOrderNew:
+		err = b.fanOutOrderCards(ctx, it, true)
+	case services.OutboxKindOrderCards:
+		err = b.fanOutOrderCards(ctx, it, false)
+	case services.OutboxKindOfferDrivers:
+		err = b.fanOutDriverOffers(ctx, it)
+	case services.OutboxKindOrderCard:
+		err = b.sendOutboxOrderCard(ctx, it)
+	case services.OutboxKindDriverOffer:
+		err = b.sendOutboxDriverOffer(ctx, it)
+	case services.OutboxKindMessage:
+		err = b.sendOutboxMessage(it)
+	default:
+		err = outboxPermanentError{"unknown outbox kind " + it.Kind}
+	}
+	if err == nil {
+		if err := services.MarkOutboxSent(ctx, it.ID); err != nil {
+			log.Printf("outbox: mark sent id=%d: %v", it.ID, err)
+		}
+		return
+	}
+	permanent, retryAfter := outboxFailure(err)
+	if permanent || it.Attempts >= services.OutboxMaxAttempts {
+		log.Printf("outbox: giving up id=%d kind=%s order_id=%d chat_id=%d after %d attempt(s): %v", it.ID, it.Kind, it.OrderID, it.ChatID, it.Attempts, err)
+		if err := services.MarkOutboxFailed(ctx, it.ID, err.Error()); err != nil {
+			log.Printf("outbox: mark failed id=%d: %v", it.ID, err)
+		}
+		return
+	}
+	delay := services.OutboxRetryDelay(it.Attempts, retryAfter)
+	log.Printf("outbox: retry id=%d kind=%s in %s: %v", it.ID, it.Kind, delay, err)
+	if err := services.MarkOutboxRetry(ctx, it.ID, delay, err.Error()); err != nil {
+		log.Printf("outbox: mark retry id=%d: %v", it.ID, err)
+	}
+}
+
+// outboxFailure classifies a delivery error: Telegram 4xx (except 429) is permanent; retry_after comes with 429.
+func outboxFailure(err error) (permanent bool, retryAfter time.Duration) {
+	var perm outboxPermanentError
+	if errors.As(err, &perm) {
+		return true, 0
+	}
+	var tgErr *tgbotapi.Error
+	if errors.As(err, &tgErr) {
+		return services.TelegramErrorPermanent(tgErr.Code), time.Duration(tgErr.RetryAfter) * time.Second
+	}
+	return false, 0
+}
+
+// fanOutOrderCards queues a card for every recipient of the order: staff who already have the card (else the on-shift
+// staff, else the superadmin), the customer and the assigned driver. A new order also sends staff the delivery pin.
+func (b *Bot) fanOutOrderCards(ctx context.Context, it services.OutboxItem, isNew bool) error {
+	o, err := services.GetOrder(ctx, it.OrderID)
+	if err != nil {
+		return err
+	}
+	if o == nil {
+		return nil
+	}
+	var adminChatIDs []int64
+	pointers, err := services.ListOrderMessagePointers(ctx, o.ID, "admin")
+	if err != nil {
+		return err
+	}
+	for _, p := range pointers {
+		adminChatIDs = append(adminChatIDs, p.ChatID)
+	}
+	if len(adminChatIDs) == 0 && o.LocationID != 0 {
+		admins, err := services.GetBranchAdminsWithLang(ctx, o.LocationID)
+		if err != nil {
+			return err
+		}
+		for _, a := range admins {
+			adminChatIDs = append(adminChatIDs, a.AdminUserID)
+		}
+	}
+	if len(adminChatIDs) == 0 && b.admin != 0 {
+		adminChatIDs = []int64{b.admin}
+	}
+	if len(adminChatIDs) == 0 {
+		log.Printf("warning: no branch admins for order #%d", o.ID)
+	}
+
+	var children []services.OutboxItem
+	card := func(audience string, chatID int64) {
+		children = append(children, services.OutboxItem{Kind: services.OutboxKindOrderCard, OrderID: o.ID, Audience: audience, ChatID: chatID})
+	}
+	for _, chatID := range adminChatIDs {
+		card("admin", chatID)
+	}
+	if customerChatID, err := strconv.ParseInt(o.ChatID, 10, 64); err == nil && customerChatID != 0 {
+		card("customer", customerChatID)
+	}
+	if o.DriverID != nil && *o.DriverID != "" {
+		driver, err := services.GetDriverByID(ctx, *o.DriverID)
+		if err != nil {
+			return err
+		}
+		if driver != nil && driver.ChatID != 0 {
+			card("driver", driver.ChatID)
+		}
+	}
+	if isNew {
+		lat, lon, err := services.GetOrderCoordinates(ctx, o.ID)
+		if err != nil {
+			return err
+		}
+		if lat != 0 || lon != 0 {
+			for _, chatID := range adminChatIDs {
+				children = append(children, services.OutboxItem{
+					Kind: services.OutboxKindMessage, OrderID: o.ID, Audience: "admin", ChatID: chatID,
+					Payload: services.OutboxPayload{Lat: lat, Lon: lon},
+				})
+			}
+		}
+	}
+	return services.FanOutOutbox(ctx, it.ID, children)
+}
+
+// fanOutDriverOffers queues an Accept offer for every nearby online driver who can take the ready order.
+// pushed_at is claimed once per order so a repeated event does not offer it twice.
+func (b *Bot) fanOutDriverOffers(ctx context.Context, it services.OutboxItem) error {
+	o, err := services.GetOrder(ctx, it.OrderID)
+	if err != nil {
+		return err
+	}
+	if o == nil || o.Status != services.OrderStatusReady || b.driverBotAPI == nil {
+		return nil
+	}
+	orderLat, orderLon, err := services.GetOrderCoordinates(ctx, o.ID)
+	if err != nil {
+		return err
+	}
+	if orderLat == 0 && orderLon == 0 {
+		return nil
+	}
+	radiusKm := b.cfg.Delivery.DriverPushRadiusKm
+	if radiusKm <= 0 {
+		radiusKm = 5
+	}
+	drivers, err := services.GetNearbyOnlineDriversForOrder(ctx, orderLat, orderLon, radiusKm, 10, o.LocationID, b.cfg.Delivery.DriverMaxActiveOrders)
+	if err != nil {
+		return fmt.Errorf("get nearby drivers: %w", err)
+	}
+	claimed, err := services.TrySetOrderPushedAt(ctx, o.ID)
+	if err != nil {
+		return fmt.Errorf("claim pushed_at: %w", err)
+	}
+	if !claimed {
+		log.Printf("push order to drivers: order_id=%d skipped due to pushed_at (already pushed)", o.ID)
+		return services.FanOutOutbox(ctx, it.ID, nil)
+	}
+	acceptData := "driver_accept:" + strconv.FormatInt(o.ID, 10)
+	// Include items total, delivery fee, and grand total so driver sees full price
+	msgText := "📦 Yangi buyurtma yaqin atrofda!\n\nMasofa: %.2f km\nBuyurtma: %d so'm\nYetkazib berish: %d so'm\nJami: %d so'm\n\nQabul qilasizmi?"
+	var children []services.OutboxItem
+	for _, d := range drivers {
+		text := fmt.Sprintf(msgText, d.DistanceKm, o.ItemsTotal, o.DeliveryFee, o.GrandTotal)
+		if d.HeadingFromBranch {
+			text = "🔁 Shu filialdan yana bir buyurtma (birga olib ketish mumkin).\n\n" + text
+		}
+		children = append(children, services.OutboxItem{
+			Kind: services.OutboxKindDriverOffer, OrderID: o.ID, Audience: "driver", ChatID: d.ChatID,
+			Payload: services.OutboxPayload{
+				Text:    text,
+				Buttons: [][]services.OrderCardButton{{{Text: "Accept Order #" + strconv.FormatInt(o.ID, 10), CallbackData: acceptData}}},
+			},
+		})
+		log.Printf("push order to driver: order_id=%d driver_chat_id=%d distance_km=%.2f", o.ID, d.ChatID, d.DistanceKm)
+	}
+	return services.FanOutOutbox(ctx, it.ID, children)
+}
+
+// sendOutboxOrderCard renders the order's current card for the recipient and upserts it.
+func (b *Bot) sendOutboxOrderCard(ctx context.Context, it services.OutboxItem) error {
+	if b.apiForAudience(it.Audience) == nil {
+		return outboxPermanentError{it.Audience + " bot is not configured"}
+	}
+	unlock := b.lockOrder(it.OrderID)
+	defer unlock()
+
+	o, err := services.GetOrder(ctx, it.OrderID)
+	if err != nil {
+		return err
+	}
+	if o == nil {
+		return nil
+	}
+	var driver *services.Driver
+	if o.DriverID != nil && *o.DriverID != "" {
+		if driver, err = services.GetDriverByID(ctx, *o.DriverID); err != nil {
+			return err
+		}
+	}
+	var content services.OrderCardContent
+	switch it.Audience {
+	case "admin":
+		adminLang, _ := services.GetAdminOrderLang(ctx, it.ChatID)
+		if adminLang == "" {
+			adminLang = lang.Uz
+		}
+		content = services.BuildAdminCard(o, driver, adminLang)
+	case "customer":
+		var trackURL string
+		if o.Status == services.OrderStatusDelivering && driver != nil {
+			loc, _ := services.GetDriverLocation(ctx, driver.ID)
+			if loc != nil {
+				trackURL = fmt.Sprintf("https://www.google.com/maps?q=%f,%f", loc.Lat, loc.Lon)
+			}
+		}
+		eta, err := services.GetOrderETAMinutes(ctx, o, b.cfg.Delivery.DriverAvgSpeedKmh)
+		if err != nil {
+			log.Printf("order card eta order_id=%d: %v", o.ID, err)
+		}
+		var rating *services.OrderRating
+		if o.Status == services.OrderStatusCompleted {
+			if rating, err = services.GetOrderRating(ctx, o.ID); err != nil {
+				return err
+			}
+		}
+		content = services.BuildCustomerCard(o, driver, trackURL, eta, rating)
+	case "driver":
+		content = services.BuildDriverCard(o, lang.Uz)
+	default:
+		return outboxPermanentError{"unknown audience " + it.Audience}
+	}
+	return b.UpsertOrderCard(ctx, it.Audience, o.ID, it.ChatID, content)
+}
+
+// sendOutboxDriverOffer sends a queued offer unless the order was taken (or is no longer ready) meanwhile.
+func (b *Bot) sendOutboxDriverOffer(ctx context.Context, it services.OutboxItem) error {
+	ok, err := services.OrderAvailableForPush(ctx, it.OrderID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		log.Printf("push order to drivers: order_id=%d skipped due to no longer available (status/assigned)", it.OrderID)
+		return nil
+	}
+	return b.sendOutboxMessage(it)
+}
+
+// sendOutboxMessage sends a queued text (with optional buttons) or location through the audience's bot.
+func (b *Bot) sendOutboxMessage(it services.OutboxItem) error {
+	api := b.apiForAudience(it.Audience)
+	if api == nil {
+		return outboxPermanentError{it.Audience + " bot is not configured"}
+	}
+	if it.Payload.Bulk {
+		api = withSendPriority(api, SendPriorityLow)
+	}
+	if it.Payload.Text == "" && (it.Payload.Lat != 0 || it.Payload.Lon != 0) {
+		return api.SendLocation(tgbotapi.NewLocation(it.ChatID, it.Payload.Lat, it.Payload.Lon))
+	}
+	msg := tgbotapi.NewMessage(it.ChatID, it.Payload.Text)
+	if kb := cardMarkup(services.OrderCardContent{Buttons: it.Payload.Buttons}); kb != nil {
+		msg.ReplyMarkup = *kb
+	} else if it.Payload.RemoveKeyboard {
+		msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
+	}
+	_, err := api.SendMessage(msg)
+	return err
+}
+
+// outboxButtons converts an inline keyboard to outbox payload buttons (cardMarkup turns them back).
+func outboxButtons(kb tgbotapi.InlineKeyboardMarkup) [][]services.OrderCardButton {
+	rows := make([][]services.OrderCardButton, 0, len(kb.InlineKeyboard))
+	for _, r := range kb.InlineKeyboard {
+		row := make([]services.OrderCardButton, 0, len(r))
+		for _, btn := range r {
+			c := services.OrderCardButton{Text: btn.Text}
+			if btn.CallbackData != nil {
+				c.CallbackData = *btn.CallbackData
+			}
+			if btn.URL != nil {
+				c.URL = *btn.URL
+			}
+			row = append(row, c)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
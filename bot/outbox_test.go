@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+
+	"food-telegram/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestOutboxButtonsRoundTrip(t *testing.T) {
+	kb := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Online", "driver:online"),
+			tgbotapi.NewInlineKeyboardButtonURL("Xarita", "https://maps.example/1"),
+		),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Hujjatlar", "driver:docs")),
+	)
+	buttons := outboxButtons(kb)
+	want := [][]services.OrderCardButton{
+		{{Text: "Online", CallbackData: "driver:online"}, {Text: "Xarita", URL: "https://maps.example/1"}},
+		{{Text: "Hujjatlar", CallbackData: "driver:docs"}},
+	}
+	if !reflect.DeepEqual(buttons, want) {
+		t.Fatalf("outboxButtons = %+v", buttons)
+	}
+	if back := cardMarkup(services.OrderCardContent{Buttons: buttons}); back == nil || !reflect.DeepEqual(*back, kb) {
+		t.Errorf("cardMarkup(outboxButtons(kb)) = %+v, want %+v", back, kb)
+	}
+}
+
+func TestSendOutboxMessageKeyboard(t *testing.T) {
+	driver := NewFakeMessenger()
+	b := &Bot{driverBotAPI: driver}
+
+	notice := services.OutboxItem{Audience: "driver", ChatID: 7, Payload: services.OutboxPayload{Text: "Offline", RemoveKeyboard: true, Bulk: true}}
+	if err := b.sendOutboxMessage(notice); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := driver.Last(7, "text"); !ok || m.Text != "Offline" || !m.RemoveKeyboard {
+		t.Errorf("notice = %+v, want text with the reply keyboard removed", m)
+	}
+
+	panel := services.OutboxItem{Audience: "driver", ChatID: 7, Payload: services.OutboxPayload{
+		Text:           "Panel",
+		Buttons:        [][]services.OrderCardButton{{{Text: "Online", CallbackData: "driver:online"}}},
+		RemoveKeyboard: true,
+	}}
+	if err := b.sendOutboxMessage(panel); err != nil {
+		t.Fatal(err)
+	}
+	m, _ := driver.Last(7, "text")
+	if m.RemoveKeyboard || !reflect.DeepEqual(m.Buttons, []string{"driver:online"}) {
+		t.Errorf("panel = %+v, want inline buttons", m)
+	}
+
+	if err := (&Bot{}).sendOutboxMessage(notice); err == nil {
+		t.Error("send without a driver bot succeeded")
+	}
+}
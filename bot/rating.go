@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"food-telegram/lang"
+	"food-telegram/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleRateCallback stores a star from the completed order card ("rate:<order_id>:<food|delivery>:<1-5>"); the
+// card itself is refreshed through the outbox.
+func (b *Bot) handleRateCallback(chatID int64, userID int64, data string) {
+	parts := strings.Split(strings.TrimPrefix(data, "rate:"), ":")
+	if len(parts) != 3 {
+		return
+	}
+	orderID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return
+	}
+	stars, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return
+	}
+	if err := services.RateOrder(context.Background(), orderID, userID, parts[1], stars); err != nil {
+		b.sendLang(chatID, userID, "rate_failed", err.Error())
+		return
+	}
+	b.KickOutbox()
+}
+
+// handleRateCommentCallback ("rate_comment:<order_id>") waits for the customer's next message as the comment.
+func (b *Bot) handleRateCommentCallback(chatID int64, userID int64, data string) {
+	orderID, err := strconv.ParseInt(strings.TrimPrefix(data, "rate_comment:"), 10, 64)
+	if err != nil {
+		return
+	}
+	b.ratingCommentsMu.Lock()
+	b.ratingComments[userID] = orderID
+	b.ratingCommentsMu.Unlock()
+	b.sendLang(chatID, userID, "rate_comment_prompt", orderID)
+}
+
+// replyKeyboardKeys are the customer bot's reply-keyboard buttons; clients that cannot share a location or contact
+// send the label as plain text.
+var replyKeyboardKeys = []string{"share_location", "share_phone"}
+
+// isReplyKeyboardLabel reports whether text is the label of a reply-keyboard button in either language.
+func isReplyKeyboardLabel(text string) bool {
+	for _, key := range replyKeyboardKeys {
+		if text == lang.T(lang.Uz, key) || text == lang.T(lang.Ru, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleRatingComment takes the message as the comment the customer was asked for; it reports whether it did.
+// Only free text is a comment: any other message (a command, a keyboard button, a shared location or contact)
+// cancels it, /cancel answers it and the rest run as usual.
+func (b *Bot) handleRatingComment(msg *tgbotapi.Message) bool {
+	chatID, userID := msg.Chat.ID, msg.From.ID
+	text := strings.TrimSpace(msg.Text)
+	b.ratingCommentsMu.Lock()
+	orderID, ok := b.ratingComments[userID]
+	if ok {
+		delete(b.ratingComments, userID)
+	}
+	b.ratingCommentsMu.Unlock()
+	if !ok {
+		return false
+	}
+	if text == "/cancel" {
+		b.sendLang(chatID, userID, "rate_comment_cancelled")
+		return true
+	}
+	if text == "" || strings.HasPrefix(text, "/") || isReplyKeyboardLabel(text) || msg.Location != nil || msg.Contact != nil {
+		return false
+	}
+	if err := services.SetOrderRatingComment(context.Background(), orderID, userID, text); err != nil {
+		b.sendLang(chatID, userID, "rate_failed", err.Error())
+		return true
+	}
+	b.sendLang(chatID, userID, "rate_comment_saved")
+	b.KickOutbox()
+	return true
+}
+
+// locationRatingLabel is the " ⭐ 4.6" shown after a branch name in the location list ("" without ratings).
+func locationRatingLabel(scores map[int64]services.RatingScore, locationID int64) string {
+	s, ok := scores[locationID]
+	if !ok || s.Count == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" ⭐ %.1f", s.Avg)
+}
+
+// recentRatingsShown is how many of the latest ratings /ratings lists for a branch.
+const recentRatingsShown = 10
+
+// formatRatingScores renders the superadmin's branch and driver scores.
+func formatRatingScores(branches, drivers []services.RatingScoreRow) string {
+	var b strings.Builder
+	b.WriteString("⭐ Baholar\n\n🏪 Filiallar (taom):")
+	writeRatingRows(&b, branches)
+	b.WriteString("\n\n🚗 Haydovchilar (yetkazish):")
+	writeRatingRows(&b, drivers)
+	return b.String()
+}
+
+func writeRatingRows(b *strings.Builder, rows []services.RatingScoreRow) {
+	if len(rows) == 0 {
+		b.WriteString("\n  hali baholanmagan")
+		return
+	}
+	for _, r := range rows {
+		name := r.Name
+		if name == "" {
+			name = "ID " + r.ID
+		}
+		b.WriteString(fmt.Sprintf("\n  • %s — %s", name, r.Score))
+	}
+}
+
+// formatBranchRatings renders a branch's score and its latest ratings.
+func formatBranchRatings(name string, score services.RatingScore, recent []services.RecentRating) string {
+	var b strings.Builder
+	b.WriteString("⭐ Baholar — " + name + "\n\n")
+	if score.Count == 0 {
+		b.WriteString("Taom: hali baholanmagan")
+	} else {
+		b.WriteString("Taom: " + score.String())
+	}
+	if len(recent) == 0 {
+		return b.String()
+	}
+	b.WriteString("\n\n🕘 Oxirgi baholar:")
+	for _, r := range recent {
+		b.WriteString(fmt.Sprintf("\n#%d %s", r.OrderID, r.CreatedAt.In(services.BranchTimezone).Format("01-02 15:04")))
+		if r.Food > 0 {
+			b.WriteString(" 🍽 " + services.RatingStars(r.Food))
+		}
+		if r.Delivery > 0 {
+			b.WriteString(" 🛵 " + services.RatingStars(r.Delivery))
+		}
+		if r.Low() {
+			b.WriteString(" ⚠️")
+		}
+		if r.Comment != "" {
+			b.WriteString("\n  💬 " + r.Comment)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"food-telegram/lang"
+	"food-telegram/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestLocationRatingLabel(t *testing.T) {
+	scores := map[int64]services.RatingScore{3: {Avg: 4.56, Count: 12}}
+	if got := locationRatingLabel(scores, 3); got != " ⭐ 4.6" {
+		t.Errorf("rated branch label = %q", got)
+	}
+	if got := locationRatingLabel(scores, 4); got != "" {
+		t.Errorf("unrated branch label = %q", got)
+	}
+}
+
+func TestFormatRatings(t *testing.T) {
+	out := formatRatingScores(
+		[]services.RatingScoreRow{{ID: "3", Name: "Chilonzor", Score: services.RatingScore{Avg: 4.5, Count: 8}}},
+		[]services.RatingScoreRow{{ID: "a1b2", Score: services.RatingScore{Avg: 3, Count: 2}}},
+	)
+	for _, want := range []string{"Chilonzor — 4.5 (8)", "ID a1b2 — 3.0 (2)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("scores miss %q:\n%s", want, out)
+		}
+	}
+	if out := formatRatingScores(nil, nil); strings.Count(out, "hali baholanmagan") != 2 {
+		t.Errorf("empty scores:\n%s", out)
+	}
+
+	at := time.Date(2024, 3, 4, 13, 5, 0, 0, services.BranchTimezone)
+	out = formatBranchRatings("Chilonzor", services.RatingScore{Avg: 4, Count: 2}, []services.RecentRating{
+		{OrderRating: services.OrderRating{OrderID: 41, Food: 1, Delivery: 5, Comment: "Sovuq edi"}, CreatedAt: at},
+		{OrderRating: services.OrderRating{OrderID: 40, Food: 5}, CreatedAt: at},
+	})
+	for _, want := range []string{"Taom: 4.0 (2)", "#41 03-04 13:05 🍽 ★☆☆☆☆ 🛵 ★★★★★ ⚠️", "💬 Sovuq edi", "#40 03-04 13:05 🍽 ★★★★★"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("branch ratings miss %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRatingCommentCancelledByButtons(t *testing.T) {
+	customer := NewFakeMessenger()
+	b := &Bot{api: customer, ratingComments: make(map[int64]int64), userLang: map[int64]string{5: lang.Uz}}
+	msg := func(text string) *tgbotapi.Message {
+		return &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 5}, From: &tgbotapi.User{ID: 5}, Text: text}
+	}
+	location := msg("")
+	location.Location = &tgbotapi.Location{Latitude: 41.3, Longitude: 69.2}
+	contact := msg("")
+	contact.Contact = &tgbotapi.Contact{PhoneNumber: "+998901234567"}
+
+	for name, m := range map[string]*tgbotapi.Message{
+		"location":       location,
+		"contact":        contact,
+		"location label": msg(lang.T(lang.Ru, "share_location")),
+		"phone label":    msg(lang.T(lang.Uz, "share_phone")),
+		"command":        msg("/menu"),
+		"sticker":        msg(""),
+	} {
+		b.ratingComments[5] = 41
+		if b.handleRatingComment(m) {
+			t.Errorf("%s was taken as the comment", name)
+		}
+		if _, pending := b.ratingComments[5]; pending {
+			t.Errorf("%s left the comment pending", name)
+		}
+	}
+	if n := len(customer.Messages()); n != 0 {
+		t.Errorf("cancelling by a button sent %d message(s)", n)
+	}
+
+	b.ratingComments[5] = 41
+	if !b.handleRatingComment(msg("/cancel")) {
+		t.Error("/cancel was not answered")
+	}
+	if b.handleRatingComment(msg("Juda mazali")) {
+		t.Error("text without a pending comment was taken")
+	}
+}
@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"food-telegram/lang"
+	"food-telegram/services"
+)
+
+const reportUsage = "Ishlatish: /report [dan YYYY-MM-DD] [gacha YYYY-MM-DD] [location_id]"
+
+// parseReportArgs reads the /report arguments (see services.ParseReportRange).
+func parseReportArgs(args string, now time.Time) (from, to time.Time, locationID int64, err error) {
+	from, to, locationID, err = services.ParseReportRange(strings.Fields(args), now)
+	if errors.Is(err, services.ErrReportArgs) {
+		err = errors.New(reportUsage)
+	}
+	return from, to, locationID, err
+}
+
+var heatmapLevels = []rune(" ▁▂▃▄▅▆▇█")
+
+// heatmapRow draws one weekday's 24 hours, scaled to the busiest hour of the week.
+func heatmapRow(hours [24]int, max int) string {
+	var b strings.Builder
+	for _, n := range hours {
+		level := 0
+		if n > 0 && max > 0 {
+			level = 1 + n*(len(heatmapLevels)-2)/max
+		}
+		b.WriteRune(heatmapLevels[level])
+	}
+	return b.String()
+}
+
+// peakHours returns up to n busiest hours of the week ("Ju 19:00 — 12").
+func peakHours(h [7][24]int, n int) []string {
+	type slot struct{ day, hour, orders int }
+	var slots []slot
+	for d := range h {
+		for hr, c := range h[d] {
+			if c > 0 {
+				slots = append(slots, slot{d, hr, c})
+			}
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].orders > slots[j].orders })
+	var out []string
+	for i := 0; i < len(slots) && i < n; i++ {
+		out = append(out, fmt.Sprintf("%s %02d:00 — %d", reportWeekdays[slots[i].day], slots[i].hour, slots[i].orders))
+	}
+	return out
+}
+
+var reportWeekdays = [7]string{"Ya", "Du", "Se", "Ch", "Pa", "Ju", "Sh"}
+
+func formatDuration(d time.Duration) string {
+	m := int(d.Round(time.Minute).Minutes())
+	if m >= 60 {
+		return fmt.Sprintf("%d soat %d daqiqa", m/60, m%60)
+	}
+	return fmt.Sprintf("%d daqiqa", m)
+}
+
+// formatOrdersReport renders the report for the adder bot; title names the branch or "Barcha filiallar".
+func formatOrdersReport(r *services.OrdersReport, title string) string {
+	var b strings.Builder
+	last := r.To.AddDate(0, 0, -1)
+	b.WriteString(fmt.Sprintf("📊 %s\n%s — %s\n\n", title, r.From.Format("2006-01-02"), last.Format("2006-01-02")))
+	if r.Orders == 0 {
+		b.WriteString("📭 Bu davrda buyurtmalar yo'q.")
+		return b.String()
+	}
+	b.WriteString(fmt.Sprintf("Buyurtmalar: %d\n", r.Orders))
+	statuses := make([]string, 0, len(r.ByStatus))
+	for st := range r.ByStatus {
+		statuses = append(statuses, st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return r.ByStatus[statuses[i]] > r.ByStatus[statuses[j]] })
+	for _, st := range statuses {
+		b.WriteString(fmt.Sprintf("  • %s: %d\n", lang.T(lang.Uz, "adm_status_"+st), r.ByStatus[st]))
+	}
+	b.WriteString(fmt.Sprintf("Rad etish: %.1f%%\n", r.RejectionRate()*100))
+	b.WriteString(fmt.Sprintf("\n💰 Tushum (yakunlangan): %d so'm\n  • mahsulotlar: %d\n  • yetkazish: %d\nO'rtacha savat: %d so'm\n",
+		r.Revenue, r.ItemsRevenue, r.DeliveryRevenue, r.AvgBasket()))
+
+	b.WriteString("\n⏱ O'rtacha vaqt:\n")
+	if r.PrepSamples > 0 {
+		b.WriteString(fmt.Sprintf("  • tayyorlash: %s (%d ta)\n", formatDuration(r.AvgPrep), r.PrepSamples))
+	} else {
+		b.WriteString("  • tayyorlash: —\n")
+	}
+	if r.DeliverySamples > 0 {
+		b.WriteString(fmt.Sprintf("  • yetkazish: %s (%d ta)\n", formatDuration(r.AvgDelivery), r.DeliverySamples))
+	} else {
+		b.WriteString("  • yetkazish: —\n")
+	}
+
+	b.WriteString(fmt.Sprintf("\n👥 Mijozlar: %d yangi, %d qaytgan\n", r.NewCustomers, r.ReturningCustomers))
+
+	if len(r.TopItems) > 0 {
+		b.WriteString("\n🏆 Eng ko'p sotilgan:\n")
+		for i, it := range r.TopItems {
+			b.WriteString(fmt.Sprintf("%d. %s — %d ta, %d so'm\n", i+1, it.Name, it.Qty, it.Revenue))
+		}
+	}
+
+	max := 0
+	for _, day := range r.Heatmap {
+		for _, n := range day {
+			if n > max {
+				max = n
+			}
+		}
+	}
+	b.WriteString("\n🕘 Soatlar bo'yicha (00 → 23):\n")
+	for _, d := range []int{1, 2, 3, 4, 5, 6, 0} { // Monday first
+		b.WriteString(fmt.Sprintf("%s %s\n", reportWeekdays[d], heatmapRow(r.Heatmap[d], max)))
+	}
+	if peaks := peakHours(r.Heatmap, 3); len(peaks) > 0 {
+		b.WriteString("Eng band: " + strings.Join(peaks, ", "))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
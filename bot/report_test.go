@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"food-telegram/services"
+)
+
+func TestParseReportArgs(t *testing.T) {
+	now := time.Date(2024, 3, 10, 23, 30, 0, 0, services.BranchTimezone)
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, services.BranchTimezone) }
+
+	from, to, loc, err := parseReportArgs("", now)
+	if err != nil || !from.Equal(day(4)) || !to.Equal(day(11)) || loc != 0 {
+		t.Errorf("default = %v, %v, %d, %v; want last 7 days", from, to, loc, err)
+	}
+	from, to, loc, err = parseReportArgs("2024-03-01 2024-03-05 7", now)
+	if err != nil || !from.Equal(day(1)) || !to.Equal(day(6)) || loc != 7 {
+		t.Errorf("range = %v, %v, %d, %v", from, to, loc, err)
+	}
+	from, to, _, err = parseReportArgs("2024-03-08", now)
+	if err != nil || !from.Equal(day(8)) || !to.Equal(day(11)) {
+		t.Errorf("from only = %v, %v, %v", from, to, err)
+	}
+	for _, bad := range []string{"2024-03-05 2024-03-01", "abc", "1 2", "0", "2024-03-01 2024-03-02 2024-03-03"} {
+		if _, _, _, err := parseReportArgs(bad, now); err == nil {
+			t.Errorf("parseReportArgs(%q) accepted", bad)
+		}
+	}
+}
+
+func TestFormatOrdersReport(t *testing.T) {
+	from := time.Date(2024, 3, 4, 0, 0, 0, 0, services.BranchTimezone)
+	r := &services.OrdersReport{
+		From: from, To: from.AddDate(0, 0, 7), Orders: 10,
+		ByStatus:     map[string]int{services.OrderStatusCompleted: 8, services.OrderStatusRejected: 2},
+		ItemsRevenue: 400000, DeliveryRevenue: 40000, Revenue: 440000,
+		AvgPrep: 14 * time.Minute, PrepSamples: 8, AvgDelivery: 75 * time.Minute, DeliverySamples: 5,
+		TopItems:     []services.ReportItem{{Name: "Osh", Qty: 12, Revenue: 540000}},
+		NewCustomers: 3, ReturningCustomers: 4,
+	}
+	r.Heatmap[5][19] = 6
+	r.Heatmap[1][12] = 2
+	out := formatOrdersReport(r, "Chilonzor")
+	for _, want := range []string{
+		"Chilonzor", "2024-03-04 — 2024-03-10", "Buyurtmalar: 10", "Rad etish: 20.0%", "O'rtacha savat: 50000",
+		"tayyorlash: 14 daqiqa (8 ta)", "yetkazish: 1 soat 15 daqiqa (5 ta)", "3 yangi, 4 qaytgan", "1. Osh — 12 ta",
+		"Eng band: Ju 19:00 — 6, Du 12:00 — 2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+	if got := heatmapRow(r.Heatmap[5], 6); []rune(got)[19] != '█' || []rune(got)[0] != ' ' {
+		t.Errorf("heatmap row = %q", got)
+	}
+
+	empty := formatOrdersReport(&services.OrdersReport{From: from, To: from.AddDate(0, 0, 1)}, "Barcha filiallar")
+	if !strings.Contains(empty, "buyurtmalar yo'q") {
+		t.Errorf("empty report = %q", empty)
+	}
+}
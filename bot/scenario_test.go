@@ -0,0 +1,539 @@
+package bot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"food-telegram/config"
+	"food-telegram/db"
+	"food-telegram/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The scenarios drive the bots with fake messengers against a real database. TEST_DATABASE_URL must point to a
+// throwaway database: migrations are applied and the tables are truncated before each scenario.
+
+const (
+	scenarioCustomer = int64(5001)
+	scenarioAdmin    = int64(6001)
+	scenarioDriver   = int64(7001)
+)
+
+type scenario struct {
+	t        *testing.T
+	ctx      context.Context
+	customer *FakeMessenger // customer bot (TOKEN)
+	admin    *FakeMessenger // message bot (MESSAGE_TOKEN)
+	driver   *FakeMessenger // driver bot (DRIVER_BOT_TOKEN)
+	b        *Bot
+	d        *DriverBot
+	locID    int64
+	itemID   int64
+}
+
+func newScenario(t *testing.T) *scenario {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	if db.Pool == nil {
+		pool, err := pgxpool.New(ctx, url)
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		db.Pool = pool
+		names, _ := filepath.Glob("../migrations/*.sql")
+		sort.Strings(names)
+		for _, name := range names {
+			sql, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.Pool.Exec(ctx, string(sql)); err != nil {
+				t.Fatalf("apply %s: %v", name, err)
+			}
+		}
+	}
+	if _, err := db.Pool.Exec(ctx, `TRUNCATE TABLE
+		notification_outbox, order_message_pointers, order_status_history, driver_earnings, driver_locations, drivers,
+		checkouts, carts, customer_users, user_delivery_coords, user_locations, branch_admin_access, branch_admins,
+		menu_items, orders, locations, driver_commission_rules, audit_events
+		RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+
+	cfg := &config.Config{Delivery: config.DeliveryConfig{
+		BaseFee: 5000, RatePerKm: 4000, DriverPushRadiusKm: 5, DriverAvgSpeedKmh: 25, DriverMaxActiveOrders: 2,
+	}}
+	s := &scenario{t: t, ctx: ctx, customer: NewFakeMessenger(), admin: NewFakeMessenger(), driver: NewFakeMessenger()}
+	s.b = &Bot{
+		api:              s.customer,
+		messageBot:       s.admin,
+		driverBotAPI:     s.driver,
+		cfg:              cfg,
+		locSuggestions:   make(map[int64][]services.LocationWithDistance),
+		userSharedCoords: make(map[int64]struct{ Lat, Lon float64 }),
+		userLang:         make(map[int64]string),
+		outboxWake:       make(chan struct{}, 1),
+	}
+	s.d = &DriverBot{
+		api:        s.driver,
+		mainBot:    s.customer,
+		messageBot: s.admin,
+		config:     cfg,
+		driverLang: make(map[int64]string),
+		driverReg:  make(map[int64]*driverRegState),
+		driverDocs: make(map[int64]*driverDocState),
+	}
+
+	var err error
+	if s.locID, err = services.AddLocation(ctx, "Chilonzor", 41.2850, 69.2040); err != nil {
+		t.Fatalf("add location: %v", err)
+	}
+	if s.itemID, err = services.AddMenuItemForLocation(ctx, "food", "Lavash", 28000, s.locID); err != nil {
+		t.Fatalf("add menu item: %v", err)
+	}
+	if err := services.AddBranchAdmin(ctx, s.locID, scenarioAdmin, 0, "hash", "uz"); err != nil {
+		t.Fatalf("add branch admin: %v", err)
+	}
+	return s
+}
+
+func (s *scenario) say(userID int64, text string) {
+	s.b.HandleUpdate(tgbotapi.Update{Message: &tgbotapi.Message{
+		From: &tgbotapi.User{ID: userID}, Chat: &tgbotapi.Chat{ID: userID}, Text: text,
+	}})
+}
+
+func callbackUpdate(userID int64, messageID int, data string) tgbotapi.Update {
+	return tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID: "cb", From: &tgbotapi.User{ID: userID}, Data: data,
+		Message: &tgbotapi.Message{MessageID: messageID, Chat: &tgbotapi.Chat{ID: userID}},
+	}}
+}
+
+// tap finds the newest button in the user's chat whose callback data starts with prefix and presses it.
+func (s *scenario) tap(f *FakeMessenger, userID int64, prefix string, handle func(tgbotapi.Update)) string {
+	s.t.Helper()
+	msgs := f.To(userID)
+	for i := len(msgs) - 1; i >= 0; i-- {
+		for _, data := range msgs[i].Buttons {
+			if strings.HasPrefix(data, prefix) {
+				handle(callbackUpdate(userID, msgs[i].MessageID, data))
+				return data
+			}
+		}
+	}
+	s.t.Fatalf("no %q button in chat %d; got %+v", prefix, userID, msgs)
+	return ""
+}
+
+func (s *scenario) deliver() {
+	s.b.DeliverOutbox(s.ctx)
+}
+
+func (s *scenario) orderStatus(orderID int64) string {
+	s.t.Helper()
+	o, err := services.GetOrder(s.ctx, orderID)
+	if err != nil || o == nil {
+		s.t.Fatalf("get order %d: %v", orderID, err)
+	}
+	return o.Status
+}
+
+// checkout runs the customer flow from /start to a delivery order and returns its ID.
+func (s *scenario) checkout() int64 {
+	s.t.Helper()
+	s.say(scenarioCustomer, "/start")
+	s.tap(s.customer, scenarioCustomer, "lang:uz", s.b.HandleUpdate)
+	s.b.HandleUpdate(tgbotapi.Update{Message: &tgbotapi.Message{
+		From: &tgbotapi.User{ID: scenarioCustomer}, Chat: &tgbotapi.Chat{ID: scenarioCustomer},
+		Location: &tgbotapi.Location{Latitude: 41.2900, Longitude: 69.2100},
+	}})
+	s.tap(s.customer, scenarioCustomer, "locsel:"+strconv.FormatInt(s.locID, 10), s.b.HandleUpdate)
+	s.tap(s.customer, scenarioCustomer, "menu", s.b.HandleUpdate)
+	s.tap(s.customer, scenarioCustomer, "cat:food", s.b.HandleUpdate)
+	s.tap(s.customer, scenarioCustomer, "add:"+strconv.FormatInt(s.itemID, 10), s.b.HandleUpdate)
+	s.tap(s.customer, scenarioCustomer, "confirm", s.b.HandleUpdate)
+	s.tap(s.customer, scenarioCustomer, "confirm_final", s.b.HandleUpdate)
+	s.b.HandleUpdate(tgbotapi.Update{Message: &tgbotapi.Message{
+		From: &tgbotapi.User{ID: scenarioCustomer}, Chat: &tgbotapi.Chat{ID: scenarioCustomer},
+		Contact: &tgbotapi.Contact{PhoneNumber: "+998901234567", UserID: scenarioCustomer},
+	}})
+	s.tap(s.customer, scenarioCustomer, "checkout_delivery:delivery", s.b.HandleUpdate)
+
+	orders, err := services.ListOrdersByUserID(s.ctx, scenarioCustomer, 10)
+	if err != nil || len(orders) != 1 {
+		s.t.Fatalf("customer orders = %+v, %v; want one", orders, err)
+	}
+	return orders[0].ID
+}
+
+func TestScenarioCustomerCheckout(t *testing.T) {
+	s := newScenario(t)
+	orderID := s.checkout()
+	if st := s.orderStatus(orderID); st != services.OrderStatusNew {
+		t.Fatalf("status = %s, want new", st)
+	}
+
+	// Cards go out through the outbox: nothing is sent to staff until it is delivered.
+	if _, ok := s.admin.Last(scenarioAdmin, ""); ok {
+		t.Fatalf("staff notified before outbox delivery")
+	}
+	s.deliver()
+	card, ok := s.admin.Last(scenarioAdmin, "text")
+	if !ok || !strings.Contains(card.Text, "#"+strconv.FormatInt(orderID, 10)) {
+		t.Fatalf("staff card = %+v, want order #%d", card, orderID)
+	}
+	if _, ok := s.admin.Last(scenarioAdmin, "location"); !ok {
+		t.Errorf("staff did not get the delivery pin")
+	}
+	if _, ok := s.customer.Last(scenarioCustomer, "text"); !ok {
+		t.Errorf("customer did not get the order card")
+	}
+	if pending, failed, err := services.CountOutbox(s.ctx); err != nil || pending != 0 || failed != 0 {
+		t.Errorf("outbox pending=%d failed=%d err=%v, want all sent", pending, failed, err)
+	}
+}
+
+func TestScenarioAdminStatusFlow(t *testing.T) {
+	s := newScenario(t)
+	orderID := s.checkout()
+	s.deliver()
+
+	s.tap(s.admin, scenarioAdmin, "order_status:"+strconv.FormatInt(orderID, 10)+":preparing", s.b.HandleMessageBotUpdate)
+	if st := s.orderStatus(orderID); st != services.OrderStatusPreparing {
+		t.Fatalf("status = %s, want preparing", st)
+	}
+	s.deliver()
+	edit, ok := s.admin.Last(scenarioAdmin, "edit")
+	if !ok {
+		t.Fatalf("staff card was not edited after preparing")
+	}
+	if !containsPrefix(edit.Buttons, "order_status:"+strconv.FormatInt(orderID, 10)+":ready") {
+		t.Errorf("edited card buttons = %v, want a ready button", edit.Buttons)
+	}
+
+	// A step the state machine does not allow is refused and changes nothing.
+	s.b.HandleMessageBotUpdate(callbackUpdate(scenarioAdmin, edit.MessageID, "order_status:"+strconv.FormatInt(orderID, 10)+":completed"))
+	if st := s.orderStatus(orderID); st != services.OrderStatusPreparing {
+		t.Fatalf("status after refused step = %s, want preparing", st)
+	}
+
+	s.tap(s.admin, scenarioAdmin, "order_status:"+strconv.FormatInt(orderID, 10)+":ready", s.b.HandleMessageBotUpdate)
+	if st := s.orderStatus(orderID); st != services.OrderStatusReady {
+		t.Fatalf("status = %s, want ready", st)
+	}
+}
+
+func TestScenarioDriverAcceptance(t *testing.T) {
+	s := newScenario(t)
+	drv, err := services.CreateDriverProfile(s.ctx, scenarioDriver, scenarioDriver, "Ali Valiyev", "+998901112233", "01A123BC", "Cobalt", "oq", nil, nil)
+	if err != nil {
+		t.Fatalf("create driver: %v", err)
+	}
+	if _, err := db.Pool.Exec(s.ctx, `UPDATE drivers SET verification_status = $1 WHERE id = $2`, services.DriverVerificationVerified, drv.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := services.UpdateDriverStatus(s.ctx, drv.ID, services.DriverStatusOnline); err != nil {
+		t.Fatalf("driver online: %v", err)
+	}
+	if err := services.UpdateDriverLocation(s.ctx, drv.ID, 41.2890, 69.2090); err != nil {
+		t.Fatalf("driver location: %v", err)
+	}
+
+	orderID := s.checkout()
+	s.deliver()
+	id := strconv.FormatInt(orderID, 10)
+	s.tap(s.admin, scenarioAdmin, "order_status:"+id+":preparing", s.b.HandleMessageBotUpdate)
+	s.deliver()
+	s.tap(s.admin, scenarioAdmin, "order_status:"+id+":ready", s.b.HandleMessageBotUpdate)
+	s.deliver()
+
+	// Ready delivery order is offered to the nearby driver, who accepts it.
+	s.tap(s.driver, scenarioDriver, "driver_accept:"+id, s.d.HandleUpdate)
+	o, err := services.GetOrder(s.ctx, orderID)
+	if err != nil || o == nil {
+		t.Fatalf("get order: %v", err)
+	}
+	if o.Status != services.OrderStatusAssigned || o.DriverID == nil || *o.DriverID != drv.ID {
+		t.Fatalf("order = status %s driver %v, want assigned to %s", o.Status, o.DriverID, drv.ID)
+	}
+	if _, ok := s.driver.Last(scenarioDriver, "location"); !ok {
+		t.Errorf("driver did not get the restaurant location")
+	}
+	s.deliver()
+	if _, ok := s.driver.Last(scenarioDriver, "text"); !ok {
+		t.Errorf("driver did not get the order card")
+	}
+
+	// A second tap on the same offer does not take the order twice.
+	s.driver.Reset()
+	s.d.HandleUpdate(callbackUpdate(scenarioDriver, 1, "driver_accept:"+id))
+	if m, ok := s.driver.Last(scenarioDriver, "text"); !ok || m.Text == "" {
+		t.Errorf("second accept got no answer")
+	}
+	if st := s.orderStatus(orderID); st != services.OrderStatusAssigned {
+		t.Errorf("status after second accept = %s, want assigned", st)
+	}
+}
+
+func containsPrefix(list []string, prefix string) bool {
+	for _, s := range list {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestScenarioOutboxPurge(t *testing.T) {
+	s := newScenario(t)
+	orderID := s.checkout()
+	s.deliver()
+
+	// Age the delivered rows past retention; a fresh failed row and a pending row must survive.
+	if _, err := db.Pool.Exec(s.ctx, `UPDATE notification_outbox SET sent_at = now() - interval '8 days' WHERE status = 'sent'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Pool.Exec(s.ctx, `
+		INSERT INTO notification_outbox (kind, order_id, audience, chat_id, status, failed_at) VALUES
+			('message', $1, 'admin', $2, 'failed', now() - interval '10 days'),
+			('message', $1, 'admin', $2, 'failed', now() - interval '40 days'),
+			('message', $1, 'admin', $2, 'pending', NULL)`,
+		orderID, scenarioAdmin,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := services.PurgeOutbox(s.ctx, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	var sent, failed, pending int
+	if err := db.Pool.QueryRow(s.ctx, `
+		SELECT COUNT(*) FILTER (WHERE status = 'sent'), COUNT(*) FILTER (WHERE status = 'failed'), COUNT(*) FILTER (WHERE status = 'pending')
+		FROM notification_outbox`,
+	).Scan(&sent, &failed, &pending); err != nil {
+		t.Fatal(err)
+	}
+	if sent != 0 || failed != 1 || pending != 1 {
+		t.Errorf("after purge sent=%d failed=%d pending=%d, want 0, 1, 1", sent, failed, pending)
+	}
+}
+
+func TestScenarioCashHandover(t *testing.T) {
+	s := newScenario(t)
+	drv, err := services.CreateDriverProfile(s.ctx, scenarioDriver, scenarioDriver, "Ali Valiyev", "+998901112233", "01A123BC", "Cobalt", "oq", nil, nil)
+	if err != nil {
+		t.Fatalf("create driver: %v", err)
+	}
+	otherLoc, err := services.AddLocation(s.ctx, "Yunusobod", 41.3650, 69.2850)
+	if err != nil {
+		t.Fatalf("add location: %v", err)
+	}
+
+	// Nothing collected yet: an empty hand-over is refused.
+	if _, err := services.RequestCashHandover(s.ctx, drv.ID, s.locID); err == nil {
+		t.Fatal("empty hand-over was accepted")
+	}
+
+	orderID := s.checkout()
+	if _, err := db.Pool.Exec(s.ctx, `
+		INSERT INTO driver_earnings (driver_id, order_id, location_id, cash_collected) VALUES ($1, $2, $3, 45000)`,
+		drv.ID, orderID, s.locID,
+	); err != nil {
+		t.Fatal(err)
+	}
+	linked := func() int {
+		t.Helper()
+		var n int
+		if err := db.Pool.QueryRow(s.ctx, `SELECT COUNT(*) FROM driver_earnings WHERE handover_id IS NOT NULL`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	h, err := services.RequestCashHandover(s.ctx, drv.ID, s.locID)
+	if err != nil {
+		t.Fatalf("request hand-over: %v", err)
+	}
+	if h.Amount != 45000 || h.Orders != 1 || linked() != 1 {
+		t.Fatalf("hand-over = %+v, linked %d; want 45000 over one linked order", h, linked())
+	}
+	// One pending hand-over per driver and branch.
+	if _, err := services.RequestCashHandover(s.ctx, drv.ID, s.locID); err == nil {
+		t.Error("second pending hand-over was accepted")
+	}
+	// An admin of another branch cannot decide it.
+	if _, err := services.DecideCashHandover(s.ctx, h.ID, true, otherLoc, scenarioAdmin); err == nil {
+		t.Error("admin of another branch decided the hand-over")
+	}
+
+	// Rejecting releases the earnings rows: the cash is outstanding again and can be handed over anew.
+	if _, err := services.DecideCashHandover(s.ctx, h.ID, false, s.locID, scenarioAdmin); err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	if n := linked(); n != 0 {
+		t.Errorf("linked earnings after reject = %d, want 0", n)
+	}
+	if _, err := services.DecideCashHandover(s.ctx, h.ID, true, s.locID, scenarioAdmin); err == nil {
+		t.Error("second decision on a rejected hand-over was accepted")
+	}
+
+	again, err := services.RequestCashHandover(s.ctx, drv.ID, s.locID)
+	if err != nil {
+		t.Fatalf("request after reject: %v", err)
+	}
+	done, err := services.DecideCashHandover(s.ctx, again.ID, true, s.locID, scenarioAdmin)
+	if err != nil || done.Status != services.CashHandoverConfirmed {
+		t.Fatalf("confirm = %+v, %v", done, err)
+	}
+	if linked() != 1 {
+		t.Errorf("confirmed hand-over must keep its earnings linked")
+	}
+	if _, err := services.DecideCashHandover(s.ctx, again.ID, false, s.locID, scenarioAdmin); err == nil {
+		t.Error("second decision on a confirmed hand-over was accepted")
+	}
+	balances, err := services.GetDriverCashBalances(s.ctx, drv.ID)
+	if err != nil || len(balances) != 0 {
+		t.Errorf("balances after confirm = %+v, %v; want none", balances, err)
+	}
+}
+
+func TestScenarioWebhookPurge(t *testing.T) {
+	s := newScenario(t)
+	var hookID int64
+	if err := db.Pool.QueryRow(s.ctx, `
+		INSERT INTO webhooks (location_id, url, secret) VALUES ($1, 'https://example.com/hook', 'whsec_test') RETURNING id`,
+		s.locID,
+	).Scan(&hookID); err != nil {
+		t.Fatal(err)
+	}
+	// Old delivered and failed rows go; a recent failed row (still replayable) and a queued one stay.
+	if _, err := db.Pool.Exec(s.ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, status, delivered_at, failed_at) VALUES
+			($1, 'order.created', '{}', 'delivered', now() - interval '8 days', NULL),
+			($1, 'order.created', '{}', 'delivered', now() - interval '1 day', NULL),
+			($1, 'order.created', '{}', 'failed', NULL, now() - interval '10 days'),
+			($1, 'order.created', '{}', 'failed', NULL, now() - interval '40 days'),
+			($1, 'order.created', '{}', 'pending', NULL, NULL)`,
+		hookID,
+	); err != nil {
+		t.Fatal(err)
+	}
+	n, err := services.PurgeWebhookDeliveries(s.ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var delivered, failed, pending int
+	if err := db.Pool.QueryRow(s.ctx, `
+		SELECT COUNT(*) FILTER (WHERE status = 'delivered'), COUNT(*) FILTER (WHERE status = 'failed'), COUNT(*) FILTER (WHERE status = 'pending')
+		FROM webhook_deliveries`,
+	).Scan(&delivered, &failed, &pending); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || delivered != 1 || failed != 1 || pending != 1 {
+		t.Errorf("purged %d; left delivered=%d failed=%d pending=%d, want 2 purged and 1, 1, 1", n, delivered, failed, pending)
+	}
+}
+
+func TestScenarioCommissionRules(t *testing.T) {
+	s := newScenario(t)
+	const envDefault = 10
+	pct := func(locationID int64) float64 {
+		t.Helper()
+		p, err := services.DriverCommissionPct(s.ctx, locationID, envDefault)
+		if err != nil {
+			t.Fatalf("commission for %d: %v", locationID, err)
+		}
+		return p
+	}
+
+	if got := pct(s.locID); got != envDefault {
+		t.Errorf("without rules = %v, want DRIVER_COMMISSION_PCT %v", got, envDefault)
+	}
+	ctx := services.WithAuditActor(s.ctx, scenarioAdmin, services.AuditRoleSuperadmin)
+	if err := services.SetCommissionRule(ctx, services.PlatformCommissionLocation, 12, scenarioAdmin); err != nil {
+		t.Fatalf("set platform rule: %v", err)
+	}
+	if got := pct(s.locID); got != 12 {
+		t.Errorf("branch without its own rule = %v, want the platform 12", got)
+	}
+	if err := services.SetCommissionRule(ctx, s.locID, 7.5, scenarioAdmin); err != nil {
+		t.Fatalf("set branch rule: %v", err)
+	}
+	if got := pct(s.locID); got != 7.5 {
+		t.Errorf("branch rule = %v, want 7.5", got)
+	}
+	if got := pct(services.PlatformCommissionLocation); got != 12 {
+		t.Errorf("platform rule = %v, want 12", got)
+	}
+	if err := services.SetCommissionRule(ctx, s.locID+100, 5, scenarioAdmin); err == nil {
+		t.Error("rule for a missing branch was stored")
+	}
+
+	if err := services.DeleteCommissionRule(ctx, s.locID); err != nil {
+		t.Fatalf("delete branch rule: %v", err)
+	}
+	if got := pct(s.locID); got != 12 {
+		t.Errorf("after delete = %v, want the platform 12", got)
+	}
+	if err := services.DeleteCommissionRule(ctx, s.locID); err == nil {
+		t.Error("deleting a missing rule succeeded")
+	}
+	events, err := services.ListAuditEvents(s.ctx, services.AuditFilter{TargetType: services.AuditTargetCommissionRule, Limit: 10})
+	if err != nil || len(events) != 3 {
+		t.Errorf("audit events = %d (%v), want 3", len(events), err)
+	}
+}
+
+func TestScenarioDocumentExpiryAudit(t *testing.T) {
+	s := newScenario(t)
+	drv, err := services.CreateDriverProfile(s.ctx, scenarioDriver, scenarioDriver, "Ali Valiyev", "+998901112233", "01A123BC", "Cobalt", "oq", nil, nil)
+	if err != nil {
+		t.Fatalf("create driver: %v", err)
+	}
+	if _, err := db.Pool.Exec(s.ctx, `UPDATE drivers SET verification_status = $1 WHERE id = $2`, services.DriverVerificationVerified, drv.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := services.UpdateDriverStatus(s.ctx, drv.ID, services.DriverStatusOnline); err != nil {
+		t.Fatalf("driver online: %v", err)
+	}
+	if _, err := db.Pool.Exec(s.ctx, `
+		INSERT INTO driver_documents (driver_id, doc_type, file_id, expires_at) VALUES ($1, 'license', 'f1', current_date - 1)`,
+		drv.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// A superadmin context must not make the background job look like a person's action.
+	ctx := services.WithAuditActor(s.ctx, scenarioAdmin, services.AuditRoleSuperadmin)
+	expired, err := services.ExpireDriverVerifications(ctx)
+	if err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if len(expired) != 1 || expired[0].ID != drv.ID || expired[0].Status != services.DriverStatusOffline {
+		t.Fatalf("expired = %+v, want the driver taken offline", expired)
+	}
+	events, err := services.ListAuditEvents(s.ctx, services.AuditFilter{TargetType: services.AuditTargetDriver, TargetID: drv.ID, Limit: 10})
+	if err != nil || len(events) != 1 {
+		t.Fatalf("audit events = %+v (%v), want one", events, err)
+	}
+	e := events[0]
+	if e.Action != services.AuditDriverVerifyExpire || e.ActorID != 0 || e.ActorRole != services.AuditRoleSystem ||
+		!strings.Contains(e.After, services.DriverVerificationPendingDocuments) {
+		t.Errorf("audit event = %+v, want a system verification_expire", e)
+	}
+
+	// Nothing left to expire: no new rows.
+	if again, err := services.ExpireDriverVerifications(s.ctx); err != nil || len(again) != 0 {
+		t.Errorf("second run = %+v (%v), want nothing", again, err)
+	}
+}
@@ -0,0 +1,331 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"food-telegram/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Send priorities (lanes). When a bot token is saturated, queued sends go out highest lane first.
+const (
+	SendPriorityHigh   = iota // order cards and driver offers (notification outbox)
+	SendPriorityNormal        // replies to what a user just did
+	SendPriorityLow           // background reminders and bulk notices
+	sendPriorities
+)
+
+// groupChatInterval is Telegram's limit for groups (about 20 messages a minute).
+const groupChatInterval = 3 * time.Second
+
+// SendLimiter throttles Telegram sends of every bot in the process: one send per globalInterval per bot token
+// and one per chatInterval per chat, handing free slots to the highest-priority waiting send.
+type SendLimiter struct {
+	globalInterval time.Duration
+	chatInterval   time.Duration
+
+	mu     sync.Mutex
+	tokens map[string]*tokenQueue
+	lanes  [sendPriorities]SendLaneStats
+	limits int // 429 responses seen
+}
+
+// SendLaneStats is what one priority lane has been through since start.
+type SendLaneStats struct {
+	Queued    int           // sends waiting right now
+	Sent      int64         // sends let through
+	Throttled int64         // sends that had to wait
+	TotalWait time.Duration // summed wait of throttled sends
+	MaxWait   time.Duration
+}
+
+// SendLimiterStats is a snapshot of the limiter for /send_stats.
+type SendLimiterStats struct {
+	Lanes           [sendPriorities]SendLaneStats
+	TooManyRequests int // 429 responses (Telegram throttled us anyway)
+}
+
+type tokenQueue struct {
+	next     time.Time           // earliest time the token may send again
+	chatNext map[int64]time.Time // earliest time each chat may get another message
+	waiting  [sendPriorities][]*sendWaiter
+	wake     chan struct{}
+}
+
+type sendWaiter struct {
+	chatID int64
+	queued time.Time
+	ready  chan struct{}
+}
+
+// NewSendLimiter returns a limiter allowing perTokenPerSec sends a second per bot token and one send per chatInterval per chat.
+func NewSendLimiter(perTokenPerSec int, chatInterval time.Duration) *SendLimiter {
+	if perTokenPerSec <= 0 {
+		perTokenPerSec = 25
+	}
+	return &SendLimiter{
+		globalInterval: time.Second / time.Duration(perTokenPerSec),
+		chatInterval:   chatInterval,
+		tokens:         make(map[string]*tokenQueue),
+	}
+}
+
+// Wait blocks until token may send to chatID (0 = unknown chat, only the token limit applies) and returns how long it waited.
+func (l *SendLimiter) Wait(token string, chatID int64, priority int) time.Duration {
+	if priority < 0 || priority >= sendPriorities {
+		priority = SendPriorityNormal
+	}
+	w := &sendWaiter{chatID: chatID, queued: time.Now(), ready: make(chan struct{})}
+	l.mu.Lock()
+	q, ok := l.tokens[token]
+	if !ok {
+		q = &tokenQueue{chatNext: make(map[int64]time.Time), wake: make(chan struct{}, 1)}
+		l.tokens[token] = q
+		go l.dispatch(q)
+	}
+	q.waiting[priority] = append(q.waiting[priority], w)
+	l.lanes[priority].Queued++
+	l.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	<-w.ready
+	return time.Since(w.queued)
+}
+
+// Backoff holds back chatID (or the whole token when the chat is unknown) for retryAfter after Telegram answered 429.
+func (l *SendLimiter) Backoff(token string, chatID int64, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits++
+	q, ok := l.tokens[token]
+	if !ok {
+		return
+	}
+	until := time.Now().Add(retryAfter)
+	if chatID == 0 {
+		if until.After(q.next) {
+			q.next = until
+		}
+		return
+	}
+	if until.After(q.chatNext[chatID]) {
+		q.chatNext[chatID] = until
+	}
+}
+
+// Stats returns queue depth and throttling per lane.
+func (l *SendLimiter) Stats() SendLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return SendLimiterStats{Lanes: l.lanes, TooManyRequests: l.limits}
+}
+
+// dispatch hands out the token's send slots; it runs for the life of the process (one per bot token).
+func (l *SendLimiter) dispatch(q *tokenQueue) {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		lane, w, wait := q.pick(now)
+		if w != nil {
+			q.next = now.Add(l.globalInterval)
+			if w.chatID != 0 {
+				q.chatNext[w.chatID] = now.Add(l.chatIntervalFor(w.chatID))
+			}
+			waited := now.Sub(w.queued)
+			st := &l.lanes[lane]
+			st.Queued--
+			st.Sent++
+			if waited > time.Millisecond {
+				st.Throttled++
+				st.TotalWait += waited
+				if waited > st.MaxWait {
+					st.MaxWait = waited
+				}
+			}
+			q.forgetIdleChats(now)
+			l.mu.Unlock()
+			close(w.ready)
+			continue
+		}
+		l.mu.Unlock()
+		if wait < 0 {
+			<-q.wake
+			continue
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-q.wake:
+			t.Stop()
+		}
+	}
+}
+
+func (l *SendLimiter) chatIntervalFor(chatID int64) time.Duration {
+	if chatID < 0 && groupChatInterval > l.chatInterval {
+		return groupChatInterval
+	}
+	return l.chatInterval
+}
+
+// pick removes and returns the oldest waiter of the highest lane whose chat is free at now.
+// With nobody eligible it returns how long until someone may be (-1 = nobody waiting).
+func (q *tokenQueue) pick(now time.Time) (int, *sendWaiter, time.Duration) {
+	wait := time.Duration(-1)
+	if now.Before(q.next) {
+		for lane := range q.waiting {
+			if len(q.waiting[lane]) > 0 {
+				return 0, nil, q.next.Sub(now)
+			}
+		}
+		return 0, nil, -1
+	}
+	for lane := range q.waiting {
+		for i, w := range q.waiting[lane] {
+			free := q.chatNext[w.chatID]
+			if w.chatID == 0 || !now.Before(free) {
+				q.waiting[lane] = append(q.waiting[lane][:i], q.waiting[lane][i+1:]...)
+				return lane, w, 0
+			}
+			if d := free.Sub(now); wait < 0 || d < wait {
+				wait = d
+			}
+		}
+	}
+	return 0, nil, wait
+}
+
+// forgetIdleChats drops per-chat slots that have passed so the map does not grow with every chat ever seen.
+func (q *tokenQueue) forgetIdleChats(now time.Time) {
+	if len(q.chatNext) < 1024 {
+		return
+	}
+	for id, t := range q.chatNext {
+		if !t.After(now) {
+			delete(q.chatNext, id)
+		}
+	}
+}
+
+// limitedClient is the HTTP client of every BotAPI: it waits for the limiter before sending methods and
+// reports 429 answers back to it. Other methods (getUpdates, getMe, answerCallbackQuery, ...) pass straight through.
+type limitedClient struct {
+	limiter  *SendLimiter
+	next     tgbotapi.HTTPClient
+	priority int
+}
+
+func (c *limitedClient) Do(req *http.Request) (*http.Response, error) {
+	token, method := splitBotPath(req.URL.Path)
+	if !limitedMethod(method) {
+		return c.next.Do(req)
+	}
+	chatID := requestChatID(req)
+	c.limiter.Wait(token, chatID, c.priority)
+	resp, err := c.next.Do(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if readErr != nil {
+		return resp, nil
+	}
+	var apiResp tgbotapi.APIResponse
+	retryAfter := time.Second
+	if json.Unmarshal(body, &apiResp) == nil && apiResp.Parameters != nil && apiResp.Parameters.RetryAfter > 0 {
+		retryAfter = time.Duration(apiResp.Parameters.RetryAfter) * time.Second
+	}
+	c.limiter.Backoff(token, chatID, retryAfter)
+	return resp, nil
+}
+
+// splitBotPath splits "/bot<token>/<method>" (file downloads have a different path and are not limited).
+func splitBotPath(path string) (token, method string) {
+	path = strings.TrimPrefix(path, "/")
+	if !strings.HasPrefix(path, "bot") {
+		return "", ""
+	}
+	token, method, _ = strings.Cut(strings.TrimPrefix(path, "bot"), "/")
+	return token, method
+}
+
+// limitedMethod reports whether a Bot API method puts a message in a chat (what Telegram rate-limits).
+func limitedMethod(method string) bool {
+	for _, p := range []string{"send", "edit", "forward", "copy"} {
+		if strings.HasPrefix(method, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestChatID reads chat_id from a form-encoded request without consuming its body; 0 if absent or not numeric
+// (uploads are multipart and only get the per-token limit).
+func requestChatID(req *http.Request) int64 {
+	if req.GetBody == nil || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return 0
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return 0
+	}
+	defer body.Close()
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return 0
+	}
+	values, err := url.ParseQuery(string(raw))
+	if err != nil {
+		return 0
+	}
+	id, _ := strconv.ParseInt(values.Get("chat_id"), 10, 64)
+	return id
+}
+
+var (
+	sendLimiterOnce sync.Once
+	sendLimiter     *SendLimiter
+)
+
+// sharedSendLimiter is the one limiter all bots of the process send through (several bots may share a token).
+func sharedSendLimiter(cfg *config.Config) *SendLimiter {
+	sendLimiterOnce.Do(func() {
+		sendLimiter = NewSendLimiter(cfg.Telegram.SendPerSecond, time.Duration(cfg.Telegram.ChatSendIntervalMs)*time.Millisecond)
+	})
+	return sendLimiter
+}
+
+// newBotAPI creates a bot whose sends go through the shared limiter at normal priority.
+func newBotAPI(cfg *config.Config, token string) (*tgbotapi.BotAPI, error) {
+	client := &limitedClient{limiter: sharedSendLimiter(cfg), next: &http.Client{}, priority: SendPriorityNormal}
+	return tgbotapi.NewBotAPIWithClient(token, tgbotapi.APIEndpoint, client)
+}
+
+// withSendPriority returns a copy of m whose sends use the given lane of the same limiter
+// (m itself if it is not a limited Telegram client, e.g. a fake in tests).
+func withSendPriority(m Messenger, priority int) Messenger {
+	bm, ok := m.(*botMessenger)
+	if !ok || bm == nil {
+		return m
+	}
+	c, ok := bm.api.Client.(*limitedClient)
+	if !ok {
+		return bm
+	}
+	cp := *bm.api
+	cp.Client = &limitedClient{limiter: c.limiter, next: c.next, priority: priority}
+	return &botMessenger{api: &cp}
+}
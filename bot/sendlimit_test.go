@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenQueuePick(t *testing.T) {
+	now := time.Now()
+	q := &tokenQueue{chatNext: map[int64]time.Time{1: now.Add(time.Second)}}
+	low := &sendWaiter{chatID: 2}
+	busyHigh := &sendWaiter{chatID: 1}
+	high := &sendWaiter{chatID: 3}
+	q.waiting[SendPriorityLow] = []*sendWaiter{low}
+	q.waiting[SendPriorityHigh] = []*sendWaiter{busyHigh, high}
+
+	// The high lane goes first, skipping a chat that is still within its interval.
+	if lane, w, _ := q.pick(now); w != high || lane != SendPriorityHigh {
+		t.Fatalf("pick = lane %d %+v, want the free high waiter", lane, w)
+	}
+	if _, w, _ := q.pick(now); w != low {
+		t.Fatalf("pick = %+v, want the low waiter while the high chat is busy", w)
+	}
+	if _, w, wait := q.pick(now); w != nil || wait != time.Second {
+		t.Fatalf("pick = %+v wait %s, want nobody for 1s", w, wait)
+	}
+	if _, w, _ := q.pick(now.Add(time.Second)); w != busyHigh {
+		t.Fatalf("pick = %+v, want the high waiter once its chat is free", w)
+	}
+	if _, w, wait := q.pick(now); w != nil || wait != -1 {
+		t.Fatalf("pick on empty queue = %+v wait %s, want nil -1", w, wait)
+	}
+
+	// The token interval holds back every lane.
+	q.next = now.Add(40 * time.Millisecond)
+	q.waiting[SendPriorityNormal] = []*sendWaiter{{chatID: 5}}
+	if _, w, wait := q.pick(now); w != nil || wait != 40*time.Millisecond {
+		t.Fatalf("pick before token slot = %+v wait %s, want nil 40ms", w, wait)
+	}
+}
+
+func TestSplitBotPath(t *testing.T) {
+	tests := []struct{ path, token, method string }{
+		{"/bot123:abc/sendMessage", "123:abc", "sendMessage"},
+		{"/bot123:abc/getUpdates", "123:abc", "getUpdates"},
+		{"/file/bot123:abc/photos/1.jpg", "", ""},
+	}
+	for _, tt := range tests {
+		token, method := splitBotPath(tt.path)
+		if token != tt.token || method != tt.method {
+			t.Errorf("splitBotPath(%q) = %q, %q, want %q, %q", tt.path, token, method, tt.token, tt.method)
+		}
+	}
+	for method, want := range map[string]bool{"sendMessage": true, "editMessageText": true, "sendLocation": true, "getUpdates": false, "answerCallbackQuery": false, "": false} {
+		if got := limitedMethod(method); got != want {
+			t.Errorf("limitedMethod(%q) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+func TestRequestChatID(t *testing.T) {
+	form := url.Values{"chat_id": {"-100123"}, "text": {"hi"}}.Encode()
+	req, _ := http.NewRequest("POST", "https://api.telegram.org/botX/sendMessage", strings.NewReader(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if got := requestChatID(req); got != -100123 {
+		t.Errorf("requestChatID = %d, want -100123", got)
+	}
+	// The body is still there for the real request.
+	if body, _ := io.ReadAll(req.Body); string(body) != form {
+		t.Errorf("body after requestChatID = %q, want %q", body, form)
+	}
+	req, _ = http.NewRequest("POST", "https://api.telegram.org/botX/sendMessage", strings.NewReader("chat_id=@channel"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if got := requestChatID(req); got != 0 {
+		t.Errorf("requestChatID(@channel) = %d, want 0", got)
+	}
+}
+
+func TestSendLimiterSpacesChat(t *testing.T) {
+	l := NewSendLimiter(1000, 30*time.Millisecond)
+	start := time.Now()
+	l.Wait("t", 7, SendPriorityNormal)
+	l.Wait("t", 7, SendPriorityNormal)
+	if d := time.Since(start); d < 30*time.Millisecond {
+		t.Errorf("two sends to one chat took %s, want >= 30ms", d)
+	}
+	st := l.Stats()
+	if st.Lanes[SendPriorityNormal].Sent != 2 || st.Lanes[SendPriorityNormal].Queued != 0 {
+		t.Errorf("stats = %+v", st.Lanes[SendPriorityNormal])
+	}
+}
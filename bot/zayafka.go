@@ -2,6 +2,7 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -24,8 +25,9 @@ type zayafkaApplyState struct {
 // ZayafkaBot is the application-form-only bot (ariza). New-application notification is sent via adder so superadmin Approve/Reject in adder.
 // Reject-reason state is stored in DB (reject_in_progress_by) for restart safety when adderAPI == nil.
 type ZayafkaBot struct {
-	api             *tgbotapi.BotAPI
-	adderAPI        *tgbotapi.BotAPI // when set, new-application notification is sent from adder (so callbacks go to adder)
+	api             Messenger
+	client          *tgbotapi.BotAPI // polls updates for api
+	adderAPI        Messenger        // when set, new-application notification is sent from adder (so callbacks go to adder)
 	superAdminID    int64
 	applyRestaurant map[int64]*zayafkaApplyState
 	onExpRenew      func(tgUserID int64, role string, replyChatID int64) // when superadmin taps renew in Zayafka
@@ -38,7 +40,7 @@ func NewZayafkaBot(cfg *config.Config, superAdminID int64, adderAPI *tgbotapi.Bo
 	if cfg.Telegram.ZayafkaToken == "" {
 		return nil, fmt.Errorf("ZAYAFKA token not set")
 	}
-	api, err := tgbotapi.NewBotAPI(cfg.Telegram.ZayafkaToken)
+	api, err := newBotAPI(cfg, cfg.Telegram.ZayafkaToken)
 	if err != nil {
 		return nil, err
 	}
@@ -47,8 +49,9 @@ func NewZayafkaBot(cfg *config.Config, superAdminID int64, adderAPI *tgbotapi.Bo
 		sid = superAdminID
 	}
 	return &ZayafkaBot{
-		api:             api,
-		adderAPI:        adderAPI,
+		api:             messenger(api),
+		client:          api,
+		adderAPI:        messenger(adderAPI),
 		superAdminID:    sid,
 		applyRestaurant: make(map[int64]*zayafkaApplyState),
 	}, nil
@@ -56,7 +59,7 @@ func NewZayafkaBot(cfg *config.Config, superAdminID int64, adderAPI *tgbotapi.Bo
 
 func (z *ZayafkaBot) send(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
-	if _, err := z.api.Send(msg); err != nil {
+	if _, err := z.api.SendMessage(msg); err != nil {
 		log.Printf("zayafka send: %v", err)
 	}
 }
@@ -64,7 +67,7 @@ func (z *ZayafkaBot) send(chatID int64, text string) {
 func (z *ZayafkaBot) sendWithInline(chatID int64, text string, kb tgbotapi.InlineKeyboardMarkup) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = kb
-	if _, err := z.api.Send(msg); err != nil {
+	if _, err := z.api.SendMessage(msg); err != nil {
 		log.Printf("zayafka send: %v", err)
 	}
 }
@@ -72,7 +75,7 @@ func (z *ZayafkaBot) sendWithInline(chatID int64, text string, kb tgbotapi.Inlin
 func (z *ZayafkaBot) sendWithReplyKeyboard(chatID int64, text string, kb tgbotapi.ReplyKeyboardMarkup) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = kb
-	if _, err := z.api.Send(msg); err != nil {
+	if _, err := z.api.SendMessage(msg); err != nil {
 		log.Printf("zayafka send: %v", err)
 	}
 }
@@ -81,7 +84,7 @@ func (z *ZayafkaBot) sendRemoveKeyboard(chatID int64, text string) {
 	kb := tgbotapi.NewRemoveKeyboard(true)
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = kb
-	if _, err := z.api.Send(msg); err != nil {
+	if _, err := z.api.SendMessage(msg); err != nil {
 		log.Printf("zayafka send: %v", err)
 	}
 }
@@ -90,7 +93,7 @@ func (z *ZayafkaBot) sendRemoveKeyboard(chatID int64, text string) {
 func (z *ZayafkaBot) answerCallback(cq *tgbotapi.CallbackQuery, text string, showAlert bool) {
 	cb := tgbotapi.NewCallback(cq.ID, text)
 	cb.ShowAlert = showAlert
-	if _, err := z.api.Request(cb); err != nil {
+	if err := z.api.AnswerCallback(cb); err != nil {
 		log.Printf("zayafka answerCallback: %v", err)
 		z.send(cq.Message.Chat.ID, "Xatolik yuz berdi. Iltimos qayta urinib ko'ring.")
 	}
@@ -99,7 +102,7 @@ func (z *ZayafkaBot) answerCallback(cq *tgbotapi.CallbackQuery, text string, sho
 // notifyAdminNewApplication sends the new-application message to superadmin. Uses adder API when set so Approve/Reject are handled in adder.
 // GetAPI returns the Zayafka bot API (e.g. for adder to send password/rejection to applicants).
 func (z *ZayafkaBot) GetAPI() *tgbotapi.BotAPI {
-	return z.api
+	return z.client
 }
 
 // SetOnExpRenew sets the callback when superadmin taps "Yangilash" in Zayafka (subscription renewal).
@@ -119,7 +122,7 @@ func (z *ZayafkaBot) notifyAdminNewApplication(appID, fullName, phone, restauran
 	adm := tgbotapi.NewMessage(z.superAdminID, adminMsg)
 	adm.ParseMode = "Markdown"
 	adm.ReplyMarkup = kb
-	if _, sendErr := z.api.Send(adm); sendErr != nil {
+	if _, sendErr := z.api.SendMessage(adm); sendErr != nil {
 		log.Printf("zayafka: notify admin of new application: %v", sendErr)
 	}
 }
@@ -127,7 +130,7 @@ func (z *ZayafkaBot) notifyAdminNewApplication(appID, fullName, phone, restauran
 func (z *ZayafkaBot) Start() {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
-	updates := z.api.GetUpdatesChan(u)
+	updates := z.client.GetUpdatesChan(u)
 
 	for update := range updates {
 		if update.CallbackQuery != nil {
@@ -397,7 +400,7 @@ func (z *ZayafkaBot) handleRejectReasonFlow(msg *tgbotapi.Message, userID int64,
 	if z.superAdminID == 0 || userID != z.superAdminID {
 		return false
 	}
-	ctx := context.Background()
+	ctx := auditContext(z.superAdminID, userID)
 	appID, err := services.GetApplicationIDByRejectInProgressBy(ctx, userID)
 	if err != nil || appID == "" {
 		return false
@@ -542,13 +545,17 @@ func (z *ZayafkaBot) handleCallback(cq *tgbotapi.CallbackQuery) {
 			z.answerCallback(cq, "", false)
 			return
 		}
-		ctx := context.Background()
+		ctx := auditContext(z.superAdminID, userID)
 		app, _, _, _ := services.GetApplicationByID(ctx, appID)
 		if app == nil || app.Status != services.ApplicationStatusPending {
 			z.answerCallback(cq, zayafkaAlreadyReviewedAlert, true)
 			return
 		}
 		plainPass, err := services.ApproveApplication(ctx, appID, userID)
+		if errors.Is(err, services.ErrApplicationNotPending) {
+			z.answerCallback(cq, zayafkaAlreadyReviewedAlert, true)
+			return
+		}
 		if err != nil {
 			z.send(chatID, "❌ "+err.Error())
 			z.answerCallback(cq, "", false)
@@ -11,6 +11,9 @@ type Config struct {
 	DB       DBConfig
 	Telegram TelegramConfig
 	Delivery DeliveryConfig
+	Data     DataConfig
+	API      APIConfig
+	Web      WebConfig
 }
 
 type DBConfig struct {
@@ -29,6 +32,10 @@ type TelegramConfig struct {
 	DriverToken   string // token for driver bot
 	Login         string // admin password for adder bot
 	SuperadminID  int64  // Telegram ID for superadmin (/applications); 0 = use ADMIN_ID from env at runtime
+	AdminSessionIdleMin   int // adder bot session ends after this many minutes without activity (default 30)
+	AdminSessionMaxHours  int // adder bot session ends this many hours after login regardless of activity (default 12)
+	SendPerSecond         int // max sends a second per bot token across all bots (default 25; Telegram allows about 30)
+	ChatSendIntervalMs    int // min gap between two messages to the same chat in ms (default 1000; groups get at least 3s)
 }
 
 type DeliveryConfig struct {
@@ -36,6 +43,24 @@ type DeliveryConfig struct {
 	RatePerKm           int64   // per km (e.g. 4000 sum)
 	DriverJobsRadius    float64 // radius in km for driver jobs search
 	DriverPushRadiusKm  float64 // radius in km for pushing READY orders to nearby drivers (default 5)
+	DriverAvgSpeedKmh   float64 // average driver speed in km/h for customer ETA (default 25)
+	DriverCommissionPct float64 // platform commission in % of delivery fee (default 10)
+	DriverMaxActiveOrders int   // max orders a driver may hold at once (batched deliveries, default 2)
+	DriverStaleLocationMin  int // minutes without a location update before an online driver is taken offline (default 30)
+	DriverOfflineReminderMin int // minutes before auto-offline (shift end / stale location) to remind the driver (default 10)
+}
+
+type DataConfig struct {
+	DeletedRetentionDays int // soft-deleted locations / menu items can be restored for this many days, then they are purged (default 30)
+	DailySummaryHour     int // Tashkent hour of the superadmin roll-up and of branch summaries without opening hours (default 23, -1 = no summaries)
+}
+
+type APIConfig struct {
+	Addr string // listen address of the HTTP API (/api/v1), e.g. ":8080"; empty = API disabled
+}
+
+type WebConfig struct {
+	Addr string // listen address of the web dashboard, e.g. ":8081"; empty = dashboard disabled (needs ADDER_TOKEN)
 }
 
 func Load() (*Config, error) {
@@ -59,12 +84,31 @@ func Load() (*Config, error) {
 			DriverToken:  getEnv("DRIVER_BOT_TOKEN", ""),
 			Login:        getEnv("LOGIN", ""),
 			SuperadminID: getSuperadminID(),
+			AdminSessionIdleMin:  getAdminSessionIdleMin(),
+			AdminSessionMaxHours: getAdminSessionMaxHours(),
+			SendPerSecond:        getSendPerSecond(),
+			ChatSendIntervalMs:   getChatSendIntervalMs(),
 		},
 		Delivery: DeliveryConfig{
 			BaseFee:            getBaseFee(),   // 5000 sum start
 			RatePerKm:          getRatePerKm(), // 4000 sum per km
 			DriverJobsRadius:   getDriverJobsRadius(),
 			DriverPushRadiusKm: getDriverPushRadiusKm(),
+			DriverAvgSpeedKmh:  getDriverAvgSpeedKmh(),
+			DriverCommissionPct: getDriverCommissionPct(),
+			DriverMaxActiveOrders: getDriverMaxActiveOrders(),
+			DriverStaleLocationMin:  getDriverStaleLocationMin(),
+			DriverOfflineReminderMin: getDriverOfflineReminderMin(),
+		},
+		Data: DataConfig{
+			DeletedRetentionDays: getDeletedRetentionDays(),
+			DailySummaryHour:     getDailySummaryHour(),
+		},
+		API: APIConfig{
+			Addr: getEnv("API_ADDR", ""),
+		},
+		Web: WebConfig{
+			Addr: getEnv("WEB_ADDR", ""),
 		},
 	}, nil
 }
@@ -113,6 +157,87 @@ func getDriverPushRadiusKm() float64 {
 	return 5.0
 }
 
+func getDriverAvgSpeedKmh() float64 {
+	if v := os.Getenv("DRIVER_AVG_SPEED_KMH"); v != "" {
+		if speed, err := strconv.ParseFloat(v, 64); err == nil && speed > 0 {
+			return speed
+		}
+	}
+	return 25.0
+}
+
+func getDriverCommissionPct() float64 {
+	if v := os.Getenv("DRIVER_COMMISSION_PCT"); v != "" {
+		if pct, err := strconv.ParseFloat(v, 64); err == nil && pct >= 0 && pct <= 100 {
+			return pct
+		}
+	}
+	return 10.0
+}
+
+func getDriverMaxActiveOrders() int {
+	if v := os.Getenv("DRIVER_MAX_ACTIVE_ORDERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 2
+}
+
+func getDriverStaleLocationMin() int {
+	if v := os.Getenv("DRIVER_STALE_LOCATION_MIN"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 30
+}
+
+func getDriverOfflineReminderMin() int {
+	if v := os.Getenv("DRIVER_OFFLINE_REMINDER_MIN"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 10
+}
+
+func getAdminSessionIdleMin() int {
+	if v := os.Getenv("ADMIN_SESSION_IDLE_MIN"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 30
+}
+
+func getAdminSessionMaxHours() int {
+	if v := os.Getenv("ADMIN_SESSION_MAX_HOURS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 12
+}
+
+func getSendPerSecond() int {
+	if v := os.Getenv("TG_SEND_PER_SECOND"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 25
+}
+
+func getChatSendIntervalMs() int {
+	if v := os.Getenv("TG_CHAT_SEND_INTERVAL_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return 1000
+}
+
 func getSuperadminID() int64 {
 	if v := os.Getenv("SUPERADMIN_TG_ID"); v != "" {
 		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
@@ -121,3 +246,23 @@ func getSuperadminID() int64 {
 	}
 	return 0
 }
+
+func getDeletedRetentionDays() int {
+	if v := os.Getenv("DELETED_RETENTION_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 30
+}
+
+func getDailySummaryHour() int {
+	v := os.Getenv("DAILY_SUMMARY_HOUR")
+	if v == "off" {
+		return -1
+	}
+	if n, err := strconv.Atoi(v); err == nil && n >= -1 && n <= 23 {
+		return n
+	}
+	return 23
+}
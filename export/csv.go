@@ -0,0 +1,51 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// WriteCSV writes a UTF-8 CSV with a byte order mark, so spreadsheet apps read non-Latin names correctly.
+// Text that a spreadsheet would run as a formula is prefixed with an apostrophe.
+func WriteCSV(w io.Writer, header []string, rows [][]any) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(header))
+	for _, row := range rows {
+		record = record[:0]
+		for _, v := range row {
+			s := cellText(v)
+			if _, ok := v.(string); ok {
+				s = neutralizeFormula(s)
+			}
+			record = append(record, s)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// neutralizeFormula keeps user text (names, notes) from being run as a formula when the CSV is opened. Phone
+// numbers and negative numbers ("+998…", "-5") are left alone.
+func neutralizeFormula(s string) string {
+	if s == "" {
+		return s
+	}
+	switch s[0] {
+	case '=', '@', '\t', '\r':
+		return "'" + s
+	case '+', '-':
+		if len(s) == 1 || s[1] < '0' || s[1] > '9' {
+			return "'" + s
+		}
+	}
+	return s
+}
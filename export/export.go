@@ -0,0 +1,59 @@
+// Package export writes tables as CSV or XLSX files. Cells may be string, int, int64, float64, time.Time or nil.
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// File formats.
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// TimeLayout is how time cells are written (in the time's own location).
+const TimeLayout = "2006-01-02 15:04:05"
+
+// Encode writes the table in format ("csv" or "xlsx"); sheet names the XLSX worksheet.
+func Encode(format, sheet string, header []string, rows [][]any) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case FormatCSV:
+		err = WriteCSV(&buf, header, rows)
+	case FormatXLSX:
+		err = WriteXLSX(&buf, sheet, header, rows)
+	default:
+		return nil, fmt.Errorf("format csv yoki xlsx bo'lishi kerak: %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cellText formats a cell for CSV, and for XLSX cells that are not numbers.
+func cellText(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case int:
+		return strconv.Itoa(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case time.Time:
+		if x.IsZero() {
+			return ""
+		}
+		return x.Format(TimeLayout)
+	default:
+		return fmt.Sprint(x)
+	}
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testHeader = []string{"id", "name", "phone", "total", "created_at", "note"}
+	testRows   = [][]any{
+		{int64(1), "Osh & choy", "+998901234567", int64(45000), time.Date(2024, 3, 4, 12, 30, 0, 0, time.UTC), nil},
+		{int64(2), "=HYPERLINK(\"x\")", "-", 2.5, time.Time{}, "<b>"},
+	}
+)
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testHeader, testRows); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "\ufeffid,name,phone,total,created_at,note\n") {
+		t.Errorf("header = %q", out)
+	}
+	for _, want := range []string{
+		"1,Osh & choy,+998901234567,45000,2024-03-04 12:30:00,\n",
+		`2,"'=HYPERLINK(""x"")",'-,2.5,,<b>` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("csv missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, "orders/2024", testHeader, testRows); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `name="orders_2024"`) {
+		t.Errorf("sheet name not sanitized: %s", files["xl/workbook.xml"])
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`,
+		`<c r="A2"><v>1</v></c>`,
+		`<c r="B2" t="inlineStr"><is><t xml:space="preserve">Osh &amp; choy</t></is></c>`,
+		`<c r="D2"><v>45000</v></c>`,
+		`<c r="E2" t="inlineStr"><is><t xml:space="preserve">2024-03-04 12:30:00</t></is></c>`,
+		`<c r="D3"><v>2.5</v></c>`,
+		`<c r="F3" t="inlineStr"><is><t xml:space="preserve">&lt;b&gt;</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet missing %s", want)
+		}
+	}
+	if strings.Contains(sheet, `r="F2"`) {
+		t.Error("nil cell written")
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestEncodeUnknownFormat(t *testing.T) {
+	if _, err := Encode("pdf", "x", testHeader, testRows); err == nil {
+		t.Error("pdf accepted")
+	}
+}
@@ -29,6 +29,10 @@ var UzStrings = map[string]string{
 	"cat_dessert_label": "Kekslar",
 	"category_choose":  "Bu kategoriyadagi maxsulotlarni tanlang.",
 	"product_added":   "Maxsulot qo'shildi.",
+	"rate_failed":     "Bahoni saqlab bo'lmadi: %s",
+	"rate_comment_prompt": "✍️ Buyurtma #%d haqida izohingizni yozing. Bekor qilish: /cancel",
+	"rate_comment_saved":  "✅ Izohingiz uchun rahmat!",
+	"rate_comment_cancelled": "Izoh bekor qilindi.",
 	"confirm_prompt":  "Buyurtmani tasdiqlash uchun *Tasdiqlash* tugmasini bosing.",
 	"your_order":      "🛒 *Buyurtmangiz*",
 	"add_more_confirm": "Yana narsa qo'shish yoki buyurtmani tasdiqlash.",
@@ -113,6 +117,46 @@ var UzStrings = map[string]string{
 	"dr_mark_collected":    "📦 Olindi",
 	"dr_start_delivering":  "🛵 Yetkazishni boshlash",
 	"dr_order_completed_btn": "✅ Yakunlandi",
+	"dr_earnings":          "💰 Daromad",
+	"dr_earnings_summary":  "💰 Daromad\n\n📅 Bugun: %d ta buyurtma\nUlush: %d so'm, choy puli: %d so'm\nNaqd olingan: %d so'm\n\n🗓 Shu hafta: %d ta buyurtma\nUlush: %d so'm, choy puli: %d so'm\nNaqd olingan: %d so'm\n\nPlatforma komissiyasi: %g%%",
+	"dr_rating_line":       "\n\n⭐ Reyting: %s",
+	"dr_tip_hint":          "Choy puli oldingizmi? Yuboring: /tip %d <summa>",
+	"dr_tip_usage":         "Ishlatish: /tip <buyurtma_id> <summa>",
+	"dr_tip_saved":         "✅ Choy puli saqlandi: #%d — %d so'm",
+	"dr_cash":              "💵 Naqd pul",
+	"dr_cash_header":       "💵 Qo'lingizdagi naqd pul (filiallarga topshirilishi kerak):\n\n",
+	"dr_cash_line":         "🏪 %s: %d so'm (%d ta buyurtma)%s\n",
+	"dr_cash_pending_mark": " — ⏳ tasdiq kutilmoqda",
+	"dr_cash_none":         "✅ Qo'lingizda topshirilmagan naqd pul yo'q.",
+	"dr_cash_handover_btn": "💵 Topshirish: %s",
+	"dr_cash_handover_sent": "✅ So'rov yuborildi: %s — %d so'm. Filial admini tasdiqlashini kuting.",
+	"dr_cash_confirmed":    "✅ %s filiali %d so'm naqd pulni qabul qildi.",
+	"dr_cash_rejected":     "❌ %s filiali %d so'm topshirishni rad etdi. Admin bilan bog'laning.",
+	"dr_active_list_header": "📦 Faol buyurtmalar: %d/%d\n\n",
+	"dr_active_list_line":  "Buyurtma #%d — %s\n🛒 %d so'm + 🚚 %d so'm = 💵 %d UZS\n\n",
+	"dr_max_active":        "❌ Bir vaqtda ko'pi bilan %d ta buyurtma olish mumkin.",
+	"dr_jobs_batch_note":   "🔁 Faqat faol buyurtmangiz filialidan qo'shimcha buyurtmalar ko'rsatiladi.\n\n",
+	"dr_shift_usage":        "Ishlatish: /shift HH:MM-HH:MM (masalan: /shift 09:00-18:00)",
+	"dr_shift_set":          "✅ Smena belgilandi: %s — %s",
+	"dr_shift_current":      "🕘 Smena: %s — %s\nO'zgartirish: /shift HH:MM-HH:MM",
+	"dr_shift_none":         "Smena belgilanmagan. Belgilash: /shift HH:MM-HH:MM",
+	"dr_reminder_shift_end":  "⏰ Smenangiz ~%d daqiqadan keyin tugaydi, shundan so'ng avtomatik oflayn bo'lasiz.",
+	"dr_reminder_stale":     "📍 Lokatsiyangiz yangilanmayapti. ~%d daqiqa ichida yubormasangiz, avtomatik oflayn bo'lasiz.",
+	"dr_auto_offline_shift_end":  "🔴 Smena tugadi — avtomatik oflayn holatga o'tdingiz.",
+	"dr_auto_offline_stale":  "🔴 Lokatsiya uzoq vaqt yangilanmadi — avtomatik oflayn holatga o'tdingiz.",
+	"dr_docs_btn":           "📄 Hujjatlarni yuborish",
+	"dr_docs_intro":         "📄 Buyurtma olish uchun hujjatlaringiz tekshirilishi kerak: haydovchilik guvohnomasi, texpasport va selfi.",
+	"dr_docs_ask_license":   "🪪 Haydovchilik guvohnomasi rasmini yuboring:",
+	"dr_docs_ask_tech_passport":  "📘 Texpasport rasmini yuboring:",
+	"dr_docs_ask_selfie":    "🤳 Guvohnomangiz bilan selfi yuboring:",
+	"dr_docs_ask_expiry":    "📅 Amal qilish muddatini yuboring (YYYY-MM-DD yoki DD.MM.YYYY):",
+	"dr_docs_send_photo":    "📷 Iltimos, rasm yuboring.",
+	"dr_docs_submitted":     "✅ Hujjatlar tekshiruvga yuborildi. Tasdiqlangandan so'ng onlayn bo'lishingiz mumkin.",
+	"dr_verif_pending":      "⏳ Hujjatlaringiz tekshirilmoqda.",
+	"dr_verif_need_docs":    "📄 Hujjatlaringizni yuboring — tasdiqlanmaguncha buyurtma ololmaysiz.",
+	"dr_verif_rejected":     "❌ Hujjatlaringiz rad etildi: %s\nQayta yuboring.",
+	"dr_verif_approved":     "✅ Hujjatlaringiz tasdiqlandi! Endi onlayn bo'lib buyurtma olishingiz mumkin.",
+	"dr_docs_expired":       "⚠️ Hujjatingiz muddati tugadi. Onlayn bo'lish uchun yangi hujjatlarni yuboring.",
 
 	// Admin order card (language admin receives orders in)
 	"adm_new_order":       "Yangi buyurtma #%d",
@@ -138,6 +182,11 @@ var UzStrings = map[string]string{
 	"adm_driver_accepted": "✅ Haydovchi qabul qildi",
 	"adm_contact_driver":  "📞 Haydovchi bilan bog'lanish",
 	"adm_order_id":        "Buyurtma #%d",
+	"adm_cash_request":    "💵 Naqd pul topshirish #%d\n\nHaydovchi: %s\nSumma: %d so'm (%d ta buyurtma)\n\nPulni qabul qildingizmi?",
+	"adm_cash_confirm":    "✅ Qabul qildim",
+	"adm_cash_reject":     "❌ Rad etish",
+	"adm_cash_confirmed":  "✅ Qabul qilindi",
+	"adm_cash_rejected":   "❌ Rad etildi",
 }
 
 var RuStrings = map[string]string{
@@ -160,6 +209,10 @@ var RuStrings = map[string]string{
 	"cat_dessert_label": "Десерты",
 	"category_choose":  "Выберите товары из этой категории.",
 	"product_added":   "Товар добавлен.",
+	"rate_failed":     "Не удалось сохранить оценку: %s",
+	"rate_comment_prompt": "✍️ Напишите отзыв о заказе #%d. Отмена: /cancel",
+	"rate_comment_saved":  "✅ Спасибо за отзыв!",
+	"rate_comment_cancelled": "Отзыв отменён.",
 	"confirm_prompt":  "Нажмите *Подтвердить*, чтобы оформить заказ.",
 	"your_order":      "🛒 *Ваш заказ*",
 	"add_more_confirm": "Добавить ещё или подтвердить заказ.",
@@ -244,6 +297,46 @@ var RuStrings = map[string]string{
 	"dr_mark_collected":    "📦 Получен",
 	"dr_start_delivering":  "🛵 Начать доставку",
 	"dr_order_completed_btn": "✅ Завершён",
+	"dr_earnings":          "💰 Заработок",
+	"dr_earnings_summary":  "💰 Заработок\n\n📅 Сегодня: заказов %d\nДоля: %d сум, чаевые: %d сум\nПолучено наличными: %d сум\n\n🗓 Эта неделя: заказов %d\nДоля: %d сум, чаевые: %d сум\nПолучено наличными: %d сум\n\nКомиссия платформы: %g%%",
+	"dr_rating_line":       "\n\n⭐ Рейтинг: %s",
+	"dr_tip_hint":          "Получили чаевые? Отправьте: /tip %d <сумма>",
+	"dr_tip_usage":         "Использование: /tip <id_заказа> <сумма>",
+	"dr_tip_saved":         "✅ Чаевые сохранены: #%d — %d сум",
+	"dr_cash":              "💵 Наличные",
+	"dr_cash_header":       "💵 Наличные у вас (нужно сдать в филиалы):\n\n",
+	"dr_cash_line":         "🏪 %s: %d сум (заказов: %d)%s\n",
+	"dr_cash_pending_mark": " — ⏳ ожидает подтверждения",
+	"dr_cash_none":         "✅ У вас нет несданных наличных.",
+	"dr_cash_handover_btn": "💵 Сдать: %s",
+	"dr_cash_handover_sent": "✅ Запрос отправлен: %s — %d сум. Дождитесь подтверждения админа филиала.",
+	"dr_cash_confirmed":    "✅ Филиал %s принял %d сум наличными.",
+	"dr_cash_rejected":     "❌ Филиал %s отклонил сдачу %d сум. Свяжитесь с админом.",
+	"dr_active_list_header": "📦 Активные заказы: %d/%d\n\n",
+	"dr_active_list_line":  "Заказ #%d — %s\n🛒 %d сум + 🚚 %d сум = 💵 %d UZS\n\n",
+	"dr_max_active":        "❌ Одновременно можно взять не более %d заказов.",
+	"dr_jobs_batch_note":   "🔁 Показаны только дополнительные заказы из филиала ваших активных заказов.\n\n",
+	"dr_shift_usage":        "Использование: /shift ЧЧ:ММ-ЧЧ:ММ (например: /shift 09:00-18:00)",
+	"dr_shift_set":          "✅ Смена назначена: %s — %s",
+	"dr_shift_current":      "🕘 Смена: %s — %s\nИзменить: /shift ЧЧ:ММ-ЧЧ:ММ",
+	"dr_shift_none":         "Смена не назначена. Назначить: /shift ЧЧ:ММ-ЧЧ:ММ",
+	"dr_reminder_shift_end":  "⏰ Ваша смена закончится через ~%d мин., после этого вы автоматически уйдёте офлайн.",
+	"dr_reminder_stale":     "📍 Ваша геолокация не обновляется. Если не отправите её в течение ~%d мин., вы автоматически уйдёте офлайн.",
+	"dr_auto_offline_shift_end":  "🔴 Смена закончилась — вы автоматически переведены в офлайн.",
+	"dr_auto_offline_stale":  "🔴 Геолокация долго не обновлялась — вы автоматически переведены в офлайн.",
+	"dr_docs_btn":           "📄 Отправить документы",
+	"dr_docs_intro":         "📄 Чтобы брать заказы, нужно проверить документы: водительское удостоверение, техпаспорт и селфи.",
+	"dr_docs_ask_license":   "🪪 Отправьте фото водительского удостоверения:",
+	"dr_docs_ask_tech_passport":  "📘 Отправьте фото техпаспорта:",
+	"dr_docs_ask_selfie":    "🤳 Отправьте селфи с удостоверением:",
+	"dr_docs_ask_expiry":    "📅 Отправьте срок действия (ГГГГ-ММ-ДД или ДД.ММ.ГГГГ):",
+	"dr_docs_send_photo":    "📷 Пожалуйста, отправьте фото.",
+	"dr_docs_submitted":     "✅ Документы отправлены на проверку. После подтверждения вы сможете выйти онлайн.",
+	"dr_verif_pending":      "⏳ Ваши документы на проверке.",
+	"dr_verif_need_docs":    "📄 Отправьте документы — до подтверждения заказы недоступны.",
+	"dr_verif_rejected":     "❌ Документы отклонены: %s\nОтправьте заново.",
+	"dr_verif_approved":     "✅ Документы подтверждены! Теперь можно выйти онлайн и брать заказы.",
+	"dr_docs_expired":       "⚠️ Срок действия документа истёк. Чтобы выйти онлайн, отправьте новые документы.",
 
 	// Admin order card
 	"adm_new_order":       "Новый заказ #%d",
@@ -269,6 +362,11 @@ var RuStrings = map[string]string{
 	"adm_driver_accepted": "✅ Водитель принял",
 	"adm_contact_driver":  "📞 Связаться с водителем",
 	"adm_order_id":        "Заказ #%d",
+	"adm_cash_request":    "💵 Сдача наличных #%d\n\nВодитель: %s\nСумма: %d сум (заказов: %d)\n\nВы получили деньги?",
+	"adm_cash_confirm":    "✅ Получил",
+	"adm_cash_reject":     "❌ Отклонить",
+	"adm_cash_confirmed":  "✅ Принято",
+	"adm_cash_rejected":   "❌ Отклонено",
 }
 
 // T returns localized string for lang. Key is from UzStrings/RuStrings. If args given, uses fmt.Sprintf.
@@ -3,15 +3,18 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
 	"time"
 
+	"food-telegram/api"
 	"food-telegram/bot"
 	"food-telegram/config"
 	"food-telegram/db"
 	"food-telegram/services"
+	"food-telegram/web"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
@@ -33,6 +36,12 @@ func main() {
 		return
 	}
 
+	// Check for export subcommand (spreadsheet of orders, items, deliveries or payments)
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		runExport(cfg, os.Args[2:])
+		return
+	}
+
 	if cfg.Telegram.Token == "" {
 		fmt.Fprintln(os.Stderr, "TOKEN not set")
 		os.Exit(1)
@@ -106,12 +115,14 @@ func main() {
 			os.Exit(1)
 		}
 		b.SetDriverBotAPI(driverBot.GetAPI())
-		driverBot.SetOnOrderUpdated(func(orderID int64) {
-			go b.RefreshOrderCards(context.Background(), orderID)
+		driverBot.SetOnOrderUpdated(func(int64) {
+			b.KickOutbox()
 		})
 		if adder != nil {
+			adder.SetDriverBotAPI(driverBot.GetAPI())
 			driverBot.SetOnSubscriptionExpired(adder.SendExpiredNotificationToSuperadmin)
 			driverBot.SetOnRenewalRequest(adder.SendRenewalRequestToSuperadmin)
+			driverBot.SetOnVerificationSubmitted(adder.NotifyDriverVerificationPending)
 		}
 		go driverBot.Start()
 		fmt.Println("Yetkazib beruvchi bot ishga tushdi.")
@@ -122,6 +133,36 @@ func main() {
 	}
 	// Background: automatically notify when subscription expires (not only on password input)
 	go runExpiredSubscriptionNotifier(adder, driverBot)
+	// Background: take drivers offline when their shift ends or location goes stale (with a reminder before)
+	go runDriverAutoOffline(cfg, driverBot)
+	// Background: drivers with expired documents must re-verify
+	go runDriverDocumentExpiry(driverBot)
+	// Background: end adder sessions past their idle / absolute timeout
+	go runAdminSessionExpiry(adder)
+	// Background: purge soft-deleted locations / menu items past the restore window and old outbox rows
+	go runDeletedDataPurge(cfg)
+	// Background: deliver queued order notifications (cards, driver offers) with retries
+	go runNotificationOutbox(b)
+	// Background: POST order events to branch webhooks with retries
+	go runWebhookDelivery()
+	superadminID := cfg.Telegram.SuperadminID
+	if superadminID == 0 {
+		superadminID = adminID
+	}
+	// Background: end-of-day summaries to branch staff and the superadmin (message bot)
+	go runDailySummaries(cfg, b, superadminID)
+	// HTTP API for POS systems and dashboards (API_ADDR; off when empty)
+	if cfg.API.Addr != "" {
+		go runAPI(cfg, b)
+	}
+	// Web dashboard for branch staff and the superadmin (WEB_ADDR; Telegram login through the adder bot)
+	if cfg.Web.Addr != "" {
+		if adder == nil {
+			fmt.Fprintln(os.Stderr, "web dashboard: WEB_ADDR needs ADDER_TOKEN for Telegram login")
+		} else {
+			go runWeb(cfg, b, adder, superadminID)
+		}
+	}
 
 	fmt.Println("Bot started.")
 	b.Start()
@@ -171,6 +212,162 @@ func runExpiredSubscriptionNotifier(adder *bot.AdderBot, driverBot *bot.DriverBo
 	}
 }
 
+func runAdminSessionExpiry(adder *bot.AdderBot) {
+	if adder == nil {
+		return
+	}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		adder.ExpireSessions()
+	}
+}
+
+func runDeletedDataPurge(cfg *config.Config) {
+	retention := time.Duration(cfg.Data.DeletedRetentionDays) * 24 * time.Hour
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		if n, err := services.PurgeOutbox(context.Background(), time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "purge outbox: %v\n", err)
+		} else if n > 0 {
+			fmt.Printf("Purged %d old outbox row(s).\n", n)
+		}
+		if n, err := services.PurgeWebhookDeliveries(context.Background(), time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "purge webhook deliveries: %v\n", err)
+		} else if n > 0 {
+			fmt.Printf("Purged %d old webhook delivery(ies).\n", n)
+		}
+		res, err := services.PurgeDeletedData(context.Background(), retention)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "purge deleted data: %v\n", err)
+			continue
+		}
+		if res.Locations > 0 || res.MenuItems > 0 {
+			fmt.Printf("Purged %d deleted location(s) and %d menu item(s).\n", res.Locations, res.MenuItems)
+		}
+	}
+}
+
+func runNotificationOutbox(b *bot.Bot) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.OutboxWake():
+		}
+		b.DeliverOutbox(context.Background())
+	}
+}
+
+func runWebhookDelivery() {
+	client := &http.Client{Timeout: 10 * time.Second}
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, failed, err := services.DeliverWebhooks(context.Background(), client); err != nil {
+			fmt.Fprintf(os.Stderr, "webhooks: %v\n", err)
+		} else if failed > 0 {
+			fmt.Fprintf(os.Stderr, "webhooks: %d delivery(ies) failed for good, see /webhook_failed\n", failed)
+		}
+	}
+}
+
+func runDailySummaries(cfg *config.Config, b *bot.Bot, superadminID int64) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := b.SendDueDailySummaries(context.Background(), time.Now(), cfg.Data.DailySummaryHour, superadminID); err != nil {
+			fmt.Fprintf(os.Stderr, "daily summaries: %v\n", err)
+		}
+	}
+}
+
+func runAPI(cfg *config.Config, b *bot.Bot) {
+	srv := api.New()
+	srv.SetOnOrderUpdated(func(int64) {
+		b.KickOutbox()
+	})
+	fmt.Printf("API listening on %s\n", cfg.API.Addr)
+	if err := srv.ListenAndServe(cfg.API.Addr); err != nil {
+		fmt.Fprintf(os.Stderr, "api: %v\n", err)
+	}
+}
+
+func runWeb(cfg *config.Config, b *bot.Bot, adder *bot.AdderBot, superadminID int64) {
+	srv, err := web.New(web.Config{
+		BotToken:     cfg.Telegram.AdderToken,
+		BotUsername:  adder.GetAPI().Self.UserName,
+		SuperadminID: superadminID,
+		SessionTTL:   time.Duration(cfg.Telegram.AdminSessionMaxHours) * time.Hour,
+		SessionIdle:  time.Duration(cfg.Telegram.AdminSessionIdleMin) * time.Minute,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "web: %v\n", err)
+		return
+	}
+	srv.SetOnOrderUpdated(func(int64) {
+		b.KickOutbox()
+	})
+	fmt.Printf("Web dashboard listening on %s\n", cfg.Web.Addr)
+	if err := srv.ListenAndServe(cfg.Web.Addr); err != nil {
+		fmt.Fprintf(os.Stderr, "web: %v\n", err)
+	}
+}
+
+func runDriverAutoOffline(cfg *config.Config, driverBot *bot.DriverBot) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	staleAfter := time.Duration(cfg.Delivery.DriverStaleLocationMin) * time.Minute
+	remindBefore := time.Duration(cfg.Delivery.DriverOfflineReminderMin) * time.Minute
+	for range ticker.C {
+		ctx := context.Background()
+		list, err := services.ListDriversToAutoOffline(ctx, staleAfter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "driver auto-offline: %v\n", err)
+			continue
+		}
+		for _, n := range list {
+			if err := services.SetDriverStatusWithReason(ctx, n.DriverID, services.DriverStatusOffline, n.Reason); err != nil {
+				fmt.Fprintf(os.Stderr, "driver auto-offline %s: %v\n", n.DriverID, err)
+				continue
+			}
+			if driverBot != nil {
+				driverBot.NotifyAutoOffline(n)
+			}
+		}
+		reminders, err := services.ClaimOfflineReminders(ctx, remindBefore, staleAfter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "driver offline reminders: %v\n", err)
+			continue
+		}
+		if driverBot != nil {
+			for _, n := range reminders {
+				driverBot.SendOfflineReminder(n, cfg.Delivery.DriverOfflineReminderMin)
+			}
+		}
+	}
+}
+
+func runDriverDocumentExpiry(driverBot *bot.DriverBot) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		list, err := services.ExpireDriverVerifications(context.Background())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "driver document expiry: %v\n", err)
+			continue
+		}
+		if driverBot == nil {
+			continue
+		}
+		for _, d := range list {
+			driverBot.NotifyDocumentsExpired(d)
+		}
+	}
+}
+
 func runMigrate(cfg *config.Config) {
 	if err := db.Init(cfg.DB); err != nil {
 		fmt.Fprintln(os.Stderr, "db:", err)
@@ -184,6 +381,50 @@ func runMigrate(cfg *config.Config) {
 	}
 }
 
+// runExport writes an export file: export <kind> [csv|xlsx] [from] [to] [location_id] [-o path]. Without -o the
+// file is created in the current directory under its default name; "-o -" writes to stdout.
+func runExport(cfg *config.Config, args []string) {
+	out := ""
+	var fields []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-o" && i+1 < len(args) {
+			out = args[i+1]
+			i++
+			continue
+		}
+		fields = append(fields, args[i])
+	}
+	req, err := services.ParseExportArgs(fields, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "usage: export <orders|items|deliveries|payments> [csv|xlsx] [from YYYY-MM-DD] [to YYYY-MM-DD] [location_id] [-o path]")
+		fmt.Fprintln(os.Stderr, "export:", err)
+		os.Exit(2)
+	}
+	if err := db.Init(cfg.DB); err != nil {
+		fmt.Fprintln(os.Stderr, "db:", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	name, data, rows, err := services.ExportFile(context.Background(), req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "export:", err)
+		os.Exit(1)
+	}
+	if out == "-" {
+		_, _ = os.Stdout.Write(data)
+		return
+	}
+	if out == "" {
+		out = name
+	}
+	if err := os.WriteFile(out, data, 0o600); err != nil {
+		fmt.Fprintln(os.Stderr, "export:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s: %d rows\n", out, rows)
+}
+
 func runResetDB(cfg *config.Config) {
 	if err := db.Init(cfg.DB); err != nil {
 		fmt.Fprintln(os.Stderr, "db:", err)
@@ -198,18 +439,37 @@ func runResetDB(cfg *config.Config) {
 		subscriptions,
 		login_attempts,
 		user_credentials,
+		branch_admin_credentials,
 		application_driver_details,
 		application_restaurant_details,
 		applications,
+		notification_outbox,
 		order_message_pointers,
 		order_status_history,
+		driver_earnings,
+		cash_handovers,
+		driver_payouts,
+		driver_shifts,
+		driver_status_log,
+		driver_documents,
 		messages,
 		checkouts,
 		carts,
 		driver_locations,
 		drivers,
 		branch_admin_access,
+		admin_sessions,
 		admin_logins,
+		audit_events,
+		api_keys,
+		webhook_deliveries,
+		webhooks,
+		web_sessions,
+		location_hours,
+		order_items,
+		daily_summary_opt_outs,
+		daily_summaries_sent,
+		order_ratings,
 		customer_users,
 		branch_admins,
 		user_delivery_coords,
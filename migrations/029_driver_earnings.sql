@@ -0,0 +1,34 @@
+-- Driver payouts: one row per settled period (superadmin /payout).
+CREATE TABLE IF NOT EXISTS driver_payouts (
+    id BIGSERIAL PRIMARY KEY,
+    driver_id UUID NOT NULL REFERENCES drivers(id) ON DELETE CASCADE,
+    period_from DATE NOT NULL,
+    period_to DATE NOT NULL,
+    orders_count INT NOT NULL DEFAULT 0,
+    delivery_fees BIGINT NOT NULL DEFAULT 0,
+    commission BIGINT NOT NULL DEFAULT 0,
+    driver_share BIGINT NOT NULL DEFAULT 0,
+    cash_collected BIGINT NOT NULL DEFAULT 0,
+    tips BIGINT NOT NULL DEFAULT 0,
+    settled_by BIGINT NOT NULL,
+    settled_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_driver_payouts_driver ON driver_payouts(driver_id);
+
+-- Driver earnings ledger: one row per completed delivery (written by CompleteDeliveryByDriver).
+CREATE TABLE IF NOT EXISTS driver_earnings (
+    id BIGSERIAL PRIMARY KEY,
+    driver_id UUID NOT NULL REFERENCES drivers(id) ON DELETE CASCADE,
+    order_id BIGINT NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    location_id BIGINT,
+    delivery_fee BIGINT NOT NULL DEFAULT 0,
+    commission_pct NUMERIC(5,2) NOT NULL DEFAULT 0,
+    commission BIGINT NOT NULL DEFAULT 0,
+    driver_share BIGINT NOT NULL DEFAULT 0,
+    cash_collected BIGINT NOT NULL DEFAULT 0,
+    tip BIGINT NOT NULL DEFAULT 0,
+    payout_id BIGINT REFERENCES driver_payouts(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_driver_earnings_driver_time ON driver_earnings(driver_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_driver_earnings_unsettled ON driver_earnings(driver_id) WHERE payout_id IS NULL;
@@ -0,0 +1,20 @@
+-- Cash-on-delivery reconciliation: driver hands collected cash over to the branch, branch admin confirms.
+CREATE TABLE IF NOT EXISTS cash_handovers (
+    id BIGSERIAL PRIMARY KEY,
+    driver_id UUID NOT NULL REFERENCES drivers(id) ON DELETE CASCADE,
+    location_id BIGINT NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL DEFAULT 0,
+    orders_count INT NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'rejected')),
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    decided_by BIGINT,
+    decided_at TIMESTAMPTZ
+);
+-- At most one open hand-over per driver and branch.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cash_handovers_pending
+    ON cash_handovers (driver_id, location_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_cash_handovers_location ON cash_handovers(location_id);
+
+-- Earnings rows covered by a hand-over (NULL = cash still with the driver).
+ALTER TABLE driver_earnings ADD COLUMN IF NOT EXISTS handover_id BIGINT REFERENCES cash_handovers(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_driver_earnings_handover ON driver_earnings(handover_id);
@@ -0,0 +1,24 @@
+-- Planned driver shifts; drivers are taken offline automatically when the shift ends.
+CREATE TABLE IF NOT EXISTS driver_shifts (
+    id BIGSERIAL PRIMARY KEY,
+    driver_id UUID NOT NULL REFERENCES drivers(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    reminded_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (ends_at > starts_at)
+);
+CREATE INDEX IF NOT EXISTS idx_driver_shifts_driver_time ON driver_shifts(driver_id, ends_at);
+
+-- Online/offline change log (reason: manual, shift_end, stale_location) for online-hours reports.
+CREATE TABLE IF NOT EXISTS driver_status_log (
+    id BIGSERIAL PRIMARY KEY,
+    driver_id UUID NOT NULL REFERENCES drivers(id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('online', 'offline')),
+    reason TEXT NOT NULL DEFAULT 'manual',
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_driver_status_log_driver_time ON driver_status_log(driver_id, changed_at);
+
+-- Stale-location reminder sent (reset on every location update).
+ALTER TABLE driver_locations ADD COLUMN IF NOT EXISTS reminded_at TIMESTAMPTZ;
@@ -0,0 +1,22 @@
+-- Driver verification: documents are reviewed by the superadmin before the driver can go online.
+-- Existing drivers are grandfathered as verified; new rows start in pending_documents.
+ALTER TABLE drivers ADD COLUMN IF NOT EXISTS verification_status TEXT NOT NULL DEFAULT 'verified'
+    CHECK (verification_status IN ('pending_documents', 'pending_verification', 'verified', 'rejected'));
+ALTER TABLE drivers ALTER COLUMN verification_status SET DEFAULT 'pending_documents';
+ALTER TABLE drivers ADD COLUMN IF NOT EXISTS verification_reject_reason TEXT;
+ALTER TABLE drivers ADD COLUMN IF NOT EXISTS verified_by BIGINT;
+ALTER TABLE drivers ADD COLUMN IF NOT EXISTS verified_at TIMESTAMPTZ;
+ALTER TABLE drivers ADD COLUMN IF NOT EXISTS review_in_progress_by BIGINT;
+CREATE INDEX IF NOT EXISTS idx_drivers_verification_status ON drivers(verification_status);
+
+-- Uploaded documents (Telegram file_id of the driver bot); one current row per type.
+CREATE TABLE IF NOT EXISTS driver_documents (
+    id BIGSERIAL PRIMARY KEY,
+    driver_id UUID NOT NULL REFERENCES drivers(id) ON DELETE CASCADE,
+    doc_type TEXT NOT NULL CHECK (doc_type IN ('license', 'tech_passport', 'selfie')),
+    file_id TEXT NOT NULL,
+    expires_at DATE,
+    uploaded_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (driver_id, doc_type)
+);
+CREATE INDEX IF NOT EXISTS idx_driver_documents_expires ON driver_documents(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,30 @@
+-- Several staff per location with roles: owner (menu + staff + orders), manager (menu + orders), cashier (orders only).
+-- Existing rows are the location owners. Each staff member has their own password_hash.
+ALTER TABLE branch_admins ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'owner'
+    CHECK (role IN ('owner', 'manager', 'cashier'));
+ALTER TABLE branch_admins ADD COLUMN IF NOT EXISTS on_shift BOOLEAN NOT NULL DEFAULT true;
+
+-- Replace one-admin-per-location (migration 011) with one row per (location, user).
+ALTER TABLE branch_admins DROP CONSTRAINT IF EXISTS branch_admins_branch_location_id_key;
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'branch_admins_branch_location_id_admin_user_id_key'
+    ) THEN
+        ALTER TABLE branch_admins ADD CONSTRAINT branch_admins_branch_location_id_admin_user_id_key UNIQUE (branch_location_id, admin_user_id);
+    END IF;
+END $$;
+
+-- Role the user logged in with (from the matched branch_admins row).
+ALTER TABLE branch_admin_access ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'owner';
+
+-- Admin order cards go to every on-shift staff member: one pointer per chat.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'order_message_pointers_chat_pkey'
+    ) THEN
+        ALTER TABLE order_message_pointers DROP CONSTRAINT IF EXISTS order_message_pointers_pkey;
+        ALTER TABLE order_message_pointers ADD CONSTRAINT order_message_pointers_chat_pkey PRIMARY KEY (order_id, audience, chat_id);
+    END IF;
+END $$;
@@ -0,0 +1,27 @@
+-- Branch admin credentials keyed by Telegram user id (like user_credentials) with optional TOTP second factor.
+-- Replaces the per-branch password in branch_admins.password_hash.
+CREATE TABLE IF NOT EXISTS branch_admin_credentials (
+    tg_user_id BIGINT NOT NULL PRIMARY KEY,
+    password_hash TEXT NOT NULL,
+    totp_secret TEXT,
+    totp_enabled BOOLEAN NOT NULL DEFAULT false,
+    totp_last_step BIGINT NOT NULL DEFAULT 0,
+    password_changed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Move existing branch passwords to their staff member: owner row first, then the latest promotion.
+INSERT INTO branch_admin_credentials (tg_user_id, password_hash)
+SELECT DISTINCT ON (admin_user_id) admin_user_id, password_hash
+FROM branch_admins
+WHERE password_hash IS NOT NULL
+ORDER BY admin_user_id, (role = 'owner') DESC, promoted_at DESC
+ON CONFLICT (tg_user_id) DO NOTHING;
+UPDATE branch_admins SET password_hash = NULL WHERE password_hash IS NOT NULL;
+
+-- Shared-password sessions of users without a staff row end; owners add them with /staff_add.
+DELETE FROM branch_admin_access a
+WHERE NOT EXISTS (
+    SELECT 1 FROM branch_admins ba WHERE ba.branch_location_id = a.branch_location_id AND ba.admin_user_id = a.tg_user_id
+);
@@ -0,0 +1,16 @@
+-- Persistent adder bot sessions (superadmin and branch staff) with idle and absolute timeouts; one session per user.
+CREATE TABLE IF NOT EXISTS admin_sessions (
+    tg_user_id BIGINT NOT NULL PRIMARY KEY,
+    role TEXT NOT NULL CHECK (role IN ('super', 'branch')),
+    branch_location_id BIGINT REFERENCES locations(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_admin_sessions_location ON admin_sessions(branch_location_id);
+
+-- admin_logins becomes the session event log: login, logout, idle_timeout, absolute_timeout, revoked.
+-- logged_in_at is the event time; actor_tg_user_id is who revoked the session (NULL otherwise). Superadmin sessions have no branch.
+ALTER TABLE admin_logins ADD COLUMN IF NOT EXISTS event TEXT NOT NULL DEFAULT 'login';
+ALTER TABLE admin_logins ADD COLUMN IF NOT EXISTS actor_tg_user_id BIGINT;
+ALTER TABLE admin_logins ALTER COLUMN branch_location_id DROP NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_admin_logins_user ON admin_logins(tg_user_id, logged_in_at);
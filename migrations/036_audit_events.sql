@@ -0,0 +1,16 @@
+-- Unified audit log of admin and superadmin actions (menu, locations, staff, credentials, subscriptions, applications, drivers).
+-- actor_tg_user_id is NULL for system actions; before/after hold the changed fields (never password hashes or secrets).
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    actor_tg_user_id BIGINT,
+    actor_role TEXT NOT NULL DEFAULT 'system',
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_time ON audit_events(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor_tg_user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target_type, target_id, created_at DESC);
@@ -0,0 +1,9 @@
+-- Soft delete for locations and menu items: rows stay (orders keep location_id) and can be restored by the superadmin
+-- within the retention window; the purge job then removes menu items, staff and credentials and marks the location purged.
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS deleted_by BIGINT;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS purged_at TIMESTAMPTZ;
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_locations_deleted ON locations(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_menu_items_deleted ON menu_items(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,25 @@
+-- Outgoing Telegram notifications, written in the same transaction as the state change and delivered by a worker.
+-- Event rows (order_new, order_cards, offer_drivers) fan out into delivery rows (order_card, driver_offer, message)
+-- for each recipient. Failed deliveries are retried with backoff; permanent failures (bot blocked) stay 'failed' for follow-up.
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL CHECK (kind IN ('order_new', 'order_cards', 'offer_drivers', 'order_card', 'driver_offer', 'message')),
+    order_id BIGINT REFERENCES orders(id) ON DELETE CASCADE,
+    audience TEXT CHECK (audience IN ('admin', 'customer', 'driver')),
+    chat_id BIGINT,
+    payload JSONB,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sending', 'sent', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at TIMESTAMPTZ,
+    failed_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_due ON notification_outbox(next_attempt_at) WHERE status IN ('pending', 'sending');
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_failed ON notification_outbox(failed_at DESC) WHERE status = 'failed';
+-- One pending refresh per order (cards are rendered at delivery time, so repeats collapse).
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_outbox_pending_cards
+    ON notification_outbox(order_id) WHERE status = 'pending' AND kind = 'order_cards';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_outbox_pending_card
+    ON notification_outbox(order_id, audience, chat_id) WHERE status = 'pending' AND kind = 'order_card';
@@ -0,0 +1,15 @@
+-- Keys for the HTTP API (/api/v1). Only a SHA-256 hash of the key is stored; the plain key is shown once when created.
+-- location_id NULL = global key (every branch, drivers); otherwise the key only sees and changes that branch.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    location_id BIGINT REFERENCES locations(id) ON DELETE CASCADE,
+    created_by BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_location ON api_keys(location_id);
+-- Paging orders by branch / status / date
+CREATE INDEX IF NOT EXISTS idx_orders_location_created ON orders(location_id, created_at DESC);
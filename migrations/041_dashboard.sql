@@ -0,0 +1,23 @@
+-- Opening hours per branch and weekday (0 = Sunday), in minutes after midnight, Tashkent time.
+-- closes_min <= opens_min means the branch closes after midnight. A weekday without a row has no hours set.
+CREATE TABLE IF NOT EXISTS location_hours (
+    location_id BIGINT NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    closed BOOLEAN NOT NULL DEFAULT false,
+    opens_min SMALLINT NOT NULL DEFAULT 0 CHECK (opens_min BETWEEN 0 AND 1439),
+    closes_min SMALLINT NOT NULL DEFAULT 0 CHECK (closes_min BETWEEN 0 AND 1439),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (location_id, weekday)
+);
+
+-- Web dashboard sessions (Telegram Login Widget). Only a SHA-256 hash of the cookie token is stored;
+-- access (branch, role, superadmin) is resolved on every request, so removing staff ends their dashboard access too.
+CREATE TABLE IF NOT EXISTS web_sessions (
+    token_hash TEXT PRIMARY KEY,
+    tg_user_id BIGINT NOT NULL,
+    csrf_token TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_web_sessions_user ON web_sessions(tg_user_id);
+CREATE INDEX IF NOT EXISTS idx_web_sessions_expires ON web_sessions(expires_at);
@@ -0,0 +1,13 @@
+-- Order line items: what the customer had in the cart at checkout, copied so later menu edits do not change
+-- past orders. menu_item_id is kept for reports; the item may be deleted since.
+CREATE TABLE IF NOT EXISTS order_items (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    menu_item_id BIGINT,
+    category TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL,
+    price BIGINT NOT NULL,
+    qty INT NOT NULL CHECK (qty > 0)
+);
+CREATE INDEX IF NOT EXISTS idx_order_items_order ON order_items(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_menu_item ON order_items(menu_item_id);
@@ -0,0 +1,13 @@
+-- Staff (and the superadmin) who turned the end-of-day summary off with /summary_off.
+CREATE TABLE IF NOT EXISTS daily_summary_opt_outs (
+    tg_user_id BIGINT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Summaries already sent, so a restart does not send the same day twice. location_id 0 is the platform roll-up.
+CREATE TABLE IF NOT EXISTS daily_summaries_sent (
+    location_id BIGINT NOT NULL,
+    day DATE NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (location_id, day)
+);
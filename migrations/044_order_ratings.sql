@@ -0,0 +1,14 @@
+-- Customer ratings of completed orders: food (the branch) and delivery (the driver), 1-5 stars each, plus an
+-- optional comment. location_id and driver_id are copied from the order so branch and driver scores need no join.
+CREATE TABLE IF NOT EXISTS order_ratings (
+    order_id BIGINT PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    location_id BIGINT REFERENCES locations(id) ON DELETE SET NULL,
+    driver_id UUID REFERENCES drivers(id) ON DELETE SET NULL,
+    food SMALLINT CHECK (food BETWEEN 1 AND 5),
+    delivery SMALLINT CHECK (delivery BETWEEN 1 AND 5),
+    comment TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_order_ratings_location ON order_ratings(location_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_order_ratings_driver ON order_ratings(driver_id) WHERE driver_id IS NOT NULL;
@@ -0,0 +1,4 @@
+-- Dashboard sessions get the adder bot's idle timeout (last_seen_at) and wait for the authenticator code of staff
+-- with 2FA (pending_totp) before they give access.
+ALTER TABLE web_sessions ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE web_sessions ADD COLUMN IF NOT EXISTS pending_totp BOOLEAN NOT NULL DEFAULT false;
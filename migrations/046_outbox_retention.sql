@@ -0,0 +1,2 @@
+-- Delivered rows are purged after a week (failed ones after 30 days, see PurgeOutbox); this index keeps the sweep cheap.
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_sent ON notification_outbox(sent_at) WHERE status = 'sent';
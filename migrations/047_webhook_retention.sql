@@ -0,0 +1,3 @@
+-- Delivered webhook deliveries are purged after a week (failed ones after 30 days, see PurgeWebhookDeliveries);
+-- this index keeps the sweep cheap. Failed ones already have idx_webhook_deliveries_failed.
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_delivered ON webhook_deliveries(delivered_at) WHERE status = 'delivered';
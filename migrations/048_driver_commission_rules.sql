@@ -0,0 +1,8 @@
+-- Driver commission rules: % of the delivery fee the platform keeps (superadmin /commission). location_id 0 is the
+-- platform-wide rule, others override it for one branch; without any row DRIVER_COMMISSION_PCT applies.
+CREATE TABLE IF NOT EXISTS driver_commission_rules (
+    location_id BIGINT PRIMARY KEY,
+    commission_pct NUMERIC(5,2) NOT NULL CHECK (commission_pct >= 0 AND commission_pct <= 100),
+    updated_by BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -11,6 +11,16 @@ type CreateOrderInput struct {
 	ItemsTotal   int64
 	LocationID   int64  // restaurant (branch) this order belongs to
 	DeliveryType string // "delivery" or "pickup", set by customer at checkout
+	Items        []OrderItem
+}
+
+// OrderItem is one line of an order, copied from the cart at checkout.
+type OrderItem struct {
+	MenuItemID int64 // 0 if the cart item had no numeric menu ID
+	Category   string
+	Name       string
+	Price      int64
+	Qty        int
 }
 
 // Order is a row from orders table (for status and location checks).
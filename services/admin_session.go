@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Adder bot session roles (admin_sessions.role).
+const (
+	AdminSessionRoleSuper  = "super"
+	AdminSessionRoleBranch = "branch"
+)
+
+// Session events logged in admin_logins.event.
+const (
+	AdminSessionEventLogin           = "login"
+	AdminSessionEventLogout          = "logout"
+	AdminSessionEventIdleTimeout     = "idle_timeout"
+	AdminSessionEventAbsoluteTimeout = "absolute_timeout"
+	AdminSessionEventRevoked         = "revoked"
+	AdminSessionEventWebLogin        = "web_login"  // web dashboard sign-in (after the 2FA code if enabled)
+	AdminSessionEventWebLogout       = "web_logout" // web dashboard sign-out
+)
+
+// AdminSession is an active adder bot login (admin_sessions row).
+type AdminSession struct {
+	TgUserID         int64
+	Role             string
+	BranchLocationID int64 // 0 for the superadmin
+	LocationName     string
+	CreatedAt        time.Time
+	LastSeenAt       time.Time
+}
+
+// AdminSessionExpiry returns the timeout event that ends a session at now, or "" if it is still valid.
+// The absolute limit wins when both are exceeded. A zero duration disables that limit.
+func AdminSessionExpiry(createdAt, lastSeenAt, now time.Time, idle, absolute time.Duration) string {
+	if absolute > 0 && now.Sub(createdAt) >= absolute {
+		return AdminSessionEventAbsoluteTimeout
+	}
+	if idle > 0 && now.Sub(lastSeenAt) >= idle {
+		return AdminSessionEventIdleTimeout
+	}
+	return ""
+}
+
+// StartAdminSession opens (or replaces) the user's session and logs the login. branchLocationID is 0 for the superadmin.
+func StartAdminSession(ctx context.Context, tgUserID int64, role string, branchLocationID int64) error {
+	if role != AdminSessionRoleSuper && role != AdminSessionRoleBranch {
+		return fmt.Errorf("unknown session role %q", role)
+	}
+	var loc *int64
+	if branchLocationID != 0 {
+		loc = &branchLocationID
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO admin_sessions (tg_user_id, role, branch_location_id, created_at, last_seen_at)
+		VALUES ($1, $2, $3, now(), now())
+		ON CONFLICT (tg_user_id) DO UPDATE SET role = EXCLUDED.role, branch_location_id = EXCLUDED.branch_location_id,
+			created_at = now(), last_seen_at = now()`,
+		tgUserID, role, loc,
+	); err != nil {
+		return fmt.Errorf("start admin session: %w", err)
+	}
+	if err := logAdminSessionEvent(ctx, tx, tgUserID, loc, AdminSessionEventLogin, 0); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetAdminSession returns the user's session, or nil if they are not logged in. Timeouts are not checked here.
+func GetAdminSession(ctx context.Context, tgUserID int64) (*AdminSession, error) {
+	var s AdminSession
+	err := db.Pool.QueryRow(ctx, `
+		SELECT s.tg_user_id, s.role, COALESCE(s.branch_location_id, 0), COALESCE(l.name, ''), s.created_at, s.last_seen_at
+		FROM admin_sessions s LEFT JOIN locations l ON l.id = s.branch_location_id
+		WHERE s.tg_user_id = $1`,
+		tgUserID,
+	).Scan(&s.TgUserID, &s.Role, &s.BranchLocationID, &s.LocationName, &s.CreatedAt, &s.LastSeenAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get admin session: %w", err)
+	}
+	return &s, nil
+}
+
+// TouchAdminSession returns the user's valid session and marks it as seen now. A session past its idle or absolute
+// timeout is ended and logged; then nil and the timeout event are returned. Not logged in: nil, "".
+func TouchAdminSession(ctx context.Context, tgUserID int64, idle, absolute time.Duration) (*AdminSession, string, error) {
+	s, err := GetAdminSession(ctx, tgUserID)
+	if err != nil || s == nil {
+		return nil, "", err
+	}
+	if event := AdminSessionExpiry(s.CreatedAt, s.LastSeenAt, time.Now(), idle, absolute); event != "" {
+		ended, err := EndAdminSession(ctx, tgUserID, event, 0)
+		if err != nil || !ended {
+			return nil, "", err
+		}
+		return nil, event, nil
+	}
+	if _, err := db.Pool.Exec(ctx, `UPDATE admin_sessions SET last_seen_at = now() WHERE tg_user_id = $1`, tgUserID); err != nil {
+		return nil, "", fmt.Errorf("touch admin session: %w", err)
+	}
+	return s, "", nil
+}
+
+// EndAdminSession removes the user's session and logs event (logout, a timeout or revoked; actorID is who revoked it, 0 otherwise).
+// A revoke also ends the user's web dashboard sessions. Returns false if the user had no adder session.
+func EndAdminSession(ctx context.Context, tgUserID int64, event string, actorID int64) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if event == AdminSessionEventRevoked {
+		if err := endWebSessions(ctx, tx, tgUserID); err != nil {
+			return false, err
+		}
+	}
+	var loc *int64
+	err = tx.QueryRow(ctx, `DELETE FROM admin_sessions WHERE tg_user_id = $1 RETURNING branch_location_id`, tgUserID).Scan(&loc)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, tx.Commit(ctx)
+		}
+		return false, fmt.Errorf("end admin session: %w", err)
+	}
+	if err := logAdminSessionEvent(ctx, tx, tgUserID, loc, event, actorID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// ListAdminSessions returns active sessions, superadmin first, then by location and login time.
+// branchLocationID > 0 limits the list to that location.
+func ListAdminSessions(ctx context.Context, branchLocationID int64) ([]AdminSession, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT s.tg_user_id, s.role, COALESCE(s.branch_location_id, 0), COALESCE(l.name, ''), s.created_at, s.last_seen_at
+		FROM admin_sessions s LEFT JOIN locations l ON l.id = s.branch_location_id
+		WHERE $1::bigint = 0 OR s.branch_location_id = $1
+		ORDER BY s.branch_location_id NULLS FIRST, s.created_at`,
+		branchLocationID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list admin sessions: %w", err)
+	}
+	defer rows.Close()
+	var list []AdminSession
+	for rows.Next() {
+		var s AdminSession
+		if err := rows.Scan(&s.TgUserID, &s.Role, &s.BranchLocationID, &s.LocationName, &s.CreatedAt, &s.LastSeenAt); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// ExpiredAdminSession is a session ended by ExpireAdminSessions.
+type ExpiredAdminSession struct {
+	TgUserID int64
+	Event    string
+}
+
+// ExpireAdminSessions ends every session past its idle or absolute timeout and returns them so the users can be told.
+func ExpireAdminSessions(ctx context.Context, idle, absolute time.Duration) ([]ExpiredAdminSession, error) {
+	list, err := ListAdminSessions(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var expired []ExpiredAdminSession
+	for _, s := range list {
+		event := AdminSessionExpiry(s.CreatedAt, s.LastSeenAt, now, idle, absolute)
+		if event == "" {
+			continue
+		}
+		ok, err := EndAdminSession(ctx, s.TgUserID, event, 0)
+		if err != nil {
+			return expired, err
+		}
+		if ok {
+			expired = append(expired, ExpiredAdminSession{TgUserID: s.TgUserID, Event: event})
+		}
+	}
+	return expired, nil
+}
+
+func logAdminSessionEvent(ctx context.Context, tx auditExecer, tgUserID int64, branchLocationID *int64, event string, actorID int64) error {
+	var actor *int64
+	if actorID != 0 {
+		actor = &actorID
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO admin_logins (tg_user_id, branch_location_id, event, actor_tg_user_id, logged_in_at) VALUES ($1, $2, $3, $4, now())`,
+		tgUserID, branchLocationID, event, actor,
+	); err != nil {
+		return fmt.Errorf("log admin session event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdminSessionExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	idle := 30 * time.Minute
+	absolute := 12 * time.Hour
+	tests := []struct {
+		name     string
+		created  time.Time
+		lastSeen time.Time
+		idle     time.Duration
+		absolute time.Duration
+		want     string
+	}{
+		{"fresh", now.Add(-time.Minute), now.Add(-time.Minute), idle, absolute, ""},
+		{"active for hours", now.Add(-11 * time.Hour), now.Add(-5 * time.Minute), idle, absolute, ""},
+		{"idle exactly", now.Add(-time.Hour), now.Add(-idle), idle, absolute, AdminSessionEventIdleTimeout},
+		{"idle", now.Add(-time.Hour), now.Add(-45 * time.Minute), idle, absolute, AdminSessionEventIdleTimeout},
+		{"absolute", now.Add(-13 * time.Hour), now.Add(-time.Minute), idle, absolute, AdminSessionEventAbsoluteTimeout},
+		{"both, absolute wins", now.Add(-13 * time.Hour), now.Add(-2 * time.Hour), idle, absolute, AdminSessionEventAbsoluteTimeout},
+		{"idle disabled", now.Add(-time.Hour), now.Add(-45 * time.Minute), 0, absolute, ""},
+		{"absolute disabled", now.Add(-48 * time.Hour), now.Add(-time.Minute), idle, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AdminSessionExpiry(tt.created, tt.lastSeen, now, tt.idle, tt.absolute); got != tt.want {
+				t.Errorf("AdminSessionExpiry() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// apiKeyPrefix marks plain API keys so a leaked one is easy to recognize.
+const apiKeyPrefix = "fk_"
+
+// APIKey is one api_keys row. LocationID nil = global key.
+type APIKey struct {
+	ID         int64
+	Name       string
+	LocationID *int64
+	CreatedBy  int64
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// Global reports whether the key may act on every branch.
+func (k *APIKey) Global() bool {
+	return k.LocationID == nil
+}
+
+// CanAccessLocation reports whether the key may see and change data of the branch.
+func (k *APIKey) CanAccessLocation(locationID int64) bool {
+	return k.LocationID == nil || *k.LocationID == locationID
+}
+
+// hashAPIKey returns the stored form of a plain key.
+func hashAPIKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey issues a key for the HTTP API, scoped to locationID (nil = global), and returns the plain key
+// (shown once; only its hash is stored).
+func CreateAPIKey(ctx context.Context, name string, locationID *int64, createdBy int64) (string, *APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("kalit nomi kerak")
+	}
+	if locationID != nil {
+		l, err := GetLiveLocation(ctx, *locationID)
+		if err != nil {
+			return "", nil, err
+		}
+		if l == nil {
+			return "", nil, fmt.Errorf("filial #%d topilmadi", *locationID)
+		}
+	}
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, fmt.Errorf("generate api key: %w", err)
+	}
+	plain := apiKeyPrefix + hex.EncodeToString(raw)
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	k := APIKey{Name: name, LocationID: locationID, CreatedBy: createdBy}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO api_keys (name, key_hash, location_id, created_by) VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		name, hashAPIKey(plain), locationID, createdBy,
+	).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return "", nil, fmt.Errorf("create api key: %w", err)
+	}
+	if err := recordAudit(ctx, tx, AuditAPIKeyCreate, AuditTargetAPIKey, strconv.FormatInt(k.ID, 10), nil,
+		map[string]interface{}{"name": name, "location_id": locationID}); err != nil {
+		return "", nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", nil, err
+	}
+	return plain, &k, nil
+}
+
+// AuthenticateAPIKey returns the live key matching plain and marks it used, or nil if there is none (unknown or revoked).
+func AuthenticateAPIKey(ctx context.Context, plain string) (*APIKey, error) {
+	if !strings.HasPrefix(plain, apiKeyPrefix) {
+		return nil, nil
+	}
+	var k APIKey
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE api_keys SET last_used_at = now() WHERE key_hash = $1 AND revoked_at IS NULL
+			AND (location_id IS NULL OR EXISTS (SELECT 1 FROM locations l WHERE l.id = api_keys.location_id AND l.deleted_at IS NULL))
+		RETURNING id, name, location_id, COALESCE(created_by, 0), created_at, last_used_at, revoked_at`,
+		hashAPIKey(plain),
+	).Scan(&k.ID, &k.Name, &k.LocationID, &k.CreatedBy, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ListAPIKeys returns all keys, live ones first, newest first.
+func ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, name, location_id, COALESCE(created_by, 0), created_at, last_used_at, revoked_at
+		FROM api_keys ORDER BY revoked_at IS NOT NULL, id DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []APIKey
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(&k.ID, &k.Name, &k.LocationID, &k.CreatedBy, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, k)
+	}
+	return list, rows.Err()
+}
+
+// RevokeAPIKey disables a key for good.
+func RevokeAPIKey(ctx context.Context, id int64) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	var name string
+	err = tx.QueryRow(ctx, `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL RETURNING name`, id).Scan(&name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("kalit #%d topilmadi yoki allaqachon bekor qilingan", id)
+		}
+		return err
+	}
+	if err := recordAudit(ctx, tx, AuditAPIKeyRevoke, AuditTargetAPIKey, strconv.FormatInt(id, 10),
+		map[string]interface{}{"name": name}, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 
 	"food-telegram/db"
 	"github.com/jackc/pgx/v5"
@@ -115,40 +116,7 @@ func coalesceLang(s string) string {
 
 // GetApplicationByID returns application with type-specific details.
 func GetApplicationByID(ctx context.Context, id string) (*Application, *ApplicationRestaurantDetails, *ApplicationDriverDetails, error) {
-	var app Application
-	err := db.Pool.QueryRow(ctx, `
-		SELECT id::text, type, tg_user_id, chat_id, COALESCE(full_name,''), COALESCE(phone,''), language, status,
-		       reviewed_by, reviewed_at::text, reject_reason
-		FROM applications WHERE id = $1`,
-		id,
-	).Scan(&app.ID, &app.Type, &app.TgUserID, &app.ChatID, &app.FullName, &app.Phone, &app.Language, &app.Status,
-		&app.ReviewedBy, &app.ReviewedAt, &app.RejectReason)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil, nil, nil
-		}
-		return nil, nil, nil, err
-	}
-	var rest *ApplicationRestaurantDetails
-	var drv *ApplicationDriverDetails
-	if app.Type == ApplicationTypeRestaurantAdmin {
-		var r ApplicationRestaurantDetails
-		r.ApplicationID = id
-		err = db.Pool.QueryRow(ctx, `SELECT restaurant_name, lat, lon, address FROM application_restaurant_details WHERE application_id = $1`, id).
-			Scan(&r.RestaurantName, &r.Lat, &r.Lon, &r.Address)
-		if err == nil {
-			rest = &r
-		}
-	} else {
-		var d ApplicationDriverDetails
-		d.ApplicationID = id
-		err = db.Pool.QueryRow(ctx, `SELECT car_plate, car_model FROM application_driver_details WHERE application_id = $1`, id).
-			Scan(&d.CarPlate, &d.CarModel)
-		if err == nil {
-			drv = &d
-		}
-	}
-	return &app, rest, drv, nil
+	return defaultStore().Applications().Get(ctx, id)
 }
 
 // GetUserApplicationStatus returns status of the latest application for (tgUserID, type), or "" if none.
@@ -171,33 +139,29 @@ func ListPendingApplications(ctx context.Context, limit int) ([]Application, err
 	if limit <= 0 {
 		limit = 10
 	}
-	rows, err := db.Pool.Query(ctx, `
-		SELECT id::text, type, tg_user_id, chat_id, COALESCE(full_name,''), COALESCE(phone,''), language, status,
-		       reviewed_by, reviewed_at::text, reject_reason
-		FROM applications WHERE status = $1 ORDER BY created_at DESC LIMIT $2`,
-		ApplicationStatusPending, limit,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var list []Application
-	for rows.Next() {
-		var a Application
-		if err := rows.Scan(&a.ID, &a.Type, &a.TgUserID, &a.ChatID, &a.FullName, &a.Phone, &a.Language, &a.Status,
-			&a.ReviewedBy, &a.ReviewedAt, &a.RejectReason); err != nil {
-			return nil, err
-		}
-		list = append(list, a)
-	}
-	return list, rows.Err()
+	return defaultStore().Applications().ListPending(ctx, limit)
 }
 
+// ErrApplicationNotPending is returned when approving or rejecting an application that is missing or already reviewed.
+var ErrApplicationNotPending = errors.New("application not found or not pending")
+
 // ApproveApplication generates password, upserts user_credentials, for restaurant creates location+branch_admin, marks app approved, returns plain password.
+// Everything happens in one transaction: a failure leaves the application pending with nothing created, so it can simply be approved again,
+// and a repeated approval gets ErrApplicationNotPending.
 func ApproveApplication(ctx context.Context, applicationID string, superadminTgID int64) (plainPassword string, err error) {
-	app, rest, drv, err := GetApplicationByID(ctx, applicationID)
-	if err != nil || app == nil || app.Status != ApplicationStatusPending {
-		return "", fmt.Errorf("application not found or not pending")
+	return approveApplication(ctx, defaultStore(), applicationID, superadminTgID)
+}
+
+func approveApplication(ctx context.Context, st Store, applicationID string, superadminTgID int64) (plainPassword string, err error) {
+	app, rest, drv, err := st.Applications().Get(ctx, applicationID)
+	if err != nil {
+		return "", err
+	}
+	if app == nil || app.Status != ApplicationStatusPending {
+		return "", ErrApplicationNotPending
+	}
+	if app.Type == ApplicationTypeRestaurantAdmin && rest == nil {
+		return "", fmt.Errorf("restaurant details missing for application %s", applicationID)
 	}
 	plainPassword, err = GenerateSecurePassword()
 	if err != nil {
@@ -208,56 +172,62 @@ func ApproveApplication(ctx context.Context, applicationID string, superadminTgI
 		return "", fmt.Errorf("hash password: %w", err)
 	}
 
-	if app.Type == ApplicationTypeRestaurantAdmin && rest != nil {
-		var locID int64
-		err = db.Pool.QueryRow(ctx, `
-			INSERT INTO locations (name, lat, lon) VALUES ($1, $2, $3) RETURNING id`,
-			rest.RestaurantName, rest.Lat, rest.Lon,
-		).Scan(&locID)
+	err = st.InTx(ctx, func(tx Store) error {
+		// Claiming the application first locks its row: a concurrent or repeated approval finds it no longer pending.
+		ok, err := tx.Applications().Review(ctx, applicationID, ApplicationStatusApproved, superadminTgID, nil)
 		if err != nil {
-			return "", fmt.Errorf("create location: %w", err)
+			return fmt.Errorf("mark approved: %w", err)
 		}
-		err = AddBranchAdmin(ctx, locID, app.TgUserID, superadminTgID, string(hash), app.Language)
-		if err != nil {
-			return "", fmt.Errorf("add branch admin: %w", err)
+		if !ok {
+			return ErrApplicationNotPending
 		}
-	}
-	if app.Type == ApplicationTypeDriver && drv != nil {
-		_, err = RegisterDriver(ctx, app.TgUserID, app.ChatID)
-		if err != nil {
-			return "", fmt.Errorf("register driver: %w", err)
+		if app.Type == ApplicationTypeRestaurantAdmin {
+			locID, err := tx.Locations().Add(ctx, rest.RestaurantName, rest.Lat, rest.Lon)
+			if err != nil {
+				return fmt.Errorf("create location: %w", err)
+			}
+			if err := tx.Audit(ctx, AuditLocationCreate, AuditTargetLocation, strconv.FormatInt(locID, 10), nil,
+				map[string]interface{}{"name": rest.RestaurantName, "lat": rest.Lat, "lon": rest.Lon, "application_id": applicationID}); err != nil {
+				return err
+			}
+			orderLang := coalesceLang(app.Language)
+			if err := tx.Locations().AddOwner(ctx, locID, app.TgUserID, superadminTgID, orderLang); err != nil {
+				return fmt.Errorf("add branch admin: %w", err)
+			}
+			if err := tx.Credentials().SetBranchPassword(ctx, app.TgUserID, string(hash)); err != nil {
+				return fmt.Errorf("add branch admin: %w", err)
+			}
+			if err := tx.Audit(ctx, AuditBranchStaffAdd, AuditTargetBranchStaff, strconv.FormatInt(app.TgUserID, 10), nil,
+				map[string]interface{}{"location_id": locID, "role": BranchRoleOwner, "order_lang": orderLang}); err != nil {
+				return err
+			}
 		}
-		if drv.CarPlate != nil && *drv.CarPlate != "" {
-			driver, _ := GetDriverByTgUserID(ctx, app.TgUserID)
-			if driver != nil {
-				_ = UpdateDriverCar(ctx, driver.ID, *drv.CarPlate)
+		if app.Type == ApplicationTypeDriver {
+			driver, err := tx.Drivers().Register(ctx, app.TgUserID, app.ChatID)
+			if err != nil {
+				return err
+			}
+			if drv != nil && drv.CarPlate != nil && *drv.CarPlate != "" {
+				if err := tx.Drivers().SetCarPlate(ctx, driver.ID, *drv.CarPlate); err != nil {
+					return fmt.Errorf("set car plate: %w", err)
+				}
 			}
 		}
-	}
-
-	_, err = db.Pool.Exec(ctx, `
-		INSERT INTO user_credentials (tg_user_id, role, password_hash, is_active, updated_at)
-		VALUES ($1, $2, $3, true, now())
-		ON CONFLICT (tg_user_id) DO UPDATE SET password_hash = EXCLUDED.password_hash, role = EXCLUDED.role, is_active = true, updated_at = now()`,
-		app.TgUserID, app.Type, string(hash),
-	)
-	if err != nil {
-		return "", fmt.Errorf("upsert user_credentials: %w", err)
-	}
-
-	if app.Type == ApplicationTypeRestaurantAdmin {
-		if err := CreateSubscription(ctx, app.TgUserID, app.Type, 1); err != nil {
-			return "", fmt.Errorf("create subscription: %w", err)
+		if err := tx.Credentials().Upsert(ctx, app.TgUserID, app.Type, string(hash)); err != nil {
+			return fmt.Errorf("upsert user_credentials: %w", err)
 		}
-	}
-	// Drivers: no subscription
-
-	_, err = db.Pool.Exec(ctx, `
-		UPDATE applications SET status = $1, reviewed_by = $2, reviewed_at = now(), updated_at = now() WHERE id = $3`,
-		ApplicationStatusApproved, superadminTgID, applicationID,
-	)
+		// Drivers: no subscription
+		if app.Type == ApplicationTypeRestaurantAdmin {
+			if err := tx.Subscriptions().Start(ctx, app.TgUserID, app.Type, 1); err != nil {
+				return fmt.Errorf("create subscription: %w", err)
+			}
+		}
+		return tx.Audit(ctx, AuditApplicationApprove, AuditTargetApplication, applicationID,
+			map[string]interface{}{"status": ApplicationStatusPending},
+			map[string]interface{}{"status": ApplicationStatusApproved, "type": app.Type, "tg_user_id": app.TgUserID})
+	})
 	if err != nil {
-		return "", fmt.Errorf("mark approved: %w", err)
+		return "", err
 	}
 	return plainPassword, nil
 }
@@ -272,7 +242,8 @@ func AddDriverDirect(ctx context.Context, tgUserID int64) (plainPassword string,
 	if err != nil {
 		return "", fmt.Errorf("hash password: %w", err)
 	}
-	if _, err = RegisterDriver(ctx, tgUserID, 0); err != nil {
+	driver, err := RegisterDriver(ctx, tgUserID, 0)
+	if err != nil {
 		return "", fmt.Errorf("register driver: %w", err)
 	}
 	_, err = db.Pool.Exec(ctx, `
@@ -285,6 +256,7 @@ func AddDriverDirect(ctx context.Context, tgUserID int64) (plainPassword string,
 		return "", fmt.Errorf("upsert user_credentials: %w", err)
 	}
 	// Drivers: no subscription
+	_ = recordAudit(ctx, db.Pool, AuditDriverAdd, AuditTargetDriver, driver.ID, nil, map[string]interface{}{"tg_user_id": tgUserID})
 	return plainPassword, nil
 }
 
@@ -297,18 +269,22 @@ func ptrStr(s *string) string {
 
 // RejectApplication marks application rejected and sets reason.
 func RejectApplication(ctx context.Context, applicationID string, superadminTgID int64, reason string) error {
-	res, err := db.Pool.Exec(ctx, `
-		UPDATE applications SET status = $1, reviewed_by = $2, reviewed_at = now(), reject_reason = $3, reject_in_progress_by = NULL, updated_at = now()
-		WHERE id = $4 AND status = $5`,
-		ApplicationStatusRejected, superadminTgID, reason, applicationID, ApplicationStatusPending,
-	)
-	if err != nil {
-		return err
-	}
-	if res.RowsAffected() == 0 {
-		return fmt.Errorf("application not found or not pending")
-	}
-	return nil
+	return rejectApplication(ctx, defaultStore(), applicationID, superadminTgID, reason)
+}
+
+func rejectApplication(ctx context.Context, st Store, applicationID string, superadminTgID int64, reason string) error {
+	return st.InTx(ctx, func(tx Store) error {
+		ok, err := tx.Applications().Review(ctx, applicationID, ApplicationStatusRejected, superadminTgID, &reason)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrApplicationNotPending
+		}
+		return tx.Audit(ctx, AuditApplicationReject, AuditTargetApplication, applicationID,
+			map[string]interface{}{"status": ApplicationStatusPending},
+			map[string]interface{}{"status": ApplicationStatusRejected, "reason": reason})
+	})
 }
 
 // SetRejectInProgress sets reject_in_progress_by for the application so the next superadmin message is treated as reject reason (restart-safe). Only updates if status is pending. Returns true if a row was updated.
@@ -362,6 +338,5 @@ func MarkApprovedRestaurantAdminRejectedIfNoCredential(ctx context.Context, tgUs
 
 // UpdateDriverCar updates driver car_plate (drivers table has car_plate only).
 func UpdateDriverCar(ctx context.Context, driverID string, carPlate string) error {
-	_, err := db.Pool.Exec(ctx, `UPDATE drivers SET car_plate = $1, updated_at = now() WHERE id = $2`, carPlate, driverID)
-	return err
+	return defaultStore().Drivers().SetCarPlate(ctx, driverID, carPlate)
 }
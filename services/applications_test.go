@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func restaurantApplication(st *MemStore, tgUserID int64) string {
+	return st.PutApplication(Application{Type: ApplicationTypeRestaurantAdmin, TgUserID: tgUserID, ChatID: tgUserID, Language: "uz"},
+		&ApplicationRestaurantDetails{RestaurantName: "Oqtepa", Lat: 41.3, Lon: 69.2}, nil)
+}
+
+func TestApproveApplicationRestaurant(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemStore()
+	appID := restaurantApplication(st, 77)
+
+	pass, err := approveApplication(ctx, st, appID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pass == "" {
+		t.Error("no password")
+	}
+	locs, _ := st.Locations().List(ctx)
+	if len(locs) != 1 || locs[0].Name != "Oqtepa" {
+		t.Fatalf("locations = %+v", locs)
+	}
+	if owners, _ := st.Locations().OwnerIDs(ctx, locs[0].ID); len(owners) != 1 || owners[0] != 77 {
+		t.Errorf("owners = %v", owners)
+	}
+	if role, ok := st.Login(77); !ok || role != ApplicationTypeRestaurantAdmin {
+		t.Errorf("login = %q, %v", role, ok)
+	}
+	if sub, _ := st.Subscriptions().Get(ctx, 77, ApplicationTypeRestaurantAdmin); sub == nil || sub.IsEffectiveExpired() {
+		t.Errorf("subscription = %+v", sub)
+	}
+	app, _, _, _ := st.Applications().Get(ctx, appID)
+	if app.Status != ApplicationStatusApproved {
+		t.Errorf("status = %s", app.Status)
+	}
+
+	// A second tap on "approve" changes nothing
+	if _, err := approveApplication(ctx, st, appID, 1); !errors.Is(err, ErrApplicationNotPending) {
+		t.Errorf("second approve err = %v", err)
+	}
+	if locs, _ := st.Locations().List(ctx); len(locs) != 1 {
+		t.Errorf("second approve created a location: %+v", locs)
+	}
+}
+
+func TestApproveApplicationDriver(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemStore()
+	plate := "01A123BC"
+	appID := st.PutApplication(Application{Type: ApplicationTypeDriver, TgUserID: 88, ChatID: 880}, nil, &ApplicationDriverDetails{CarPlate: &plate})
+
+	if _, err := approveApplication(ctx, st, appID, 1); err != nil {
+		t.Fatal(err)
+	}
+	d, _ := st.Drivers().GetByTgUserID(ctx, 88)
+	if d == nil || d.ChatID != 880 || d.CarPlate != plate {
+		t.Fatalf("driver = %+v", d)
+	}
+	if sub, _ := st.Subscriptions().Get(ctx, 88, ApplicationTypeDriver); sub != nil {
+		t.Errorf("driver got a subscription: %+v", sub)
+	}
+}
+
+func TestApproveApplicationPartialFailure(t *testing.T) {
+	steps := []string{
+		"applications.review",
+		"locations.add",
+		"locations.addowner",
+		"credentials.setbranchpassword",
+		"credentials.upsert",
+		"subscriptions.start",
+		"audit",
+	}
+	for _, op := range steps {
+		t.Run(op, func(t *testing.T) {
+			ctx := context.Background()
+			st := NewMemStore()
+			appID := restaurantApplication(st, 77)
+			boom := errors.New("boom")
+			st.FailOn(op, boom)
+
+			if _, err := approveApplication(ctx, st, appID, 1); !errors.Is(err, boom) {
+				t.Fatalf("err = %v, want boom", err)
+			}
+			if locs, _ := st.Locations().List(ctx); len(locs) != 0 {
+				t.Errorf("orphan location: %+v", locs)
+			}
+			if _, ok := st.Login(77); ok {
+				t.Error("login left behind")
+			}
+			if sub, _ := st.Subscriptions().Get(ctx, 77, ApplicationTypeRestaurantAdmin); sub != nil {
+				t.Errorf("subscription left behind: %+v", sub)
+			}
+			if events := st.AuditEvents(); len(events) != 0 {
+				t.Errorf("audit left behind: %+v", events)
+			}
+			app, _, _, _ := st.Applications().Get(ctx, appID)
+			if app.Status != ApplicationStatusPending {
+				t.Fatalf("status = %s, want pending", app.Status)
+			}
+
+			// The retry goes through and creates exactly one branch
+			if _, err := approveApplication(ctx, st, appID, 1); err != nil {
+				t.Fatalf("retry: %v", err)
+			}
+			if locs, _ := st.Locations().List(ctx); len(locs) != 1 {
+				t.Errorf("locations after retry = %+v", locs)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Audit actor roles besides the branch staff roles (owner, manager, cashier).
+const (
+	AuditRoleSuperadmin = "superadmin"
+	AuditRoleSystem     = "system"
+	AuditRoleAPI        = "api" // HTTP API; the actor is the superadmin who issued the key
+)
+
+// Audit target types (audit_events.target_type).
+const (
+	AuditTargetMenuItem       = "menu_item"
+	AuditTargetLocation       = "location"
+	AuditTargetBranchStaff    = "branch_staff"
+	AuditTargetCredential     = "credential"
+	AuditTargetSubscription   = "subscription"
+	AuditTargetApplication    = "application"
+	AuditTargetDriver         = "driver"
+	AuditTargetCashHandover   = "cash_handover"
+	AuditTargetOrder          = "order"
+	AuditTargetAPIKey         = "api_key"
+	AuditTargetWebhook        = "webhook"
+	AuditTargetCommissionRule = "commission_rule" // target_id = location ID, 0 = platform-wide
+)
+
+// Audit actions (audit_events.action): "<target>.<verb>".
+const (
+	AuditMenuItemCreate           = "menu_item.create"
+	AuditMenuItemDelete           = "menu_item.delete"
+	AuditMenuItemRestore          = "menu_item.restore"
+	AuditMenuItemPurge            = "menu_item.purge"
+	AuditMenuItemUpdate           = "menu_item.update"
+	AuditLocationCreate           = "location.create"
+	AuditLocationDelete           = "location.delete"
+	AuditLocationRestore          = "location.restore"
+	AuditLocationPurge            = "location.purge"
+	AuditLocationHoursUpdate      = "location.hours_update"
+	AuditBranchStaffAdd           = "branch_staff.add"
+	AuditBranchStaffRemove        = "branch_staff.remove"
+	AuditBranchStaffShift         = "branch_staff.shift"
+	AuditCredentialPasswordReset  = "credential.password_reset"
+	AuditCredentialPasswordChange = "credential.password_change"
+	AuditCredentialTOTPEnable     = "credential.totp_enable"
+	AuditCredentialTOTPDisable    = "credential.totp_disable"
+	AuditSubscriptionRenew        = "subscription.renew"
+	AuditSubscriptionPause        = "subscription.pause"
+	AuditSubscriptionUnpause      = "subscription.unpause"
+	AuditApplicationApprove       = "application.approve"
+	AuditApplicationReject        = "application.reject"
+	AuditDriverAdd                = "driver.add"
+	AuditDriverVerifyApprove      = "driver.verification_approve"
+	AuditDriverVerifyReject       = "driver.verification_reject"
+	AuditDriverVerifyExpire       = "driver.verification_expire"
+	AuditDriverPayout             = "driver.payout"
+	AuditCashHandoverDecide       = "cash_handover.decide"
+	AuditOrderFeeOverride         = "order.fee_override"
+	AuditAPIKeyCreate             = "api_key.create"
+	AuditAPIKeyRevoke             = "api_key.revoke"
+	AuditWebhookCreate            = "webhook.create"
+	AuditWebhookDisable           = "webhook.disable"
+	AuditCommissionRuleSet        = "commission_rule.set"
+	AuditCommissionRuleDelete     = "commission_rule.delete"
+)
+
+type auditActorKey struct{}
+
+type auditActor struct {
+	TgUserID int64
+	Role     string
+}
+
+// WithAuditActor returns ctx attributing the audited service calls made with it to the user and role
+// (AuditRoleSuperadmin or a branch role). Without it, events are recorded as system actions.
+func WithAuditActor(ctx context.Context, tgUserID int64, role string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, auditActor{TgUserID: tgUserID, Role: role})
+}
+
+func auditActorFrom(ctx context.Context) (*int64, string) {
+	a, ok := ctx.Value(auditActorKey{}).(auditActor)
+	if !ok || a.TgUserID == 0 {
+		return nil, AuditRoleSystem
+	}
+	role := a.Role
+	if role == "" {
+		role = AuditRoleSystem
+	}
+	id := a.TgUserID
+	return &id, role
+}
+
+// auditExecer is db.Pool or a pgx.Tx, so events can be written in the same transaction as the change.
+type auditExecer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// recordAudit writes one audit_events row for the actor in ctx. before/after may be nil.
+// Functions that hand out a new password ignore a failed write here so the already-applied password is not lost.
+func recordAudit(ctx context.Context, q auditExecer, action, targetType, targetID string, before, after map[string]interface{}) error {
+	beforeJSON, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+	actorID, role := auditActorFrom(ctx)
+	if _, err := q.Exec(ctx, `
+		INSERT INTO audit_events (actor_tg_user_id, actor_role, action, target_type, target_id, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7::jsonb)`,
+		actorID, role, action, targetType, targetID, beforeJSON, afterJSON,
+	); err != nil {
+		return fmt.Errorf("record audit %s: %w", action, err)
+	}
+	return nil
+}
+
+func auditJSON(m map[string]interface{}) (*string, error) {
+	if m == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("marshal audit: %w", err)
+	}
+	s := string(b)
+	return &s, nil
+}
+
+// AuditEvent is one audit_events row.
+type AuditEvent struct {
+	ID         int64
+	ActorID    int64 // 0 = system
+	ActorRole  string
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     string // JSON or ""
+	After      string // JSON or ""
+	CreatedAt  time.Time
+}
+
+// AuditFilter narrows ListAuditEvents: by actor, by target (type and optionally id), or neither for the latest events.
+type AuditFilter struct {
+	ActorID    int64
+	TargetType string
+	TargetID   string
+	Limit      int
+}
+
+const (
+	auditDefaultLimit = 20
+	auditMaxLimit     = 100
+)
+
+// ParseAuditQuery parses the superadmin /audit arguments:
+// "" (latest), "actor <tg_user_id>", "<target_type> [target_id]", each optionally followed by a limit.
+func ParseAuditQuery(args string) (AuditFilter, error) {
+	f := AuditFilter{Limit: auditDefaultLimit}
+	parts := strings.Fields(args)
+	// A trailing number is the limit only where it cannot be an id: "/audit 50", "/audit actor 1 50", "/audit location 5 50".
+	if n := len(parts); n == 1 || n == 3 {
+		if limit, err := strconv.Atoi(parts[n-1]); err == nil {
+			if limit <= 0 || limit > auditMaxLimit {
+				return f, fmt.Errorf("limit 1..%d oralig'ida bo'lishi kerak", auditMaxLimit)
+			}
+			f.Limit = limit
+			parts = parts[:n-1]
+		}
+	}
+	switch {
+	case len(parts) == 0:
+	case parts[0] == "actor":
+		if len(parts) != 2 {
+			return f, fmt.Errorf("actor uchun tg_user_id kerak")
+		}
+		id, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || id <= 0 {
+			return f, fmt.Errorf("tg_user_id musbat raqam bo'lishi kerak")
+		}
+		f.ActorID = id
+	case len(parts) <= 2:
+		if !isAuditTargetType(parts[0]) {
+			return f, fmt.Errorf("noma'lum obyekt turi: %s", parts[0])
+		}
+		f.TargetType = parts[0]
+		if len(parts) == 2 {
+			f.TargetID = parts[1]
+		}
+	default:
+		return f, fmt.Errorf("argumentlar juda ko'p")
+	}
+	return f, nil
+}
+
+func isAuditTargetType(t string) bool {
+	switch t {
+	case AuditTargetMenuItem, AuditTargetLocation, AuditTargetBranchStaff, AuditTargetCredential, AuditTargetSubscription,
+		AuditTargetApplication, AuditTargetDriver, AuditTargetCashHandover, AuditTargetOrder, AuditTargetAPIKey,
+		AuditTargetWebhook, AuditTargetCommissionRule:
+		return true
+	}
+	return false
+}
+
+// ListAuditEvents returns the newest events matching the filter.
+func ListAuditEvents(ctx context.Context, f AuditFilter) ([]AuditEvent, error) {
+	if f.Limit <= 0 || f.Limit > auditMaxLimit {
+		f.Limit = auditDefaultLimit
+	}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, COALESCE(actor_tg_user_id, 0), actor_role, action, target_type, target_id,
+			COALESCE(before::text, ''), COALESCE(after::text, ''), created_at
+		FROM audit_events
+		WHERE ($1::bigint = 0 OR actor_tg_user_id = $1)
+			AND ($2 = '' OR target_type = $2)
+			AND ($3 = '' OR target_id = $3)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4`,
+		f.ActorID, f.TargetType, f.TargetID, f.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list audit events: %w", err)
+	}
+	defer rows.Close()
+	var list []AuditEvent
+	for rows.Next() {
+		var e AuditEvent
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorRole, &e.Action, &e.TargetType, &e.TargetID, &e.Before, &e.After, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// auditJSONMaxLen caps each before/after snippet in FormatAuditEvent so a page of events fits one Telegram message.
+const auditJSONMaxLen = 200
+
+// FormatAuditEvent returns one event as plain text for the superadmin /audit listing.
+func FormatAuditEvent(e AuditEvent) string {
+	actor := e.ActorRole
+	if e.ActorID != 0 {
+		actor = fmt.Sprintf("%s %d", e.ActorRole, e.ActorID)
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("#%d %s — %s: %s %s#%s", e.ID, e.CreatedAt.Local().Format("02.01 15:04"), actor, e.Action, e.TargetType, e.TargetID))
+	if e.Before != "" {
+		b.WriteString("\n  oldin: " + truncateAuditJSON(e.Before))
+	}
+	if e.After != "" {
+		b.WriteString("\n  keyin: " + truncateAuditJSON(e.After))
+	}
+	return b.String()
+}
+
+func truncateAuditJSON(s string) string {
+	r := []rune(s)
+	if len(r) <= auditJSONMaxLen {
+		return s
+	}
+	return string(r[:auditJSONMaxLen]) + "…"
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAuditQuery(t *testing.T) {
+	tests := []struct {
+		args    string
+		want    AuditFilter
+		wantErr bool
+	}{
+		{"", AuditFilter{Limit: 20}, false},
+		{"50", AuditFilter{Limit: 50}, false},
+		{"actor 123", AuditFilter{ActorID: 123, Limit: 20}, false},
+		{"actor 123 5", AuditFilter{ActorID: 123, Limit: 5}, false},
+		{"location", AuditFilter{TargetType: AuditTargetLocation, Limit: 20}, false},
+		{"location 7", AuditFilter{TargetType: AuditTargetLocation, TargetID: "7", Limit: 20}, false},
+		{"location 7 10", AuditFilter{TargetType: AuditTargetLocation, TargetID: "7", Limit: 10}, false},
+		{"subscription 42:restaurant_admin", AuditFilter{TargetType: AuditTargetSubscription, TargetID: "42:restaurant_admin", Limit: 20}, false},
+		{"actor", AuditFilter{}, true},
+		{"actor abc", AuditFilter{}, true},
+		{"actor -5", AuditFilter{}, true},
+		{"0", AuditFilter{}, true},
+		{"500", AuditFilter{}, true},
+		{"pizza 1", AuditFilter{}, true},
+		{"location 1 2 3", AuditFilter{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			got, err := ParseAuditQuery(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAuditQuery(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseAuditQuery(%q) = %+v, want %+v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatAuditEvent(t *testing.T) {
+	e := AuditEvent{
+		ID: 9, ActorID: 77, ActorRole: BranchRoleOwner, Action: AuditMenuItemDelete,
+		TargetType: AuditTargetMenuItem, TargetID: "5", Before: `{"name":"Lavash"}`,
+		CreatedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local),
+	}
+	got := FormatAuditEvent(e)
+	want := "#9 01.03 12:00 — owner 77: menu_item.delete menu_item#5\n  oldin: {\"name\":\"Lavash\"}"
+	if got != want {
+		t.Errorf("FormatAuditEvent() = %q, want %q", got, want)
+	}
+
+	e = AuditEvent{ID: 1, ActorRole: AuditRoleSystem, Action: AuditLocationDelete, TargetType: AuditTargetLocation, TargetID: "3",
+		After: "{\"x\":\"" + strings.Repeat("a", 300) + "\"}", CreatedAt: e.CreatedAt}
+	got = FormatAuditEvent(e)
+	if !strings.Contains(got, "— system: ") {
+		t.Errorf("system actor not shown: %q", got)
+	}
+	if !strings.HasSuffix(got, "…") {
+		t.Errorf("long JSON not truncated: %q", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 
 	"food-telegram/db"
 	"golang.org/x/crypto/bcrypt"
@@ -27,7 +28,9 @@ func EnsureBranchAdminsTable(ctx context.Context) error {
 				promoted_by BIGINT NOT NULL,
 				promoted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
 				password_hash TEXT,
-				UNIQUE(branch_location_id)
+				role TEXT NOT NULL DEFAULT 'owner',
+				on_shift BOOLEAN NOT NULL DEFAULT true,
+				UNIQUE(branch_location_id, admin_user_id)
 			);
 
 			CREATE INDEX IF NOT EXISTS idx_branch_admins_location ON branch_admins(branch_location_id);
@@ -39,6 +42,9 @@ func EnsureBranchAdminsTable(ctx context.Context) error {
 	}
 	// Add order_lang column if missing (admin receives order cards in this language: uz or ru)
 	_, _ = db.Pool.Exec(ctx, `ALTER TABLE branch_admins ADD COLUMN IF NOT EXISTS order_lang VARCHAR(2) NOT NULL DEFAULT 'uz'`)
+	// Staff roles and shift flag (migration 033)
+	_, _ = db.Pool.Exec(ctx, `ALTER TABLE branch_admins ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'owner'`)
+	_, _ = db.Pool.Exec(ctx, `ALTER TABLE branch_admins ADD COLUMN IF NOT EXISTS on_shift BOOLEAN NOT NULL DEFAULT true`)
 	return nil
 }
 
@@ -54,68 +60,47 @@ func HashBranchAdminPassword(plain string) (string, error) {
 	return string(hash), nil
 }
 
-// IsBranchAdminPasswordUnique returns true if no branch admin row has this password hash (ensures unique passwords).
-// excludeLocationID: when > 0, ignore the row for this location (used when replacing that location's admin).
-func IsBranchAdminPasswordUnique(ctx context.Context, passwordHash string, excludeLocationID int64) (bool, error) {
-	if err := EnsureBranchAdminsTable(ctx); err != nil {
-		return false, err
-	}
-	var count int
-	if excludeLocationID > 0 {
-		err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM branch_admins WHERE password_hash = $1 AND branch_location_id != $2`, passwordHash, excludeLocationID).Scan(&count)
-		if err != nil {
-			return false, fmt.Errorf("check password uniqueness: %w", err)
-		}
-	} else {
-		err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM branch_admins WHERE password_hash = $1`, passwordHash).Scan(&count)
-		if err != nil {
-			return false, fmt.Errorf("check password uniqueness: %w", err)
-		}
-	}
-	return count == 0, nil
-}
-
-// AuthenticateBranchAdmin checks plainPassword against any branch's password; on match records this tg_user_id as having access and logs the login. Multiple users can log in with the same restaurant password.
+// AuthenticateBranchAdmin checks plainPassword against the user's own credential (branch_admin_credentials) and returns
+// the location they are staff of (owner row first). It does not record the login; call RecordBranchAdminLogin once
+// any second factor has passed.
 func AuthenticateBranchAdmin(ctx context.Context, userID int64, plainPassword string) (branchLocationID int64, ok bool, err error) {
 	if err := EnsureBranchAdminsTable(ctx); err != nil {
 		return 0, false, err
 	}
-	rows, err := db.Pool.Query(ctx, `
-		SELECT branch_location_id, password_hash FROM branch_admins WHERE password_hash IS NOT NULL`,
-	)
-	if err != nil {
-		return 0, false, fmt.Errorf("query branch admins: %w", err)
+	ok, err = VerifyBranchAdminPassword(ctx, userID, plainPassword)
+	if err != nil || !ok {
+		return 0, false, err
 	}
-	defer rows.Close()
-	var matchedLocID int64
-	for rows.Next() {
-		var locID int64
-		var hash string
-		if err := rows.Scan(&locID, &hash); err != nil {
-			return 0, false, err
-		}
-		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(plainPassword)) == nil {
-			matchedLocID = locID
-			break
+	err = db.Pool.QueryRow(ctx, `
+		SELECT ba.branch_location_id FROM branch_admins ba JOIN locations l ON l.id = ba.branch_location_id
+		WHERE ba.admin_user_id = $1 AND l.deleted_at IS NULL
+		ORDER BY array_position(ARRAY['owner', 'manager', 'cashier'], ba.role), ba.promoted_at LIMIT 1`,
+		userID,
+	).Scan(&branchLocationID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
 		}
+		return 0, false, fmt.Errorf("query branch admin: %w", err)
 	}
-	if matchedLocID == 0 {
-		return 0, false, rows.Err()
+	return branchLocationID, true, nil
+}
+
+// RecordBranchAdminLogin records this tg_user_id as having access to the branch (with their staff role).
+// The login event itself is logged by StartAdminSession.
+func RecordBranchAdminLogin(ctx context.Context, userID, branchLocationID int64) error {
+	if _, err := db.Pool.Exec(ctx, `
+		INSERT INTO branch_admin_access (tg_user_id, branch_location_id, role, logged_in_at)
+		SELECT $1, $2, role, now() FROM branch_admins WHERE branch_location_id = $2 AND admin_user_id = $1
+		ON CONFLICT (tg_user_id) DO UPDATE SET branch_location_id = EXCLUDED.branch_location_id, role = EXCLUDED.role, logged_in_at = now()`,
+		userID, branchLocationID,
+	); err != nil {
+		return fmt.Errorf("record branch admin access: %w", err)
 	}
-	// Record session and audit
-	_, _ = db.Pool.Exec(ctx, `
-		INSERT INTO branch_admin_access (tg_user_id, branch_location_id, logged_in_at)
-		VALUES ($1, $2, now())
-		ON CONFLICT (tg_user_id) DO UPDATE SET branch_location_id = $2, logged_in_at = now()`,
-		userID, matchedLocID,
-	)
-	_, _ = db.Pool.Exec(ctx, `INSERT INTO admin_logins (tg_user_id, branch_location_id, logged_in_at) VALUES ($1, $2, now())`,
-		userID, matchedLocID,
-	)
-	return matchedLocID, true, nil
+	return nil
 }
 
-// AddBranchAdmin adds a new admin for a specific branch with a unique password (hash).
+// AddBranchAdmin adds the owner of a specific branch and sets their password (hash) in branch_admin_credentials.
 // orderLang is the language in which this admin receives order cards: "uz" or "ru" (default "uz" if empty).
 func AddBranchAdmin(ctx context.Context, branchLocationID int64, adminUserID int64, promotedBy int64, passwordHash string, orderLang string) error {
 	if err := EnsureBranchAdminsTable(ctx); err != nil {
@@ -131,7 +116,7 @@ func AddBranchAdmin(ctx context.Context, branchLocationID int64, adminUserID int
 	// Validate that the branch location exists
 	var exists bool
 	err := db.Pool.QueryRow(ctx, `
-		SELECT EXISTS(SELECT 1 FROM locations WHERE id = $1)`,
+		SELECT EXISTS(SELECT 1 FROM locations WHERE id = $1 AND deleted_at IS NULL)`,
 		branchLocationID,
 	).Scan(&exists)
 	if err != nil {
@@ -141,15 +126,6 @@ func AddBranchAdmin(ctx context.Context, branchLocationID int64, adminUserID int
 		return fmt.Errorf("branch location with ID %d does not exist", branchLocationID)
 	}
 
-	// Ensure password is unique (allow re-use when replacing this location's admin)
-	unique, err := IsBranchAdminPasswordUnique(ctx, passwordHash, branchLocationID)
-	if err != nil {
-		return err
-	}
-	if !unique {
-		return fmt.Errorf("this password is already used by another branch admin; choose a unique password")
-	}
-
 	// Read branch name for audit/debug
 	var branchName string
 	if err := db.Pool.QueryRow(ctx, `SELECT name FROM locations WHERE id = $1`, branchLocationID).Scan(&branchName); err != nil {
@@ -157,21 +133,24 @@ func AddBranchAdmin(ctx context.Context, branchLocationID int64, adminUserID int
 	}
 
 	_, err = db.Pool.Exec(ctx, `
-		INSERT INTO branch_admins (branch_location_id, branch_name, admin_user_id, promoted_by, password_hash, order_lang)
+		INSERT INTO branch_admins (branch_location_id, branch_name, admin_user_id, promoted_by, order_lang, role)
 		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (branch_location_id) DO UPDATE SET
+		ON CONFLICT (branch_location_id, admin_user_id) DO UPDATE SET
 			branch_name = EXCLUDED.branch_name,
-			admin_user_id = EXCLUDED.admin_user_id,
 			promoted_by = EXCLUDED.promoted_by,
-			password_hash = EXCLUDED.password_hash,
 			order_lang = EXCLUDED.order_lang,
+			role = EXCLUDED.role,
 			promoted_at = now()`,
-		branchLocationID, branchName, adminUserID, promotedBy, passwordHash, orderLang,
+		branchLocationID, branchName, adminUserID, promotedBy, orderLang, BranchRoleOwner,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to add branch admin (location_id=%d, admin_user_id=%d): %w", branchLocationID, adminUserID, err)
 	}
-	return nil
+	if err := SetBranchAdminPassword(ctx, adminUserID, passwordHash); err != nil {
+		return err
+	}
+	after := map[string]interface{}{"location_id": branchLocationID, "role": BranchRoleOwner, "order_lang": orderLang}
+	return recordAudit(ctx, db.Pool, AuditBranchStaffAdd, AuditTargetBranchStaff, strconv.FormatInt(adminUserID, 10), nil, after)
 }
 
 // GetAdminLocationID returns the location (restaurant) ID for which the user is acting as admin, or 0 if none.
@@ -198,14 +177,15 @@ func GetAdminLocationID(ctx context.Context, adminUserID int64) (int64, error) {
 	return locID, nil
 }
 
-// GetBranchAdmins returns all admin user IDs for a specific branch (primary only from branch_admins).
+// GetBranchAdmins returns all staff user IDs for a specific branch from branch_admins (owner first).
 func GetBranchAdmins(ctx context.Context, branchLocationID int64) ([]int64, error) {
 	if err := EnsureBranchAdminsTable(ctx); err != nil {
 		return nil, err
 	}
 
 	rows, err := db.Pool.Query(ctx, `
-		SELECT admin_user_id FROM branch_admins WHERE branch_location_id = $1`,
+		SELECT admin_user_id FROM branch_admins WHERE branch_location_id = $1
+		ORDER BY array_position(ARRAY['owner', 'manager', 'cashier'], role), promoted_at`,
 		branchLocationID,
 	)
 	if err != nil {
@@ -224,13 +204,13 @@ func GetBranchAdmins(ctx context.Context, branchLocationID int64) ([]int64, erro
 	return adminIDs, rows.Err()
 }
 
-// GetPrimaryAdminUserID returns the primary branch admin's tg_user_id for the branch (subscription holder). Returns 0 if not found.
+// GetPrimaryAdminUserID returns the branch owner's tg_user_id (subscription holder). Returns 0 if not found.
 func GetPrimaryAdminUserID(ctx context.Context, branchLocationID int64) (int64, error) {
 	if err := EnsureBranchAdminsTable(ctx); err != nil {
 		return 0, err
 	}
 	var id int64
-	err := db.Pool.QueryRow(ctx, `SELECT admin_user_id FROM branch_admins WHERE branch_location_id = $1`, branchLocationID).Scan(&id)
+	err := db.Pool.QueryRow(ctx, `SELECT admin_user_id FROM branch_admins WHERE branch_location_id = $1 AND role = 'owner' ORDER BY promoted_at LIMIT 1`, branchLocationID).Scan(&id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return 0, nil
@@ -255,13 +235,14 @@ type BranchAdminWithLang struct {
 	OrderLang   string // "uz" or "ru"
 }
 
-// GetBranchAdminsWithLang returns all admins for a branch with their order_lang: primary from branch_admins plus anyone in branch_admin_access for this branch (multi-user same password). Access users get primary's order_lang.
+// GetBranchAdminsWithLang returns the on-shift staff of a branch with their own order_lang, plus anyone in branch_admin_access for this branch
+// who has no staff row (legacy sessions). Access users get the owner's order_lang. Off-shift staff are skipped.
 func GetBranchAdminsWithLang(ctx context.Context, branchLocationID int64) ([]BranchAdminWithLang, error) {
 	if err := EnsureBranchAdminsTable(ctx); err != nil {
 		return nil, err
 	}
 	var primaryLang string
-	err := db.Pool.QueryRow(ctx, `SELECT COALESCE(NULLIF(TRIM(order_lang), ''), 'uz') FROM branch_admins WHERE branch_location_id = $1`, branchLocationID).Scan(&primaryLang)
+	err := db.Pool.QueryRow(ctx, `SELECT COALESCE(NULLIF(TRIM(order_lang), ''), 'uz') FROM branch_admins WHERE branch_location_id = $1 AND role = 'owner' ORDER BY promoted_at LIMIT 1`, branchLocationID).Scan(&primaryLang)
 	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		return nil, fmt.Errorf("failed to get primary lang: %w", err)
 	}
@@ -271,7 +252,9 @@ func GetBranchAdminsWithLang(ctx context.Context, branchLocationID int64) ([]Bra
 	seen := make(map[int64]bool)
 	var out []BranchAdminWithLang
 	rows, err := db.Pool.Query(ctx, `
-		SELECT admin_user_id FROM branch_admins WHERE branch_location_id = $1`,
+		SELECT admin_user_id, COALESCE(NULLIF(TRIM(order_lang), ''), 'uz') FROM branch_admins
+		WHERE branch_location_id = $1 AND on_shift = true
+		ORDER BY array_position(ARRAY['owner', 'manager', 'cashier'], role), promoted_at`,
 		branchLocationID,
 	)
 	if err != nil {
@@ -279,18 +262,24 @@ func GetBranchAdminsWithLang(ctx context.Context, branchLocationID int64) ([]Bra
 	}
 	for rows.Next() {
 		var id int64
-		if err := rows.Scan(&id); err != nil {
+		var orderLang string
+		if err := rows.Scan(&id, &orderLang); err != nil {
 			rows.Close()
 			return nil, err
 		}
+		if orderLang != "ru" {
+			orderLang = "uz"
+		}
 		if !seen[id] {
 			seen[id] = true
-			out = append(out, BranchAdminWithLang{AdminUserID: id, OrderLang: primaryLang})
+			out = append(out, BranchAdminWithLang{AdminUserID: id, OrderLang: orderLang})
 		}
 	}
 	rows.Close()
 	rows2, err := db.Pool.Query(ctx, `
-		SELECT tg_user_id FROM branch_admin_access WHERE branch_location_id = $1`,
+		SELECT a.tg_user_id FROM branch_admin_access a
+		WHERE a.branch_location_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM branch_admins ba WHERE ba.branch_location_id = a.branch_location_id AND ba.admin_user_id = a.tg_user_id)`,
 		branchLocationID,
 	)
 	if err != nil {
@@ -311,7 +300,7 @@ func GetBranchAdminsWithLang(ctx context.Context, branchLocationID int64) ([]Bra
 	return out, rows2.Err()
 }
 
-// GetAdminOrderLang returns the order card language for an admin user ("uz" or "ru"). Checks branch_admins first, then branch_admin_access (uses branch's primary lang). Returns "uz" if not found.
+// GetAdminOrderLang returns the order card language for an admin user ("uz" or "ru"). Checks branch_admins first, then branch_admin_access (uses the branch owner's lang). Returns "uz" if not found.
 func GetAdminOrderLang(ctx context.Context, adminUserID int64) (string, error) {
 	if err := EnsureBranchAdminsTable(ctx); err != nil {
 		return "uz", err
@@ -328,7 +317,7 @@ func GetAdminOrderLang(ctx context.Context, adminUserID int64) (string, error) {
 		return "uz", err
 	}
 	// Access-only user: get branch and use that branch's order_lang
-	err = db.Pool.QueryRow(ctx, `SELECT COALESCE(NULLIF(TRIM(ba.order_lang), ''), 'uz') FROM branch_admin_access a JOIN branch_admins ba ON ba.branch_location_id = a.branch_location_id WHERE a.tg_user_id = $1 LIMIT 1`, adminUserID).Scan(&orderLang)
+	err = db.Pool.QueryRow(ctx, `SELECT COALESCE(NULLIF(TRIM(ba.order_lang), ''), 'uz') FROM branch_admin_access a JOIN branch_admins ba ON ba.branch_location_id = a.branch_location_id AND ba.role = 'owner' WHERE a.tg_user_id = $1 LIMIT 1`, adminUserID).Scan(&orderLang)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return "uz", nil
@@ -347,18 +336,21 @@ func RemoveBranchAdmin(ctx context.Context, branchLocationID int64, adminUserID
 		return err
 	}
 
-	result, err := db.Pool.Exec(ctx, `
+	var role string
+	err := db.Pool.QueryRow(ctx, `
 		DELETE FROM branch_admins 
-		WHERE branch_location_id = $1 AND admin_user_id = $2`,
+		WHERE branch_location_id = $1 AND admin_user_id = $2
+		RETURNING role`,
 		branchLocationID, adminUserID,
-	)
+	).Scan(&role)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("branch admin not found")
+		}
 		return fmt.Errorf("failed to remove branch admin: %w", err)
 	}
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("branch admin not found")
-	}
-	return nil
+	before := map[string]interface{}{"location_id": branchLocationID, "role": role}
+	return recordAudit(ctx, db.Pool, AuditBranchStaffRemove, AuditTargetBranchStaff, strconv.FormatInt(adminUserID, 10), before, nil)
 }
 
 // RemoveAllBranchAdminsForLocation removes all branch admins for a location (e.g. before changing admin).
@@ -366,11 +358,7 @@ func RemoveAllBranchAdminsForLocation(ctx context.Context, branchLocationID int6
 	if err := EnsureBranchAdminsTable(ctx); err != nil {
 		return err
 	}
-	_, err := db.Pool.Exec(ctx, `DELETE FROM branch_admins WHERE branch_location_id = $1`, branchLocationID)
-	if err != nil {
-		return fmt.Errorf("failed to remove branch admins for location %d: %w", branchLocationID, err)
-	}
-	return nil
+	return removeBranchStaffWhere(ctx, branchLocationID, "")
 }
 
 // ListBranchAdmins returns all admins for a branch with their promotion info
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// branchAdminMinPasswordLen is the minimum length of a self-chosen branch admin password.
+	branchAdminMinPasswordLen = 8
+	// totpPeriod is the TOTP time step (RFC 6238 default).
+	totpPeriod = 30
+	// totpSkewSteps is how many steps before/after now a code is still accepted (clock drift).
+	totpSkewSteps = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ValidateNewPassword checks a self-chosen password: at least 8 characters with a letter and a digit.
+func ValidateNewPassword(plain string) error {
+	if len([]rune(plain)) < branchAdminMinPasswordLen {
+		return fmt.Errorf("parol kamida %d belgidan iborat bo'lishi kerak", branchAdminMinPasswordLen)
+	}
+	var letter, digit bool
+	for _, r := range plain {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsSpace(r):
+			return fmt.Errorf("parolda bo'sh joy bo'lmasligi kerak")
+		}
+	}
+	if !letter || !digit {
+		return fmt.Errorf("parolda kamida bitta harf va bitta raqam bo'lishi kerak")
+	}
+	return nil
+}
+
+// SetBranchAdminPassword stores the branch admin's password hash (branch_admin_credentials) keyed by Telegram user id.
+// An owner's approved-applicant credential (user_credentials) is kept in sync so both logins accept the same password.
+func SetBranchAdminPassword(ctx context.Context, tgUserID int64, passwordHash string) error {
+	if passwordHash == "" {
+		return fmt.Errorf("password is required for branch admin")
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err := setBranchAdminPasswordTx(ctx, tx, tgUserID, passwordHash); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func setBranchAdminPasswordTx(ctx context.Context, tx auditExecer, tgUserID int64, passwordHash string) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO branch_admin_credentials (tg_user_id, password_hash, password_changed_at, updated_at)
+		VALUES ($1, $2, now(), now())
+		ON CONFLICT (tg_user_id) DO UPDATE SET password_hash = EXCLUDED.password_hash, password_changed_at = now(), updated_at = now()`,
+		tgUserID, passwordHash,
+	); err != nil {
+		return fmt.Errorf("upsert branch admin credential: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE user_credentials SET password_hash = $1, updated_at = now() WHERE tg_user_id = $2 AND role = $3`,
+		passwordHash, tgUserID, UserRoleRestaurantAdmin,
+	); err != nil {
+		return fmt.Errorf("sync user credential: %w", err)
+	}
+	return nil
+}
+
+// VerifyBranchAdminPassword checks plainPassword against the user's own branch admin credential.
+func VerifyBranchAdminPassword(ctx context.Context, tgUserID int64, plainPassword string) (bool, error) {
+	var hash string
+	err := db.Pool.QueryRow(ctx, `SELECT password_hash FROM branch_admin_credentials WHERE tg_user_id = $1`, tgUserID).Scan(&hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plainPassword)) == nil, nil
+}
+
+// ChangeBranchAdminPassword replaces the user's password after checking the current one.
+func ChangeBranchAdminPassword(ctx context.Context, tgUserID int64, currentPlain, newPlain string) error {
+	ok, err := VerifyBranchAdminPassword(ctx, tgUserID, currentPlain)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("joriy parol noto'g'ri")
+	}
+	if err := ValidateNewPassword(newPlain); err != nil {
+		return err
+	}
+	if currentPlain == newPlain {
+		return fmt.Errorf("yangi parol eskisidan farq qilishi kerak")
+	}
+	hash, err := HashBranchAdminPassword(newPlain)
+	if err != nil {
+		return err
+	}
+	if err := SetBranchAdminPassword(ctx, tgUserID, hash); err != nil {
+		return err
+	}
+	return recordAudit(ctx, db.Pool, AuditCredentialPasswordChange, AuditTargetCredential, strconv.FormatInt(tgUserID, 10), nil, nil)
+}
+
+// GenerateTOTPSecret returns a random base32 (no padding) secret for an authenticator app.
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("totp secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPProvisioningURI returns the otpauth:// URI authenticator apps accept (as text or QR code).
+func TOTPProvisioningURI(secret, issuer, account string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// totpCodeAt returns the 6-digit RFC 6238 (HMAC-SHA1) code for the time step.
+func totpCodeAt(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimSpace(secret)))
+	if err != nil {
+		return "", fmt.Errorf("totp secret: %w", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", bin%1000000), nil
+}
+
+// TOTPCode returns the current 6-digit code for secret at t.
+func TOTPCode(secret string, t time.Time) (string, error) {
+	return totpCodeAt(secret, t.Unix()/totpPeriod)
+}
+
+// MatchTOTP returns the time step the code belongs to if it is valid at now (± one step for clock drift).
+func MatchTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != 6 {
+		return 0, false
+	}
+	cur := now.Unix() / totpPeriod
+	for d := int64(-totpSkewSteps); d <= totpSkewSteps; d++ {
+		want, err := totpCodeAt(secret, cur+d)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(want), []byte(code)) {
+			return cur + d, true
+		}
+	}
+	return 0, false
+}
+
+// BranchAdminTOTPEnabled reports whether the user must enter an authenticator code after the password.
+func BranchAdminTOTPEnabled(ctx context.Context, tgUserID int64) (bool, error) {
+	var enabled bool
+	err := db.Pool.QueryRow(ctx, `SELECT totp_enabled FROM branch_admin_credentials WHERE tg_user_id = $1`, tgUserID).Scan(&enabled)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return enabled, nil
+}
+
+// BeginTOTPEnrollment stores a new (not yet enabled) TOTP secret for the user and returns it.
+func BeginTOTPEnrollment(ctx context.Context, tgUserID int64) (string, error) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	res, err := db.Pool.Exec(ctx, `
+		UPDATE branch_admin_credentials SET totp_secret = $2, totp_last_step = 0, updated_at = now()
+		WHERE tg_user_id = $1 AND totp_enabled = false`,
+		tgUserID, secret,
+	)
+	if err != nil {
+		return "", fmt.Errorf("begin totp: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return "", fmt.Errorf("2FA allaqachon yoqilgan yoki parol o'rnatilmagan")
+	}
+	return secret, nil
+}
+
+// ConfirmTOTPEnrollment enables TOTP once the user proves their app produces a valid code for the pending secret.
+func ConfirmTOTPEnrollment(ctx context.Context, tgUserID int64, code string) error {
+	var secret *string
+	err := db.Pool.QueryRow(ctx, `SELECT totp_secret FROM branch_admin_credentials WHERE tg_user_id = $1 AND totp_enabled = false`, tgUserID).Scan(&secret)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if secret == nil || *secret == "" {
+		return fmt.Errorf("avval /2fa_on buyrug'ini yuboring")
+	}
+	step, ok := MatchTOTP(*secret, code, time.Now())
+	if !ok {
+		return fmt.Errorf("kod noto'g'ri")
+	}
+	_, err = db.Pool.Exec(ctx, `
+		UPDATE branch_admin_credentials SET totp_enabled = true, totp_last_step = $2, updated_at = now() WHERE tg_user_id = $1`,
+		tgUserID, step,
+	)
+	if err != nil {
+		return err
+	}
+	return recordAudit(ctx, db.Pool, AuditCredentialTOTPEnable, AuditTargetCredential, strconv.FormatInt(tgUserID, 10),
+		map[string]interface{}{"totp_enabled": false}, map[string]interface{}{"totp_enabled": true})
+}
+
+// VerifyBranchAdminTOTP checks a login code. Each code is accepted once (steps at or before the last used one are rejected).
+func VerifyBranchAdminTOTP(ctx context.Context, tgUserID int64, code string) (bool, error) {
+	var secret *string
+	err := db.Pool.QueryRow(ctx, `SELECT totp_secret FROM branch_admin_credentials WHERE tg_user_id = $1 AND totp_enabled = true`, tgUserID).Scan(&secret)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	if secret == nil {
+		return false, nil
+	}
+	step, ok := MatchTOTP(*secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	res, err := db.Pool.Exec(ctx, `
+		UPDATE branch_admin_credentials SET totp_last_step = $2 WHERE tg_user_id = $1 AND totp_last_step < $2`,
+		tgUserID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected() == 1, nil
+}
+
+// DisableBranchAdminTOTP turns off the second factor after checking a current code.
+func DisableBranchAdminTOTP(ctx context.Context, tgUserID int64, code string) error {
+	ok, err := VerifyBranchAdminTOTP(ctx, tgUserID, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("kod noto'g'ri")
+	}
+	_, err = db.Pool.Exec(ctx, `
+		UPDATE branch_admin_credentials SET totp_enabled = false, totp_secret = NULL, totp_last_step = 0, updated_at = now() WHERE tg_user_id = $1`,
+		tgUserID,
+	)
+	if err != nil {
+		return err
+	}
+	return recordAudit(ctx, db.Pool, AuditCredentialTOTPDisable, AuditTargetCredential, strconv.FormatInt(tgUserID, 10),
+		map[string]interface{}{"totp_enabled": true}, map[string]interface{}{"totp_enabled": false})
+}
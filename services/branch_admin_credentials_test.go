@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the RFC 6238 SHA1 test key "12345678901234567890" in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 Appendix B (SHA1), last 6 digits of the 8-digit values.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d) err = %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / totpPeriod
+	prev, _ := TOTPCode(rfc6238Secret, now.Add(-totpPeriod*time.Second))
+	next, _ := TOTPCode(rfc6238Secret, now.Add(totpPeriod*time.Second))
+	old, _ := TOTPCode(rfc6238Secret, now.Add(-3*totpPeriod*time.Second))
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current", "005924", step, true},
+		{"previous step", prev, step - 1, true},
+		{"next step", next, step + 1, true},
+		{"too old", old, 0, false},
+		{"spaces trimmed", " 005924 ", step, true},
+		{"wrong", "123456", 0, false},
+		{"short", "5924", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := MatchTOTP(rfc6238Secret, tt.code, now)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("MatchTOTP(%q) = %d, %v, want %d, %v", tt.code, gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TOTPCode(secret, time.Now()); err != nil {
+		t.Errorf("generated secret %q is not usable: %v", secret, err)
+	}
+}
+
+func TestValidateNewPassword(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr bool
+	}{
+		{"Secret123", false},
+		{"parol2026", false},
+		{"short1", true},
+		{"onlyletters", true},
+		{"1234567890", true},
+		{"with space 1", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateNewPassword(tt.in); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateNewPassword(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+	}
+}
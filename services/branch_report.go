@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"food-telegram/db"
+)
+
+// BranchDayReport is one day of a branch's orders (or of every branch).
+type BranchDayReport struct {
+	Day         time.Time
+	Orders      int
+	Completed   int
+	Rejected    int
+	ItemsTotal  int64 // of completed orders
+	DeliveryFee int64 // of completed orders
+	Revenue     int64 // grand total of completed orders
+}
+
+// ListBranchDailyReport returns one row per day in [from, to) that had orders, newest first, for the branch
+// (0 = every branch). Days are counted in BranchTimezone.
+func ListBranchDailyReport(ctx context.Context, locationID int64, from, to time.Time) ([]BranchDayReport, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT (created_at AT TIME ZONE 'Asia/Tashkent')::date AS day,
+		       COUNT(*)::int,
+		       COUNT(*) FILTER (WHERE status = 'completed')::int,
+		       COUNT(*) FILTER (WHERE status = 'rejected')::int,
+		       COALESCE(SUM(items_total) FILTER (WHERE status = 'completed'), 0)::bigint,
+		       COALESCE(SUM(delivery_fee) FILTER (WHERE status = 'completed'), 0)::bigint,
+		       COALESCE(SUM(grand_total) FILTER (WHERE status = 'completed'), 0)::bigint
+		FROM orders
+		WHERE created_at >= $2 AND created_at < $3 AND ($1::bigint = 0 OR location_id = $1)
+		GROUP BY day ORDER BY day DESC`,
+		locationID, from, to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []BranchDayReport
+	for rows.Next() {
+		var r BranchDayReport
+		if err := rows.Scan(&r.Day, &r.Orders, &r.Completed, &r.Rejected, &r.ItemsTotal, &r.DeliveryFee, &r.Revenue); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Branch staff roles (branch_admins.role).
+const (
+	BranchRoleOwner   = "owner"
+	BranchRoleManager = "manager"
+	BranchRoleCashier = "cashier"
+)
+
+// Branch permissions checked with BranchRoleCan.
+const (
+	BranchPermManageMenu   = "manage_menu"
+	BranchPermManageStaff  = "manage_staff"
+	BranchPermHandleOrders = "handle_orders"
+	BranchPermExport       = "export" // spreadsheets with customer phones and revenue
+)
+
+var branchRolePerms = map[string][]string{
+	BranchRoleOwner:   {BranchPermManageMenu, BranchPermManageStaff, BranchPermHandleOrders, BranchPermExport},
+	BranchRoleManager: {BranchPermManageMenu, BranchPermHandleOrders, BranchPermExport},
+	BranchRoleCashier: {BranchPermHandleOrders},
+}
+
+// IsValidBranchRole reports whether role is a known branch staff role.
+func IsValidBranchRole(role string) bool {
+	_, ok := branchRolePerms[role]
+	return ok
+}
+
+// BranchRoleCan reports whether the role grants perm. Unknown roles grant nothing.
+func BranchRoleCan(role, perm string) bool {
+	for _, p := range branchRolePerms[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// BranchStaff is one staff member of a location (branch_admins row).
+type BranchStaff struct {
+	AdminUserID int64
+	Role        string
+	OnShift     bool
+	OrderLang   string
+	PromotedBy  int64
+	PromotedAt  time.Time
+}
+
+// ListBranchStaff returns the staff of a location, owners first.
+func ListBranchStaff(ctx context.Context, branchLocationID int64) ([]BranchStaff, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT admin_user_id, role, on_shift, COALESCE(NULLIF(TRIM(order_lang), ''), 'uz'), promoted_by, promoted_at
+		FROM branch_admins WHERE branch_location_id = $1
+		ORDER BY array_position(ARRAY['owner', 'manager', 'cashier'], role), promoted_at`,
+		branchLocationID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list branch staff: %w", err)
+	}
+	defer rows.Close()
+	var list []BranchStaff
+	for rows.Next() {
+		var s BranchStaff
+		if err := rows.Scan(&s.AdminUserID, &s.Role, &s.OnShift, &s.OrderLang, &s.PromotedBy, &s.PromotedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// AddBranchStaff adds a manager or cashier to the location and returns a freshly generated password for them.
+// A user who already has a branch admin credential keeps it and "" is returned. Owners are assigned by the superadmin (AddBranchAdmin), not here.
+func AddBranchStaff(ctx context.Context, branchLocationID, staffUserID, addedBy int64, role, orderLang string) (string, error) {
+	if role != BranchRoleManager && role != BranchRoleCashier {
+		return "", fmt.Errorf("rol faqat %s yoki %s bo'lishi mumkin", BranchRoleManager, BranchRoleCashier)
+	}
+	if orderLang != "ru" {
+		orderLang = "uz"
+	}
+	plain, err := GenerateSecurePassword()
+	if err != nil {
+		return "", err
+	}
+	hash, err := HashBranchAdminPassword(plain)
+	if err != nil {
+		return "", err
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	res, err := tx.Exec(ctx, `
+		INSERT INTO branch_admins (branch_location_id, branch_name, admin_user_id, promoted_by, order_lang, role)
+		SELECT l.id, l.name, $2, $3, $4, $5 FROM locations l WHERE l.id = $1 AND l.deleted_at IS NULL
+		ON CONFLICT (branch_location_id, admin_user_id) DO NOTHING`,
+		branchLocationID, staffUserID, addedBy, orderLang, role,
+	)
+	if err != nil {
+		return "", fmt.Errorf("add branch staff (location_id=%d, user=%d): %w", branchLocationID, staffUserID, err)
+	}
+	if res.RowsAffected() == 0 {
+		return "", fmt.Errorf("bu foydalanuvchi allaqachon filial xodimi")
+	}
+	res, err = tx.Exec(ctx, `
+		INSERT INTO branch_admin_credentials (tg_user_id, password_hash) VALUES ($1, $2)
+		ON CONFLICT (tg_user_id) DO NOTHING`,
+		staffUserID, hash,
+	)
+	if err != nil {
+		return "", fmt.Errorf("add branch staff credential: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		plain = ""
+	}
+	after := map[string]interface{}{"location_id": branchLocationID, "role": role, "order_lang": orderLang}
+	if err := recordAudit(ctx, tx, AuditBranchStaffAdd, AuditTargetBranchStaff, strconv.FormatInt(staffUserID, 10), nil, after); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// RemoveBranchStaff removes a manager or cashier from the location and ends their admin access. Owners cannot be removed here.
+func RemoveBranchStaff(ctx context.Context, branchLocationID, staffUserID int64) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var role string
+	err = tx.QueryRow(ctx, `
+		DELETE FROM branch_admins WHERE branch_location_id = $1 AND admin_user_id = $2 AND role <> $3
+		RETURNING role`,
+		branchLocationID, staffUserID, BranchRoleOwner,
+	).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("xodim topilmadi (egasini o'chirib bo'lmaydi)")
+		}
+		return fmt.Errorf("remove branch staff: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM branch_admin_access WHERE tg_user_id = $1 AND branch_location_id = $2`, staffUserID, branchLocationID); err != nil {
+		return fmt.Errorf("remove branch staff access: %w", err)
+	}
+	before := map[string]interface{}{"location_id": branchLocationID, "role": role}
+	if err := recordAudit(ctx, tx, AuditBranchStaffRemove, AuditTargetBranchStaff, strconv.FormatInt(staffUserID, 10), before, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RemoveBranchOwners removes the owner row(s) of a location before the superadmin assigns a new owner. Other staff stay.
+func RemoveBranchOwners(ctx context.Context, branchLocationID int64) error {
+	return removeBranchStaffWhere(ctx, branchLocationID, BranchRoleOwner)
+}
+
+// removeBranchStaffWhere deletes the location's staff rows with the role ("" = every role) and audits each removal.
+func removeBranchStaffWhere(ctx context.Context, branchLocationID int64, role string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	rows, err := tx.Query(ctx, `
+		DELETE FROM branch_admins WHERE branch_location_id = $1 AND ($2 = '' OR role = $2)
+		RETURNING admin_user_id, role`,
+		branchLocationID, role,
+	)
+	if err != nil {
+		return fmt.Errorf("remove branch staff for location %d: %w", branchLocationID, err)
+	}
+	var removed []BranchStaff
+	for rows.Next() {
+		var s BranchStaff
+		if err := rows.Scan(&s.AdminUserID, &s.Role); err != nil {
+			rows.Close()
+			return err
+		}
+		removed = append(removed, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("remove branch staff for location %d: %w", branchLocationID, err)
+	}
+	for _, s := range removed {
+		before := map[string]interface{}{"location_id": branchLocationID, "role": s.Role}
+		if err := recordAudit(ctx, tx, AuditBranchStaffRemove, AuditTargetBranchStaff, strconv.FormatInt(s.AdminUserID, 10), before, nil); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// GetBranchRole returns the location and role the user acts with: the role they logged in with (branch_admin_access),
+// else their own branch_admins row. Returns 0, "" if the user is not staff of a live (not deleted) branch.
+func GetBranchRole(ctx context.Context, userID int64) (int64, string, error) {
+	var locID int64
+	var role string
+	err := db.Pool.QueryRow(ctx, `
+		SELECT a.branch_location_id, a.role FROM branch_admin_access a
+		JOIN locations l ON l.id = a.branch_location_id AND l.deleted_at IS NULL
+		WHERE a.tg_user_id = $1`,
+		userID,
+	).Scan(&locID, &role)
+	if err == nil {
+		return locID, role, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, "", err
+	}
+	err = db.Pool.QueryRow(ctx, `
+		SELECT ba.branch_location_id, ba.role FROM branch_admins ba
+		JOIN locations l ON l.id = ba.branch_location_id AND l.deleted_at IS NULL
+		WHERE ba.admin_user_id = $1
+		ORDER BY array_position(ARRAY['owner', 'manager', 'cashier'], ba.role) LIMIT 1`,
+		userID,
+	).Scan(&locID, &role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, "", nil
+		}
+		return 0, "", err
+	}
+	return locID, role, nil
+}
+
+// SetBranchStaffOnShift marks the staff member on or off shift; only on-shift staff receive order cards.
+func SetBranchStaffOnShift(ctx context.Context, branchLocationID, staffUserID int64, onShift bool) error {
+	res, err := db.Pool.Exec(ctx, `
+		UPDATE branch_admins SET on_shift = $3 WHERE branch_location_id = $1 AND admin_user_id = $2`,
+		branchLocationID, staffUserID, onShift,
+	)
+	if err != nil {
+		return fmt.Errorf("set branch staff shift: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("siz bu filial xodimlari ro'yxatida yo'qsiz")
+	}
+	after := map[string]interface{}{"location_id": branchLocationID, "on_shift": onShift}
+	return recordAudit(ctx, db.Pool, AuditBranchStaffShift, AuditTargetBranchStaff, strconv.FormatInt(staffUserID, 10), nil, after)
+}
@@ -0,0 +1,44 @@
+package services
+
+import "testing"
+
+func TestBranchRoleCan(t *testing.T) {
+	tests := []struct {
+		role string
+		perm string
+		want bool
+	}{
+		{BranchRoleOwner, BranchPermManageMenu, true},
+		{BranchRoleOwner, BranchPermManageStaff, true},
+		{BranchRoleOwner, BranchPermHandleOrders, true},
+		{BranchRoleManager, BranchPermManageMenu, true},
+		{BranchRoleManager, BranchPermManageStaff, false},
+		{BranchRoleManager, BranchPermHandleOrders, true},
+		{BranchRoleCashier, BranchPermManageMenu, false},
+		{BranchRoleCashier, BranchPermManageStaff, false},
+		{BranchRoleCashier, BranchPermHandleOrders, true},
+		{BranchRoleOwner, BranchPermExport, true},
+		{BranchRoleManager, BranchPermExport, true},
+		{BranchRoleCashier, BranchPermExport, false},
+		{"", BranchPermHandleOrders, false},
+		{"waiter", BranchPermHandleOrders, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role+"/"+tt.perm, func(t *testing.T) {
+			if got := BranchRoleCan(tt.role, tt.perm); got != tt.want {
+				t.Errorf("BranchRoleCan(%q, %q) = %v, want %v", tt.role, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidBranchRole(t *testing.T) {
+	for _, role := range []string{BranchRoleOwner, BranchRoleManager, BranchRoleCashier} {
+		if !IsValidBranchRole(role) {
+			t.Errorf("IsValidBranchRole(%q) = false, want true", role)
+		}
+	}
+	if IsValidBranchRole("admin") {
+		t.Errorf("IsValidBranchRole(%q) = true, want false", "admin")
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	CashHandoverPending   = "pending"
+	CashHandoverConfirmed = "confirmed"
+	CashHandoverRejected  = "rejected"
+)
+
+// CashBalance is cash a driver holds for one branch (collected, not yet confirmed as handed over).
+type CashBalance struct {
+	DriverID     string
+	DriverTgID   int64
+	DriverName   string
+	LocationID   int64
+	LocationName string
+	Amount       int64
+	Orders       int
+	Pending      bool // a hand-over is waiting for branch confirmation
+}
+
+// CashHandover is one cash_handovers row.
+type CashHandover struct {
+	ID          int64
+	DriverID    string
+	LocationID  int64
+	Amount      int64
+	Orders      int
+	Status      string
+	RequestedAt time.Time
+}
+
+// outstandingCashSQL selects earnings rows whose cash is still with the driver (no hand-over or hand-over pending).
+const outstandingCashSQL = `
+	SELECT e.driver_id, d.tg_user_id, COALESCE(d.full_name, ''), e.location_id, COALESCE(l.name, ''),
+	       COALESCE(SUM(e.cash_collected), 0), COUNT(*), BOOL_OR(h.status = 'pending')
+	FROM driver_earnings e
+	JOIN drivers d ON d.id = e.driver_id
+	LEFT JOIN locations l ON l.id = e.location_id
+	LEFT JOIN cash_handovers h ON h.id = e.handover_id
+	WHERE e.location_id IS NOT NULL AND e.cash_collected > 0
+	  AND (e.handover_id IS NULL OR h.status = 'pending')`
+
+func scanCashBalances(rows pgx.Rows) ([]CashBalance, error) {
+	defer rows.Close()
+	var list []CashBalance
+	for rows.Next() {
+		var b CashBalance
+		var pending *bool
+		if err := rows.Scan(&b.DriverID, &b.DriverTgID, &b.DriverName, &b.LocationID, &b.LocationName, &b.Amount, &b.Orders, &pending); err != nil {
+			return nil, err
+		}
+		b.Pending = pending != nil && *pending
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
+// GetDriverCashBalances returns the driver's outstanding cash per branch.
+func GetDriverCashBalances(ctx context.Context, driverID string) ([]CashBalance, error) {
+	rows, err := db.Pool.Query(ctx, outstandingCashSQL+`
+		  AND e.driver_id = $1
+		GROUP BY e.driver_id, d.tg_user_id, d.full_name, e.location_id, l.name
+		ORDER BY e.location_id`,
+		driverID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("driver cash balances: %w", err)
+	}
+	return scanCashBalances(rows)
+}
+
+// GetBranchCashBalances returns outstanding cash per driver for one branch.
+func GetBranchCashBalances(ctx context.Context, locationID int64) ([]CashBalance, error) {
+	rows, err := db.Pool.Query(ctx, outstandingCashSQL+`
+		  AND e.location_id = $1
+		GROUP BY e.driver_id, d.tg_user_id, d.full_name, e.location_id, l.name
+		ORDER BY SUM(e.cash_collected) DESC`,
+		locationID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("branch cash balances: %w", err)
+	}
+	return scanCashBalances(rows)
+}
+
+// ListOutstandingCashBalances returns all driver/branch pairs with cash not yet handed over (superadmin view).
+func ListOutstandingCashBalances(ctx context.Context) ([]CashBalance, error) {
+	rows, err := db.Pool.Query(ctx, outstandingCashSQL+`
+		GROUP BY e.driver_id, d.tg_user_id, d.full_name, e.location_id, l.name
+		ORDER BY e.location_id, SUM(e.cash_collected) DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("outstanding cash balances: %w", err)
+	}
+	return scanCashBalances(rows)
+}
+
+// checkCashHandoverRequest refuses a hand-over while another one for the driver and branch is pending, or when the
+// driver holds no cash for the branch (outstanding earnings rows).
+func checkCashHandoverRequest(pending bool, outstanding int) error {
+	if pending {
+		return fmt.Errorf("bu filial uchun topshirish allaqachon tasdiq kutmoqda")
+	}
+	if outstanding == 0 {
+		return fmt.Errorf("topshiriladigan naqd pul yo'q")
+	}
+	return nil
+}
+
+// checkCashHandoverDecision refuses a decision by an admin of another branch or on a hand-over already decided.
+func checkCashHandoverDecision(h CashHandover, adminLocationID int64) error {
+	if h.LocationID != adminLocationID {
+		return fmt.Errorf("bu topshirish sizning filialingizga tegishli emas")
+	}
+	if h.Status != CashHandoverPending {
+		return fmt.Errorf("topshirish allaqachon ko'rib chiqilgan (%s)", h.Status)
+	}
+	return nil
+}
+
+// cashHandoverOutcome is the status a decision sets and whether it releases the hand-over's earnings rows
+// (a rejection does: the cash counts as outstanding again and can be handed over anew).
+func cashHandoverOutcome(confirm bool) (status string, release bool) {
+	if confirm {
+		return CashHandoverConfirmed, false
+	}
+	return CashHandoverRejected, true
+}
+
+// RequestCashHandover opens a hand-over of all cash the driver holds for the branch. The branch admin confirms or rejects it.
+func RequestCashHandover(ctx context.Context, driverID string, locationID int64) (*CashHandover, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var pending bool
+	var outstanding int
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM cash_handovers WHERE driver_id = $1 AND location_id = $2 AND status = $3),
+		       (SELECT COUNT(*) FROM driver_earnings
+		        WHERE driver_id = $1 AND location_id = $2 AND handover_id IS NULL AND cash_collected > 0)`,
+		driverID, locationID, CashHandoverPending,
+	).Scan(&pending, &outstanding)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCashHandoverRequest(pending, outstanding); err != nil {
+		return nil, err
+	}
+
+	h := CashHandover{DriverID: driverID, LocationID: locationID, Status: CashHandoverPending}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO cash_handovers (driver_id, location_id) VALUES ($1, $2)
+		RETURNING id, requested_at`,
+		driverID, locationID,
+	).Scan(&h.ID, &h.RequestedAt)
+	if err != nil {
+		return nil, fmt.Errorf("create cash handover: %w", err)
+	}
+	err = tx.QueryRow(ctx, `
+		WITH linked AS (
+			UPDATE driver_earnings SET handover_id = $1
+			WHERE driver_id = $2 AND location_id = $3 AND handover_id IS NULL AND cash_collected > 0
+			RETURNING cash_collected
+		)
+		SELECT COALESCE(SUM(cash_collected), 0), COUNT(*) FROM linked`,
+		h.ID, driverID, locationID,
+	).Scan(&h.Amount, &h.Orders)
+	if err != nil {
+		return nil, fmt.Errorf("link cash handover: %w", err)
+	}
+	if err := checkCashHandoverRequest(false, h.Orders); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE cash_handovers SET amount = $1, orders_count = $2 WHERE id = $3`, h.Amount, h.Orders, h.ID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// DecideCashHandover confirms or rejects a pending hand-over. Only an admin of the hand-over's branch may decide.
+// Rejected hand-overs release their earnings rows so the cash counts as outstanding again.
+func DecideCashHandover(ctx context.Context, handoverID int64, confirm bool, adminLocationID int64, adminUserID int64) (*CashHandover, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var h CashHandover
+	err = tx.QueryRow(ctx, `
+		SELECT id, driver_id, location_id, amount, orders_count, status, requested_at
+		FROM cash_handovers WHERE id = $1 FOR UPDATE`,
+		handoverID,
+	).Scan(&h.ID, &h.DriverID, &h.LocationID, &h.Amount, &h.Orders, &h.Status, &h.RequestedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("topshirish topilmadi")
+		}
+		return nil, err
+	}
+	if err := checkCashHandoverDecision(h, adminLocationID); err != nil {
+		return nil, err
+	}
+
+	var release bool
+	h.Status, release = cashHandoverOutcome(confirm)
+	if _, err := tx.Exec(ctx, `
+		UPDATE cash_handovers SET status = $1, decided_by = $2, decided_at = now() WHERE id = $3`,
+		h.Status, adminUserID, h.ID,
+	); err != nil {
+		return nil, err
+	}
+	if release {
+		if _, err := tx.Exec(ctx, `UPDATE driver_earnings SET handover_id = NULL WHERE handover_id = $1`, h.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := recordAudit(ctx, tx, AuditCashHandoverDecide, AuditTargetCashHandover, strconv.FormatInt(h.ID, 10),
+		map[string]interface{}{"status": CashHandoverPending},
+		map[string]interface{}{"status": h.Status, "driver_id": h.DriverID, "location_id": h.LocationID, "amount": h.Amount}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
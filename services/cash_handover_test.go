@@ -0,0 +1,73 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckCashHandoverRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		pending     bool
+		outstanding int
+		wantErr     string
+	}{
+		{"cash to hand over", false, 3, ""},
+		{"one pending per driver and branch", true, 3, "tasdiq kutmoqda"},
+		{"pending wins over empty", true, 0, "tasdiq kutmoqda"},
+		{"nothing to hand over", false, 0, "naqd pul yo'q"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCashHandoverRequest(tt.pending, tt.outstanding)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkCashHandoverRequest(%v, %d) = %v, want nil", tt.pending, tt.outstanding, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkCashHandoverRequest(%v, %d) = %v, want error containing %q", tt.pending, tt.outstanding, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckCashHandoverDecision(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		adminLoc int64
+		wantErr  string
+	}{
+		{"branch admin decides pending", CashHandoverPending, 7, ""},
+		{"admin of another branch", CashHandoverPending, 8, "filialingizga tegishli emas"},
+		{"second decision after confirm", CashHandoverConfirmed, 7, "allaqachon ko'rib chiqilgan (confirmed)"},
+		{"second decision after reject", CashHandoverRejected, 7, "allaqachon ko'rib chiqilgan (rejected)"},
+		{"wrong branch checked first", CashHandoverConfirmed, 8, "filialingizga tegishli emas"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := CashHandover{ID: 1, LocationID: 7, Status: tt.status}
+			err := checkCashHandoverDecision(h, tt.adminLoc)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkCashHandoverDecision(%s, %d) = %v, want nil", tt.status, tt.adminLoc, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkCashHandoverDecision(%s, %d) = %v, want error containing %q", tt.status, tt.adminLoc, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCashHandoverOutcome(t *testing.T) {
+	if status, release := cashHandoverOutcome(true); status != CashHandoverConfirmed || release {
+		t.Errorf("confirm = (%s, %v), want (confirmed, false)", status, release)
+	}
+	if status, release := cashHandoverOutcome(false); status != CashHandoverRejected || !release {
+		t.Errorf("reject = (%s, %v), want (rejected, true): rejected cash must count as outstanding again", status, release)
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PlatformCommissionLocation is the driver_commission_rules.location_id of the platform-wide rule.
+const PlatformCommissionLocation = 0
+
+// CommissionRule is one driver_commission_rules row: the % of the delivery fee the platform keeps.
+type CommissionRule struct {
+	LocationID   int64 // PlatformCommissionLocation = every branch without a rule of its own
+	LocationName string
+	Pct          float64
+	UpdatedBy    int64
+	UpdatedAt    time.Time
+}
+
+// CommissionChange is a parsed superadmin /commission change.
+type CommissionChange struct {
+	LocationID int64
+	Pct        float64
+	Clear      bool // drop the branch rule so the platform-wide one applies again
+}
+
+// ParseCommissionArgs parses "/commission <pct>" (platform-wide), "/commission <location_id> <pct>" and
+// "/commission <location_id> off".
+func ParseCommissionArgs(args string) (CommissionChange, error) {
+	var c CommissionChange
+	parts := strings.Fields(args)
+	switch len(parts) {
+	case 1:
+	case 2:
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || id <= 0 {
+			return c, fmt.Errorf("location_id musbat raqam bo'lishi kerak")
+		}
+		c.LocationID = id
+		if parts[1] == "off" {
+			c.Clear = true
+			return c, nil
+		}
+	default:
+		return c, fmt.Errorf("argumentlar soni noto'g'ri")
+	}
+	pct, err := strconv.ParseFloat(strings.TrimSuffix(parts[len(parts)-1], "%"), 64)
+	if err != nil || pct < 0 || pct > 100 {
+		return c, fmt.Errorf("komissiya 0..100 oralig'ida bo'lishi kerak")
+	}
+	c.Pct = pct
+	return c, nil
+}
+
+// ListCommissionRules returns the platform-wide rule (if set) first, then the branch rules by branch.
+func ListCommissionRules(ctx context.Context) ([]CommissionRule, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT r.location_id, COALESCE(l.name, ''), r.commission_pct::float8, r.updated_by, r.updated_at
+		FROM driver_commission_rules r
+		LEFT JOIN locations l ON l.id = r.location_id
+		ORDER BY r.location_id`)
+	if err != nil {
+		return nil, fmt.Errorf("list commission rules: %w", err)
+	}
+	defer rows.Close()
+	var list []CommissionRule
+	for rows.Next() {
+		var r CommissionRule
+		if err := rows.Scan(&r.LocationID, &r.LocationName, &r.Pct, &r.UpdatedBy, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// SetCommissionRule stores the rule for a live branch or, with PlatformCommissionLocation, the platform-wide one.
+func SetCommissionRule(ctx context.Context, locationID int64, pct float64, updatedBy int64) error {
+	if pct < 0 || pct > 100 {
+		return fmt.Errorf("komissiya 0..100 oralig'ida bo'lishi kerak")
+	}
+	if locationID != PlatformCommissionLocation {
+		loc, err := GetLiveLocation(ctx, locationID)
+		if err != nil {
+			return err
+		}
+		if loc == nil {
+			return fmt.Errorf("filial #%d topilmadi", locationID)
+		}
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	before, err := commissionRuleAuditTx(ctx, tx, locationID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO driver_commission_rules (location_id, commission_pct, updated_by) VALUES ($1, $2, $3)
+		ON CONFLICT (location_id) DO UPDATE SET commission_pct = EXCLUDED.commission_pct, updated_by = EXCLUDED.updated_by, updated_at = now()`,
+		locationID, pct, updatedBy,
+	); err != nil {
+		return fmt.Errorf("set commission rule: %w", err)
+	}
+	if err := recordAudit(ctx, tx, AuditCommissionRuleSet, AuditTargetCommissionRule, strconv.FormatInt(locationID, 10),
+		before, map[string]interface{}{"commission_pct": pct}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteCommissionRule drops a rule; new deliveries then fall back to the platform-wide rule (or DRIVER_COMMISSION_PCT).
+func DeleteCommissionRule(ctx context.Context, locationID int64) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	before, err := commissionRuleAuditTx(ctx, tx, locationID)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return fmt.Errorf("filial #%d uchun alohida komissiya yo'q", locationID)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM driver_commission_rules WHERE location_id = $1`, locationID); err != nil {
+		return fmt.Errorf("delete commission rule: %w", err)
+	}
+	if err := recordAudit(ctx, tx, AuditCommissionRuleDelete, AuditTargetCommissionRule, strconv.FormatInt(locationID, 10),
+		before, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// commissionRuleAuditTx locks the rule and returns it as audit "before" (nil if there is none).
+func commissionRuleAuditTx(ctx context.Context, tx pgx.Tx, locationID int64) (map[string]interface{}, error) {
+	var pct float64
+	err := tx.QueryRow(ctx, `
+		SELECT commission_pct::float8 FROM driver_commission_rules WHERE location_id = $1 FOR UPDATE`,
+		locationID,
+	).Scan(&pct)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get commission rule: %w", err)
+	}
+	return map[string]interface{}{"commission_pct": pct}, nil
+}
+
+// commissionQuerier is db.Pool or a pgx.Tx.
+type commissionQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// DriverCommissionPct returns the commission for deliveries of the branch: its own rule, else the platform-wide
+// one, else defaultPct (DRIVER_COMMISSION_PCT). PlatformCommissionLocation gives the platform-wide rate.
+func DriverCommissionPct(ctx context.Context, locationID int64, defaultPct float64) (float64, error) {
+	return driverCommissionPct(ctx, db.Pool, locationID, defaultPct)
+}
+
+func driverCommissionPct(ctx context.Context, q commissionQuerier, locationID int64, defaultPct float64) (float64, error) {
+	var pct float64
+	err := q.QueryRow(ctx, `
+		SELECT commission_pct::float8 FROM driver_commission_rules
+		WHERE location_id IN ($1, 0)
+		ORDER BY location_id DESC LIMIT 1`,
+		locationID,
+	).Scan(&pct)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return defaultPct, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("driver commission: %w", err)
+	}
+	return pct, nil
+}
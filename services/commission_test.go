@@ -0,0 +1,31 @@
+package services
+
+import "testing"
+
+func TestParseCommissionArgs(t *testing.T) {
+	tests := []struct {
+		args    string
+		want    CommissionChange
+		wantErr bool
+	}{
+		{"12.5", CommissionChange{LocationID: PlatformCommissionLocation, Pct: 12.5}, false},
+		{"10%", CommissionChange{Pct: 10}, false},
+		{"3 20", CommissionChange{LocationID: 3, Pct: 20}, false},
+		{"3 off", CommissionChange{LocationID: 3, Clear: true}, false},
+		{"0 20", CommissionChange{}, true},
+		{"off", CommissionChange{}, true},
+		{"101", CommissionChange{}, true},
+		{"3 -1", CommissionChange{}, true},
+		{"3 20 x", CommissionChange{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCommissionArgs(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCommissionArgs(%q) err = %v, want error %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseCommissionArgs(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"food-telegram/db"
+)
+
+// DailySummaryGrace is how late a summary may still go out after its day closed (e.g. the bot was down at closing).
+const DailySummaryGrace = 6 * time.Hour
+
+// dailySummarySlowest is how many slowest orders a summary lists.
+const dailySummarySlowest = 3
+
+// DailySummary is one branch's day (or, with LocationID 0, every branch's), sent when the day closes.
+type DailySummary struct {
+	LocationID int64 // 0 = platform roll-up
+	Name       string
+	Day        time.Time // midnight in BranchTimezone
+	From       time.Time // orders placed in [From, To)
+	To         time.Time
+
+	Orders    int
+	Completed int
+	Rejected  int
+	Revenue   int64 // grand total of completed orders
+	Slowest   []SlowOrder
+	Branches  []BranchDayTotal // roll-up only, busiest branch first
+}
+
+// SlowOrder is a completed order and how long it took from placing to completed.
+type SlowOrder struct {
+	ID         int64
+	LocationID int64
+	Took       time.Duration
+}
+
+// BranchDayTotal is one branch's line in the platform roll-up.
+type BranchDayTotal struct {
+	LocationID int64
+	Name       string
+	Orders     int
+	Rejected   int
+	Revenue    int64
+}
+
+// DailySummaryWindow returns the orders range [from, to) of day (midnight in BranchTimezone) for a branch with
+// these weekly hours: from the previous day's closing (else midnight) to the day's closing, which may fall on the
+// next day, so consecutive windows leave no gap. A weekday without hours closes at fallbackHour; ok is false on
+// closed days, or for days without hours when fallbackHour is negative.
+func DailySummaryWindow(week [7]LocationHours, day time.Time, fallbackHour int) (from, to time.Time, ok bool) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, BranchTimezone)
+	from = day
+	if prev, ok := dailySummaryClose(week, day.AddDate(0, 0, -1), fallbackHour); ok {
+		from = prev
+	}
+	to, ok = dailySummaryClose(week, day, fallbackHour)
+	if !ok || !to.After(from) {
+		return from, from, false
+	}
+	return from, to, true
+}
+
+// dailySummaryClose is when the summary window of day (midnight) ends: the day's closing time, which may fall on
+// the next day, or fallbackHour o'clock for a weekday without hours. ok is false on closed days, or for days
+// without hours when fallbackHour is negative.
+func dailySummaryClose(week [7]LocationHours, day time.Time, fallbackHour int) (time.Time, bool) {
+	h := week[day.Weekday()]
+	switch {
+	case !h.Set:
+		if fallbackHour < 0 {
+			return time.Time{}, false
+		}
+		return day.Add(time.Duration(fallbackHour) * time.Hour), true
+	case h.Closed:
+		return time.Time{}, false
+	case h.ClosesMin <= h.OpensMin: // past midnight or round the clock
+		return day.AddDate(0, 0, 1).Add(time.Duration(h.ClosesMin) * time.Minute), true
+	default:
+		return day.Add(time.Duration(h.ClosesMin) * time.Minute), true
+	}
+}
+
+// DueDailySummaryDays returns the days (yesterday and/or today, midnight in BranchTimezone) whose summary window
+// closed at most DailySummaryGrace before now, with their windows.
+func DueDailySummaryDays(week [7]LocationHours, now time.Time, fallbackHour int) []DailySummary {
+	today := startOfBranchDay(now)
+	var due []DailySummary
+	for _, day := range []time.Time{today.AddDate(0, 0, -1), today} {
+		from, to, ok := DailySummaryWindow(week, day, fallbackHour)
+		if ok && !now.Before(to) && now.Sub(to) < DailySummaryGrace {
+			due = append(due, DailySummary{Day: day, From: from, To: to})
+		}
+	}
+	return due
+}
+
+// DueRollupDay returns the platform roll-up that is due at now: the orders from yesterday's hour o'clock up to
+// today's, once that hour has passed (within DailySummaryGrace), so consecutive roll-ups leave no gap. ok is false
+// when hour is negative or it is not time yet.
+func DueRollupDay(now time.Time, hour int) (DailySummary, bool) {
+	if hour < 0 {
+		return DailySummary{}, false
+	}
+	day := startOfBranchDay(now)
+	to := day.Add(time.Duration(hour) * time.Hour)
+	if hour == 0 { // midnight: the whole of yesterday
+		day, to = day.AddDate(0, 0, -1), day
+	}
+	if now.Before(to) || now.Sub(to) >= DailySummaryGrace {
+		return DailySummary{}, false
+	}
+	return DailySummary{Day: day, From: to.AddDate(0, 0, -1), To: to}, true
+}
+
+func startOfBranchDay(t time.Time) time.Time {
+	t = t.In(BranchTimezone)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, BranchTimezone)
+}
+
+// ClaimDailySummary records that the summary of the branch (0 = roll-up) for day is being sent. It returns false
+// when it was claimed before, so every summary goes out at most once even across restarts.
+func ClaimDailySummary(ctx context.Context, locationID int64, day time.Time) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		INSERT INTO daily_summaries_sent (location_id, day) VALUES ($1, $2::date)
+		ON CONFLICT (location_id, day) DO NOTHING`,
+		locationID, day.Format("2006-01-02"),
+	)
+	if err != nil {
+		return false, fmt.Errorf("claim daily summary: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// FillDailySummary loads the numbers of s (LocationID, From and To set) the way GetDailyStats does, per branch
+// for the roll-up.
+func FillDailySummary(ctx context.Context, s *DailySummary) error {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT COALESCE(o.location_id, 0), COALESCE(l.name, ''),
+		       COUNT(*)::int,
+		       COUNT(*) FILTER (WHERE o.status = 'completed')::int,
+		       COUNT(*) FILTER (WHERE o.status = 'rejected')::int,
+		       COALESCE(SUM(o.grand_total) FILTER (WHERE o.status = 'completed'), 0)::bigint
+		FROM orders o LEFT JOIN locations l ON l.id = o.location_id
+		WHERE `+reportScope("o.")+`
+		GROUP BY 1, 2 ORDER BY 3 DESC, 1`,
+		s.LocationID, s.From, s.To,
+	)
+	if err != nil {
+		return fmt.Errorf("daily summary: %w", err)
+	}
+	for rows.Next() {
+		var b BranchDayTotal
+		var completed int
+		if err := rows.Scan(&b.LocationID, &b.Name, &b.Orders, &completed, &b.Rejected, &b.Revenue); err != nil {
+			rows.Close()
+			return err
+		}
+		s.Orders += b.Orders
+		s.Completed += completed
+		s.Rejected += b.Rejected
+		s.Revenue += b.Revenue
+		if s.LocationID == 0 {
+			s.Branches = append(s.Branches, b)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.Pool.Query(ctx, `
+		SELECT o.id, COALESCE(o.location_id, 0), EXTRACT(EPOCH FROM MIN(sh.created_at) - o.created_at)::float8
+		FROM orders o JOIN order_status_history sh ON sh.order_id = o.id AND sh.to_status = 'completed'
+		WHERE o.status = 'completed' AND `+reportScope("o.")+`
+		GROUP BY o.id ORDER BY 3 DESC LIMIT $4`,
+		s.LocationID, s.From, s.To, dailySummarySlowest,
+	)
+	if err != nil {
+		return fmt.Errorf("daily summary slowest: %w", err)
+	}
+	for rows.Next() {
+		var o SlowOrder
+		var sec float64
+		if err := rows.Scan(&o.ID, &o.LocationID, &sec); err != nil {
+			rows.Close()
+			return err
+		}
+		o.Took = time.Duration(sec * float64(time.Second)).Round(time.Second)
+		s.Slowest = append(s.Slowest, o)
+	}
+	rows.Close()
+	return rows.Err()
+}
+
+// SetDailySummaryOptOut turns the end-of-day summary off (optOut) or back on for a Telegram user.
+func SetDailySummaryOptOut(ctx context.Context, tgUserID int64, optOut bool) error {
+	var err error
+	if optOut {
+		_, err = db.Pool.Exec(ctx, `INSERT INTO daily_summary_opt_outs (tg_user_id) VALUES ($1) ON CONFLICT (tg_user_id) DO NOTHING`, tgUserID)
+	} else {
+		_, err = db.Pool.Exec(ctx, `DELETE FROM daily_summary_opt_outs WHERE tg_user_id = $1`, tgUserID)
+	}
+	if err != nil {
+		return fmt.Errorf("daily summary opt-out: %w", err)
+	}
+	return nil
+}
+
+// DailySummaryOptedOut returns which of the users turned the summary off.
+func DailySummaryOptedOut(ctx context.Context, tgUserIDs []int64) (map[int64]bool, error) {
+	out := make(map[int64]bool)
+	if len(tgUserIDs) == 0 {
+		return out, nil
+	}
+	rows, err := db.Pool.Query(ctx, `SELECT tg_user_id FROM daily_summary_opt_outs WHERE tg_user_id = ANY($1)`, tgUserIDs)
+	if err != nil {
+		return nil, fmt.Errorf("daily summary opt-outs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out[id] = true
+	}
+	return out, rows.Err()
+}
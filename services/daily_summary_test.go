@@ -0,0 +1,110 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func summaryWeek() [7]LocationHours {
+	var week [7]LocationHours
+	for i := range week {
+		week[i].Weekday = time.Weekday(i)
+	}
+	week[time.Monday] = LocationHours{Weekday: time.Monday, Set: true, OpensMin: 9 * 60, ClosesMin: 22 * 60}
+	week[time.Friday] = LocationHours{Weekday: time.Friday, Set: true, OpensMin: 18 * 60, ClosesMin: 2 * 60}
+	week[time.Saturday] = LocationHours{Weekday: time.Saturday, Set: true, Closed: true}
+	week[time.Sunday] = LocationHours{Weekday: time.Sunday, Set: true, OpensMin: 0, ClosesMin: 0}
+	return week
+}
+
+func TestDailySummaryWindow(t *testing.T) {
+	week := summaryWeek()
+	// 2024-01-01 is a Monday.
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, 1, day, hour, 0, 0, 0, BranchTimezone)
+	}
+	tests := []struct {
+		name     string
+		day      int
+		fallback int
+		from, to time.Time
+		ok       bool
+	}{
+		{"monday closes at 22", 1, 23, at(1, 0), at(1, 22), true},
+		{"tuesday without hours uses the fallback", 2, 23, at(1, 22), at(2, 23), true},
+		{"tuesday without hours and no fallback", 2, -1, time.Time{}, time.Time{}, false},
+		{"friday closes after midnight", 5, 23, at(4, 23), at(6, 2), true},
+		{"saturday closed", 6, 23, time.Time{}, time.Time{}, false},
+		{"sunday round the clock", 7, 23, at(7, 0), at(8, 0), true},
+	}
+	for _, tt := range tests {
+		from, to, ok := DailySummaryWindow(week, at(tt.day, 0), tt.fallback)
+		if ok != tt.ok || (ok && (!from.Equal(tt.from) || !to.Equal(tt.to))) {
+			t.Errorf("%s: DailySummaryWindow = %v, %v, %v; want %v, %v, %v", tt.name, from, to, ok, tt.from, tt.to, tt.ok)
+		}
+	}
+
+	// Each window starts where the previous day's closed, so no order is counted twice or left out.
+	week[time.Saturday] = LocationHours{Weekday: time.Saturday, Set: true, OpensMin: 10 * 60, ClosesMin: 20 * 60}
+	if from, _, _ := DailySummaryWindow(week, at(6, 0), 23); !from.Equal(at(6, 2)) {
+		t.Errorf("saturday after late friday starts at %v, want %v", from, at(6, 2))
+	}
+	// Days without hours: Wednesday starts at Tuesday's fallback closing, not at midnight.
+	_, tue, _ := DailySummaryWindow(week, at(2, 0), 23)
+	if wed, _, _ := DailySummaryWindow(week, at(3, 0), 23); !wed.Equal(tue) {
+		t.Errorf("wednesday starts at %v, want tuesday's end %v", wed, tue)
+	}
+}
+
+func TestDueDailySummaryDays(t *testing.T) {
+	week := summaryWeek()
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2024, 1, day, hour, min, 0, 0, BranchTimezone)
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want []int // days of January
+	}{
+		{"before monday closing", at(1, 21, 59), nil},
+		{"at monday closing", at(1, 22, 0), []int{1}},
+		{"friday closes on saturday", at(6, 2, 30), []int{5}},
+		{"past the grace", at(2, 4, 0), nil},
+		{"utc time converted", time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC), []int{1}},
+	}
+	for _, tt := range tests {
+		due := DueDailySummaryDays(week, tt.now, 23)
+		var got []int
+		for _, d := range due {
+			got = append(got, d.Day.Day())
+		}
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("%s: due days = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDueRollupDay(t *testing.T) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2024, 1, day, hour, min, 0, 0, BranchTimezone)
+	}
+	if _, ok := DueRollupDay(at(1, 22, 59), 23); ok {
+		t.Error("roll-up due before its hour")
+	}
+	s, ok := DueRollupDay(at(1, 23, 5), 23)
+	if !ok || !s.From.Equal(at(0, 23, 0)) || !s.To.Equal(at(1, 23, 0)) {
+		t.Errorf("roll-up at 23:05 = %v..%v, %v; want from yesterday 23:00", s.From, s.To, ok)
+	}
+	// The next day's roll-up starts where this one ended: orders between 23:00 and midnight are not lost.
+	next, ok := DueRollupDay(at(2, 23, 5), 23)
+	if !ok || !next.From.Equal(s.To) {
+		t.Errorf("next roll-up from %v, want %v", next.From, s.To)
+	}
+	s, ok = DueRollupDay(at(2, 0, 30), 0)
+	if !ok || s.Day.Day() != 1 || !s.From.Equal(at(1, 0, 0)) || !s.To.Equal(at(2, 0, 0)) {
+		t.Errorf("midnight roll-up = day %v to %v, %v; want all of Jan 1", s.Day, s.To, ok)
+	}
+	if _, ok := DueRollupDay(at(1, 23, 5), -1); ok {
+		t.Error("roll-up due while turned off")
+	}
+}
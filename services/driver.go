@@ -28,6 +28,35 @@ type Driver struct {
 	CarColor  string
 	Status    string
 	IsOnline  bool
+	VerificationStatus       string
+	VerificationRejectReason string
+}
+
+// Batched delivery rules (see CanTakeAnotherOrder).
+var (
+	ErrDriverAtCapacity       = errors.New("faol buyurtmalar soni maksimal darajada")
+	ErrBatchOtherBranch       = errors.New("qo'shimcha buyurtma faqat faol buyurtmalaringiz filialidan bo'lishi mumkin")
+	ErrBatchAlreadyDelivering = errors.New("yetkazish boshlangan: yangi buyurtma olishdan oldin faol buyurtmalarni yakunlang")
+)
+
+// CanTakeAnotherOrder reports whether a driver holding active orders may accept one more from candidateLocationID.
+// Extra orders must come from the same branch and only while the driver has not started delivering. maxActive <= 0 means 1.
+func CanTakeAnotherOrder(active []models.Order, candidateLocationID int64, maxActive int) error {
+	if maxActive <= 0 {
+		maxActive = 1
+	}
+	if len(active) >= maxActive {
+		return ErrDriverAtCapacity
+	}
+	for _, o := range active {
+		if o.Status == OrderStatusDelivering {
+			return ErrBatchAlreadyDelivering
+		}
+		if o.LocationID != candidateLocationID {
+			return ErrBatchOtherBranch
+		}
+	}
+	return nil
 }
 
 // DriverLocation represents a driver's current location.
@@ -40,21 +69,10 @@ type DriverLocation struct {
 
 // RegisterDriver creates or updates a driver record (minimal; use CreateDriverProfile for full onboarding).
 func RegisterDriver(ctx context.Context, tgUserID int64, chatID int64) (*Driver, error) {
-	var id string
-	err := db.Pool.QueryRow(ctx, `
-		INSERT INTO drivers (tg_user_id, chat_id, status, is_online)
-		VALUES ($1, $2, $3, false)
-		ON CONFLICT (tg_user_id) DO UPDATE SET chat_id = EXCLUDED.chat_id, updated_at = now()
-		RETURNING id`,
-		tgUserID, chatID, DriverStatusOffline,
-	).Scan(&id)
-	if err != nil {
-		return nil, fmt.Errorf("register driver: %w", err)
-	}
-	return &Driver{ID: id, TgUserID: tgUserID, ChatID: chatID, Status: DriverStatusOffline}, nil
+	return defaultStore().Drivers().Register(ctx, tgUserID, chatID)
 }
 
-// CreateDriverProfile creates a new driver with full profile (onboarding). Returns the driver or error if tg_user_id already exists.
+// CreateDriverProfile creates a new driver with full profile (onboarding) in pending_documents state. Returns the driver or error if tg_user_id already exists.
 func CreateDriverProfile(ctx context.Context, tgUserID, chatID int64, fullName, phone, carPlate, carModel, carColor string, lat, lon *float64) (*Driver, error) {
 	var id string
 	err := db.Pool.QueryRow(ctx, `
@@ -70,13 +88,13 @@ func CreateDriverProfile(ctx context.Context, tgUserID, chatID int64, fullName,
 		ID: id, TgUserID: tgUserID, ChatID: chatID,
 		FullName: strings.TrimSpace(fullName), Phone: strings.TrimSpace(phone),
 		CarPlate: strings.TrimSpace(carPlate), CarModel: strings.TrimSpace(carModel), CarColor: strings.TrimSpace(carColor),
-		Status: DriverStatusOffline,
+		Status: DriverStatusOffline, VerificationStatus: DriverVerificationPendingDocuments,
 	}
 	if lat != nil && lon != nil {
 		_, _ = db.Pool.Exec(ctx, `
 			INSERT INTO driver_locations (driver_id, lat, lon, updated_at)
 			VALUES ($1, $2, $3, now())
-			ON CONFLICT (driver_id) DO UPDATE SET lat = EXCLUDED.lat, lon = EXCLUDED.lon, updated_at = now()`,
+			ON CONFLICT (driver_id) DO UPDATE SET lat = EXCLUDED.lat, lon = EXCLUDED.lon, updated_at = now(), reminded_at = NULL`,
 			id, *lat, *lon,
 		)
 	}
@@ -85,42 +103,39 @@ func CreateDriverProfile(ctx context.Context, tgUserID, chatID int64, fullName,
 
 // GetDriverByTgUserID loads a driver by Telegram user ID.
 func GetDriverByTgUserID(ctx context.Context, tgUserID int64) (*Driver, error) {
-	var d Driver
-	err := db.Pool.QueryRow(ctx, `
-		SELECT id, tg_user_id, chat_id,
-		       COALESCE(full_name, ''), COALESCE(phone, ''), COALESCE(car_plate, ''),
-		       COALESCE(car_model, ''), COALESCE(car_color, ''),
-		       status, COALESCE(is_online, false)
-		FROM drivers WHERE tg_user_id = $1`,
-		tgUserID,
-	).Scan(&d.ID, &d.TgUserID, &d.ChatID, &d.FullName, &d.Phone, &d.CarPlate, &d.CarModel, &d.CarColor, &d.Status, &d.IsOnline)
+	return defaultStore().Drivers().GetByTgUserID(ctx, tgUserID)
+}
+
+// ListDrivers returns drivers by tg_user_id, optionally only those with the status (online / offline).
+func ListDrivers(ctx context.Context, status string, limit, offset int) ([]Driver, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+driverColumns+` FROM drivers
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY tg_user_id LIMIT $2 OFFSET $3`,
+		status, limit, offset,
+	)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
 		return nil, err
 	}
-	return &d, nil
+	defer rows.Close()
+	var list []Driver
+	for rows.Next() {
+		var d Driver
+		if err := rows.Scan(&d.ID, &d.TgUserID, &d.ChatID, &d.FullName, &d.Phone, &d.CarPlate, &d.CarModel, &d.CarColor,
+			&d.Status, &d.IsOnline, &d.VerificationStatus, &d.VerificationRejectReason); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
 }
 
 // GetDriverByID loads a driver by driver ID.
 func GetDriverByID(ctx context.Context, driverID string) (*Driver, error) {
-	var d Driver
-	err := db.Pool.QueryRow(ctx, `
-		SELECT id, tg_user_id, chat_id,
-		       COALESCE(full_name, ''), COALESCE(phone, ''), COALESCE(car_plate, ''),
-		       COALESCE(car_model, ''), COALESCE(car_color, ''),
-		       status, COALESCE(is_online, false)
-		FROM drivers WHERE id = $1`,
-		driverID,
-	).Scan(&d.ID, &d.TgUserID, &d.ChatID, &d.FullName, &d.Phone, &d.CarPlate, &d.CarModel, &d.CarColor, &d.Status, &d.IsOnline)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &d, nil
+	return defaultStore().Drivers().GetByID(ctx, driverID)
 }
 
 // UpdateDriverChatID sets the driver's chat_id (e.g. when they first message the bot after being added with chat_id=0).
@@ -131,15 +146,51 @@ func UpdateDriverChatID(ctx context.Context, driverID string, chatID int64) erro
 
 // UpdateDriverStatus updates driver status (online/offline) and is_online.
 func UpdateDriverStatus(ctx context.Context, driverID string, status string) error {
+	return SetDriverStatusWithReason(ctx, driverID, status, DriverStatusReasonManual)
+}
+
+// SetDriverStatusWithReason sets online/offline and appends driver_status_log when the status actually changes.
+// Only verified drivers may go online (ErrDriverNotVerified).
+func SetDriverStatusWithReason(ctx context.Context, driverID string, status string, reason string) error {
 	if status != DriverStatusOnline && status != DriverStatusOffline {
 		return fmt.Errorf("invalid driver status: %s", status)
 	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err := setDriverStatusTx(ctx, tx, driverID, status, reason); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// setDriverStatusTx is SetDriverStatusWithReason inside the caller's transaction.
+func setDriverStatusTx(ctx context.Context, tx pgx.Tx, driverID string, status string, reason string) error {
+	var prev, verification string
+	if err := tx.QueryRow(ctx, `SELECT status, verification_status FROM drivers WHERE id = $1 FOR UPDATE`, driverID).Scan(&prev, &verification); err != nil {
+		return err
+	}
+	if status == DriverStatusOnline && verification != DriverVerificationVerified {
+		return ErrDriverNotVerified
+	}
 	isOnline := status == DriverStatusOnline
-	_, err := db.Pool.Exec(ctx, `
+	if _, err := tx.Exec(ctx, `
 		UPDATE drivers SET status = $1, is_online = $2, updated_at = now() WHERE id = $3`,
 		status, isOnline, driverID,
-	)
-	return err
+	); err != nil {
+		return err
+	}
+	if prev != status {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO driver_status_log (driver_id, status, reason) VALUES ($1, $2, $3)`,
+			driverID, status, reason,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // UpdateDriverLocation updates or inserts driver location.
@@ -147,7 +198,7 @@ func UpdateDriverLocation(ctx context.Context, driverID string, lat, lon float64
 	_, err := db.Pool.Exec(ctx, `
 		INSERT INTO driver_locations (driver_id, lat, lon, updated_at)
 		VALUES ($1, $2, $3, now())
-		ON CONFLICT (driver_id) DO UPDATE SET lat = EXCLUDED.lat, lon = EXCLUDED.lon, updated_at = now()`,
+		ON CONFLICT (driver_id) DO UPDATE SET lat = EXCLUDED.lat, lon = EXCLUDED.lon, updated_at = now(), reminded_at = NULL`,
 		driverID, lat, lon,
 	)
 	return err
@@ -265,36 +316,50 @@ func GetNearbyReadyOrders(ctx context.Context, driverLat, driverLon float64, rad
 
 // NearbyDriverForPush is a driver candidate to receive a READY order push (chat_id for Telegram, distance in km).
 type NearbyDriverForPush struct {
-	DriverID   string
-	ChatID     int64
-	DistanceKm float64
+	DriverID          string
+	ChatID            int64
+	DistanceKm        float64
+	HeadingFromBranch bool // driver already holds an order from the same branch (batch candidate)
 }
 
 // GetNearbyOnlineDriversForOrder returns up to limit drivers who are online, have location updated within last 5 minutes,
-// and are within radiusKm of (orderLat, orderLon). Ordered by distance ascending. Used to push READY orders to drivers.
-func GetNearbyOnlineDriversForOrder(ctx context.Context, orderLat, orderLon float64, radiusKm float64, limit int) ([]NearbyDriverForPush, error) {
+// and are within radiusKm of (orderLat, orderLon). Drivers that cannot take the order (at maxActive, holding orders from
+// another branch, or already delivering) are skipped. Drivers already heading from locationID come first, then by distance.
+func GetNearbyOnlineDriversForOrder(ctx context.Context, orderLat, orderLon float64, radiusKm float64, limit int, locationID int64, maxActive int) ([]NearbyDriverForPush, error) {
 	if limit <= 0 {
 		limit = 10
 	}
+	if maxActive <= 0 {
+		maxActive = 1
+	}
 	rows, err := db.Pool.Query(ctx, `
 		SELECT d.id, d.chat_id,
 		       (6371 * acos(
 		           cos(radians($1)) * cos(radians(dl.lat)) *
 		           cos(radians(dl.lon) - radians($2)) +
 		           sin(radians($1)) * sin(radians(dl.lat))
-		       )) AS distance_km
+		       )) AS distance_km,
+		       a.cnt > 0 AS heading_from_branch
 		FROM drivers d
 		INNER JOIN driver_locations dl ON dl.driver_id = d.id
 		  AND dl.updated_at >= now() - interval '5 minutes'
-		WHERE d.is_online = true
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS cnt,
+			       COALESCE(BOOL_AND(COALESCE(o.location_id, 0) = $5 AND o.status <> $7), true) AS batchable
+			FROM orders o
+			WHERE o.driver_id = d.id AND o.status IN ($8, $9, $7)
+		) a
+		WHERE d.is_online = true AND d.verification_status = $10
 		  AND (6371 * acos(
 		      cos(radians($1)) * cos(radians(dl.lat)) *
 		      cos(radians(dl.lon) - radians($2)) +
 		      sin(radians($1)) * sin(radians(dl.lat))
 		  )) <= $3
-		ORDER BY distance_km ASC
+		  AND a.cnt < $6 AND a.batchable
+		ORDER BY heading_from_branch DESC, distance_km ASC
 		LIMIT $4`,
-		orderLat, orderLon, radiusKm, limit,
+		orderLat, orderLon, radiusKm, limit, locationID, maxActive,
+		OrderStatusDelivering, OrderStatusAssigned, OrderStatusPickedUp, DriverVerificationVerified,
 	)
 	if err != nil {
 		return nil, err
@@ -303,7 +368,7 @@ func GetNearbyOnlineDriversForOrder(ctx context.Context, orderLat, orderLon floa
 	var out []NearbyDriverForPush
 	for rows.Next() {
 		var r NearbyDriverForPush
-		if err := rows.Scan(&r.DriverID, &r.ChatID, &r.DistanceKm); err != nil {
+		if err := rows.Scan(&r.DriverID, &r.ChatID, &r.DistanceKm, &r.HeadingFromBranch); err != nil {
 			return nil, err
 		}
 		out = append(out, r)
@@ -311,135 +376,170 @@ func GetNearbyOnlineDriversForOrder(ctx context.Context, orderLat, orderLon floa
 	return out, rows.Err()
 }
 
-// AcceptOrder assigns a driver to a READY order and transitions status to 'assigned' (atomic, prevents double assign).
+// AcceptOrder assigns a driver to a READY order and transitions status to 'assigned' (row lock prevents double assign).
 // Returns order details if successful, error if already assigned or invalid.
-func AcceptOrder(ctx context.Context, orderID int64, driverID string, driverTgUserID int64) (*models.Order, error) {
+// maxActive limits how many orders the driver may hold at once (see CanTakeAnotherOrder).
+func AcceptOrder(ctx context.Context, orderID int64, driverID string, driverTgUserID int64, maxActive int) (*models.Order, error) {
 	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
-	var o models.Order
-	err = tx.QueryRow(ctx, `
-		UPDATE orders
-		SET driver_id = $1, assigned_at = now(), status = $4, updated_at = now()
-		WHERE id = $2 AND status = $3 AND driver_id IS NULL
-		RETURNING id, COALESCE(location_id, 0), status, chat_id, items_total, grand_total, COALESCE(delivery_fee, 0), COALESCE(distance_km, 0)`,
-		driverID, orderID, OrderStatusReady, OrderStatusAssigned,
-	).Scan(&o.ID, &o.LocationID, &o.Status, &o.ChatID, &o.ItemsTotal, &o.GrandTotal, &o.DeliveryFee, &o.DistanceKm)
+
+	// Serialize accepts of the same driver so two parallel taps cannot exceed maxActive.
+	var verification string
+	if err := tx.QueryRow(ctx, `SELECT verification_status FROM drivers WHERE id = $1 FOR UPDATE`, driverID).Scan(&verification); err != nil {
+		return nil, err
+	}
+	if verification != DriverVerificationVerified {
+		return nil, ErrDriverNotVerified
+	}
+	locked, err := lockOrderTx(ctx, tx, orderID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			// Check if order exists but was already taken
-			var existingDriverID *string
-			checkErr := tx.QueryRow(ctx, `SELECT driver_id FROM orders WHERE id = $1`, orderID).Scan(&existingDriverID)
-			if checkErr == nil && existingDriverID != nil {
-				return nil, fmt.Errorf("bu buyurtma allaqachon olingan")
-			}
 			return nil, fmt.Errorf("buyurtma topilmadi yoki tayyor emas")
 		}
 		return nil, err
 	}
-	// Record status transition: ready -> assigned
-	_, err = tx.Exec(ctx, `
-		INSERT INTO order_status_history (order_id, from_status, to_status, actor_id)
-		VALUES ($1, $2, $3, $4)`,
-		orderID, OrderStatusReady, OrderStatusAssigned, driverTgUserID,
-	)
+	if locked.status != OrderStatusReady {
+		if locked.facts.DriverAssigned {
+			return nil, errOrderAlreadyTaken
+		}
+		return nil, fmt.Errorf("buyurtma topilmadi yoki tayyor emas")
+	}
+	t, err := CheckOrderTransition(locked.status, OrderStatusAssigned, OrderActorDriver, locked.facts)
+	if err != nil {
+		return nil, err
+	}
+	active, err := queryDriverActiveOrders(ctx, tx, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if len(active) > 0 {
+		if err := CanTakeAnotherOrder(active, locked.locationID, maxActive); err != nil {
+			return nil, err
+		}
+	}
+
+	var o models.Order
+	err = tx.QueryRow(ctx, `
+		UPDATE orders SET driver_id = $1, assigned_at = now() WHERE id = $2
+		RETURNING id, COALESCE(location_id, 0), chat_id, items_total, grand_total, COALESCE(delivery_fee, 0), COALESCE(distance_km, 0)`,
+		driverID, orderID,
+	).Scan(&o.ID, &o.LocationID, &o.ChatID, &o.ItemsTotal, &o.GrandTotal, &o.DeliveryFee, &o.DistanceKm)
 	if err != nil {
 		return nil, err
 	}
+	if err := applyOrderTransitionTx(ctx, tx, orderID, t, locked.facts, driverTgUserID); err != nil {
+		return nil, err
+	}
+	o.Status = t.To
 	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
 	return &o, nil
 }
 
-// GetDriverActiveOrder returns the active order assigned to a driver (status in assigned/picked_up/delivering).
+// GetDriverActiveOrder returns the most recently assigned active order of a driver (status in assigned/picked_up/delivering).
+// Drivers may hold several orders; use GetDriverActiveOrders for the full list.
 func GetDriverActiveOrder(ctx context.Context, driverID string) (*models.Order, error) {
-	var o models.Order
-	err := db.Pool.QueryRow(ctx, `
-		SELECT id, COALESCE(location_id, 0), status, chat_id, items_total, grand_total, COALESCE(delivery_fee, 0), COALESCE(distance_km, 0)
+	list, err := GetDriverActiveOrders(ctx, driverID)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[len(list)-1], nil
+}
+
+// GetDriverActiveOrders returns all active orders of a driver, oldest assignment first.
+func GetDriverActiveOrders(ctx context.Context, driverID string) ([]models.Order, error) {
+	return defaultStore().Orders().ActiveByDriver(ctx, driverID)
+}
+
+// rowQuerier is satisfied by *pgxpool.Pool and pgx.Tx.
+type rowQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func queryDriverActiveOrders(ctx context.Context, q rowQuerier, driverID string) ([]models.Order, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, COALESCE(location_id, 0), status, chat_id, items_total, grand_total, COALESCE(delivery_fee, 0), COALESCE(distance_km, 0), delivery_type
 		FROM orders
 		WHERE driver_id = $1 AND status IN ($2, $3, $4)
-		ORDER BY assigned_at DESC
-		LIMIT 1`,
+		ORDER BY assigned_at ASC, id ASC`,
 		driverID, OrderStatusAssigned, OrderStatusPickedUp, OrderStatusDelivering,
-	).Scan(&o.ID, &o.LocationID, &o.Status, &o.ChatID, &o.ItemsTotal, &o.GrandTotal, &o.DeliveryFee, &o.DistanceKm)
+	)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
 		return nil, err
 	}
-	return &o, nil
+	defer rows.Close()
+	var list []models.Order
+	for rows.Next() {
+		var o models.Order
+		if err := rows.Scan(&o.ID, &o.LocationID, &o.Status, &o.ChatID, &o.ItemsTotal, &o.GrandTotal, &o.DeliveryFee, &o.DistanceKm, &o.DeliveryType); err != nil {
+			return nil, err
+		}
+		o.DriverID = &driverID
+		list = append(list, o)
+	}
+	return list, rows.Err()
 }
 
-// UpdateDriverOrderStatus updates order status by the assigned driver (assigned -> picked_up -> delivering -> completed).
-// Only the assigned driver can update status. Validates status transition.
+// UpdateDriverOrderStatus moves the order along as its assigned driver (picked_up, delivering).
+// Completing goes through CompleteDeliveryByDriver, which also records the earning.
 func UpdateDriverOrderStatus(ctx context.Context, orderID int64, driverID string, driverTgUserID int64, newStatus string) error {
-	// Validate new status is driver-controlled
-	validDriverStatuses := map[string]bool{
-		OrderStatusPickedUp:   true,
-		OrderStatusDelivering: true,
-		OrderStatusCompleted: true,
-	}
-	if !validDriverStatuses[newStatus] {
-		return fmt.Errorf("invalid driver status: %s", newStatus)
-	}
-
 	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	// Get current status and verify driver assignment
-	var fromStatus string
-	err = tx.QueryRow(ctx, `
-		SELECT status FROM orders WHERE id = $1 AND driver_id = $2`,
-		orderID, driverID,
-	).Scan(&fromStatus)
+	t, facts, err := driverOrderTransitionTx(ctx, tx, orderID, driverID, newStatus)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return fmt.Errorf("order not found or not assigned to you")
-		}
 		return err
 	}
+	if t.Has(OrderEffectDriverEarning) {
+		return fmt.Errorf("use CompleteDeliveryByDriver to complete an order")
+	}
+	if err := applyOrderTransitionTx(ctx, tx, orderID, t, facts, driverTgUserID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
 
-	// Validate status transition
-	if !ValidStatusTransition(fromStatus, newStatus) {
-		return fmt.Errorf("invalid status transition from %q to %q", fromStatus, newStatus)
+// driverOrderTransitionTx locks the driver's order and checks the driver may move it to newStatus.
+func driverOrderTransitionTx(ctx context.Context, tx pgx.Tx, orderID int64, driverID, newStatus string) (*OrderTransition, OrderFacts, error) {
+	o, err := lockOrderTx(ctx, tx, orderID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, o.facts, err
 	}
+	if err != nil || o.driverID != driverID {
+		return nil, o.facts, fmt.Errorf("order not found or not assigned to you")
+	}
+	t, err := CheckOrderTransition(o.status, newStatus, OrderActorDriver, o.facts)
+	return t, o.facts, err
+}
 
-	// Update status
-	var rowsAffected int64
-	err = tx.QueryRow(ctx, `
-		UPDATE orders 
-		SET status = $1, updated_at = now() 
-		WHERE id = $2 AND driver_id = $3 AND status = $4
-		RETURNING 1`,
-		newStatus, orderID, driverID, fromStatus,
-	).Scan(&rowsAffected)
+// CompleteDeliveryByDriver marks an order as completed by the assigned driver (from delivering status)
+// and writes the driver_earnings ledger row in the same transaction with the branch's commission rule
+// (defaultCommissionPct, DRIVER_COMMISSION_PCT, when no rule is set).
+func CompleteDeliveryByDriver(ctx context.Context, orderID int64, driverID string, driverTgUserID int64, defaultCommissionPct float64) error {
+	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return fmt.Errorf("status mos emas yoki sizga tegishli emas")
-		}
 		return err
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	// Record status transition
-	_, err = tx.Exec(ctx, `
-		INSERT INTO order_status_history (order_id, from_status, to_status, actor_id)
-		VALUES ($1, $2, $3, $4)`,
-		orderID, fromStatus, newStatus, driverTgUserID,
-	)
+	t, facts, err := driverOrderTransitionTx(ctx, tx, orderID, driverID, OrderStatusCompleted)
 	if err != nil {
 		return err
 	}
+	if err := applyOrderTransitionTx(ctx, tx, orderID, t, facts, driverTgUserID); err != nil {
+		return err
+	}
+	if t.Has(OrderEffectDriverEarning) {
+		if err := recordDriverEarningTx(ctx, tx, orderID, driverID, defaultCommissionPct); err != nil {
+			return fmt.Errorf("driver earnings: %w", err)
+		}
+	}
 	return tx.Commit(ctx)
 }
-
-// CompleteDeliveryByDriver marks an order as completed by the assigned driver (from delivering status).
-func CompleteDeliveryByDriver(ctx context.Context, orderID int64, driverID string, driverTgUserID int64) error {
-	return UpdateDriverOrderStatus(ctx, orderID, driverID, driverTgUserID, OrderStatusCompleted)
-}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DriverEarningsSummary aggregates driver_earnings rows for a period.
+type DriverEarningsSummary struct {
+	Orders        int
+	DeliveryFees  int64
+	Commission    int64
+	DriverShare   int64
+	CashCollected int64
+	Tips          int64
+}
+
+// DriverPayout is a settled period (driver_payouts row).
+type DriverPayout struct {
+	ID         int64
+	DriverID   string
+	PeriodFrom time.Time
+	PeriodTo   time.Time
+	Summary    DriverEarningsSummary
+	SettledBy  int64
+	SettledAt  time.Time
+}
+
+// UnsettledDriverBalance is one driver with earnings not yet paid out.
+type UnsettledDriverBalance struct {
+	DriverID    string
+	TgUserID    int64
+	FullName    string
+	Orders      int
+	DriverShare int64
+	Tips        int64
+	Since       time.Time
+}
+
+// CalcDriverShare splits a delivery fee into platform commission and driver share (commission rounded to whole sum).
+func CalcDriverShare(deliveryFee int64, commissionPct float64) (share, commission int64) {
+	if deliveryFee <= 0 {
+		return 0, 0
+	}
+	if commissionPct < 0 {
+		commissionPct = 0
+	}
+	if commissionPct > 100 {
+		commissionPct = 100
+	}
+	commission = int64(math.Round(float64(deliveryFee) * commissionPct / 100))
+	return deliveryFee - commission, commission
+}
+
+// recordDriverEarningTx writes the ledger row for a completed delivery with the branch's commission rule
+// (defaultCommissionPct without one). Idempotent per order.
+func recordDriverEarningTx(ctx context.Context, tx pgx.Tx, orderID int64, driverID string, defaultCommissionPct float64) error {
+	var locationID *int64
+	var deliveryFee, grandTotal int64
+	err := tx.QueryRow(ctx, `
+		SELECT location_id, COALESCE(delivery_fee, 0), grand_total FROM orders WHERE id = $1`,
+		orderID,
+	).Scan(&locationID, &deliveryFee, &grandTotal)
+	if err != nil {
+		return err
+	}
+	ruleLocation := int64(PlatformCommissionLocation)
+	if locationID != nil {
+		ruleLocation = *locationID
+	}
+	commissionPct, err := driverCommissionPct(ctx, tx, ruleLocation, defaultCommissionPct)
+	if err != nil {
+		return err
+	}
+	share, commission := CalcDriverShare(deliveryFee, commissionPct)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO driver_earnings (driver_id, order_id, location_id, delivery_fee, commission_pct, commission, driver_share, cash_collected)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (order_id) DO NOTHING`,
+		driverID, orderID, locationID, deliveryFee, commissionPct, commission, share, grandTotal,
+	)
+	return err
+}
+
+// AddDriverTip records a tip for a delivery of this driver. Settled rows cannot be changed.
+func AddDriverTip(ctx context.Context, orderID int64, driverID string, amount int64) error {
+	if amount < 0 {
+		return fmt.Errorf("summa manfiy bo'lishi mumkin emas")
+	}
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE driver_earnings SET tip = $1
+		WHERE order_id = $2 AND driver_id = $3 AND payout_id IS NULL`,
+		amount, orderID, driverID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("buyurtma topilmadi yoki allaqachon hisob-kitob qilingan")
+	}
+	return nil
+}
+
+// GetDriverEarningsSummary sums ledger rows for driverID with created_at in [from, to).
+func GetDriverEarningsSummary(ctx context.Context, driverID string, from, to time.Time) (*DriverEarningsSummary, error) {
+	var s DriverEarningsSummary
+	err := db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(delivery_fee), 0), COALESCE(SUM(commission), 0), COALESCE(SUM(driver_share), 0),
+		       COALESCE(SUM(cash_collected), 0), COALESCE(SUM(tip), 0)
+		FROM driver_earnings
+		WHERE driver_id = $1 AND created_at >= $2 AND created_at < $3`,
+		driverID, from, to,
+	).Scan(&s.Orders, &s.DeliveryFees, &s.Commission, &s.DriverShare, &s.CashCollected, &s.Tips)
+	if err != nil {
+		return nil, fmt.Errorf("driver earnings summary: %w", err)
+	}
+	return &s, nil
+}
+
+// StartOfWeek returns Monday 00:00 of the week containing t (in t's location).
+func StartOfWeek(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := (int(day.Weekday()) + 6) % 7 // Monday = 0
+	return day.AddDate(0, 0, -offset)
+}
+
+// ListUnsettledDriverBalances returns drivers that have earnings not yet covered by a payout.
+func ListUnsettledDriverBalances(ctx context.Context) ([]UnsettledDriverBalance, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT d.id, d.tg_user_id, COALESCE(d.full_name, ''), COUNT(*), COALESCE(SUM(e.driver_share), 0),
+		       COALESCE(SUM(e.tip), 0), MIN(e.created_at)
+		FROM driver_earnings e
+		JOIN drivers d ON d.id = e.driver_id
+		WHERE e.payout_id IS NULL
+		GROUP BY d.id, d.tg_user_id, d.full_name
+		ORDER BY SUM(e.driver_share) DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []UnsettledDriverBalance
+	for rows.Next() {
+		var r UnsettledDriverBalance
+		if err := rows.Scan(&r.DriverID, &r.TgUserID, &r.FullName, &r.Orders, &r.DriverShare, &r.Tips, &r.Since); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// SettleDriverPayout marks all unsettled earnings of the driver created in [from, to] (dates, inclusive) as paid
+// and stores the payout row. Returns an error if there is nothing to settle.
+func SettleDriverPayout(ctx context.Context, driverID string, from, to time.Time, settledBy int64) (*DriverPayout, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("davr noto'g'ri: boshlanish sanasi tugash sanasidan keyin")
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	p := DriverPayout{DriverID: driverID, PeriodFrom: from, PeriodTo: to, SettledBy: settledBy}
+	// Lock the rows being settled so a concurrent payout cannot take them twice.
+	err = tx.QueryRow(ctx, `
+		WITH locked AS (
+			SELECT delivery_fee, commission, driver_share, cash_collected, tip
+			FROM driver_earnings
+			WHERE driver_id = $1 AND payout_id IS NULL
+			  AND created_at >= $2::date AND created_at < ($3::date + 1)
+			FOR UPDATE
+		)
+		SELECT COUNT(*), COALESCE(SUM(delivery_fee), 0), COALESCE(SUM(commission), 0), COALESCE(SUM(driver_share), 0),
+		       COALESCE(SUM(cash_collected), 0), COALESCE(SUM(tip), 0)
+		FROM locked`,
+		driverID, from, to,
+	).Scan(&p.Summary.Orders, &p.Summary.DeliveryFees, &p.Summary.Commission, &p.Summary.DriverShare, &p.Summary.CashCollected, &p.Summary.Tips)
+	if err != nil {
+		return nil, fmt.Errorf("payout totals: %w", err)
+	}
+	if p.Summary.Orders == 0 {
+		return nil, fmt.Errorf("bu davr uchun to'lanmagan daromad yo'q")
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO driver_payouts (driver_id, period_from, period_to, orders_count, delivery_fees, commission, driver_share, cash_collected, tips, settled_by)
+		VALUES ($1, $2::date, $3::date, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, settled_at`,
+		driverID, from, to, p.Summary.Orders, p.Summary.DeliveryFees, p.Summary.Commission, p.Summary.DriverShare,
+		p.Summary.CashCollected, p.Summary.Tips, settledBy,
+	).Scan(&p.ID, &p.SettledAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert payout: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE driver_earnings SET payout_id = $1
+		WHERE driver_id = $2 AND payout_id IS NULL
+		  AND created_at >= $3::date AND created_at < ($4::date + 1)`,
+		p.ID, driverID, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("mark earnings settled: %w", err)
+	}
+	after := map[string]interface{}{
+		"payout_id": p.ID, "period_from": from.Format("2006-01-02"), "period_to": to.Format("2006-01-02"),
+		"orders": p.Summary.Orders, "driver_share": p.Summary.DriverShare, "cash_collected": p.Summary.CashCollected, "tips": p.Summary.Tips,
+	}
+	if err := recordAudit(ctx, tx, AuditDriverPayout, AuditTargetDriver, driverID, nil, after); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetDriverPayout loads a payout by ID. Returns nil if not found.
+func GetDriverPayout(ctx context.Context, payoutID int64) (*DriverPayout, error) {
+	var p DriverPayout
+	err := db.Pool.QueryRow(ctx, `
+		SELECT id, driver_id, period_from, period_to, orders_count, delivery_fees, commission, driver_share,
+		       cash_collected, tips, settled_by, settled_at
+		FROM driver_payouts WHERE id = $1`,
+		payoutID,
+	).Scan(&p.ID, &p.DriverID, &p.PeriodFrom, &p.PeriodTo, &p.Summary.Orders, &p.Summary.DeliveryFees, &p.Summary.Commission,
+		&p.Summary.DriverShare, &p.Summary.CashCollected, &p.Summary.Tips, &p.SettledBy, &p.SettledAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// FormatPayoutStatement returns the plain-text statement for a settled payout.
+func FormatPayoutStatement(p *DriverPayout, driverName string) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("🧾 To'lov #%d\n", p.ID))
+	if driverName != "" {
+		b.WriteString("Haydovchi: " + driverName + "\n")
+	}
+	b.WriteString(fmt.Sprintf("Davr: %s — %s\n\n", p.PeriodFrom.Format("2006-01-02"), p.PeriodTo.Format("2006-01-02")))
+	b.WriteString(fmt.Sprintf("Buyurtmalar: %d\n", p.Summary.Orders))
+	b.WriteString(fmt.Sprintf("Yetkazish to'lovlari: %d so'm\n", p.Summary.DeliveryFees))
+	b.WriteString(fmt.Sprintf("Platforma komissiyasi: -%d so'm\n", p.Summary.Commission))
+	b.WriteString(fmt.Sprintf("Haydovchi ulushi: %d so'm\n", p.Summary.DriverShare))
+	b.WriteString(fmt.Sprintf("Mijozdan olingan naqd: %d so'm\n\n", p.Summary.CashCollected))
+	b.WriteString(fmt.Sprintf("💰 To'lanadi: %d so'm\n", p.Summary.DriverShare))
+	if p.Summary.Tips > 0 {
+		// Tips are handed to the driver by the customer (/tip records them), so the platform does not pay them again.
+		b.WriteString(fmt.Sprintf("Choy puli: %d so'm — mijoz to'g'ridan-to'g'ri bergan, to'lovga kirmaydi\n", p.Summary.Tips))
+		b.WriteString(fmt.Sprintf("Jami daromad (choy puli bilan): %d so'm\n", p.Summary.DriverShare+p.Summary.Tips))
+	}
+	b.WriteString("Sana: " + p.SettledAt.Format("2006-01-02 15:04"))
+	return b.String()
+}
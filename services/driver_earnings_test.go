@@ -0,0 +1,73 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalcDriverShare(t *testing.T) {
+	tests := []struct {
+		name           string
+		fee            int64
+		pct            float64
+		wantShare      int64
+		wantCommission int64
+	}{
+		{"ten percent", 15000, 10, 13500, 1500},
+		{"zero commission", 15000, 0, 15000, 0},
+		{"rounds commission", 9999, 12.5, 8749, 1250},
+		{"negative pct treated as zero", 10000, -5, 10000, 0},
+		{"pct above 100 capped", 10000, 150, 0, 10000},
+		{"pickup order has no fee", 0, 10, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			share, commission := CalcDriverShare(tt.fee, tt.pct)
+			if share != tt.wantShare || commission != tt.wantCommission {
+				t.Errorf("CalcDriverShare(%d, %v) = (%d, %d), want (%d, %d)", tt.fee, tt.pct, share, commission, tt.wantShare, tt.wantCommission)
+			}
+			if share+commission != tt.fee && tt.fee > 0 {
+				t.Errorf("share + commission must equal fee")
+			}
+		})
+	}
+}
+
+func TestStartOfWeek(t *testing.T) {
+	// 2026-03-05 is a Thursday.
+	thu := time.Date(2026, 3, 5, 18, 30, 0, 0, time.UTC)
+	want := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	if got := StartOfWeek(thu); !got.Equal(want) {
+		t.Errorf("StartOfWeek(thu) = %v, want %v", got, want)
+	}
+	sun := time.Date(2026, 3, 8, 23, 0, 0, 0, time.UTC)
+	if got := StartOfWeek(sun); !got.Equal(want) {
+		t.Errorf("StartOfWeek(sun) = %v, want %v", got, want)
+	}
+	if got := StartOfWeek(want); !got.Equal(want) {
+		t.Errorf("StartOfWeek(monday) = %v, want %v", got, want)
+	}
+}
+
+func TestFormatPayoutStatement(t *testing.T) {
+	p := &DriverPayout{
+		ID:         7,
+		PeriodFrom: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		PeriodTo:   time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC),
+		Summary:    DriverEarningsSummary{Orders: 3, DeliveryFees: 30000, Commission: 3000, DriverShare: 27000, CashCollected: 150000, Tips: 5000},
+		SettledAt:  time.Date(2026, 3, 8, 10, 0, 0, 0, time.UTC),
+	}
+	s := FormatPayoutStatement(p, "Ali")
+	for _, want := range []string{"#7", "Ali", "2026-03-01", "2026-03-07", "Buyurtmalar: 3", "-3000", "To'lanadi: 27000",
+		"Choy puli: 5000 so'm — mijoz to'g'ridan-to'g'ri bergan, to'lovga kirmaydi", "Jami daromad (choy puli bilan): 32000"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("statement should contain %q:\n%s", want, s)
+		}
+	}
+
+	p.Summary.Tips = 0
+	if s := FormatPayoutStatement(p, "Ali"); strings.Contains(s, "Choy puli") {
+		t.Errorf("statement without tips mentions them:\n%s", s)
+	}
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Reasons stored in driver_status_log.
+const (
+	DriverStatusReasonManual        = "manual"
+	DriverStatusReasonShiftEnd      = "shift_end"
+	DriverStatusReasonStaleLocation = "stale_location"
+)
+
+// DriverShift is a planned working window (driver_shifts row).
+type DriverShift struct {
+	ID       int64
+	DriverID string
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+// DriverOfflineNotice is an online driver to remind or take offline (Reason: shift_end or stale_location).
+type DriverOfflineNotice struct {
+	DriverID string
+	TgUserID int64
+	ChatID   int64
+	Reason   string
+}
+
+// StatusEvent is one online/offline change from driver_status_log.
+type StatusEvent struct {
+	Status string
+	At     time.Time
+}
+
+// DriverOnlineHours is total online time of a driver in a report period.
+type DriverOnlineHours struct {
+	DriverID string
+	TgUserID int64
+	FullName string
+	Online   time.Duration
+}
+
+// ParseShiftRange parses "HH:MM-HH:MM" into the next matching window relative to now (in now's location).
+// An end not after the start rolls over midnight; a window that already ended today is moved to tomorrow.
+func ParseShiftRange(s string, now time.Time) (start, end time.Time, err error) {
+	parts := strings.Split(strings.ReplaceAll(s, " ", ""), "-")
+	if len(parts) != 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("format: HH:MM-HH:MM")
+	}
+	st, err1 := time.Parse("15:04", parts[0])
+	et, err2 := time.Parse("15:04", parts[1])
+	if err1 != nil || err2 != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("format: HH:MM-HH:MM")
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start = day.Add(time.Duration(st.Hour())*time.Hour + time.Duration(st.Minute())*time.Minute)
+	end = day.Add(time.Duration(et.Hour())*time.Hour + time.Duration(et.Minute())*time.Minute)
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	if !end.After(now) {
+		start, end = start.AddDate(0, 0, 1), end.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
+
+// SetDriverShift replaces the driver's current/upcoming shift with [start, end).
+func SetDriverShift(ctx context.Context, driverID string, start, end time.Time) (*DriverShift, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("smena tugashi boshlanishidan keyin bo'lishi kerak")
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `DELETE FROM driver_shifts WHERE driver_id = $1 AND ends_at > now()`, driverID); err != nil {
+		return nil, err
+	}
+	sh := DriverShift{DriverID: driverID, StartsAt: start, EndsAt: end}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO driver_shifts (driver_id, starts_at, ends_at) VALUES ($1, $2, $3) RETURNING id`,
+		driverID, start, end,
+	).Scan(&sh.ID)
+	if err != nil {
+		return nil, fmt.Errorf("insert shift: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &sh, nil
+}
+
+// GetDriverUpcomingShift returns the current or next shift of the driver, or nil if none.
+func GetDriverUpcomingShift(ctx context.Context, driverID string) (*DriverShift, error) {
+	sh := DriverShift{DriverID: driverID}
+	err := db.Pool.QueryRow(ctx, `
+		SELECT id, starts_at, ends_at FROM driver_shifts
+		WHERE driver_id = $1 AND ends_at > now()
+		ORDER BY starts_at LIMIT 1`,
+		driverID,
+	).Scan(&sh.ID, &sh.StartsAt, &sh.EndsAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &sh, nil
+}
+
+// ClaimOfflineReminders returns online drivers that will be taken offline within remindBefore
+// (shift ending soon, or location about to go stale) and marks them reminded so each is notified once.
+// Drivers holding active orders are skipped.
+func ClaimOfflineReminders(ctx context.Context, remindBefore, staleAfter time.Duration) ([]DriverOfflineNotice, error) {
+	var out []DriverOfflineNotice
+	rows, err := db.Pool.Query(ctx, `
+		UPDATE driver_shifts s SET reminded_at = now()
+		FROM drivers d
+		WHERE d.id = s.driver_id AND d.is_online = true AND s.reminded_at IS NULL
+		  AND s.ends_at > now() AND s.ends_at <= now() + make_interval(secs => $1)
+		  AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.driver_id = d.id AND o.status IN ($2, $3, $4))
+		RETURNING d.id, d.tg_user_id, d.chat_id`,
+		remindBefore.Seconds(), OrderStatusAssigned, OrderStatusPickedUp, OrderStatusDelivering,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("shift reminders: %w", err)
+	}
+	if out, err = appendOfflineNotices(out, rows, DriverStatusReasonShiftEnd); err != nil {
+		return nil, err
+	}
+	rows, err = db.Pool.Query(ctx, `
+		UPDATE driver_locations dl SET reminded_at = now()
+		FROM drivers d
+		WHERE d.id = dl.driver_id AND d.is_online = true
+		  AND (dl.reminded_at IS NULL OR dl.reminded_at < d.updated_at)
+		  AND GREATEST(dl.updated_at, d.updated_at) < now() - make_interval(secs => $1)
+		  AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.driver_id = d.id AND o.status IN ($2, $3, $4))
+		RETURNING d.id, d.tg_user_id, d.chat_id`,
+		(staleAfter - remindBefore).Seconds(), OrderStatusAssigned, OrderStatusPickedUp, OrderStatusDelivering,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("stale location reminders: %w", err)
+	}
+	return appendOfflineNotices(out, rows, DriverStatusReasonStaleLocation)
+}
+
+// ListDriversToAutoOffline returns online drivers whose shift ended since they last went online,
+// or whose last location update (or going online, whichever is later) is older than staleAfter. Drivers holding active orders are skipped.
+func ListDriversToAutoOffline(ctx context.Context, staleAfter time.Duration) ([]DriverOfflineNotice, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT d.id, d.tg_user_id, d.chat_id,
+		       CASE WHEN shift_over THEN $5 ELSE $6 END
+		FROM drivers d
+		LEFT JOIN driver_locations dl ON dl.driver_id = d.id
+		CROSS JOIN LATERAL (
+			SELECT EXISTS (
+				SELECT 1 FROM driver_shifts s
+				WHERE s.driver_id = d.id AND s.ends_at <= now() AND s.ends_at > now() - interval '1 day'
+				  AND s.ends_at > COALESCE((SELECT MAX(l.changed_at) FROM driver_status_log l
+				                            WHERE l.driver_id = d.id AND l.status = 'online'), '-infinity'::timestamptz)
+			) AND NOT EXISTS (
+				SELECT 1 FROM driver_shifts s WHERE s.driver_id = d.id AND s.starts_at <= now() AND s.ends_at > now()
+			) AS shift_over
+		) sh
+		WHERE d.is_online = true
+		  AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.driver_id = d.id AND o.status IN ($2, $3, $4))
+		  AND (sh.shift_over OR GREATEST(dl.updated_at, d.updated_at) < now() - make_interval(secs => $1))`,
+		staleAfter.Seconds(), OrderStatusAssigned, OrderStatusPickedUp, OrderStatusDelivering,
+		DriverStatusReasonShiftEnd, DriverStatusReasonStaleLocation,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("auto-offline candidates: %w", err)
+	}
+	defer rows.Close()
+	var out []DriverOfflineNotice
+	for rows.Next() {
+		var n DriverOfflineNotice
+		if err := rows.Scan(&n.DriverID, &n.TgUserID, &n.ChatID, &n.Reason); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+func appendOfflineNotices(out []DriverOfflineNotice, rows pgx.Rows, reason string) ([]DriverOfflineNotice, error) {
+	defer rows.Close()
+	for rows.Next() {
+		n := DriverOfflineNotice{Reason: reason}
+		if err := rows.Scan(&n.DriverID, &n.TgUserID, &n.ChatID); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// OnlineDuration sums time spent online in [from, to). initialOnline is the status before from; events must be sorted by time.
+func OnlineDuration(initialOnline bool, events []StatusEvent, from, to time.Time) time.Duration {
+	var total time.Duration
+	online := initialOnline
+	cursor := from
+	for _, e := range events {
+		at := e.At
+		if at.Before(from) {
+			online = e.Status == DriverStatusOnline
+			continue
+		}
+		if !at.Before(to) {
+			break
+		}
+		if online {
+			total += at.Sub(cursor)
+		}
+		online = e.Status == DriverStatusOnline
+		cursor = at
+	}
+	if online && to.After(cursor) {
+		total += to.Sub(cursor)
+	}
+	return total
+}
+
+// GetDriverOnlineHours returns online time per driver in [from, to) from driver_status_log, longest first.
+// to is capped at now. Drivers with no online time are omitted.
+func GetDriverOnlineHours(ctx context.Context, from, to time.Time) ([]DriverOnlineHours, error) {
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+	type driverState struct {
+		info    DriverOnlineHours
+		initial bool
+		events  []StatusEvent
+	}
+	states := make(map[string]*driverState)
+	rows, err := db.Pool.Query(ctx, `
+		SELECT d.id, d.tg_user_id, COALESCE(d.full_name, ''),
+		       COALESCE((SELECT l.status FROM driver_status_log l
+		                 WHERE l.driver_id = d.id AND l.changed_at < $1
+		                 ORDER BY l.changed_at DESC LIMIT 1), 'offline')
+		FROM drivers d`,
+		from,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("online hours drivers: %w", err)
+	}
+	for rows.Next() {
+		var st driverState
+		var initial string
+		if err := rows.Scan(&st.info.DriverID, &st.info.TgUserID, &st.info.FullName, &initial); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		st.initial = initial == DriverStatusOnline
+		states[st.info.DriverID] = &st
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Pool.Query(ctx, `
+		SELECT driver_id, status, changed_at FROM driver_status_log
+		WHERE changed_at >= $1 AND changed_at < $2
+		ORDER BY driver_id, changed_at`,
+		from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("online hours events: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var driverID string
+		var e StatusEvent
+		if err := rows.Scan(&driverID, &e.Status, &e.At); err != nil {
+			return nil, err
+		}
+		if st := states[driverID]; st != nil {
+			st.events = append(st.events, e)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []DriverOnlineHours
+	for _, st := range states {
+		st.info.Online = OnlineDuration(st.initial, st.events, from, to)
+		if st.info.Online > 0 {
+			out = append(out, st.info)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Online > out[j].Online })
+	return out, nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseShiftRange(t *testing.T) {
+	now := time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC)
+	at := func(day, h, m int) time.Time { return time.Date(2026, 3, day, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		name      string
+		in        string
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{"current shift", "09:00-18:00", at(5, 9, 0), at(5, 18, 0), false},
+		{"later today", "14:00-22:30", at(5, 14, 0), at(5, 22, 30), false},
+		{"over midnight", "20:00-04:00", at(5, 20, 0), at(6, 4, 0), false},
+		{"already ended moves to tomorrow", "06:00-09:30", at(6, 6, 0), at(6, 9, 30), false},
+		{"spaces allowed", " 09:00 - 18:00 ", at(5, 9, 0), at(5, 18, 0), false},
+		{"missing end", "09:00", time.Time{}, time.Time{}, true},
+		{"bad hour", "25:00-18:00", time.Time{}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := ParseShiftRange(tt.in, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseShiftRange(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("ParseShiftRange(%q) = %v — %v, want %v — %v", tt.in, start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestOnlineDuration(t *testing.T) {
+	from := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	h := func(n float64) time.Time { return from.Add(time.Duration(n * float64(time.Hour))) }
+	tests := []struct {
+		name    string
+		initial bool
+		events  []StatusEvent
+		want    time.Duration
+	}{
+		{"no events offline", false, nil, 0},
+		{"no events online all day", true, nil, 24 * time.Hour},
+		{"one session", false, []StatusEvent{{DriverStatusOnline, h(9)}, {DriverStatusOffline, h(17)}}, 8 * time.Hour},
+		{"online from before period", true, []StatusEvent{{DriverStatusOffline, h(2)}}, 2 * time.Hour},
+		{"still online at end", false, []StatusEvent{{DriverStatusOnline, h(20)}}, 4 * time.Hour},
+		{"two sessions", false, []StatusEvent{
+			{DriverStatusOnline, h(8)}, {DriverStatusOffline, h(12)},
+			{DriverStatusOnline, h(13)}, {DriverStatusOffline, h(15.5)},
+		}, 6*time.Hour + 30*time.Minute},
+		{"events outside period ignored", false, []StatusEvent{
+			{DriverStatusOnline, from.Add(-time.Hour)}, {DriverStatusOffline, h(1)},
+			{DriverStatusOnline, h(23)}, {DriverStatusOffline, to.Add(time.Hour)},
+		}, 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OnlineDuration(tt.initial, tt.events, from, to); got != tt.want {
+				t.Errorf("OnlineDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
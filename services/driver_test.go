@@ -2,6 +2,8 @@ package services
 
 import (
 	"testing"
+
+	"food-telegram/models"
 )
 
 func TestAcceptOrderRaceCondition(t *testing.T) {
@@ -63,3 +65,31 @@ func TestDriverFlowIntegration(t *testing.T) {
 	// - Assert the successful driver can complete delivery (assigned -> completed)
 	// - Assert admin cannot complete assigned order
 }
+
+func TestCanTakeAnotherOrder(t *testing.T) {
+	assigned := models.Order{ID: 1, LocationID: 10, Status: OrderStatusAssigned}
+	picked := models.Order{ID: 2, LocationID: 10, Status: OrderStatusPickedUp}
+	delivering := models.Order{ID: 3, LocationID: 10, Status: OrderStatusDelivering}
+	tests := []struct {
+		name      string
+		active    []models.Order
+		location  int64
+		maxActive int
+		want      error
+	}{
+		{"no active orders", nil, 20, 2, nil},
+		{"same branch within max", []models.Order{assigned}, 10, 2, nil},
+		{"same branch picked up", []models.Order{picked}, 10, 3, nil},
+		{"at capacity", []models.Order{assigned, picked}, 10, 2, ErrDriverAtCapacity},
+		{"zero max means single order", []models.Order{assigned}, 10, 0, ErrDriverAtCapacity},
+		{"other branch", []models.Order{assigned}, 20, 2, ErrBatchOtherBranch},
+		{"already delivering", []models.Order{delivering}, 10, 2, ErrBatchAlreadyDelivering},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanTakeAnotherOrder(tt.active, tt.location, tt.maxActive); got != tt.want {
+				t.Errorf("CanTakeAnotherOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Driver verification states (drivers.verification_status).
+const (
+	DriverVerificationPendingDocuments = "pending_documents"
+	DriverVerificationPending          = "pending_verification"
+	DriverVerificationVerified         = "verified"
+	DriverVerificationRejected         = "rejected"
+)
+
+// Driver document types (driver_documents.doc_type).
+const (
+	DriverDocLicense      = "license"
+	DriverDocTechPassport = "tech_passport"
+	DriverDocSelfie       = "selfie"
+)
+
+// DriverStatusReasonDocumentsExpired is logged when a driver is taken offline for expired documents.
+const DriverStatusReasonDocumentsExpired = "documents_expired"
+
+// DriverRequiredDocuments lists the documents in the order the driver bot asks for them.
+var DriverRequiredDocuments = []string{DriverDocLicense, DriverDocTechPassport, DriverDocSelfie}
+
+// ErrDriverNotVerified is returned when an unverified driver tries to go online or take an order.
+var ErrDriverNotVerified = errors.New("hujjatlaringiz hali tasdiqlanmagan")
+
+// DriverDocument is one uploaded document (driver_documents row).
+type DriverDocument struct {
+	DriverID   string
+	DocType    string
+	FileID     string
+	ExpiresAt  *time.Time
+	UploadedAt time.Time
+}
+
+// DriverDocumentHasExpiry reports whether the document type carries an expiry date (license, tech passport).
+func DriverDocumentHasExpiry(docType string) bool {
+	return docType == DriverDocLicense || docType == DriverDocTechPassport
+}
+
+// ParseDocumentExpiry parses an expiry date (YYYY-MM-DD or DD.MM.YYYY) in now's location. The date must be after today.
+func ParseDocumentExpiry(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	var t time.Time
+	var err error
+	for _, layout := range []string{"2006-01-02", "02.01.2006"} {
+		if t, err = time.ParseInLocation(layout, s, now.Location()); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("sana formati: YYYY-MM-DD yoki DD.MM.YYYY")
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if !t.After(today) {
+		return time.Time{}, fmt.Errorf("hujjat muddati o'tgan")
+	}
+	return t, nil
+}
+
+// MissingDriverDocuments returns required document types that are not uploaded or already expired at now.
+func MissingDriverDocuments(docs []DriverDocument, now time.Time) []string {
+	have := make(map[string]bool)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, d := range docs {
+		if d.FileID == "" {
+			continue
+		}
+		if d.ExpiresAt != nil && !d.ExpiresAt.After(today) {
+			continue
+		}
+		have[d.DocType] = true
+	}
+	var missing []string
+	for _, t := range DriverRequiredDocuments {
+		if !have[t] {
+			missing = append(missing, t)
+		}
+	}
+	return missing
+}
+
+// SaveDriverDocument stores (or replaces) a document of the driver.
+func SaveDriverDocument(ctx context.Context, driverID, docType, fileID string, expiresAt *time.Time) error {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO driver_documents (driver_id, doc_type, file_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (driver_id, doc_type) DO UPDATE SET file_id = EXCLUDED.file_id, expires_at = EXCLUDED.expires_at, uploaded_at = now()`,
+		driverID, docType, fileID, expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("save driver document: %w", err)
+	}
+	return nil
+}
+
+// GetDriverDocuments returns the driver's uploaded documents in DriverRequiredDocuments order.
+func GetDriverDocuments(ctx context.Context, driverID string) ([]DriverDocument, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT driver_id, doc_type, file_id, expires_at, uploaded_at
+		FROM driver_documents WHERE driver_id = $1
+		ORDER BY array_position(ARRAY['license', 'tech_passport', 'selfie'], doc_type)`,
+		driverID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []DriverDocument
+	for rows.Next() {
+		var d DriverDocument
+		if err := rows.Scan(&d.DriverID, &d.DocType, &d.FileID, &d.ExpiresAt, &d.UploadedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// SubmitDriverVerification sends the driver's documents to the superadmin review queue.
+// All required documents must be uploaded and valid; an already verified or pending driver cannot resubmit.
+func SubmitDriverVerification(ctx context.Context, driverID string) error {
+	docs, err := GetDriverDocuments(ctx, driverID)
+	if err != nil {
+		return err
+	}
+	if missing := MissingDriverDocuments(docs, time.Now()); len(missing) > 0 {
+		return fmt.Errorf("hujjatlar to'liq emas: %s", strings.Join(missing, ", "))
+	}
+	res, err := db.Pool.Exec(ctx, `
+		UPDATE drivers SET verification_status = $1, verification_reject_reason = NULL, updated_at = now()
+		WHERE id = $2 AND verification_status IN ($3, $4)`,
+		DriverVerificationPending, driverID, DriverVerificationPendingDocuments, DriverVerificationRejected,
+	)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("hujjatlar allaqachon tekshiruvda yoki tasdiqlangan")
+	}
+	return nil
+}
+
+// ListDriversPendingVerification returns drivers waiting for document review, oldest first.
+func ListDriversPendingVerification(ctx context.Context, limit int) ([]Driver, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, tg_user_id, chat_id,
+		       COALESCE(full_name, ''), COALESCE(phone, ''), COALESCE(car_plate, ''),
+		       COALESCE(car_model, ''), COALESCE(car_color, ''),
+		       status, COALESCE(is_online, false), verification_status, COALESCE(verification_reject_reason, '')
+		FROM drivers WHERE verification_status = $1
+		ORDER BY updated_at
+		LIMIT $2`,
+		DriverVerificationPending, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []Driver
+	for rows.Next() {
+		var d Driver
+		if err := rows.Scan(&d.ID, &d.TgUserID, &d.ChatID, &d.FullName, &d.Phone, &d.CarPlate, &d.CarModel, &d.CarColor,
+			&d.Status, &d.IsOnline, &d.VerificationStatus, &d.VerificationRejectReason); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// ApproveDriverVerification marks a pending driver verified.
+func ApproveDriverVerification(ctx context.Context, driverID string, superadminTgID int64) error {
+	res, err := db.Pool.Exec(ctx, `
+		UPDATE drivers SET verification_status = $1, verified_by = $2, verified_at = now(),
+		       verification_reject_reason = NULL, review_in_progress_by = NULL, updated_at = now()
+		WHERE id = $3 AND verification_status = $4`,
+		DriverVerificationVerified, superadminTgID, driverID, DriverVerificationPending,
+	)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("driver not found or not pending verification")
+	}
+	return recordAudit(ctx, db.Pool, AuditDriverVerifyApprove, AuditTargetDriver, driverID,
+		map[string]interface{}{"verification_status": DriverVerificationPending},
+		map[string]interface{}{"verification_status": DriverVerificationVerified})
+}
+
+// RejectDriverVerification marks a pending driver rejected with reason; the driver may upload documents again.
+func RejectDriverVerification(ctx context.Context, driverID string, superadminTgID int64, reason string) error {
+	res, err := db.Pool.Exec(ctx, `
+		UPDATE drivers SET verification_status = $1, verified_by = $2, verified_at = NULL,
+		       verification_reject_reason = $3, review_in_progress_by = NULL, updated_at = now()
+		WHERE id = $4 AND verification_status = $5`,
+		DriverVerificationRejected, superadminTgID, reason, driverID, DriverVerificationPending,
+	)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("driver not found or not pending verification")
+	}
+	return recordAudit(ctx, db.Pool, AuditDriverVerifyReject, AuditTargetDriver, driverID,
+		map[string]interface{}{"verification_status": DriverVerificationPending},
+		map[string]interface{}{"verification_status": DriverVerificationRejected, "reason": reason})
+}
+
+// SetDriverReviewRejectInProgress marks that the superadmin's next message is the reject reason for this driver (restart-safe).
+// Returns true if the driver is still pending verification.
+func SetDriverReviewRejectInProgress(ctx context.Context, driverID string, superadminTgID int64) (bool, error) {
+	if _, err := db.Pool.Exec(ctx, `UPDATE drivers SET review_in_progress_by = NULL WHERE review_in_progress_by = $1`, superadminTgID); err != nil {
+		return false, err
+	}
+	res, err := db.Pool.Exec(ctx, `
+		UPDATE drivers SET review_in_progress_by = $2 WHERE id = $1 AND verification_status = $3`,
+		driverID, superadminTgID, DriverVerificationPending,
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected() == 1, nil
+}
+
+// GetDriverIDByReviewRejectInProgressBy returns the driver ID for which this superadmin is typing a reject reason, or "" if none.
+func GetDriverIDByReviewRejectInProgressBy(ctx context.Context, superadminTgID int64) (string, error) {
+	var id string
+	err := db.Pool.QueryRow(ctx, `SELECT id::text FROM drivers WHERE review_in_progress_by = $1 LIMIT 1`, superadminTgID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+// ClearDriverReviewRejectInProgress abandons the reject-reason flow of the superadmin.
+func ClearDriverReviewRejectInProgress(ctx context.Context, superadminTgID int64) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE drivers SET review_in_progress_by = NULL WHERE review_in_progress_by = $1`, superadminTgID)
+	return err
+}
+
+// ExpireDriverVerifications sends verified drivers with an expired document back to pending_documents and takes them offline.
+// Drivers holding active orders are left until they finish. Each expiry is audited as a system action in the same
+// transaction. Returns the drivers that must re-verify.
+func ExpireDriverVerifications(ctx context.Context) ([]Driver, error) {
+	// The background job acts on its own, whoever ctx would attribute the change to.
+	ctx = context.WithValue(ctx, auditActorKey{}, auditActor{Role: AuditRoleSystem})
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+		UPDATE drivers d SET verification_status = $1, updated_at = now()
+		WHERE d.verification_status = $2
+		  AND EXISTS (SELECT 1 FROM driver_documents dd WHERE dd.driver_id = d.id AND dd.expires_at <= current_date)
+		  AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.driver_id = d.id AND o.status IN ($3, $4, $5))
+		RETURNING d.id, d.tg_user_id, d.chat_id, d.status`,
+		DriverVerificationPendingDocuments, DriverVerificationVerified,
+		OrderStatusAssigned, OrderStatusPickedUp, OrderStatusDelivering,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("expire driver verifications: %w", err)
+	}
+	var list []Driver
+	for rows.Next() {
+		d := Driver{VerificationStatus: DriverVerificationPendingDocuments}
+		if err := rows.Scan(&d.ID, &d.TgUserID, &d.ChatID, &d.Status); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range list {
+		before := map[string]interface{}{"verification_status": DriverVerificationVerified, "status": list[i].Status}
+		if list[i].Status == DriverStatusOnline {
+			if err := setDriverStatusTx(ctx, tx, list[i].ID, DriverStatusOffline, DriverStatusReasonDocumentsExpired); err != nil {
+				return nil, err
+			}
+			list[i].Status = DriverStatusOffline
+		}
+		if err := recordAudit(ctx, tx, AuditDriverVerifyExpire, AuditTargetDriver, list[i].ID, before,
+			map[string]interface{}{"verification_status": DriverVerificationPendingDocuments, "status": list[i].Status}); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
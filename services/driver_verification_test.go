@@ -0,0 +1,69 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDocumentExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"iso date", "2028-06-30", time.Date(2028, 6, 30, 0, 0, 0, 0, time.UTC), false},
+		{"dotted date", "30.06.2028", time.Date(2028, 6, 30, 0, 0, 0, 0, time.UTC), false},
+		{"tomorrow is valid", "2026-03-06", time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC), false},
+		{"today is expired", "2026-03-05", time.Time{}, true},
+		{"past date", "2025-01-01", time.Time{}, true},
+		{"garbage", "next year", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDocumentExpiry(tt.in, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDocumentExpiry(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("ParseDocumentExpiry(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingDriverDocuments(t *testing.T) {
+	now := time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC)
+	valid := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	expired := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		docs []DriverDocument
+		want []string
+	}{
+		{"nothing uploaded", nil, []string{DriverDocLicense, DriverDocTechPassport, DriverDocSelfie}},
+		{"complete", []DriverDocument{
+			{DocType: DriverDocLicense, FileID: "a", ExpiresAt: &valid},
+			{DocType: DriverDocTechPassport, FileID: "b", ExpiresAt: &valid},
+			{DocType: DriverDocSelfie, FileID: "c"},
+		}, nil},
+		{"expired license", []DriverDocument{
+			{DocType: DriverDocLicense, FileID: "a", ExpiresAt: &expired},
+			{DocType: DriverDocTechPassport, FileID: "b", ExpiresAt: &valid},
+			{DocType: DriverDocSelfie, FileID: "c"},
+		}, []string{DriverDocLicense}},
+		{"selfie missing", []DriverDocument{
+			{DocType: DriverDocLicense, FileID: "a", ExpiresAt: &valid},
+			{DocType: DriverDocTechPassport, FileID: "b", ExpiresAt: &valid},
+		}, []string{DriverDocSelfie}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MissingDriverDocuments(tt.docs, now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MissingDriverDocuments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"food-telegram/db"
+	"food-telegram/models"
+)
+
+const (
+	// DefaultPrepMinutes is used when a branch has no completed new→ready history yet.
+	DefaultPrepMinutes = 15.0
+	// MinRemainingPrepMinutes keeps the estimate from hitting zero while the kitchen is still working.
+	MinRemainingPrepMinutes = 2.0
+	// DriverPickupBufferMinutes covers finding a driver and handing over the order.
+	DriverPickupBufferMinutes = 5.0
+	// prepHistorySampleSize is how many recent orders are averaged for prep time.
+	prepHistorySampleSize = 50
+)
+
+// ETAInput holds everything needed to estimate minutes until the customer gets the order.
+// Distances < 0 mean unknown.
+type ETAInput struct {
+	Status             string
+	Pickup             bool
+	AvgPrepMin         float64 // branch average new→ready
+	ElapsedMin         float64 // minutes since order was created
+	BranchToCustomerKm float64
+	DriverToBranchKm   float64
+	DriverToCustomerKm float64
+	SpeedKmh           float64
+}
+
+// TravelMinutes returns driving time for distanceKm at speedKmh (0 if either is not positive).
+func TravelMinutes(distanceKm, speedKmh float64) float64 {
+	if distanceKm <= 0 || speedKmh <= 0 {
+		return 0
+	}
+	return distanceKm / speedKmh * 60
+}
+
+// EstimateETAMinutes returns estimated minutes until delivery (or until ready for pickup orders).
+// Returns 0 when no ETA applies (completed, rejected, pickup order already ready).
+func EstimateETAMinutes(in ETAInput) int {
+	avgPrep := in.AvgPrepMin
+	if avgPrep <= 0 {
+		avgPrep = DefaultPrepMinutes
+	}
+	remainingPrep := math.Max(avgPrep-in.ElapsedMin, MinRemainingPrepMinutes)
+	toCustomer := TravelMinutes(in.BranchToCustomerKm, in.SpeedKmh)
+
+	var eta float64
+	switch in.Status {
+	case OrderStatusNew, OrderStatusPreparing:
+		eta = remainingPrep
+		if !in.Pickup {
+			eta += DriverPickupBufferMinutes + toCustomer
+		}
+	case OrderStatusReady:
+		if in.Pickup {
+			return 0
+		}
+		eta = DriverPickupBufferMinutes + toCustomer
+	case OrderStatusAssigned:
+		toBranch := DriverPickupBufferMinutes
+		if in.DriverToBranchKm >= 0 {
+			toBranch = TravelMinutes(in.DriverToBranchKm, in.SpeedKmh)
+		}
+		eta = toBranch + toCustomer
+	case OrderStatusPickedUp, OrderStatusDelivering:
+		if in.DriverToCustomerKm >= 0 {
+			eta = TravelMinutes(in.DriverToCustomerKm, in.SpeedKmh)
+		} else {
+			eta = toCustomer
+		}
+	default:
+		return 0
+	}
+	return int(math.Max(1, math.Ceil(eta)))
+}
+
+// FormatETA returns the customer-facing ETA line, or "" when minutes is 0.
+func FormatETA(minutes int) string {
+	if minutes <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("⏱ Taxminiy vaqt: ~%d daqiqa", minutes)
+}
+
+// GetBranchAvgPrepMinutes returns the average new→ready time (minutes) over recent orders of a branch.
+// Returns 0 when the branch has no history yet.
+func GetBranchAvgPrepMinutes(ctx context.Context, locationID int64) (float64, error) {
+	var avg float64
+	err := db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(AVG(EXTRACT(EPOCH FROM (t.ready_at - t.created_at)) / 60), 0)
+		FROM (
+			SELECT o.created_at, MIN(h.created_at) AS ready_at
+			FROM orders o
+			JOIN order_status_history h ON h.order_id = o.id AND h.to_status = $2
+			WHERE o.location_id = $1 AND o.created_at > now() - interval '30 days'
+			GROUP BY o.id, o.created_at
+			ORDER BY o.created_at DESC
+			LIMIT $3
+		) t`,
+		locationID, OrderStatusReady, prepHistorySampleSize,
+	).Scan(&avg)
+	if err != nil {
+		return 0, fmt.Errorf("avg prep time: %w", err)
+	}
+	return avg, nil
+}
+
+// GetOrderETAMinutes loads branch history, coordinates and driver location and returns the ETA for the order.
+// Returns 0 when no ETA applies or data is missing.
+func GetOrderETAMinutes(ctx context.Context, o *models.Order, speedKmh float64) (int, error) {
+	if o == nil {
+		return 0, nil
+	}
+	in := ETAInput{
+		Status:             o.Status,
+		Pickup:             o.DeliveryType != nil && *o.DeliveryType == "pickup",
+		BranchToCustomerKm: o.DistanceKm,
+		DriverToBranchKm:   -1,
+		DriverToCustomerKm: -1,
+		SpeedKmh:           speedKmh,
+	}
+	switch o.Status {
+	case OrderStatusCompleted, OrderStatusRejected:
+		return 0, nil
+	}
+
+	avg, err := GetBranchAvgPrepMinutes(ctx, o.LocationID)
+	if err != nil {
+		return 0, err
+	}
+	in.AvgPrepMin = avg
+	if err := db.Pool.QueryRow(ctx, `SELECT EXTRACT(EPOCH FROM (now() - created_at)) / 60 FROM orders WHERE id = $1`, o.ID).Scan(&in.ElapsedMin); err != nil {
+		return 0, fmt.Errorf("order age: %w", err)
+	}
+
+	if o.DriverID != nil && *o.DriverID != "" {
+		loc, err := GetDriverLocation(ctx, *o.DriverID)
+		if err != nil {
+			return 0, err
+		}
+		if loc != nil {
+			if o.Status == OrderStatusAssigned {
+				if branch, err := GetLocationByID(ctx, o.LocationID); err == nil && branch != nil {
+					in.DriverToBranchKm = HaversineDistanceKm(loc.Lat, loc.Lon, branch.Lat, branch.Lon)
+				}
+			} else {
+				lat, lon, err := GetOrderCoordinates(ctx, o.ID)
+				if err == nil && (lat != 0 || lon != 0) {
+					in.DriverToCustomerKm = HaversineDistanceKm(loc.Lat, loc.Lon, lat, lon)
+				}
+			}
+		}
+	}
+	return EstimateETAMinutes(in), nil
+}
@@ -0,0 +1,40 @@
+package services
+
+import "testing"
+
+func TestEstimateETAMinutes(t *testing.T) {
+	tests := []struct {
+		name string
+		in   ETAInput
+		want int
+	}{
+		{"new delivery uses full prep + buffer + travel", ETAInput{Status: OrderStatusNew, AvgPrepMin: 20, BranchToCustomerKm: 5, DriverToBranchKm: -1, DriverToCustomerKm: -1, SpeedKmh: 30}, 35},
+		{"no history falls back to default prep", ETAInput{Status: OrderStatusPreparing, Pickup: true, SpeedKmh: 30}, 15},
+		{"elapsed prep keeps minimum", ETAInput{Status: OrderStatusPreparing, Pickup: true, AvgPrepMin: 10, ElapsedMin: 30, SpeedKmh: 30}, 2},
+		{"pickup ready has no eta", ETAInput{Status: OrderStatusReady, Pickup: true, SpeedKmh: 30}, 0},
+		{"ready delivery waits for driver", ETAInput{Status: OrderStatusReady, BranchToCustomerKm: 3, SpeedKmh: 30}, 11},
+		{"assigned uses driver to branch", ETAInput{Status: OrderStatusAssigned, BranchToCustomerKm: 3, DriverToBranchKm: 2, DriverToCustomerKm: -1, SpeedKmh: 30}, 10},
+		{"assigned without driver location uses buffer", ETAInput{Status: OrderStatusAssigned, BranchToCustomerKm: 3, DriverToBranchKm: -1, DriverToCustomerKm: -1, SpeedKmh: 30}, 11},
+		{"delivering uses driver to customer", ETAInput{Status: OrderStatusDelivering, BranchToCustomerKm: 10, DriverToBranchKm: -1, DriverToCustomerKm: 1, SpeedKmh: 30}, 2},
+		{"delivering without location uses branch distance", ETAInput{Status: OrderStatusPickedUp, BranchToCustomerKm: 10, DriverToBranchKm: -1, DriverToCustomerKm: -1, SpeedKmh: 30}, 20},
+		{"arrived driver still shows one minute", ETAInput{Status: OrderStatusDelivering, DriverToBranchKm: -1, DriverToCustomerKm: 0, SpeedKmh: 30}, 1},
+		{"completed has no eta", ETAInput{Status: OrderStatusCompleted, SpeedKmh: 30}, 0},
+		{"rejected has no eta", ETAInput{Status: OrderStatusRejected, SpeedKmh: 30}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateETAMinutes(tt.in); got != tt.want {
+				t.Errorf("EstimateETAMinutes() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatETA(t *testing.T) {
+	if FormatETA(0) != "" {
+		t.Error("zero minutes should produce no ETA line")
+	}
+	if got := FormatETA(12); got != "⏱ Taxminiy vaqt: ~12 daqiqa" {
+		t.Errorf("FormatETA(12) = %q", got)
+	}
+}
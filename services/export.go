@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"food-telegram/db"
+	"food-telegram/export"
+)
+
+// Export kinds: what a spreadsheet lists.
+const (
+	ExportOrders     = "orders"     // one row per order
+	ExportOrderItems = "items"      // one row per order line
+	ExportDeliveries = "deliveries" // one row per completed driver delivery (driver_earnings)
+	ExportPayments   = "payments"   // subscription payment receipts
+)
+
+// ExportKinds lists the kinds in the order they are offered.
+var ExportKinds = []string{ExportOrders, ExportOrderItems, ExportDeliveries, ExportPayments}
+
+// ExportMaxRows caps one file; a longer range has to be split.
+const ExportMaxRows = 100000
+
+// ExportRequest is one export: kind and format, orders placed (or deliveries, payments made) in [From, To), for
+// a branch (0 = every branch).
+type ExportRequest struct {
+	Kind       string
+	Format     string
+	From       time.Time
+	To         time.Time
+	LocationID int64
+}
+
+// FileName is "<kind>_<from>_<to>[_loc<id>].<format>" with the inclusive last day.
+func (r ExportRequest) FileName() string {
+	name := fmt.Sprintf("%s_%s_%s", r.Kind, r.From.Format("2006-01-02"), r.To.AddDate(0, 0, -1).Format("2006-01-02"))
+	if r.LocationID != 0 {
+		name += fmt.Sprintf("_loc%d", r.LocationID)
+	}
+	return name + "." + r.Format
+}
+
+// ParseExportArgs reads "<kind> [csv|xlsx] [from YYYY-MM-DD] [to YYYY-MM-DD] [location_id]"; the format defaults
+// to xlsx and the range to the last ReportDefaultDays days.
+func ParseExportArgs(fields []string, now time.Time) (ExportRequest, error) {
+	var req ExportRequest
+	if len(fields) == 0 {
+		return req, ErrReportArgs
+	}
+	req.Kind = fields[0]
+	known := false
+	for _, k := range ExportKinds {
+		known = known || k == req.Kind
+	}
+	if !known {
+		return req, ErrReportArgs
+	}
+	fields = fields[1:]
+	req.Format = export.FormatXLSX
+	if len(fields) > 0 && (fields[0] == export.FormatCSV || fields[0] == export.FormatXLSX) {
+		req.Format, fields = fields[0], fields[1:]
+	}
+	var err error
+	req.From, req.To, req.LocationID, err = ParseReportRange(fields, now)
+	return req, err
+}
+
+// ExportTable is the content of an export file.
+type ExportTable struct {
+	Header []string
+	Rows   [][]any
+}
+
+var errExportTooLarge = fmt.Errorf("%d qatordan ko'p: davrni qisqartiring", ExportMaxRows)
+
+// BuildExport loads the rows of the export. Times are in BranchTimezone, money in sum.
+func BuildExport(ctx context.Context, req ExportRequest) (*ExportTable, error) {
+	args := []any{req.LocationID, req.From, req.To, ExportMaxRows + 1}
+	var t ExportTable
+	var sql string
+	switch req.Kind {
+	case ExportOrders:
+		t.Header = []string{"order_id", "created_at", "location_id", "location", "status", "delivery_type", "phone",
+			"items_total", "delivery_fee", "grand_total", "distance_km", "driver", "delivery_fee_overridden"}
+		sql = `
+			SELECT o.id, o.created_at, COALESCE(o.location_id, 0), COALESCE(l.name, ''), o.status, COALESCE(o.delivery_type, ''),
+			       COALESCE(o.phone, ''), o.items_total, COALESCE(o.delivery_fee, 0), o.grand_total, COALESCE(o.distance_km, 0)::float8,
+			       COALESCE(d.full_name, ''), COALESCE(o.delivery_fee_overridden, false)
+			FROM orders o
+			LEFT JOIN locations l ON l.id = o.location_id
+			LEFT JOIN drivers d ON d.id = o.driver_id
+			WHERE ` + reportScope("o.") + `
+			ORDER BY o.created_at, o.id LIMIT $4`
+	case ExportOrderItems:
+		t.Header = []string{"order_id", "created_at", "location_id", "location", "order_status", "menu_item_id", "category",
+			"name", "price", "qty", "line_total"}
+		sql = `
+			SELECT o.id, o.created_at, COALESCE(o.location_id, 0), COALESCE(l.name, ''), o.status, COALESCE(i.menu_item_id, 0),
+			       i.category, i.name, i.price, i.qty, i.price * i.qty
+			FROM order_items i
+			JOIN orders o ON o.id = i.order_id
+			LEFT JOIN locations l ON l.id = o.location_id
+			WHERE ` + reportScope("o.") + `
+			ORDER BY o.created_at, o.id, i.id LIMIT $4`
+	case ExportDeliveries:
+		t.Header = []string{"order_id", "completed_at", "location_id", "location", "driver_tg_id", "driver", "delivery_fee",
+			"commission_pct", "commission", "driver_share", "cash_collected", "tip", "payout_id", "handover_id"}
+		sql = `
+			SELECT e.order_id, e.created_at, COALESCE(e.location_id, 0), COALESCE(l.name, ''), d.tg_user_id, COALESCE(d.full_name, ''),
+			       e.delivery_fee, e.commission_pct::float8, e.commission, e.driver_share, e.cash_collected, e.tip,
+			       COALESCE(e.payout_id, 0), COALESCE(e.handover_id, 0)
+			FROM driver_earnings e
+			JOIN drivers d ON d.id = e.driver_id
+			LEFT JOIN locations l ON l.id = e.location_id
+			WHERE ` + reportScope("e.") + `
+			ORDER BY e.created_at, e.id LIMIT $4`
+	case ExportPayments:
+		// Branch subscriptions are paid by the branch's staff accounts; driver subscriptions only show platform-wide
+		t.Header = []string{"receipt_id", "paid_at", "tg_user_id", "role", "locations", "amount", "currency", "note", "approved_by"}
+		sql = `
+			SELECT p.id::text, p.paid_at, p.tg_user_id, p.role,
+			       COALESCE((SELECT string_agg(DISTINCT l.name, ', ')
+			                 FROM branch_admins ba JOIN locations l ON l.id = ba.branch_location_id
+			                 WHERE ba.admin_user_id = p.tg_user_id AND p.role = 'restaurant_admin'), ''),
+			       p.amount::float8, p.currency, COALESCE(p.note, ''), p.approved_by
+			FROM payment_receipts p
+			WHERE p.paid_at >= $2 AND p.paid_at < $3
+			  AND ($1::bigint = 0 OR (p.role = 'restaurant_admin' AND p.tg_user_id IN (
+			       SELECT admin_user_id FROM branch_admins WHERE branch_location_id = $1)))
+			ORDER BY p.paid_at, p.id LIMIT $4`
+	default:
+		return nil, fmt.Errorf("unknown export kind %q", req.Kind)
+	}
+	rows, err := db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, err
+		}
+		if len(t.Rows) == ExportMaxRows {
+			return nil, errExportTooLarge
+		}
+		t.Rows = append(t.Rows, exportCells(values))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// exportCells turns scanned values into export cells: times in BranchTimezone, booleans as yes/no, small integer
+// types widened.
+func exportCells(values []any) []any {
+	cells := make([]any, len(values))
+	for i, v := range values {
+		switch x := v.(type) {
+		case time.Time:
+			cells[i] = x.In(BranchTimezone)
+		case int32:
+			cells[i] = int64(x)
+		case int16:
+			cells[i] = int64(x)
+		case bool:
+			if x {
+				cells[i] = "yes"
+			} else {
+				cells[i] = "no"
+			}
+		default:
+			cells[i] = x
+		}
+	}
+	return cells
+}
+
+// ExportFile builds the export and encodes it; the sheet is named after the kind.
+func ExportFile(ctx context.Context, req ExportRequest) (name string, data []byte, rows int, err error) {
+	t, err := BuildExport(ctx, req)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	data, err = export.Encode(req.Format, req.Kind, t.Header, t.Rows)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	return req.FileName(), data, len(t.Rows), nil
+}
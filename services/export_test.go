@@ -0,0 +1,36 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseExportArgs(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, BranchTimezone)
+	req, err := ParseExportArgs([]string{"items", "csv", "2024-03-01", "2024-03-05", "3"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Kind != ExportOrderItems || req.Format != "csv" || req.LocationID != 3 ||
+		!req.From.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, BranchTimezone)) || !req.To.Equal(time.Date(2024, 3, 6, 0, 0, 0, 0, BranchTimezone)) {
+		t.Errorf("req = %+v", req)
+	}
+	if got := req.FileName(); got != "items_2024-03-01_2024-03-05_loc3.csv" {
+		t.Errorf("FileName = %q", got)
+	}
+
+	req, err = ParseExportArgs([]string{"orders"}, now)
+	if err != nil || req.Format != "xlsx" || req.FileName() != "orders_2024-03-04_2024-03-10.xlsx" {
+		t.Errorf("defaults = %+v, %v", req, err)
+	}
+
+	for _, bad := range [][]string{nil, {"menu"}, {"orders", "pdf"}, {"payments", "xlsx", "2024-03-05", "2024-03-01"}} {
+		if _, err := ParseExportArgs(bad, now); err == nil {
+			t.Errorf("ParseExportArgs(%q) accepted", bad)
+		}
+	}
+	if _, err := ParseExportArgs([]string{"orders", "pdf"}, now); !errors.Is(err, ErrReportArgs) {
+		t.Errorf("unknown format err = %v, want ErrReportArgs", err)
+	}
+}
@@ -3,7 +3,9 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sort"
+	"strconv"
 
 	"food-telegram/db"
 	"food-telegram/models"
@@ -13,57 +15,56 @@ import (
 
 // AddLocation inserts a new fast food location (branch) with coordinates.
 func AddLocation(ctx context.Context, name string, lat, lon float64) (int64, error) {
+	return addLocation(ctx, defaultStore(), name, lat, lon)
+}
+
+func addLocation(ctx context.Context, st Store, name string, lat, lon float64) (int64, error) {
 	var id int64
-	err := db.Pool.QueryRow(ctx, `
-		INSERT INTO locations (name, lat, lon)
-		VALUES ($1, $2, $3)
-		RETURNING id`,
-		name, lat, lon,
-	).Scan(&id)
-	return id, err
+	err := st.InTx(ctx, func(tx Store) error {
+		var err error
+		id, err = tx.Locations().Add(ctx, name, lat, lon)
+		if err != nil {
+			return err
+		}
+		after := map[string]interface{}{"name": name, "lat": lat, "lon": lon}
+		return tx.Audit(ctx, AuditLocationCreate, AuditTargetLocation, strconv.FormatInt(id, 10), nil, after)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
 }
 
-// GetLocationName returns the name of a location by ID, or empty string if not found.
+// GetLocationName returns the name of a location by ID, or pgx.ErrNoRows if not found.
 func GetLocationName(ctx context.Context, id int64) (string, error) {
-	var name string
-	err := db.Pool.QueryRow(ctx, `SELECT name FROM locations WHERE id = $1`, id).Scan(&name)
+	l, err := GetLocationByID(ctx, id)
 	if err != nil {
 		return "", err
 	}
-	return name, nil
+	return l.Name, nil
 }
 
-// GetLocationByID returns a location by ID with coordinates, or nil if not found.
+// GetLocationByID returns a location by ID with coordinates, or pgx.ErrNoRows if not found.
 func GetLocationByID(ctx context.Context, id int64) (*models.Location, error) {
-	var l models.Location
-	err := db.Pool.QueryRow(ctx, `SELECT id, name, lat, lon FROM locations WHERE id = $1`, id).Scan(&l.ID, &l.Name, &l.Lat, &l.Lon)
+	l, err := defaultStore().Locations().Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	return &l, nil
+	if l == nil {
+		return nil, pgx.ErrNoRows
+	}
+	return l, nil
 }
 
-// ListLocations returns all configured locations.
-func ListLocations(ctx context.Context) ([]models.Location, error) {
-	rows, err := db.Pool.Query(ctx, `
-		SELECT id, name, lat, lon
-		FROM locations
-		ORDER BY id`,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// GetLiveLocation returns the branch if it exists and is not deleted, else nil. The API, API keys and webhooks only
+// work on live branches; GetLocationByID also finds deleted ones (order history, restore, audit).
+func GetLiveLocation(ctx context.Context, id int64) (*models.Location, error) {
+	return defaultStore().Locations().GetLive(ctx, id)
+}
 
-	var res []models.Location
-	for rows.Next() {
-		var l models.Location
-		if err := rows.Scan(&l.ID, &l.Name, &l.Lat, &l.Lon); err != nil {
-			return nil, err
-		}
-		res = append(res, l)
-	}
-	return res, rows.Err()
+// ListLocations returns all configured (not deleted) locations.
+func ListLocations(ctx context.Context) ([]models.Location, error) {
+	return defaultStore().Locations().List(ctx)
 }
 
 // ListLocationsForCustomer returns only locations that have at least one branch admin with an active (non-expired) subscription. Used by the TOKEN (customer) bot so expired branches are hidden until they subscribe again.
@@ -73,7 +74,7 @@ func ListLocationsForCustomer(ctx context.Context) ([]models.Location, error) {
 		FROM locations l
 		INNER JOIN branch_admins ba ON ba.branch_location_id = l.id
 		INNER JOIN subscriptions s ON s.tg_user_id = ba.admin_user_id AND s.role = 'restaurant_admin'
-		WHERE s.status = 'active' AND s.expires_at > now()
+		WHERE s.status = 'active' AND s.expires_at > now() AND l.deleted_at IS NULL
 		ORDER BY l.id`,
 	)
 	if err != nil {
@@ -92,11 +93,13 @@ func ListLocationsForCustomer(ctx context.Context) ([]models.Location, error) {
 	return res, rows.Err()
 }
 
-// LocationHasActiveSubscription returns true if the location has at least one branch admin with an active subscription.
+// LocationHasActiveSubscription returns true if the location is live (not deleted) and has at least one branch admin
+// with an active subscription.
 func LocationHasActiveSubscription(ctx context.Context, locationID int64) (bool, error) {
 	var n int
 	err := db.Pool.QueryRow(ctx, `
 		SELECT 1 FROM branch_admins ba
+		INNER JOIN locations l ON l.id = ba.branch_location_id AND l.deleted_at IS NULL
 		INNER JOIN subscriptions s ON s.tg_user_id = ba.admin_user_id AND s.role = 'restaurant_admin'
 		WHERE ba.branch_location_id = $1 AND s.status = 'active' AND s.expires_at > now()
 		LIMIT 1`,
@@ -132,29 +135,61 @@ func SortLocationsByDistance(userLat, userLon float64, locs []models.Location) [
 	return withDist
 }
 
-// DeleteLocation removes a location and all related data. For each branch admin of this location it also removes their credential and subscription and marks their application rejected so they can request to add a branch again via Zayavka.
+// DeleteLocation soft-deletes a location: it and its menu are hidden from customers and admins, staff sessions end,
+// and each owner's applicant login is removed with their application marked rejected so they can apply again via Zayavka.
+// Orders keep their location_id. The superadmin can RestoreLocation within the retention window; PurgeDeletedData cleans up after it.
+// All of it happens in one transaction; deleting an already deleted location is a no-op, so a retry is safe.
 func DeleteLocation(ctx context.Context, id int64) error {
-	// Get branch admin user IDs before we delete the location (branch_admins CASCADE when location is deleted)
-	adminIDs, err := GetBranchAdmins(ctx, id)
-	if err != nil {
-		return err
-	}
-	for _, tgUserID := range adminIDs {
-		_, _ = db.Pool.Exec(ctx, `DELETE FROM user_credentials WHERE tg_user_id = $1 AND role = 'restaurant_admin'`, tgUserID)
-		_, _ = db.Pool.Exec(ctx, `DELETE FROM subscriptions WHERE tg_user_id = $1 AND role = 'restaurant_admin'`, tgUserID)
-		_, _ = db.Pool.Exec(ctx, `UPDATE applications SET status = 'rejected', reject_reason = 'Filial o''chirilgan', updated_at = now() WHERE tg_user_id = $1 AND type = 'restaurant_admin'`, tgUserID)
-	}
-	// Delete menu items for this location
-	if _, err := db.Pool.Exec(ctx, `DELETE FROM menu_items WHERE location_id = $1`, id); err != nil {
-		return err
-	}
-	// Delete user-location mappings
-	if _, err := db.Pool.Exec(ctx, `DELETE FROM user_locations WHERE location_id = $1`, id); err != nil {
-		return err
-	}
-	// Finally delete the location itself (branch_admins CASCADE)
-	_, err = db.Pool.Exec(ctx, `DELETE FROM locations WHERE id = $1`, id)
-	return err
+	return deleteLocation(ctx, defaultStore(), id)
 }
 
+func deleteLocation(ctx context.Context, st Store, id int64) error {
+	actorID, _ := auditActorFrom(ctx)
+	var actor int64
+	if actorID != nil {
+		actor = *actorID
+	}
+	return st.InTx(ctx, func(tx Store) error {
+		loc, err := tx.Locations().Get(ctx, id)
+		if err != nil {
+			return fmt.Errorf("delete location: %w", err)
+		}
+		if loc == nil {
+			return fmt.Errorf("filial topilmadi")
+		}
+		deletedAt, err := tx.Locations().SoftDelete(ctx, id, actorID)
+		if err != nil {
+			return fmt.Errorf("delete location: %w", err)
+		}
+		if deletedAt == nil {
+			// Already deleted by an earlier (or concurrent) call
+			return nil
+		}
+		// Items deleted together with the location share its deleted_at, so a restore brings back exactly these.
+		menuItems, err := tx.Menu().DeleteForLocation(ctx, id, *deletedAt)
+		if err != nil {
+			return fmt.Errorf("delete location menu: %w", err)
+		}
+		ownerIDs, err := tx.Locations().OwnerIDs(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, tgUserID := range ownerIDs {
+			if err := tx.Credentials().RemoveRestaurantLogin(ctx, tgUserID); err != nil {
+				return fmt.Errorf("delete owner credential: %w", err)
+			}
+			if err := tx.Applications().RejectApproved(ctx, tgUserID, ApplicationTypeRestaurantAdmin, locationDeletedReason); err != nil {
+				return fmt.Errorf("reject owner application: %w", err)
+			}
+		}
+		if err := tx.Locations().EndAccess(ctx, id, actor); err != nil {
+			return err
+		}
+		before := map[string]interface{}{"name": loc.Name, "lat": loc.Lat, "lon": loc.Lon, "owner_user_ids": ownerIDs}
+		after := map[string]interface{}{"deleted_at": *deletedAt, "menu_items_deleted": menuItems}
+		return tx.Audit(ctx, AuditLocationDelete, AuditTargetLocation, strconv.FormatInt(id, 10), before, after)
+	})
+}
 
+// locationDeletedReason is the reject_reason set on owners' applications when their location is deleted.
+const locationDeletedReason = "Filial o'chirilgan"
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"food-telegram/db"
+)
+
+// BranchTimezone is where opening hours and daily reports are counted (every branch is in Uzbekistan).
+var BranchTimezone = time.FixedZone("Asia/Tashkent", 5*60*60)
+
+// LocationHours is one weekday of a branch's opening hours. OpensMin/ClosesMin are minutes after midnight;
+// ClosesMin <= OpensMin means the branch closes after midnight (open all day when they are equal).
+type LocationHours struct {
+	Weekday   time.Weekday
+	Set       bool // false = no hours configured for the day
+	Closed    bool
+	OpensMin  int
+	ClosesMin int
+}
+
+// FormatClock formats minutes after midnight as HH:MM.
+func FormatClock(min int) string {
+	return fmt.Sprintf("%02d:%02d", min/60, min%60)
+}
+
+// ParseClock parses HH:MM into minutes after midnight.
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("vaqt formati HH:MM bo'lishi kerak: %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// GetLocationHours returns the branch's hours for every weekday, Sunday first.
+func GetLocationHours(ctx context.Context, locationID int64) ([7]LocationHours, error) {
+	var week [7]LocationHours
+	for i := range week {
+		week[i].Weekday = time.Weekday(i)
+	}
+	rows, err := db.Pool.Query(ctx, `SELECT weekday, closed, opens_min, closes_min FROM location_hours WHERE location_id = $1`, locationID)
+	if err != nil {
+		return week, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var h LocationHours
+		var wd int
+		if err := rows.Scan(&wd, &h.Closed, &h.OpensMin, &h.ClosesMin); err != nil {
+			return week, err
+		}
+		h.Weekday, h.Set = time.Weekday(wd), true
+		week[wd] = h
+	}
+	return week, rows.Err()
+}
+
+// SetLocationHours replaces the branch's opening hours. Days with Set false are cleared.
+func SetLocationHours(ctx context.Context, locationID int64, week [7]LocationHours) error {
+	for _, h := range week {
+		if h.OpensMin < 0 || h.OpensMin >= 24*60 || h.ClosesMin < 0 || h.ClosesMin >= 24*60 {
+			return fmt.Errorf("noto'g'ri vaqt: %s", h.Weekday)
+		}
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if _, err := tx.Exec(ctx, `DELETE FROM location_hours WHERE location_id = $1`, locationID); err != nil {
+		return err
+	}
+	after := map[string]interface{}{}
+	for i, h := range week {
+		if !h.Set {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO location_hours (location_id, weekday, closed, opens_min, closes_min) VALUES ($1, $2, $3, $4, $5)`,
+			locationID, i, h.Closed, h.OpensMin, h.ClosesMin,
+		); err != nil {
+			return fmt.Errorf("set location hours: %w", err)
+		}
+		after[time.Weekday(i).String()] = h.String()
+	}
+	if err := recordAudit(ctx, tx, AuditLocationHoursUpdate, AuditTargetLocation, strconv.FormatInt(locationID, 10), nil, after); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// String is "closed", "-" (not set) or "HH:MM-HH:MM".
+func (h LocationHours) String() string {
+	switch {
+	case !h.Set:
+		return "-"
+	case h.Closed:
+		return "closed"
+	}
+	return FormatClock(h.OpensMin) + "-" + FormatClock(h.ClosesMin)
+}
+
+// IsOpenAt reports whether the branch is open at t by its weekly hours; ok is false if the hours that decide it
+// are not set. After midnight the previous day's late hours still count.
+func IsOpenAt(week [7]LocationHours, t time.Time) (open, ok bool) {
+	t = t.In(BranchTimezone)
+	now := t.Hour()*60 + t.Minute()
+	today := week[t.Weekday()]
+	yesterday := week[(t.Weekday()+6)%7]
+	if yesterday.Set && !yesterday.Closed && yesterday.ClosesMin < yesterday.OpensMin && now < yesterday.ClosesMin {
+		return true, true
+	}
+	if !today.Set {
+		return false, false
+	}
+	if today.Closed {
+		return false, true
+	}
+	if today.ClosesMin <= today.OpensMin { // past midnight or round the clock
+		return now >= today.OpensMin || today.ClosesMin == today.OpensMin, true
+	}
+	return now >= today.OpensMin && now < today.ClosesMin, true
+}
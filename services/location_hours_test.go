@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsOpenAt(t *testing.T) {
+	var week [7]LocationHours
+	for i := range week {
+		week[i].Weekday = time.Weekday(i)
+	}
+	week[time.Monday] = LocationHours{Weekday: time.Monday, Set: true, OpensMin: 9 * 60, ClosesMin: 22 * 60}
+	week[time.Friday] = LocationHours{Weekday: time.Friday, Set: true, OpensMin: 18 * 60, ClosesMin: 2 * 60}
+	week[time.Saturday] = LocationHours{Weekday: time.Saturday, Set: true, Closed: true}
+	week[time.Sunday] = LocationHours{Weekday: time.Sunday, Set: true, OpensMin: 0, ClosesMin: 0}
+
+	// 2024-01-01 is a Monday.
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2024, 1, day, hour, min, 0, 0, BranchTimezone)
+	}
+	tests := []struct {
+		name     string
+		t        time.Time
+		open, ok bool
+	}{
+		{"monday before opening", at(1, 8, 59), false, true},
+		{"monday at opening", at(1, 9, 0), true, true},
+		{"monday at closing", at(1, 22, 0), false, true},
+		{"tuesday not set", at(2, 12, 0), false, false},
+		{"friday evening", at(5, 23, 30), true, true},
+		{"saturday after midnight from friday", at(6, 1, 59), true, true},
+		{"saturday closed", at(6, 2, 0), false, true},
+		{"sunday round the clock", at(7, 4, 0), true, true},
+		{"utc time converted", time.Date(2024, 1, 1, 4, 0, 0, 0, time.UTC), true, true},
+	}
+	for _, tt := range tests {
+		open, ok := IsOpenAt(week, tt.t)
+		if open != tt.open || ok != tt.ok {
+			t.Errorf("%s: IsOpenAt = %v, %v; want %v, %v", tt.name, open, ok, tt.open, tt.ok)
+		}
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	if m, err := ParseClock("09:30"); err != nil || m != 570 {
+		t.Errorf("ParseClock(09:30) = %d, %v", m, err)
+	}
+	for _, s := range []string{"", "9", "24:00", "12:60"} {
+		if _, err := ParseClock(s); err == nil {
+			t.Errorf("ParseClock(%q) accepted", s)
+		}
+	}
+	if got := FormatClock(570); got != "09:30" {
+		t.Errorf("FormatClock(570) = %q", got)
+	}
+}
@@ -3,11 +3,12 @@ package services
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"food-telegram/db"
 )
 
-// CreateLocationWithAdmin atomically creates a new location and assigns a branch admin with password.
+// CreateLocationWithAdmin atomically creates a new location and assigns its owner with password.
 // orderLang is the language the admin receives order cards in: "uz" or "ru" (default "uz" if empty).
 func CreateLocationWithAdmin(ctx context.Context, name string, lat, lon float64, adminUserID int64, promotedBy int64, passwordHash string, orderLang string) (int64, error) {
 	if adminUserID <= 0 {
@@ -28,15 +29,6 @@ func CreateLocationWithAdmin(ctx context.Context, name string, lat, lon float64,
 		return 0, err
 	}
 
-	// Ensure password is unique (new location, so no row to exclude)
-	unique, err := IsBranchAdminPasswordUnique(ctx, passwordHash, 0)
-	if err != nil {
-		return 0, err
-	}
-	if !unique {
-		return 0, fmt.Errorf("this password is already used by another branch admin; choose a unique password")
-	}
-
 	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("begin tx: %w", err)
@@ -55,13 +47,24 @@ func CreateLocationWithAdmin(ctx context.Context, name string, lat, lon float64,
 	}
 
 	_, err = tx.Exec(ctx, `
-		INSERT INTO branch_admins (branch_location_id, branch_name, admin_user_id, promoted_by, password_hash, order_lang)
-		VALUES ($1, $2, $3, $4, $5, $6)`,
-		locationID, name, adminUserID, promotedBy, passwordHash, orderLang,
+		INSERT INTO branch_admins (branch_location_id, branch_name, admin_user_id, promoted_by, order_lang)
+		VALUES ($1, $2, $3, $4, $5)`,
+		locationID, name, adminUserID, promotedBy, orderLang,
 	)
 	if err != nil {
 		return 0, fmt.Errorf("insert branch admin: %w", err)
 	}
+	if err := setBranchAdminPasswordTx(ctx, tx, adminUserID, passwordHash); err != nil {
+		return 0, err
+	}
+	locAfter := map[string]interface{}{"name": name, "lat": lat, "lon": lon}
+	if err := recordAudit(ctx, tx, AuditLocationCreate, AuditTargetLocation, strconv.FormatInt(locationID, 10), nil, locAfter); err != nil {
+		return 0, err
+	}
+	staffAfter := map[string]interface{}{"location_id": locationID, "role": BranchRoleOwner, "order_lang": orderLang}
+	if err := recordAudit(ctx, tx, AuditBranchStaffAdd, AuditTargetBranchStaff, strconv.FormatInt(adminUserID, 10), nil, staffAfter); err != nil {
+		return 0, err
+	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return 0, fmt.Errorf("commit tx: %w", err)
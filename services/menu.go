@@ -2,163 +2,202 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 
 	"food-telegram/db"
 	"food-telegram/models"
+
+	"github.com/jackc/pgx/v5"
 )
 
 func ListMenuByCategory(ctx context.Context, category string) ([]models.MenuItem, error) {
-	rows, err := db.Pool.Query(ctx, `
-		SELECT id, category, name, price FROM menu_items
-		WHERE category = $1
-		ORDER BY id`,
-		category,
-	)
-	if err != nil {
-		return nil, err
+	return defaultStore().Menu().List(ctx, category, 0)
+}
+
+// ListMenuByCategoryAndLocation lists menu items for a given category and location.
+// Only items that belong to this location (location_id = locationID) and are not deleted are returned.
+// New locations have no items until the admin adds them.
+func ListMenuByCategoryAndLocation(ctx context.Context, category string, locationID int64) ([]models.MenuItem, error) {
+	if locationID <= 0 {
+		return nil, nil
 	}
-	defer rows.Close()
+	return defaultStore().Menu().List(ctx, category, locationID)
+}
 
-	var items []models.MenuItem
-	for rows.Next() {
-		var id int64
-		var cat, name string
-		var price int64
-		if err := rows.Scan(&id, &cat, &name, &price); err != nil {
-			return nil, err
+func ListAllMenu(ctx context.Context) ([]models.MenuItem, error) {
+	return defaultStore().Menu().ListAll(ctx)
+}
+
+func AddMenuItem(ctx context.Context, category, name string, price int64) (int64, error) {
+	return addMenuItem(ctx, defaultStore(), category, name, price, nil)
+}
+
+// AddMenuItemForLocation inserts a menu item bound to a specific location.
+func AddMenuItemForLocation(ctx context.Context, category, name string, price int64, locationID int64) (int64, error) {
+	if locationID <= 0 {
+		return 0, fmt.Errorf("location_id must be > 0")
+	}
+	return addMenuItem(ctx, defaultStore(), category, name, price, &locationID)
+}
+
+func addMenuItem(ctx context.Context, st Store, category, name string, price int64, locationID *int64) (int64, error) {
+	if category != models.CategoryFood && category != models.CategoryDrink && category != models.CategoryDessert {
+		return 0, fmt.Errorf("invalid category: %s", category)
+	}
+	if name == "" {
+		return 0, fmt.Errorf("name is required")
+	}
+	if price < 0 {
+		return 0, fmt.Errorf("price must be >= 0")
+	}
+
+	var id int64
+	err := st.InTx(ctx, func(tx Store) error {
+		var err error
+		id, err = tx.Menu().Add(ctx, category, name, price, locationID)
+		if err != nil {
+			return err
 		}
-		items = append(items, models.MenuItem{
-			ID:       strconv.FormatInt(id, 10),
-			Category: cat,
-			Name:     name,
-			Price:    price,
-		})
+		after := map[string]interface{}{"category": category, "name": name, "price": price}
+		if locationID != nil {
+			after["location_id"] = *locationID
+		}
+		return tx.Audit(ctx, AuditMenuItemCreate, AuditTargetMenuItem, strconv.FormatInt(id, 10), nil, after)
+	})
+	if err != nil {
+		return 0, err
 	}
-	return items, rows.Err()
+	return id, nil
 }
 
-// ListMenuByCategoryAndLocation lists menu items for a given category and location.
-// Only items that belong to this location (location_id = locationID) are returned.
-// New locations have no items until the admin adds them.
-func ListMenuByCategoryAndLocation(ctx context.Context, category string, locationID int64) ([]models.MenuItem, error) {
-	rows, err := db.Pool.Query(ctx, `
-		SELECT id, category, name, price FROM menu_items
-		WHERE category = $1 AND location_id = $2
-		ORDER BY id`,
-		category, locationID,
-	)
+// GetMenuItem returns a menu item that is not deleted, or pgx.ErrNoRows.
+func GetMenuItem(ctx context.Context, idStr string) (*models.MenuItem, error) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var items []models.MenuItem
-	for rows.Next() {
-		var id int64
-		var cat, name string
-		var price int64
-		if err := rows.Scan(&id, &cat, &name, &price); err != nil {
-			return nil, err
-		}
-		items = append(items, models.MenuItem{
-			ID:       strconv.FormatInt(id, 10),
-			Category: cat,
-			Name:     name,
-			Price:    price,
-		})
+	item, err := defaultStore().Menu().Get(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	return items, rows.Err()
+	if item == nil {
+		return nil, pgx.ErrNoRows
+	}
+	return item, nil
 }
 
-func ListAllMenu(ctx context.Context) ([]models.MenuItem, error) {
+// BranchMenuItem is a menu item with the branch it belongs to (nil = shared item from before branches).
+type BranchMenuItem struct {
+	ID         int64
+	LocationID *int64
+	Category   string
+	Name       string
+	Price      int64
+}
+
+// ListBranchMenu lists live menu items of a branch (0 = every branch), optionally of one category.
+func ListBranchMenu(ctx context.Context, locationID int64, category string) ([]BranchMenuItem, error) {
 	rows, err := db.Pool.Query(ctx, `
-		SELECT id, category, name, price FROM menu_items
-		ORDER BY category, id`,
+		SELECT id, location_id, category, name, price FROM menu_items
+		WHERE deleted_at IS NULL AND ($1::bigint = 0 OR location_id = $1) AND ($2 = '' OR category = $2)
+		ORDER BY location_id, category, id`,
+		locationID, category,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-
-	var items []models.MenuItem
+	var items []BranchMenuItem
 	for rows.Next() {
-		var id int64
-		var cat, name string
-		var price int64
-		if err := rows.Scan(&id, &cat, &name, &price); err != nil {
+		var it BranchMenuItem
+		if err := rows.Scan(&it.ID, &it.LocationID, &it.Category, &it.Name, &it.Price); err != nil {
 			return nil, err
 		}
-		items = append(items, models.MenuItem{
-			ID:       strconv.FormatInt(id, 10),
-			Category: cat,
-			Name:     name,
-			Price:    price,
-		})
+		items = append(items, it)
 	}
 	return items, rows.Err()
 }
 
-func AddMenuItem(ctx context.Context, category, name string, price int64) (int64, error) {
-	if category != models.CategoryFood && category != models.CategoryDrink && category != models.CategoryDessert {
-		return 0, fmt.Errorf("invalid category: %s", category)
-	}
-	if name == "" {
-		return 0, fmt.Errorf("name is required")
-	}
-	if price < 0 {
-		return 0, fmt.Errorf("price must be >= 0")
+// GetBranchMenuItem returns a live menu item with its branch, or nil if missing or deleted.
+func GetBranchMenuItem(ctx context.Context, id int64) (*BranchMenuItem, error) {
+	it := BranchMenuItem{ID: id}
+	err := db.Pool.QueryRow(ctx, `SELECT location_id, category, name, price FROM menu_items WHERE id = $1 AND deleted_at IS NULL`, id).
+		Scan(&it.LocationID, &it.Category, &it.Name, &it.Price)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
 	}
-
-	var id int64
-	err := db.Pool.QueryRow(ctx, `
-		INSERT INTO menu_items (category, name, price) VALUES ($1, $2, $3)
-		RETURNING id`,
-		category, name, price,
-	).Scan(&id)
-	return id, err
+	return &it, nil
 }
 
-// AddMenuItemForLocation inserts a menu item bound to a specific location.
-func AddMenuItemForLocation(ctx context.Context, category, name string, price int64, locationID int64) (int64, error) {
+// UpdateMenuItem changes a live item's category, name and price; the old values go to the audit log.
+func UpdateMenuItem(ctx context.Context, id int64, category, name string, price int64) error {
 	if category != models.CategoryFood && category != models.CategoryDrink && category != models.CategoryDessert {
-		return 0, fmt.Errorf("invalid category: %s", category)
+		return fmt.Errorf("invalid category: %s", category)
 	}
 	if name == "" {
-		return 0, fmt.Errorf("name is required")
+		return fmt.Errorf("name is required")
 	}
 	if price < 0 {
-		return 0, fmt.Errorf("price must be >= 0")
+		return fmt.Errorf("price must be >= 0")
 	}
-	if locationID <= 0 {
-		return 0, fmt.Errorf("location_id must be > 0")
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
 	}
-
-	var id int64
-	err := db.Pool.QueryRow(ctx, `
-		INSERT INTO menu_items (category, name, price, location_id) VALUES ($1, $2, $3, $4)
-		RETURNING id`,
-		category, name, price, locationID,
-	).Scan(&id)
-	return id, err
+	defer func() { _ = tx.Rollback(ctx) }()
+	var oldCategory, oldName string
+	var oldPrice int64
+	err = tx.QueryRow(ctx, `SELECT category, name, price FROM menu_items WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).
+		Scan(&oldCategory, &oldName, &oldPrice)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("mahsulot topilmadi")
+		}
+		return err
+	}
+	if oldCategory == category && oldName == name && oldPrice == price {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, `UPDATE menu_items SET category = $2, name = $3, price = $4 WHERE id = $1`, id, category, name, price); err != nil {
+		return err
+	}
+	before := map[string]interface{}{"category": oldCategory, "name": oldName, "price": oldPrice}
+	after := map[string]interface{}{"category": category, "name": name, "price": price}
+	if err := recordAudit(ctx, tx, AuditMenuItemUpdate, AuditTargetMenuItem, strconv.FormatInt(id, 10), before, after); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func GetMenuItem(ctx context.Context, idStr string) (*models.MenuItem, error) {
-	id, err := strconv.ParseInt(idStr, 10, 64)
+// DeleteMenuItem soft-deletes the item (deleted_at); the superadmin can restore it within the retention window.
+func DeleteMenuItem(ctx context.Context, id int64) error {
+	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 	var category, name string
 	var price int64
-	err = db.Pool.QueryRow(ctx, `SELECT category, name, price FROM menu_items WHERE id = $1`, id).Scan(&category, &name, &price)
+	var locationID *int64
+	err = tx.QueryRow(ctx, `
+		UPDATE menu_items SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL
+		RETURNING category, name, price, location_id`,
+		id,
+	).Scan(&category, &name, &price, &locationID)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
 	}
-	return &models.MenuItem{ID: idStr, Category: category, Name: name, Price: price}, nil
-}
-
-func DeleteMenuItem(ctx context.Context, id int64) error {
-	_, err := db.Pool.Exec(ctx, `DELETE FROM menu_items WHERE id = $1`, id)
-	return err
+	before := map[string]interface{}{"category": category, "name": name, "price": price, "location_id": locationID}
+	if err := recordAudit(ctx, tx, AuditMenuItemDelete, AuditTargetMenuItem, strconv.FormatInt(id, 10), before, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strconv"
 
 	"food-telegram/db"
 	"food-telegram/models"
@@ -16,7 +17,7 @@ const (
 	defaultRatePerKm = 4000 // sum per km
 )
 
-// Order status enum: new (waiting) -> preparing -> ready -> ... -> completed; or new -> rejected
+// Order status enum: new (waiting) -> preparing -> ready -> ... -> completed; or new -> rejected (transitions: order_state.go)
 const (
 	OrderStatusNew        = "new"
 	OrderStatusRejected   = "rejected"
@@ -84,8 +85,13 @@ func CreateOrder(ctx context.Context, input models.CreateOrderInput) (int64, err
 		deliveryFee = 0
 	}
 	grandTotal := input.ItemsTotal + deliveryFee
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
 	var id int64
-	err := db.Pool.QueryRow(ctx, `
+	err = tx.QueryRow(ctx, `
 		INSERT INTO orders (
 			user_id, chat_id, phone, lat, lon, distance_km, rate_per_km,
 			delivery_fee, items_total, grand_total, status, location_id, delivery_type
@@ -94,41 +100,40 @@ func CreateOrder(ctx context.Context, input models.CreateOrderInput) (int64, err
 		input.UserID, input.ChatID, input.Phone, input.Lat, input.Lon, input.DistanceKm,
 		4000, deliveryFee, input.ItemsTotal, grandTotal, OrderStatusNew, input.LocationID, deliveryType,
 	).Scan(&id)
-	return id, err
+	if err != nil {
+		return 0, err
+	}
+	for _, it := range input.Items {
+		var menuItemID *int64
+		if it.MenuItemID > 0 {
+			menuItemID = &it.MenuItemID
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO order_items (order_id, menu_item_id, category, name, price, qty) VALUES ($1, $2, $3, $4, $5, $6)`,
+			id, menuItemID, it.Category, it.Name, it.Price, it.Qty,
+		); err != nil {
+			return 0, fmt.Errorf("order item: %w", err)
+		}
+	}
+	// The "" -> new history row marks when the order was placed (and feeds the order.created webhook)
+	if err := recordOrderHistoryTx(ctx, tx, id, "", OrderStatusNew, input.UserID); err != nil {
+		return 0, err
+	}
+	// Customer and staff cards go out through the outbox worker
+	if err := EnqueueOrderNotification(ctx, tx, OutboxKindOrderNew, id); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit(ctx)
 }
 
 // GetOrder loads an order by ID. Returns nil if not found.
 func GetOrder(ctx context.Context, orderID int64) (*models.Order, error) {
-	var o models.Order
-	var deliveryType *string
-	var driverID *string
-	err := db.Pool.QueryRow(ctx, `
-		SELECT id, COALESCE(location_id, 0), status, chat_id, items_total, grand_total,
-		       COALESCE(delivery_fee, 0), COALESCE(distance_km, 0), delivery_type, driver_id
-		FROM orders WHERE id = $1`,
-		orderID,
-	).Scan(&o.ID, &o.LocationID, &o.Status, &o.ChatID, &o.ItemsTotal, &o.GrandTotal, &o.DeliveryFee, &o.DistanceKm, &deliveryType, &driverID)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	o.DeliveryType = deliveryType
-	o.DriverID = driverID
-	return &o, nil
+	return defaultStore().Orders().Get(ctx, orderID)
 }
 
 // GetOrderCoordinates returns the delivery coordinates (lat, lon) for an order.
 func GetOrderCoordinates(ctx context.Context, orderID int64) (lat, lon float64, err error) {
-	err = db.Pool.QueryRow(ctx, `SELECT lat, lon FROM orders WHERE id = $1`, orderID).Scan(&lat, &lon)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return 0, 0, nil
-		}
-		return 0, 0, err
-	}
-	return lat, lon, nil
+	return defaultStore().Orders().Coordinates(ctx, orderID)
 }
 
 // CustomerOrderRow is a summary row for /orders list.
@@ -144,24 +149,7 @@ func ListOrdersByUserID(ctx context.Context, userID int64, limit int) ([]Custome
 	if limit <= 0 {
 		limit = 20
 	}
-	rows, err := db.Pool.Query(ctx, `
-		SELECT id, status, grand_total, created_at::text
-		FROM orders WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`,
-		userID, limit,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var list []CustomerOrderRow
-	for rows.Next() {
-		var r CustomerOrderRow
-		if err := rows.Scan(&r.ID, &r.Status, &r.GrandTotal, &r.CreatedAt); err != nil {
-			return nil, err
-		}
-		list = append(list, r)
-	}
-	return list, rows.Err()
+	return defaultStore().Orders().ListByUser(ctx, userID, limit)
 }
 
 // TrySetOrderPushedAt sets orders.pushed_at = NOW() only if pushed_at IS NULL (one-shot claim for driver push).
@@ -208,108 +196,16 @@ func OrderAvailableForPush(ctx context.Context, orderID int64) (bool, error) {
 	return status == OrderStatusReady && driverID == nil, nil
 }
 
-// validStatusTransition defines allowed order status changes (no skip).
-var validStatusTransition = map[string]string{
-	OrderStatusNew:        OrderStatusPreparing,
-	OrderStatusPreparing:  OrderStatusReady,
-	OrderStatusReady:      OrderStatusAssigned,   // Driver accepts -> assigned
-	OrderStatusAssigned:   OrderStatusPickedUp,   // Driver collects -> picked_up
-	OrderStatusPickedUp:   OrderStatusDelivering, // Driver starts delivering -> delivering
-	OrderStatusDelivering: OrderStatusCompleted,  // Driver completes -> completed
-}
-
-// ValidStatusTransition returns true if transitioning from 'from' to 'to' is allowed.
-func ValidStatusTransition(from, to string) bool {
-	// ready -> completed is only allowed in UpdateOrderStatus for pickup (validated there)
-	if from == OrderStatusReady && to == OrderStatusCompleted {
-		return false
-	}
-	// Driver can mark assigned -> completed (or via picked_up -> delivering -> completed)
-	if from == OrderStatusAssigned && to == OrderStatusCompleted {
-		return true
-	}
-	// Admin can reject a new order
-	if from == OrderStatusNew && to == OrderStatusRejected {
-		return true
-	}
-	next, ok := validStatusTransition[from]
-	return ok && next == to
-}
-
-// UpdateOrderStatus updates order status in a transaction and records history. Validates transition and that order belongs to admin's restaurant.
-// actorID is the Telegram user ID of the admin who performed the change.
-func UpdateOrderStatus(ctx context.Context, orderID int64, newStatus string, adminLocationID int64, actorID int64) error {
-	o, err := GetOrder(ctx, orderID)
-	if err != nil {
-		return err
-	}
-	if o == nil {
-		return fmt.Errorf("order not found")
-	}
-	if o.LocationID != adminLocationID {
-		return fmt.Errorf("order does not belong to your restaurant")
-	}
-	// Prevent admin from completing if driver is assigned
-	if newStatus == OrderStatusCompleted {
-		var driverID *string
-		var deliveryType *string
-		err := db.Pool.QueryRow(ctx, `SELECT driver_id, delivery_type FROM orders WHERE id = $1`, orderID).Scan(&driverID, &deliveryType)
-		if err == nil && driverID != nil {
-			return fmt.Errorf("bu buyurtma driverga biriktirilgan. Yakunlashni driver qiladi")
-		}
-		// For ready -> completed, only allow if delivery_type is 'pickup'
-		if o.Status == OrderStatusReady {
-			if deliveryType == nil || *deliveryType != "pickup" {
-				return fmt.Errorf("bu buyurtma yetkazib berish uchun. Yakunlashni driver qiladi")
-			}
-		}
-	}
-	// Prevent admin from changing status once driver is assigned (except new->preparing->ready)
-	var driverID *string
-	err = db.Pool.QueryRow(ctx, `SELECT driver_id FROM orders WHERE id = $1`, orderID).Scan(&driverID)
-	if err == nil && driverID != nil {
-		// Admin can only change status if current status is new, preparing, or ready
-		if o.Status != OrderStatusNew && o.Status != OrderStatusPreparing && o.Status != OrderStatusReady {
-			return fmt.Errorf("bu buyurtma driverga biriktirilgan. Statusni driver o'zgartiradi")
-		}
-		// Prevent admin from setting completed if driver is assigned
-		if newStatus == OrderStatusCompleted {
-			return fmt.Errorf("bu buyurtma driverga biriktirilgan. Yakunlashni driver qiladi")
-		}
-	}
-	// Allow ready -> completed only for pickup (already validated above when newStatus == completed)
-	allowTransition := ValidStatusTransition(o.Status, newStatus) ||
-		(o.Status == OrderStatusReady && newStatus == OrderStatusCompleted)
-	if !allowTransition {
-		return fmt.Errorf("invalid status transition from %q to %q", o.Status, newStatus)
-	}
-	fromStatus := o.Status
-	tx, err := db.Pool.Begin(ctx)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = tx.Rollback(ctx) }()
-	_, err = tx.Exec(ctx, `UPDATE orders SET status = $1, updated_at = now() WHERE id = $2`, newStatus, orderID)
-	if err != nil {
-		return err
-	}
-	_, err = tx.Exec(ctx, `
-		INSERT INTO order_status_history (order_id, from_status, to_status, actor_id)
-		VALUES ($1, $2, $3, $4)`,
-		orderID, fromStatus, newStatus, actorID,
-	)
-	if err != nil {
-		return err
-	}
-	return tx.Commit(ctx)
-}
-
 // SetDeliveryType sets the delivery type for an order (pickup or delivery).
 func SetDeliveryType(ctx context.Context, orderID int64, deliveryType string, adminLocationID int64) error {
+	return setDeliveryType(ctx, defaultStore(), orderID, deliveryType, adminLocationID)
+}
+
+func setDeliveryType(ctx context.Context, st Store, orderID int64, deliveryType string, adminLocationID int64) error {
 	if deliveryType != "pickup" && deliveryType != "delivery" {
 		return fmt.Errorf("invalid delivery_type: %s", deliveryType)
 	}
-	o, err := GetOrder(ctx, orderID)
+	o, err := st.Orders().Get(ctx, orderID)
 	if err != nil {
 		return err
 	}
@@ -324,8 +220,7 @@ func SetDeliveryType(ctx context.Context, orderID int64, deliveryType string, ad
 	}
 	// For delivery orders, verify they have valid coordinates
 	if deliveryType == "delivery" {
-		var lat, lon float64
-		err := db.Pool.QueryRow(ctx, `SELECT lat, lon FROM orders WHERE id = $1`, orderID).Scan(&lat, &lon)
+		lat, lon, err := st.Orders().Coordinates(ctx, orderID)
 		if err != nil {
 			return fmt.Errorf("failed to get order coordinates: %v", err)
 		}
@@ -333,38 +228,23 @@ func SetDeliveryType(ctx context.Context, orderID int64, deliveryType string, ad
 			return fmt.Errorf("delivery orders must have valid coordinates")
 		}
 	}
-	_, err = db.Pool.Exec(ctx, `UPDATE orders SET delivery_type = $1 WHERE id = $2`, deliveryType, orderID)
-	return err
+	return st.Orders().SetDeliveryType(ctx, orderID, deliveryType)
 }
 
-// CustomerMessageForOrderStatus returns the Uzbek notification text for the customer (with order summary).
-func CustomerMessageForOrderStatus(o *models.Order, newStatus string) string {
-	summary := fmt.Sprintf("\n\nJami: %d UZS", o.GrandTotal)
-	switch newStatus {
-	case OrderStatusPreparing:
-		return fmt.Sprintf("Sizning buyurtmangiz #%d tayyorlanmoqda. Tez orada yetkaziladi.%s\n\nKeyingi qadam: tayyor bo'lganda sizga xabar beramiz.", o.ID, summary)
-	case OrderStatusReady:
-		if o.DeliveryType != nil && *o.DeliveryType == "pickup" {
-			return fmt.Sprintf("Sizning buyurtmangiz #%d tayyor va o'zingiz olib ketishingiz mumkin.%s", o.ID, summary)
+func OverrideDeliveryFee(ctx context.Context, input models.OverrideDeliveryFeeInput) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	var oldFee int64
+	if err := tx.QueryRow(ctx, `SELECT delivery_fee FROM orders WHERE id = $1 FOR UPDATE`, input.OrderID).Scan(&oldFee); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("order %d not found", input.OrderID)
 		}
-		return fmt.Sprintf("Sizning buyurtmangiz #%d hozir tayyor — yetkazib beruvchi olib ketishga tayyorlanmoqda.%s\n\nKeyingi qadam: yetkazib berish.", o.ID, summary)
-	case OrderStatusAssigned:
-		return fmt.Sprintf("🚚 Driver topildi, buyurtma yo'lga tayyorlanmoqda.%s", summary)
-	case OrderStatusPickedUp:
-		return fmt.Sprintf("📦 Buyurtmangiz restorandan olib ketildi.%s", summary)
-	case OrderStatusDelivering:
-		return fmt.Sprintf("🛵 Buyurtmangiz yo'lda.%s", summary)
-	case OrderStatusCompleted:
-		return fmt.Sprintf("✅ Buyurtmangiz yetkazildi. Rahmat!%s", summary)
-	case OrderStatusRejected:
-		return fmt.Sprintf("❌ Afsuski, buyurtmangiz #%d rad etildi. Savol bo'lsa, biz bilan bog'laning.", o.ID)
-	default:
-		return fmt.Sprintf("Buyurtma #%d — yangilandi: %s.%s", o.ID, newStatus, summary)
+		return err
 	}
-}
-
-func OverrideDeliveryFee(ctx context.Context, input models.OverrideDeliveryFeeInput) error {
-	_, err := db.Pool.Exec(ctx, `
+	_, err = tx.Exec(ctx, `
 		UPDATE orders SET
 			delivery_fee = $1,
 			grand_total = items_total + $1,
@@ -376,7 +256,15 @@ func OverrideDeliveryFee(ctx context.Context, input models.OverrideDeliveryFeeIn
 		WHERE id = $4`,
 		input.NewFee, input.OverrideBy, input.Note, input.OrderID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, AuditOrderFeeOverride, AuditTargetOrder, strconv.FormatInt(input.OrderID, 10),
+		map[string]interface{}{"delivery_fee": oldFee},
+		map[string]interface{}{"delivery_fee": input.NewFee, "note": input.Note}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func GetDailyStats(ctx context.Context, date string) (*models.DailyStats, error) {
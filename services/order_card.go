@@ -103,7 +103,9 @@ func BuildAdminCard(o *models.Order, driver *Driver, adminLang string) OrderCard
 }
 
 // BuildCustomerCard returns full card text and optional Track Driver button when status is delivering.
-func BuildCustomerCard(o *models.Order, driver *Driver, trackURL string) OrderCardContent {
+// etaMinutes > 0 adds the estimated time line (see GetOrderETAMinutes). A completed order gets the rating buttons
+// (rating nil = not rated yet).
+func BuildCustomerCard(o *models.Order, driver *Driver, trackURL string, etaMinutes int, rating *OrderRating) OrderCardContent {
 	text := fmt.Sprintf("Buyurtma #%d\n\n", o.ID)
 	text += fmt.Sprintf("🛒 Mahsulotlar: %d so'm\n", o.ItemsTotal)
 	text += fmt.Sprintf("💵 Jami: %d so'm\n", o.GrandTotal)
@@ -136,6 +138,9 @@ func BuildCustomerCard(o *models.Order, driver *Driver, trackURL string) OrderCa
 	default:
 		text += o.Status
 	}
+	if eta := FormatETA(etaMinutes); eta != "" {
+		text += "\n" + eta
+	}
 	if driver != nil {
 		text += "\n\nHaydovchi"
 		if driver.FullName != "" {
@@ -153,9 +158,67 @@ func BuildCustomerCard(o *models.Order, driver *Driver, trackURL string) OrderCa
 	if o.Status == OrderStatusDelivering && trackURL != "" {
 		buttons = [][]OrderCardButton{{{Text: "📍 Track Driver", URL: trackURL, CallbackData: ""}}}
 	}
+	if o.Status == OrderStatusCompleted {
+		var rateText string
+		rateText, buttons = customerRatingSection(o, rating)
+		text += rateText
+	}
 	return OrderCardContent{Text: text, Buttons: buttons}
 }
 
+// ratingAspect is one rated aspect on the customer card.
+type ratingAspect struct {
+	key   string // RatingFood or RatingDelivery
+	icon  string
+	label string
+	stars int // 0 = not rated yet
+}
+
+// customerRatingSection is the rating part of a completed order's customer card: the scores given so far, a row of
+// 1-5 buttons for each aspect not rated yet, and the comment button once something is rated.
+func customerRatingSection(o *models.Order, r *OrderRating) (string, [][]OrderCardButton) {
+	if r == nil {
+		r = &OrderRating{}
+	}
+	aspects := []ratingAspect{{RatingFood, "🍽", "Ovqat", r.Food}}
+	if RatesDelivery(o) {
+		aspects = append(aspects, ratingAspect{RatingDelivery, "🛵", "Yetkazish", r.Delivery})
+	}
+	id := strconv.FormatInt(o.ID, 10)
+	var lines string
+	var buttons [][]OrderCardButton
+	for _, a := range aspects {
+		if a.stars > 0 {
+			lines += fmt.Sprintf("\n%s %s: %s", a.icon, a.label, RatingStars(a.stars))
+			continue
+		}
+		var row []OrderCardButton
+		for n := 1; n <= 5; n++ {
+			row = append(row, OrderCardButton{Text: fmt.Sprintf("%s %d", a.icon, n), CallbackData: fmt.Sprintf("rate:%s:%s:%d", id, a.key, n)})
+		}
+		buttons = append(buttons, row)
+	}
+	if r.Comment != "" {
+		lines += "\n💬 " + r.Comment
+	}
+	text := "\n\n"
+	switch {
+	case len(buttons) == len(aspects):
+		text += "⭐ Buyurtmani baholang (1 — yomon, 5 — a'lo):"
+		for _, a := range aspects {
+			text += fmt.Sprintf("\n%s %s", a.icon, a.label)
+		}
+	case len(buttons) > 0:
+		text += "⭐ Bahoyingiz:" + lines + "\n\nQolganini ham baholang:"
+	default:
+		text += "⭐ Bahoyingiz uchun rahmat!" + lines
+	}
+	if len(buttons) < len(aspects) && r.Comment == "" {
+		buttons = append(buttons, []OrderCardButton{{Text: "✍️ Izoh qoldirish", CallbackData: "rate_comment:" + id}})
+	}
+	return text, buttons
+}
+
 // BuildDriverCard returns full card text and next-action buttons for driver.
 func BuildDriverCard(o *models.Order, driverLang string) OrderCardContent {
 	if driverLang == "" {
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+
+	"food-telegram/db"
+	"food-telegram/models"
+)
+
+// ListOrderItems returns the lines of the given orders keyed by order ID, in the order they were added.
+// Orders placed before order_items existed have no lines.
+func ListOrderItems(ctx context.Context, orderIDs []int64) (map[int64][]models.OrderItem, error) {
+	items := make(map[int64][]models.OrderItem)
+	if len(orderIDs) == 0 {
+		return items, nil
+	}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT order_id, COALESCE(menu_item_id, 0), category, name, price, qty
+		FROM order_items WHERE order_id = ANY($1) ORDER BY order_id, id`,
+		orderIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var orderID int64
+		var it models.OrderItem
+		if err := rows.Scan(&orderID, &it.MenuItemID, &it.Category, &it.Name, &it.Price, &it.Qty); err != nil {
+			return nil, err
+		}
+		items[orderID] = append(items[orderID], it)
+	}
+	return items, rows.Err()
+}
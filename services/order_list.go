@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// OrderRecord is an order as listed by the HTTP API.
+type OrderRecord struct {
+	ID           int64
+	LocationID   int64
+	Status       string
+	Phone        string
+	Lat          float64
+	Lon          float64
+	DistanceKm   float64
+	ItemsTotal   int64
+	DeliveryFee  int64
+	GrandTotal   int64
+	DeliveryType *string
+	DriverID     *string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// OrderFilter narrows ListOrders. Zero values mean "any"; From is inclusive, To exclusive.
+type OrderFilter struct {
+	LocationID int64
+	Status     string
+	Active     bool // only orders still in progress (ActiveOrderStatuses)
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+const orderRecordColumns = `id, COALESCE(location_id, 0), status, COALESCE(phone, ''), lat, lon, COALESCE(distance_km, 0),
+		       items_total, COALESCE(delivery_fee, 0), grand_total, delivery_type, driver_id, created_at, updated_at`
+
+func scanOrderRecord(row pgx.Row, o *OrderRecord) error {
+	return row.Scan(&o.ID, &o.LocationID, &o.Status, &o.Phone, &o.Lat, &o.Lon, &o.DistanceKm,
+		&o.ItemsTotal, &o.DeliveryFee, &o.GrandTotal, &o.DeliveryType, &o.DriverID, &o.CreatedAt, &o.UpdatedAt)
+}
+
+// ActiveOrderStatuses are the statuses of orders still in progress, in flow order.
+var ActiveOrderStatuses = []string{
+	OrderStatusNew, OrderStatusPreparing, OrderStatusReady, OrderStatusAssigned, OrderStatusPickedUp, OrderStatusDelivering,
+}
+
+// ListOrders returns orders matching f, newest first.
+func ListOrders(ctx context.Context, f OrderFilter) ([]OrderRecord, error) {
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.LocationID > 0 {
+		add("location_id = $%d", f.LocationID)
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if f.Active {
+		add("status = ANY($%d)", ActiveOrderStatuses)
+	}
+	if !f.From.IsZero() {
+		add("created_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		add("created_at < $%d", f.To)
+	}
+	sql := `SELECT ` + orderRecordColumns + ` FROM orders`
+	if len(where) > 0 {
+		sql += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	args = append(args, f.Limit, f.Offset)
+	sql += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []OrderRecord
+	for rows.Next() {
+		var o OrderRecord
+		if err := scanOrderRecord(rows, &o); err != nil {
+			return nil, err
+		}
+		list = append(list, o)
+	}
+	return list, rows.Err()
+}
+
+// GetOrderRecord returns one order for the HTTP API, or nil if it does not exist.
+func GetOrderRecord(ctx context.Context, id int64) (*OrderRecord, error) {
+	var o OrderRecord
+	err := scanOrderRecord(db.Pool.QueryRow(ctx, `SELECT `+orderRecordColumns+` FROM orders WHERE id = $1`, id), &o)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &o, nil
+}
@@ -18,7 +18,7 @@ func EnsureOrderMessagePointersTable(ctx context.Context) error {
 			chat_id BIGINT NOT NULL,
 			message_id INT NOT NULL,
 			updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
-			PRIMARY KEY (order_id, audience)
+			CONSTRAINT order_message_pointers_chat_pkey PRIMARY KEY (order_id, audience, chat_id)
 		);
 		CREATE INDEX IF NOT EXISTS idx_order_message_pointers_order_id ON order_message_pointers(order_id);
 	`)
@@ -29,34 +29,64 @@ func isRelationNotExist(err error) bool {
 	return err != nil && strings.Contains(err.Error(), "order_message_pointers") && strings.Contains(err.Error(), "does not exist")
 }
 
-// GetOrderMessagePointer returns the chat_id and message_id for the order's card for the given audience.
+// OrderMessagePointer is the order card message sent to one chat.
+type OrderMessagePointer struct {
+	ChatID    int64
+	MessageID int
+}
+
+// GetOrderMessagePointer returns the message_id of the order's card for the given audience in chatID.
 // ok is false if no pointer exists.
-func GetOrderMessagePointer(ctx context.Context, orderID int64, audience string) (chatID int64, messageID int, ok bool, err error) {
+func GetOrderMessagePointer(ctx context.Context, orderID int64, audience string, chatID int64) (messageID int, ok bool, err error) {
 	err = db.Pool.QueryRow(ctx, `
-		SELECT chat_id, message_id FROM order_message_pointers WHERE order_id = $1 AND audience = $2`,
-		orderID, audience,
-	).Scan(&chatID, &messageID)
+		SELECT message_id FROM order_message_pointers WHERE order_id = $1 AND audience = $2 AND chat_id = $3`,
+		orderID, audience, chatID,
+	).Scan(&messageID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return 0, 0, false, nil
+			return 0, false, nil
 		}
 		if isRelationNotExist(err) {
 			if ensureErr := EnsureOrderMessagePointersTable(ctx); ensureErr != nil {
-				return 0, 0, false, ensureErr
+				return 0, false, ensureErr
 			}
-			return GetOrderMessagePointer(ctx, orderID, audience)
+			return GetOrderMessagePointer(ctx, orderID, audience, chatID)
+		}
+		return 0, false, err
+	}
+	return messageID, true, nil
+}
+
+// ListOrderMessagePointers returns every card of the order for the audience (admin cards go to several staff chats).
+func ListOrderMessagePointers(ctx context.Context, orderID int64, audience string) ([]OrderMessagePointer, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT chat_id, message_id FROM order_message_pointers WHERE order_id = $1 AND audience = $2 ORDER BY updated_at`,
+		orderID, audience,
+	)
+	if err != nil {
+		if isRelationNotExist(err) {
+			return nil, EnsureOrderMessagePointersTable(ctx)
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	var list []OrderMessagePointer
+	for rows.Next() {
+		var p OrderMessagePointer
+		if err := rows.Scan(&p.ChatID, &p.MessageID); err != nil {
+			return nil, err
 		}
-		return 0, 0, false, err
+		list = append(list, p)
 	}
-	return chatID, messageID, true, nil
+	return list, rows.Err()
 }
 
-// UpsertOrderMessagePointer inserts or updates the message pointer for (order_id, audience).
+// UpsertOrderMessagePointer inserts or updates the message pointer for (order_id, audience, chat_id).
 func UpsertOrderMessagePointer(ctx context.Context, orderID int64, audience string, chatID int64, messageID int) error {
 	_, err := db.Pool.Exec(ctx, `
 		INSERT INTO order_message_pointers (order_id, audience, chat_id, message_id, updated_at)
 		VALUES ($1, $2, $3, $4, now())
-		ON CONFLICT (order_id, audience) DO UPDATE SET chat_id = EXCLUDED.chat_id, message_id = EXCLUDED.message_id, updated_at = now()`,
+		ON CONFLICT (order_id, audience, chat_id) DO UPDATE SET message_id = EXCLUDED.message_id, updated_at = now()`,
 		orderID, audience, chatID, messageID,
 	)
 	if err != nil && isRelationNotExist(err) {
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Actors that fire order transitions.
+const (
+	OrderActorBranch = "branch" // restaurant staff (order card buttons in the message bot)
+	OrderActorDriver = "driver" // the driver the order is (or is being) assigned to
+)
+
+// Side effects of an order transition. applyOrderTransitionTx writes the history row and queues the notifications
+// (notification_outbox) in the caller's transaction; the driver earning is recorded by CompleteDeliveryByDriver.
+const (
+	OrderEffectHistory       = "history"        // order_status_history row
+	OrderEffectRefreshCards  = "refresh_cards"  // re-render admin, customer and driver order cards
+	OrderEffectOfferDrivers  = "offer_drivers"  // push a delivery order to nearby online drivers
+	OrderEffectDriverEarning = "driver_earning" // driver_earnings ledger row
+)
+
+// OrderFacts is what the transition guards look at besides the current status.
+type OrderFacts struct {
+	DeliveryType   string // "pickup", "delivery" or "" (not chosen yet)
+	DriverAssigned bool
+}
+
+// OrderTransition is one allowed status change: who may fire it, when (Guard, nil = always) and what follows.
+type OrderTransition struct {
+	From    string
+	To      string
+	Actor   string
+	Guard   func(OrderFacts) error
+	Effects []string
+}
+
+// Has reports whether the transition has the side effect.
+func (t OrderTransition) Has(effect string) bool {
+	for _, e := range t.Effects {
+		if e == effect {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// ErrOrderNotFound is returned when a status change names an order that does not exist.
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrOrderOtherBranch is returned when a branch changes the status of another branch's order.
+	ErrOrderOtherBranch = errors.New("order does not belong to your restaurant")
+	// ErrOrderTransition matches (errors.Is) every refusal of the state machine: no such transition, the other
+	// actor's step, a failed guard or a status changed meanwhile. The refusal reads as its own reason.
+	ErrOrderTransition = errors.New("order status transition refused")
+
+	errOrderHasDriver      = transitionRefusal("bu buyurtma driverga biriktirilgan. Yakunlashni driver qiladi")
+	errOrderIsDelivery     = transitionRefusal("bu buyurtma yetkazib berish uchun. Yakunlashni driver qiladi")
+	errOrderAlreadyTaken   = transitionRefusal("bu buyurtma allaqachon olingan")
+	errOrderDriverControls = transitionRefusal("bu buyurtma driverga biriktirilgan. Statusni driver o'zgartiradi")
+	errOrderBranchControls = transitionRefusal("bu statusni restoran o'zgartiradi")
+	errOrderStatusChanged  = transitionRefusal("status mos emas")
+)
+
+// orderTransitionError is a state machine refusal; it matches ErrOrderTransition.
+type orderTransitionError struct{ reason string }
+
+func (e *orderTransitionError) Error() string { return e.reason }
+
+func (e *orderTransitionError) Is(target error) bool { return target == ErrOrderTransition }
+
+// transitionRefusal returns a refusal reading as reason.
+func transitionRefusal(reason string) error {
+	return &orderTransitionError{reason: reason}
+}
+
+// orderStates lists every order status; terminal ones have no outgoing transitions.
+var orderStates = []string{
+	OrderStatusNew, OrderStatusRejected, OrderStatusPreparing, OrderStatusReady,
+	OrderStatusAssigned, OrderStatusPickedUp, OrderStatusDelivering, OrderStatusCompleted,
+}
+
+// orderTransitions is the order state machine: new -> preparing -> ready, then either the branch completes a pickup
+// or a driver takes it (assigned -> picked_up -> delivering -> completed); a new order can be rejected.
+var orderTransitions = []OrderTransition{
+	{From: OrderStatusNew, To: OrderStatusPreparing, Actor: OrderActorBranch,
+		Effects: []string{OrderEffectHistory, OrderEffectRefreshCards}},
+	{From: OrderStatusNew, To: OrderStatusRejected, Actor: OrderActorBranch,
+		Effects: []string{OrderEffectHistory, OrderEffectRefreshCards}},
+	{From: OrderStatusPreparing, To: OrderStatusReady, Actor: OrderActorBranch,
+		Effects: []string{OrderEffectHistory, OrderEffectRefreshCards, OrderEffectOfferDrivers}},
+	{From: OrderStatusReady, To: OrderStatusCompleted, Actor: OrderActorBranch, Guard: guardPickupWithoutDriver,
+		Effects: []string{OrderEffectHistory, OrderEffectRefreshCards}},
+	{From: OrderStatusReady, To: OrderStatusAssigned, Actor: OrderActorDriver, Guard: guardNoDriver,
+		Effects: []string{OrderEffectHistory, OrderEffectRefreshCards}},
+	{From: OrderStatusAssigned, To: OrderStatusPickedUp, Actor: OrderActorDriver,
+		Effects: []string{OrderEffectHistory, OrderEffectRefreshCards}},
+	{From: OrderStatusAssigned, To: OrderStatusCompleted, Actor: OrderActorDriver,
+		Effects: []string{OrderEffectHistory, OrderEffectRefreshCards, OrderEffectDriverEarning}},
+	{From: OrderStatusPickedUp, To: OrderStatusDelivering, Actor: OrderActorDriver,
+		Effects: []string{OrderEffectHistory, OrderEffectRefreshCards}},
+	{From: OrderStatusDelivering, To: OrderStatusCompleted, Actor: OrderActorDriver,
+		Effects: []string{OrderEffectHistory, OrderEffectRefreshCards, OrderEffectDriverEarning}},
+}
+
+func guardNoDriver(f OrderFacts) error {
+	if f.DriverAssigned {
+		return errOrderAlreadyTaken
+	}
+	return nil
+}
+
+// guardPickupWithoutDriver: the branch completes only pickup orders; delivery orders are completed by their driver.
+func guardPickupWithoutDriver(f OrderFacts) error {
+	if f.DriverAssigned {
+		return errOrderHasDriver
+	}
+	if f.DeliveryType != "pickup" {
+		return errOrderIsDelivery
+	}
+	return nil
+}
+
+// IsOrderStatus reports whether s is one of the order statuses.
+func IsOrderStatus(s string) bool {
+	for _, st := range orderStates {
+		if st == s {
+			return true
+		}
+	}
+	return false
+}
+
+// FindOrderTransition returns the transition from -> to, or nil if the state machine has none.
+func FindOrderTransition(from, to string) *OrderTransition {
+	for i := range orderTransitions {
+		if orderTransitions[i].From == from && orderTransitions[i].To == to {
+			return &orderTransitions[i]
+		}
+	}
+	return nil
+}
+
+// CheckOrderTransition returns the transition if actor may move an order with facts from -> to, else the reason it may not.
+func CheckOrderTransition(from, to, actor string, f OrderFacts) (*OrderTransition, error) {
+	t := FindOrderTransition(from, to)
+	if t == nil {
+		return nil, transitionRefusal(fmt.Sprintf("invalid status transition from %q to %q", from, to))
+	}
+	if t.Actor != actor {
+		if t.Actor == OrderActorDriver {
+			return nil, errOrderDriverControls
+		}
+		return nil, errOrderBranchControls
+	}
+	if t.Guard != nil {
+		if err := t.Guard(f); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// NextOrderStatuses returns the statuses actor may move an order with facts to from status from, in table order.
+func NextOrderStatuses(from, actor string, f OrderFacts) []string {
+	var next []string
+	for _, t := range orderTransitions {
+		if t.From != from {
+			continue
+		}
+		if _, err := CheckOrderTransition(from, t.To, actor, f); err == nil {
+			next = append(next, t.To)
+		}
+	}
+	return next
+}
+
+// ValidStatusTransition reports whether from -> to is allowed for some actor on an order with no delivery type
+// and no driver yet (so ready -> completed, which needs a pickup order, is not).
+func ValidStatusTransition(from, to string) bool {
+	t := FindOrderTransition(from, to)
+	return t != nil && (t.Guard == nil || t.Guard(OrderFacts{}) == nil)
+}
+
+// lockedOrder is the part of an order row the state machine needs, read under FOR UPDATE.
+type lockedOrder struct {
+	status     string
+	locationID int64
+	driverID   string // "" = no driver
+	facts      OrderFacts
+}
+
+// lockOrderTx locks the order row and returns its status, location, driver and guard facts.
+func lockOrderTx(ctx context.Context, tx pgx.Tx, orderID int64) (lockedOrder, error) {
+	var o lockedOrder
+	var driverID, deliveryType *string
+	err := tx.QueryRow(ctx, `
+		SELECT status, COALESCE(location_id, 0), driver_id, delivery_type FROM orders WHERE id = $1 FOR UPDATE`,
+		orderID,
+	).Scan(&o.status, &o.locationID, &driverID, &deliveryType)
+	if err != nil {
+		return o, err
+	}
+	if driverID != nil {
+		o.driverID = *driverID
+		o.facts.DriverAssigned = true
+	}
+	if deliveryType != nil {
+		o.facts.DeliveryType = *deliveryType
+	}
+	return o, nil
+}
+
+// applyOrderTransitionTx sets the new status (only if the order is still in t.From), writes the history row
+// and queues the transition's notifications. Only delivery orders are offered to drivers.
+func applyOrderTransitionTx(ctx context.Context, tx pgx.Tx, orderID int64, t *OrderTransition, f OrderFacts, actorID int64) error {
+	res, err := tx.Exec(ctx, `UPDATE orders SET status = $1, updated_at = now() WHERE id = $2 AND status = $3`, t.To, orderID, t.From)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return errOrderStatusChanged
+	}
+	if err := recordOrderHistoryTx(ctx, tx, orderID, t.From, t.To, actorID); err != nil {
+		return err
+	}
+	if t.Has(OrderEffectRefreshCards) {
+		if err := EnqueueOrderNotification(ctx, tx, OutboxKindOrderCards, orderID); err != nil {
+			return err
+		}
+	}
+	if t.Has(OrderEffectOfferDrivers) && f.DeliveryType == "delivery" {
+		if err := EnqueueOrderNotification(ctx, tx, OutboxKindOfferDrivers, orderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordOrderHistoryTx writes the history row (from "" = order placed) and queues the webhook events it stands for.
+func recordOrderHistoryTx(ctx context.Context, tx pgx.Tx, orderID int64, from, to string, actorID int64) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO order_status_history (order_id, from_status, to_status, actor_id)
+		VALUES ($1, $2, $3, $4)`,
+		orderID, from, to, actorID,
+	); err != nil {
+		return err
+	}
+	return enqueueWebhookEventsTx(ctx, tx, orderID, from, to)
+}
+
+// UpdateOrderStatus moves an order of the admin's restaurant to newStatus as the branch actor and records history.
+// actorID is the Telegram user ID of the admin who performed the change. Notifications go through the outbox.
+// Errors: ErrOrderNotFound, ErrOrderOtherBranch, a refusal matching ErrOrderTransition, or a database error.
+func UpdateOrderStatus(ctx context.Context, orderID int64, newStatus string, adminLocationID int64, actorID int64) (*OrderTransition, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	o, err := lockOrderTx(ctx, tx, orderID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	if o.locationID != adminLocationID {
+		return nil, ErrOrderOtherBranch
+	}
+	t, err := CheckOrderTransition(o.status, newStatus, OrderActorBranch, o.facts)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyOrderTransitionTx(ctx, tx, orderID, t, o.facts, actorID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestCheckOrderTransitionExhaustive checks every (from, to, actor, facts) combination against the expected rules.
+func TestCheckOrderTransitionExhaustive(t *testing.T) {
+	type key struct{ from, to, actor string }
+	// allowed lists the permitted moves; the func says whether the facts pass the guard (nil = always).
+	pickupNoDriver := func(f OrderFacts) bool { return f.DeliveryType == "pickup" && !f.DriverAssigned }
+	noDriver := func(f OrderFacts) bool { return !f.DriverAssigned }
+	allowed := map[key]func(OrderFacts) bool{
+		{OrderStatusNew, OrderStatusPreparing, OrderActorBranch}:        nil,
+		{OrderStatusNew, OrderStatusRejected, OrderActorBranch}:         nil,
+		{OrderStatusPreparing, OrderStatusReady, OrderActorBranch}:      nil,
+		{OrderStatusReady, OrderStatusCompleted, OrderActorBranch}:      pickupNoDriver,
+		{OrderStatusReady, OrderStatusAssigned, OrderActorDriver}:       noDriver,
+		{OrderStatusAssigned, OrderStatusPickedUp, OrderActorDriver}:    nil,
+		{OrderStatusAssigned, OrderStatusCompleted, OrderActorDriver}:   nil,
+		{OrderStatusPickedUp, OrderStatusDelivering, OrderActorDriver}:  nil,
+		{OrderStatusDelivering, OrderStatusCompleted, OrderActorDriver}: nil,
+	}
+	var facts []OrderFacts
+	for _, dt := range []string{"", "pickup", "delivery"} {
+		for _, assigned := range []bool{false, true} {
+			facts = append(facts, OrderFacts{DeliveryType: dt, DriverAssigned: assigned})
+		}
+	}
+	states := append([]string{"", "unknown"}, orderStates...)
+	for _, from := range states {
+		for _, to := range states {
+			for _, actor := range []string{OrderActorBranch, OrderActorDriver, ""} {
+				for _, f := range facts {
+					guard, ok := allowed[key{from, to, actor}]
+					want := ok && (guard == nil || guard(f))
+					tr, err := CheckOrderTransition(from, to, actor, f)
+					if got := err == nil; got != want {
+						t.Errorf("CheckOrderTransition(%q, %q, %q, %+v) allowed = %v, want %v (err: %v)", from, to, actor, f, got, want, err)
+						continue
+					}
+					if want && (tr == nil || tr.From != from || tr.To != to || tr.Actor != actor) {
+						t.Errorf("CheckOrderTransition(%q, %q, %q) returned %+v", from, to, actor, tr)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCheckOrderTransitionErrors(t *testing.T) {
+	tests := []struct {
+		name            string
+		from, to, actor string
+		facts           OrderFacts
+		want            error
+	}{
+		{"branch completes delivery order", OrderStatusReady, OrderStatusCompleted, OrderActorBranch, OrderFacts{DeliveryType: "delivery"}, errOrderIsDelivery},
+		{"branch completes undecided order", OrderStatusReady, OrderStatusCompleted, OrderActorBranch, OrderFacts{}, errOrderIsDelivery},
+		{"branch completes order with driver", OrderStatusReady, OrderStatusCompleted, OrderActorBranch, OrderFacts{DeliveryType: "pickup", DriverAssigned: true}, errOrderHasDriver},
+		{"branch moves driver's order", OrderStatusAssigned, OrderStatusPickedUp, OrderActorBranch, OrderFacts{DriverAssigned: true}, errOrderDriverControls},
+		{"driver prepares order", OrderStatusNew, OrderStatusPreparing, OrderActorDriver, OrderFacts{}, errOrderBranchControls},
+		{"second driver accepts", OrderStatusReady, OrderStatusAssigned, OrderActorDriver, OrderFacts{DriverAssigned: true}, errOrderAlreadyTaken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CheckOrderTransition(tt.from, tt.to, tt.actor, tt.facts)
+			if err != tt.want {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if !errors.Is(err, ErrOrderTransition) {
+				t.Errorf("err %v does not match ErrOrderTransition", err)
+			}
+		})
+	}
+}
+
+func TestOrderTransitionEffects(t *testing.T) {
+	for _, tr := range orderTransitions {
+		if !tr.Has(OrderEffectHistory) || !tr.Has(OrderEffectRefreshCards) {
+			t.Errorf("%s -> %s: every transition must write history and refresh cards", tr.From, tr.To)
+		}
+		wantEarning := tr.To == OrderStatusCompleted && tr.Actor == OrderActorDriver
+		if tr.Has(OrderEffectDriverEarning) != wantEarning {
+			t.Errorf("%s -> %s by %s: driver earning = %v, want %v", tr.From, tr.To, tr.Actor, tr.Has(OrderEffectDriverEarning), wantEarning)
+		}
+		if tr.Has(OrderEffectOfferDrivers) != (tr.To == OrderStatusReady) {
+			t.Errorf("%s -> %s: offer to drivers only when the order becomes ready", tr.From, tr.To)
+		}
+	}
+}
+
+func TestOrderStatesShape(t *testing.T) {
+	known := map[string]bool{}
+	for _, s := range orderStates {
+		known[s] = true
+	}
+	seen := map[[2]string]bool{}
+	for _, tr := range orderTransitions {
+		if !known[tr.From] || !known[tr.To] {
+			t.Errorf("transition %s -> %s uses an unknown state", tr.From, tr.To)
+		}
+		if tr.Actor != OrderActorBranch && tr.Actor != OrderActorDriver {
+			t.Errorf("transition %s -> %s has unknown actor %q", tr.From, tr.To, tr.Actor)
+		}
+		k := [2]string{tr.From, tr.To}
+		if seen[k] {
+			t.Errorf("duplicate transition %s -> %s", tr.From, tr.To)
+		}
+		seen[k] = true
+		if tr.From == OrderStatusCompleted || tr.From == OrderStatusRejected {
+			t.Errorf("terminal state %s has outgoing transition to %s", tr.From, tr.To)
+		}
+	}
+	// Every state is reachable from new.
+	reached := map[string]bool{OrderStatusNew: true}
+	for changed := true; changed; {
+		changed = false
+		for _, tr := range orderTransitions {
+			if reached[tr.From] && !reached[tr.To] {
+				reached[tr.To] = true
+				changed = true
+			}
+		}
+	}
+	for _, s := range orderStates {
+		if !reached[s] {
+			t.Errorf("state %s is not reachable from %s", s, OrderStatusNew)
+		}
+	}
+}
+
+func TestNextOrderStatuses(t *testing.T) {
+	pickup := OrderFacts{DeliveryType: "pickup"}
+	delivery := OrderFacts{DeliveryType: "delivery"}
+	tests := []struct {
+		from  string
+		actor string
+		f     OrderFacts
+		want  []string
+	}{
+		{OrderStatusNew, OrderActorBranch, OrderFacts{}, []string{OrderStatusPreparing, OrderStatusRejected}},
+		{OrderStatusPreparing, OrderActorBranch, delivery, []string{OrderStatusReady}},
+		{OrderStatusReady, OrderActorBranch, pickup, []string{OrderStatusCompleted}},
+		{OrderStatusReady, OrderActorBranch, delivery, nil},
+		{OrderStatusReady, OrderActorDriver, delivery, []string{OrderStatusAssigned}},
+		{OrderStatusAssigned, OrderActorBranch, OrderFacts{DeliveryType: "delivery", DriverAssigned: true}, nil},
+		{OrderStatusCompleted, OrderActorBranch, pickup, nil},
+	}
+	for _, tt := range tests {
+		got := NextOrderStatuses(tt.from, tt.actor, tt.f)
+		if len(got) != len(tt.want) {
+			t.Errorf("NextOrderStatuses(%s, %s, %+v) = %v, want %v", tt.from, tt.actor, tt.f, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("NextOrderStatuses(%s, %s, %+v) = %v, want %v", tt.from, tt.actor, tt.f, got, tt.want)
+				break
+			}
+		}
+	}
+}
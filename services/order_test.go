@@ -1,11 +1,6 @@
 package services
 
-import (
-	"strings"
-	"testing"
-
-	"food-telegram/models"
-)
+import "testing"
 
 func TestValidStatusTransition(t *testing.T) {
 	tests := []struct {
@@ -34,18 +29,3 @@ func TestValidStatusTransition(t *testing.T) {
 		}
 	}
 }
-
-func TestCustomerMessageForOrderStatus(t *testing.T) {
-	o := &models.Order{ID: 123, GrandTotal: 75000}
-	m := CustomerMessageForOrderStatus(o, OrderStatusPreparing)
-	if m == "" {
-		t.Error("expected non-empty message for preparing")
-	}
-	if !strings.Contains(m, "123") || !strings.Contains(m, "75000") {
-		t.Errorf("message should contain order id and total: %s", m)
-	}
-	m = CustomerMessageForOrderStatus(o, OrderStatusCompleted)
-	if !strings.Contains(m, "yetkazildi") {
-		t.Errorf("completed message should contain Uzbek 'yetkazildi': %s", m)
-	}
-}
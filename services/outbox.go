@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Outbox row kinds. Event rows are queued with the state change and fanned out by the worker into delivery rows.
+const (
+	OutboxKindOrderNew     = "order_new"     // event: cards for customer and staff, plus the delivery pin for staff
+	OutboxKindOrderCards   = "order_cards"   // event: refresh every card of the order
+	OutboxKindOfferDrivers = "offer_drivers" // event: offer a ready delivery order to nearby drivers
+	OutboxKindOrderCard    = "order_card"    // delivery: upsert one card (rendered at send time)
+	OutboxKindDriverOffer  = "driver_offer"  // delivery: offer to one driver, skipped if the order was taken meanwhile
+	OutboxKindMessage      = "message"       // delivery: plain text or location
+)
+
+// Outbox row statuses.
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSending = "sending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusFailed  = "failed"
+)
+
+const (
+	// OutboxMaxAttempts is how many times a row is tried before it is marked failed.
+	OutboxMaxAttempts = 10
+	outboxBaseDelay   = 5 * time.Second
+	outboxMaxDelay    = 15 * time.Minute
+
+	// OutboxSentRetention is how long delivered rows are kept before PurgeOutbox deletes them.
+	OutboxSentRetention = 7 * 24 * time.Hour
+	// OutboxFailedRetention is how long failed rows stay for /outbox follow-up.
+	OutboxFailedRetention = 30 * 24 * time.Hour
+	// outboxPurgeBatch caps one DELETE so a large backlog does not hold a long lock.
+	outboxPurgeBatch = 5000
+)
+
+// OutboxPayload is the content of message and driver_offer rows (order cards are rendered at send time).
+type OutboxPayload struct {
+	Text    string              `json:"text,omitempty"`
+	Buttons [][]OrderCardButton `json:"buttons,omitempty"`
+	Lat     float64             `json:"lat,omitempty"`
+	Lon     float64             `json:"lon,omitempty"`
+	// RemoveKeyboard hides the reply keyboard (e.g. location sharing) when the message has no buttons.
+	RemoveKeyboard bool `json:"remove_keyboard,omitempty"`
+	// Bulk sends on the low lane so background reminders do not hold up order cards.
+	Bulk bool `json:"bulk,omitempty"`
+}
+
+// OutboxItem is one notification_outbox row.
+type OutboxItem struct {
+	ID        int64
+	Kind      string
+	OrderID   int64
+	Audience  string // admin, customer or driver (which bot sends it)
+	ChatID    int64
+	Payload   OutboxPayload
+	Attempts  int
+	LastError string
+	CreatedAt time.Time
+}
+
+// OutboxRetryDelay returns how long to wait before the next attempt after attempts failed tries:
+// exponential from 5s, capped at 15 minutes, and never shorter than Telegram's retry_after.
+func OutboxRetryDelay(attempts int, retryAfter time.Duration) time.Duration {
+	d := outboxBaseDelay
+	for i := 1; i < attempts && d < outboxMaxDelay; i++ {
+		d *= 2
+	}
+	if d > outboxMaxDelay {
+		d = outboxMaxDelay
+	}
+	if retryAfter > d {
+		d = retryAfter
+	}
+	return d
+}
+
+// TelegramErrorPermanent reports whether a Telegram API error code means retrying cannot help
+// (403 bot blocked / user deactivated, 400 chat not found, ...). 0 is a network error; 429 and 5xx are transient.
+func TelegramErrorPermanent(code int) bool {
+	return code >= 400 && code < 500 && code != 429
+}
+
+// EnqueueOrderNotification queues an order event (OutboxKindOrderNew, OrderCards or OfferDrivers) in q,
+// normally the transaction that changed the order. A pending order_cards row for the same order absorbs repeats.
+func EnqueueOrderNotification(ctx context.Context, q auditExecer, kind string, orderID int64) error {
+	if _, err := q.Exec(ctx, `
+		INSERT INTO notification_outbox (kind, order_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`,
+		kind, orderID,
+	); err != nil {
+		return fmt.Errorf("enqueue %s: %w", kind, err)
+	}
+	return nil
+}
+
+// EnqueueOrderCards queues a refresh of every card of the order outside a state change (e.g. a new driver position changes the ETA).
+func EnqueueOrderCards(ctx context.Context, orderID int64) error {
+	return EnqueueOrderNotification(ctx, db.Pool, OutboxKindOrderCards, orderID)
+}
+
+// EnqueueOutboxMessage queues a message row (text or location) in q, normally the transaction of the change it
+// reports, so the notification is sent exactly when the change commits.
+func EnqueueOutboxMessage(ctx context.Context, q auditExecer, it OutboxItem) error {
+	payload, err := json.Marshal(it.Payload)
+	if err != nil {
+		return fmt.Errorf("outbox payload: %w", err)
+	}
+	var orderID *int64
+	if it.OrderID != 0 {
+		orderID = &it.OrderID
+	}
+	if _, err := q.Exec(ctx, `
+		INSERT INTO notification_outbox (kind, order_id, audience, chat_id, payload) VALUES ($1, $2, $3, $4, $5::jsonb)`,
+		OutboxKindMessage, orderID, it.Audience, it.ChatID, string(payload),
+	); err != nil {
+		return fmt.Errorf("enqueue message: %w", err)
+	}
+	return nil
+}
+
+// EnqueueOutboxMessages queues message rows in one transaction, for notifications not tied to a state change
+// of their own (background reminders, a notice followed by the driver panel). They are sent in the given order.
+func EnqueueOutboxMessages(ctx context.Context, items ...OutboxItem) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	for _, it := range items {
+		if err := EnqueueOutboxMessage(ctx, tx, it); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// ClaimOutbox marks up to limit due rows as sending (for lease; a crashed worker's rows are picked up again after it)
+// and returns them oldest first.
+func ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]OutboxItem, error) {
+	rows, err := db.Pool.Query(ctx, `
+		UPDATE notification_outbox SET status = 'sending', attempts = attempts + 1, next_attempt_at = now() + $2 * interval '1 second'
+		WHERE id IN (
+			SELECT id FROM notification_outbox
+			WHERE status IN ('pending', 'sending') AND next_attempt_at <= now()
+			ORDER BY next_attempt_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, kind, COALESCE(order_id, 0), COALESCE(audience, ''), COALESCE(chat_id, 0), COALESCE(payload::text, ''), attempts, created_at`,
+		limit, lease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox: %w", err)
+	}
+	defer rows.Close()
+	var list []OutboxItem
+	for rows.Next() {
+		var it OutboxItem
+		var payload string
+		if err := rows.Scan(&it.ID, &it.Kind, &it.OrderID, &it.Audience, &it.ChatID, &payload, &it.Attempts, &it.CreatedAt); err != nil {
+			return nil, err
+		}
+		if payload != "" {
+			if err := json.Unmarshal([]byte(payload), &it.Payload); err != nil {
+				return nil, fmt.Errorf("outbox %d payload: %w", it.ID, err)
+			}
+		}
+		list = append(list, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// FanOutOutbox queues the delivery rows of an event row and marks the event sent, in one transaction.
+func FanOutOutbox(ctx context.Context, parentID int64, children []OutboxItem) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	for _, c := range children {
+		var payload *string
+		if c.Kind != OutboxKindOrderCard {
+			b, err := json.Marshal(c.Payload)
+			if err != nil {
+				return fmt.Errorf("outbox payload: %w", err)
+			}
+			s := string(b)
+			payload = &s
+		}
+		var orderID *int64
+		if c.OrderID != 0 {
+			orderID = &c.OrderID
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO notification_outbox (kind, order_id, audience, chat_id, payload) VALUES ($1, $2, $3, $4, $5::jsonb)
+			ON CONFLICT DO NOTHING`,
+			c.Kind, orderID, c.Audience, c.ChatID, payload,
+		); err != nil {
+			return fmt.Errorf("fan out outbox %d: %w", parentID, err)
+		}
+	}
+	if _, err := tx.Exec(ctx, `UPDATE notification_outbox SET status = 'sent', sent_at = now() WHERE id = $1`, parentID); err != nil {
+		return fmt.Errorf("fan out outbox %d: %w", parentID, err)
+	}
+	return tx.Commit(ctx)
+}
+
+// MarkOutboxSent marks a row delivered (or skipped because it no longer applies).
+func MarkOutboxSent(ctx context.Context, id int64) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE notification_outbox SET status = 'sent', sent_at = now(), last_error = NULL WHERE id = $1`, id)
+	return err
+}
+
+// MarkOutboxRetry puts a row back to pending to be tried again after delay.
+func MarkOutboxRetry(ctx context.Context, id int64, delay time.Duration, lastError string) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE notification_outbox SET status = 'pending', next_attempt_at = now() + $2 * interval '1 second', last_error = $3 WHERE id = $1`,
+		id, delay.Seconds(), lastError,
+	)
+	if isUniqueViolation(err) {
+		// A newer pending row for the same card already exists; it renders the latest state, so this one is done.
+		return MarkOutboxSent(ctx, id)
+	}
+	return err
+}
+
+// MarkOutboxFailed gives up on a row (permanent error or too many attempts); it stays for /outbox follow-up.
+func MarkOutboxFailed(ctx context.Context, id int64, lastError string) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE notification_outbox SET status = 'failed', failed_at = now(), last_error = $2 WHERE id = $1`, id, lastError)
+	return err
+}
+
+// ListFailedOutbox returns the most recently failed rows.
+func ListFailedOutbox(ctx context.Context, limit int) ([]OutboxItem, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, kind, COALESCE(order_id, 0), COALESCE(audience, ''), COALESCE(chat_id, 0), attempts, COALESCE(last_error, ''), created_at
+		FROM notification_outbox WHERE status = 'failed'
+		ORDER BY failed_at DESC LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list failed outbox: %w", err)
+	}
+	defer rows.Close()
+	var list []OutboxItem
+	for rows.Next() {
+		var it OutboxItem
+		if err := rows.Scan(&it.ID, &it.Kind, &it.OrderID, &it.Audience, &it.ChatID, &it.Attempts, &it.LastError, &it.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, it)
+	}
+	return list, rows.Err()
+}
+
+// CountOutbox returns the number of rows waiting for delivery and the number of failed rows.
+func CountOutbox(ctx context.Context) (pending, failed int, err error) {
+	err = db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE status IN ('pending', 'sending')), COUNT(*) FILTER (WHERE status = 'failed')
+		FROM notification_outbox`,
+	).Scan(&pending, &failed)
+	return pending, failed, err
+}
+
+// RetryFailedOutbox queues a failed row again with a fresh attempt budget (e.g. after the user unblocked the bot).
+func RetryFailedOutbox(ctx context.Context, id int64) error {
+	res, err := db.Pool.Exec(ctx, `
+		UPDATE notification_outbox SET status = 'pending', attempts = 0, next_attempt_at = now(), failed_at = NULL
+		WHERE id = $1 AND status = 'failed'`,
+		id,
+	)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("bu xabar allaqachon navbatda")
+	}
+	if err != nil {
+		return fmt.Errorf("retry outbox: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("xato holatidagi xabar topilmadi")
+	}
+	return nil
+}
+
+// OutboxPurgeCutoffs returns the times before which sent and failed rows are deleted at now.
+func OutboxPurgeCutoffs(now time.Time) (sentBefore, failedBefore time.Time) {
+	return now.Add(-OutboxSentRetention), now.Add(-OutboxFailedRetention)
+}
+
+// PurgeOutbox deletes rows sent before OutboxSentRetention and rows failed before OutboxFailedRetention, in batches,
+// and returns how many it deleted. Pending and sending rows are never touched.
+func PurgeOutbox(ctx context.Context, now time.Time) (int64, error) {
+	sentBefore, failedBefore := OutboxPurgeCutoffs(now)
+	var total int64
+	for {
+		res, err := db.Pool.Exec(ctx, `
+			DELETE FROM notification_outbox WHERE id IN (
+				SELECT id FROM notification_outbox
+				WHERE (status = 'sent' AND sent_at < $1) OR (status = 'failed' AND failed_at < $2)
+				LIMIT $3)`,
+			sentBefore, failedBefore, outboxPurgeBatch,
+		)
+		if err != nil {
+			return total, fmt.Errorf("purge outbox: %w", err)
+		}
+		total += res.RowsAffected()
+		if res.RowsAffected() < outboxPurgeBatch {
+			return total, nil
+		}
+	}
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutboxRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts   int
+		retryAfter time.Duration
+		want       time.Duration
+	}{
+		{0, 0, 5 * time.Second},
+		{1, 0, 5 * time.Second},
+		{2, 0, 10 * time.Second},
+		{3, 0, 20 * time.Second},
+		{8, 0, 640 * time.Second},
+		{9, 0, 15 * time.Minute},
+		{50, 0, 15 * time.Minute},
+		{1, 30 * time.Second, 30 * time.Second},
+		{3, 2 * time.Second, 20 * time.Second},
+		{10, time.Hour, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := OutboxRetryDelay(tt.attempts, tt.retryAfter); got != tt.want {
+			t.Errorf("OutboxRetryDelay(%d, %s) = %s, want %s", tt.attempts, tt.retryAfter, got, tt.want)
+		}
+	}
+}
+
+func TestTelegramErrorPermanent(t *testing.T) {
+	tests := []struct {
+		code int
+		want bool
+	}{
+		{0, false},
+		{400, true},
+		{403, true},
+		{429, false},
+		{500, false},
+		{502, false},
+	}
+	for _, tt := range tests {
+		if got := TelegramErrorPermanent(tt.code); got != tt.want {
+			t.Errorf("TelegramErrorPermanent(%d) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestOutboxPurgeCutoffs(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	sent, failed := OutboxPurgeCutoffs(now)
+	if want := time.Date(2024, 3, 24, 12, 0, 0, 0, time.UTC); !sent.Equal(want) {
+		t.Errorf("sent cutoff = %v, want %v", sent, want)
+	}
+	if want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC); !failed.Equal(want) {
+		t.Errorf("failed cutoff = %v, want %v", failed, want)
+	}
+	if !failed.Before(sent) {
+		t.Error("failed rows must be kept longer than sent ones")
+	}
+}
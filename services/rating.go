@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"food-telegram/db"
+	"food-telegram/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Rated aspects of an order (order_ratings columns).
+const (
+	RatingFood     = "food"     // the branch
+	RatingDelivery = "delivery" // the driver
+)
+
+const (
+	// LowRatingMax is the highest score that alerts the branch staff at once.
+	LowRatingMax = 2
+	// RatingCommentMaxLen caps the customer's comment (in characters).
+	RatingCommentMaxLen = 500
+)
+
+// OrderRating is the customer's rating of one order; 0 = not rated yet.
+type OrderRating struct {
+	OrderID  int64
+	Food     int
+	Delivery int
+	Comment  string
+}
+
+// RatesDelivery reports whether the customer is asked to rate the delivery: delivery orders a driver brought.
+func RatesDelivery(o *models.Order) bool {
+	return o.DeliveryType != nil && *o.DeliveryType == "delivery" && o.DriverID != nil && *o.DriverID != ""
+}
+
+// Low reports whether any score is at most LowRatingMax.
+func (r *OrderRating) Low() bool {
+	return (r.Food > 0 && r.Food <= LowRatingMax) || (r.Delivery > 0 && r.Delivery <= LowRatingMax)
+}
+
+// GetOrderRating returns the order's rating, or nil if the customer has not rated it.
+func GetOrderRating(ctx context.Context, orderID int64) (*OrderRating, error) {
+	r := OrderRating{OrderID: orderID}
+	err := db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(food, 0), COALESCE(delivery, 0), COALESCE(comment, '') FROM order_ratings WHERE order_id = $1`,
+		orderID,
+	).Scan(&r.Food, &r.Delivery, &r.Comment)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ratedOrder is what RateOrder and SetOrderRatingComment read of the order, locked for the change.
+type ratedOrder struct {
+	locationID *int64
+	driverID   *string
+	delivered  bool
+}
+
+func lockRatedOrder(ctx context.Context, tx pgx.Tx, orderID, userID int64) (*ratedOrder, error) {
+	var o ratedOrder
+	var status string
+	err := tx.QueryRow(ctx, `
+		SELECT status, location_id, driver_id::text, COALESCE(delivery_type, '') = 'delivery' AND driver_id IS NOT NULL
+		FROM orders WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+		orderID, userID,
+	).Scan(&status, &o.locationID, &o.driverID, &o.delivered)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("buyurtma topilmadi")
+		}
+		return nil, err
+	}
+	if status != OrderStatusCompleted {
+		return nil, fmt.Errorf("faqat yakunlangan buyurtmani baholash mumkin")
+	}
+	return &o, nil
+}
+
+// RateOrder stores the customer's 1-5 score of the order's food or delivery. Each aspect is rated once; a repeat
+// is ignored. The customer's card is refreshed, and a score of LowRatingMax or less alerts the branch staff.
+func RateOrder(ctx context.Context, orderID, userID int64, aspect string, stars int) error {
+	if aspect != RatingFood && aspect != RatingDelivery {
+		return fmt.Errorf("invalid rating aspect: %s", aspect)
+	}
+	if stars < 1 || stars > 5 {
+		return fmt.Errorf("baho 1 dan 5 gacha bo'lishi kerak")
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	o, err := lockRatedOrder(ctx, tx, orderID, userID)
+	if err != nil {
+		return err
+	}
+	if aspect == RatingDelivery && !o.delivered {
+		return fmt.Errorf("bu buyurtma yetkazib berilmagan")
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO order_ratings (order_id, location_id, driver_id) VALUES ($1, $2, $3::uuid)
+		ON CONFLICT (order_id) DO NOTHING`,
+		orderID, o.locationID, o.driverID,
+	); err != nil {
+		return fmt.Errorf("rate order: %w", err)
+	}
+	// aspect is one of the two column names checked above
+	tag, err := tx.Exec(ctx, `UPDATE order_ratings SET `+aspect+` = $2, updated_at = now() WHERE order_id = $1 AND `+aspect+` IS NULL`,
+		orderID, stars)
+	if err != nil {
+		return fmt.Errorf("rate order: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if stars <= LowRatingMax {
+		label := "🍽 Ovqat"
+		if aspect == RatingDelivery {
+			label = "🛵 Yetkazish"
+		}
+		text := fmt.Sprintf("⚠️ Past baho — buyurtma #%d\n%s: %s", orderID, label, RatingStars(stars))
+		if err := enqueueRatingAlert(ctx, tx, orderID, o.locationID, text); err != nil {
+			return err
+		}
+	}
+	if err := EnqueueOrderNotification(ctx, tx, OutboxKindOrderCards, orderID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetOrderRatingComment stores the customer's comment on an order they rated (once). A comment on a low rating is
+// forwarded to the branch staff like the rating itself.
+func SetOrderRatingComment(ctx context.Context, orderID, userID int64, comment string) error {
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return fmt.Errorf("izoh bo'sh")
+	}
+	if utf8.RuneCountInString(comment) > RatingCommentMaxLen {
+		comment = string([]rune(comment)[:RatingCommentMaxLen])
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	o, err := lockRatedOrder(ctx, tx, orderID, userID)
+	if err != nil {
+		return err
+	}
+	r := OrderRating{OrderID: orderID}
+	err = tx.QueryRow(ctx, `
+		UPDATE order_ratings SET comment = $2, updated_at = now() WHERE order_id = $1 AND comment IS NULL
+		RETURNING COALESCE(food, 0), COALESCE(delivery, 0)`,
+		orderID, comment,
+	).Scan(&r.Food, &r.Delivery)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil // not rated yet, or commented already
+		}
+		return fmt.Errorf("rating comment: %w", err)
+	}
+	if r.Low() {
+		text := fmt.Sprintf("💬 Past baho izohi — buyurtma #%d\n%s", orderID, comment)
+		if err := enqueueRatingAlert(ctx, tx, orderID, o.locationID, text); err != nil {
+			return err
+		}
+	}
+	if err := EnqueueOrderNotification(ctx, tx, OutboxKindOrderCards, orderID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// enqueueRatingAlert queues text, through the message bot, for the branch's owners and its staff on shift.
+func enqueueRatingAlert(ctx context.Context, tx pgx.Tx, orderID int64, locationID *int64, text string) error {
+	if locationID == nil {
+		return nil
+	}
+	staff, err := ListBranchStaff(ctx, *locationID)
+	if err != nil {
+		return err
+	}
+	for _, st := range staff {
+		if !st.OnShift && st.Role != BranchRoleOwner {
+			continue
+		}
+		if err := EnqueueOutboxMessage(ctx, tx, OutboxItem{OrderID: orderID, Audience: "admin", ChatID: st.AdminUserID,
+			Payload: OutboxPayload{Text: text}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RatingStars draws a 1-5 score as stars ("★★★☆☆").
+func RatingStars(n int) string {
+	if n < 0 {
+		n = 0
+	}
+	if n > 5 {
+		n = 5
+	}
+	return strings.Repeat("★", n) + strings.Repeat("☆", 5-n)
+}
+
+// RatingScore is an average score and how many ratings it is built from.
+type RatingScore struct {
+	Avg   float64
+	Count int
+}
+
+// String is "4.6 (12)".
+func (s RatingScore) String() string {
+	return fmt.Sprintf("%.1f (%d)", s.Avg, s.Count)
+}
+
+// ListLocationRatings returns the food score of each given branch that has ratings.
+func ListLocationRatings(ctx context.Context, locationIDs []int64) (map[int64]RatingScore, error) {
+	out := make(map[int64]RatingScore)
+	if len(locationIDs) == 0 {
+		return out, nil
+	}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT location_id, AVG(food)::float8, COUNT(*)::int FROM order_ratings
+		WHERE food IS NOT NULL AND location_id = ANY($1)
+		GROUP BY location_id`,
+		locationIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("location ratings: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var s RatingScore
+		if err := rows.Scan(&id, &s.Avg, &s.Count); err != nil {
+			return nil, err
+		}
+		out[id] = s
+	}
+	return out, rows.Err()
+}
+
+// GetDriverRating returns the driver's delivery score (Count 0 = not rated yet).
+func GetDriverRating(ctx context.Context, driverID string) (RatingScore, error) {
+	var s RatingScore
+	err := db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(AVG(delivery), 0)::float8, COUNT(*)::int FROM order_ratings
+		WHERE delivery IS NOT NULL AND driver_id = $1::uuid`,
+		driverID,
+	).Scan(&s.Avg, &s.Count)
+	if err != nil {
+		return s, fmt.Errorf("driver rating: %w", err)
+	}
+	return s, nil
+}
+
+// RatingScoreRow is one branch or driver in a scores list.
+type RatingScoreRow struct {
+	ID    string // location ID or driver UUID
+	Name  string
+	Score RatingScore
+}
+
+// ListBranchRatingScores returns every live branch's food score, best first; branches without ratings are left out.
+func ListBranchRatingScores(ctx context.Context) ([]RatingScoreRow, error) {
+	return listRatingScores(ctx, `
+		SELECT l.id::text, l.name, AVG(r.food)::float8, COUNT(*)::int
+		FROM order_ratings r JOIN locations l ON l.id = r.location_id
+		WHERE r.food IS NOT NULL AND l.deleted_at IS NULL
+		GROUP BY l.id, l.name ORDER BY 3 DESC, 4 DESC`)
+}
+
+// ListDriverRatingScores returns every driver's delivery score, best first; drivers without ratings are left out.
+func ListDriverRatingScores(ctx context.Context) ([]RatingScoreRow, error) {
+	return listRatingScores(ctx, `
+		SELECT d.id::text, COALESCE(d.full_name, ''), AVG(r.delivery)::float8, COUNT(*)::int
+		FROM order_ratings r JOIN drivers d ON d.id = r.driver_id
+		WHERE r.delivery IS NOT NULL
+		GROUP BY d.id, d.full_name ORDER BY 3 DESC, 4 DESC`)
+}
+
+func listRatingScores(ctx context.Context, sql string) ([]RatingScoreRow, error) {
+	rows, err := db.Pool.Query(ctx, sql)
+	if err != nil {
+		return nil, fmt.Errorf("rating scores: %w", err)
+	}
+	defer rows.Close()
+	var list []RatingScoreRow
+	for rows.Next() {
+		var r RatingScoreRow
+		if err := rows.Scan(&r.ID, &r.Name, &r.Score.Avg, &r.Score.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// RecentRating is one rated order of a branch.
+type RecentRating struct {
+	OrderRating
+	CreatedAt time.Time
+}
+
+// ListRecentRatings returns the branch's latest rated orders, newest first.
+func ListRecentRatings(ctx context.Context, locationID int64, limit int) ([]RecentRating, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT order_id, COALESCE(food, 0), COALESCE(delivery, 0), COALESCE(comment, ''), created_at
+		FROM order_ratings WHERE location_id = $1
+		ORDER BY created_at DESC LIMIT $2`,
+		locationID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("recent ratings: %w", err)
+	}
+	defer rows.Close()
+	var list []RecentRating
+	for rows.Next() {
+		var r RecentRating
+		if err := rows.Scan(&r.OrderID, &r.Food, &r.Delivery, &r.Comment, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"food-telegram/models"
+)
+
+func TestOrderRatingLow(t *testing.T) {
+	tests := []struct {
+		r    OrderRating
+		want bool
+	}{
+		{OrderRating{}, false},
+		{OrderRating{Food: 5, Delivery: 4}, false},
+		{OrderRating{Food: 3}, false},
+		{OrderRating{Food: 2}, true},
+		{OrderRating{Food: 5, Delivery: 1}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.r.Low(); got != tt.want {
+			t.Errorf("%+v.Low() = %v, want %v", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestRatingStars(t *testing.T) {
+	for n, want := range map[int]string{0: "☆☆☆☆☆", 3: "★★★☆☆", 5: "★★★★★", 7: "★★★★★"} {
+		if got := RatingStars(n); got != want {
+			t.Errorf("RatingStars(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestBuildCustomerCardRating(t *testing.T) {
+	delivery, driverID := "delivery", "d-1"
+	o := &models.Order{ID: 7, Status: OrderStatusCompleted, DeliveryType: &delivery, DriverID: &driverID}
+
+	card := BuildCustomerCard(o, nil, "", 0, nil)
+	if len(card.Buttons) != 2 || len(card.Buttons[0]) != 5 || len(card.Buttons[1]) != 5 {
+		t.Fatalf("unrated delivery order: want food and delivery rows, got %+v", card.Buttons)
+	}
+	if card.Buttons[0][0].CallbackData != "rate:7:food:1" || card.Buttons[1][4].CallbackData != "rate:7:delivery:5" {
+		t.Errorf("rating callbacks = %q, %q", card.Buttons[0][0].CallbackData, card.Buttons[1][4].CallbackData)
+	}
+
+	card = BuildCustomerCard(o, nil, "", 0, &OrderRating{OrderID: 7, Food: 4})
+	if len(card.Buttons) != 2 || card.Buttons[0][0].CallbackData != "rate:7:delivery:1" ||
+		card.Buttons[1][0].CallbackData != "rate_comment:7" {
+		t.Errorf("food rated: want delivery row and comment button, got %+v", card.Buttons)
+	}
+	if !strings.Contains(card.Text, "★★★★☆") {
+		t.Errorf("food rated card misses the stars:\n%s", card.Text)
+	}
+
+	card = BuildCustomerCard(o, nil, "", 0, &OrderRating{OrderID: 7, Food: 4, Delivery: 5, Comment: "Issiq keldi"})
+	if len(card.Buttons) != 0 || !strings.Contains(card.Text, "rahmat") || !strings.Contains(card.Text, "Issiq keldi") {
+		t.Errorf("fully rated card: buttons %+v\n%s", card.Buttons, card.Text)
+	}
+
+	pickup := "pickup"
+	card = BuildCustomerCard(&models.Order{ID: 8, Status: OrderStatusCompleted, DeliveryType: &pickup}, nil, "", 0, nil)
+	if len(card.Buttons) != 1 || card.Buttons[0][0].CallbackData != "rate:8:food:1" {
+		t.Errorf("pickup order: want only the food row, got %+v", card.Buttons)
+	}
+
+	card = BuildCustomerCard(&models.Order{ID: 9, Status: OrderStatusReady}, nil, "", 0, nil)
+	if strings.Contains(card.Text, "baholang") {
+		t.Errorf("rating asked before completion:\n%s", card.Text)
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"food-telegram/db"
+)
+
+// OrdersReport summarizes the orders of a branch (or of every branch) placed in [From, To).
+type OrdersReport struct {
+	LocationID int64 // 0 = every branch
+	From       time.Time
+	To         time.Time
+
+	Orders          int
+	ByStatus        map[string]int
+	ItemsRevenue    int64 // of completed orders
+	DeliveryRevenue int64 // of completed orders
+	Revenue         int64 // grand total of completed orders
+
+	// Timings come from order_status_history; Samples are the orders that had both ends of the interval.
+	AvgPrep            time.Duration // preparing -> ready
+	PrepSamples        int
+	AvgDelivery        time.Duration // driver took it (picked_up, else assigned) -> completed
+	DeliverySamples    int
+	TopItems           []ReportItem
+	Heatmap            [7][24]int // orders by weekday (Sunday first) and hour, in BranchTimezone
+	NewCustomers       int        // first order in scope falls in the range
+	ReturningCustomers int        // ordered in scope before the range too
+}
+
+// ReportItem is one line of the top items: quantity and revenue across completed orders.
+type ReportItem struct {
+	Name    string
+	Qty     int
+	Revenue int64
+}
+
+// Completed is the number of completed orders.
+func (r *OrdersReport) Completed() int { return r.ByStatus[OrderStatusCompleted] }
+
+// Rejected is the number of rejected orders.
+func (r *OrdersReport) Rejected() int { return r.ByStatus[OrderStatusRejected] }
+
+// RejectionRate is rejected orders over all orders (0 when there are none).
+func (r *OrdersReport) RejectionRate() float64 {
+	if r.Orders == 0 {
+		return 0
+	}
+	return float64(r.Rejected()) / float64(r.Orders)
+}
+
+// AvgBasket is the average items total of completed orders.
+func (r *OrdersReport) AvgBasket() int64 {
+	if r.Completed() == 0 {
+		return 0
+	}
+	return r.ItemsRevenue / int64(r.Completed())
+}
+
+// ReportDefaultDays is the range reports and exports cover without dates: the last week including today.
+const ReportDefaultDays = 7
+
+// ErrReportArgs means the arguments are neither dates nor one location ID; callers answer with their usage line.
+var ErrReportArgs = errors.New("noto'g'ri argumentlar")
+
+// ParseReportRange reads "[from YYYY-MM-DD] [to YYYY-MM-DD] [location_id]" (dates inclusive, in BranchTimezone) in
+// any order of dates and ID. It returns the half-open range [from, to) and the location (0 = not given).
+func ParseReportRange(fields []string, now time.Time) (from, to time.Time, locationID int64, err error) {
+	now = now.In(BranchTimezone)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, BranchTimezone)
+	from, to = today.AddDate(0, 0, 1-ReportDefaultDays), today.AddDate(0, 0, 1)
+	var dates []time.Time
+	for _, p := range fields {
+		if d, err := time.ParseInLocation("2006-01-02", p, BranchTimezone); err == nil {
+			dates = append(dates, d)
+			continue
+		}
+		id, err := strconv.ParseInt(p, 10, 64)
+		if err != nil || id <= 0 || locationID != 0 {
+			return from, to, 0, ErrReportArgs
+		}
+		locationID = id
+	}
+	switch len(dates) {
+	case 0:
+	case 1:
+		from = dates[0]
+	case 2:
+		from, to = dates[0], dates[1].AddDate(0, 0, 1)
+	default:
+		return from, to, 0, ErrReportArgs
+	}
+	if !to.After(from) {
+		return from, to, 0, errors.New("Davr noto'g'ri: boshlanish sanasi tugash sanasidan keyin.")
+	}
+	return from, to, locationID, nil
+}
+
+// reportScope is the orders condition of a report query: $1 location (0 = all), $2 from, $3 to. alias prefixes
+// the columns ("o." when orders is joined).
+func reportScope(alias string) string {
+	return alias + "created_at >= $2 AND " + alias + "created_at < $3 AND ($1::bigint = 0 OR " + alias + "location_id = $1)"
+}
+
+// reportTopItems is how many items GetOrdersReport lists.
+const reportTopItems = 10
+
+// GetOrdersReport builds the report for the branch (0 = every branch) over [from, to).
+func GetOrdersReport(ctx context.Context, locationID int64, from, to time.Time) (*OrdersReport, error) {
+	r := &OrdersReport{LocationID: locationID, From: from, To: to, ByStatus: make(map[string]int)}
+	scope := reportScope("")
+	args := []any{locationID, from, to}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT status, COUNT(*)::int,
+		       COALESCE(SUM(items_total), 0)::bigint,
+		       COALESCE(SUM(delivery_fee), 0)::bigint,
+		       COALESCE(SUM(grand_total), 0)::bigint
+		FROM orders WHERE `+scope+` GROUP BY status`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var status string
+		var n int
+		var items, delivery, grand int64
+		if err := rows.Scan(&status, &n, &items, &delivery, &grand); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.ByStatus[status] = n
+		r.Orders += n
+		if status == OrderStatusCompleted {
+			r.ItemsRevenue, r.DeliveryRevenue, r.Revenue = items, delivery, grand
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var prepSec, deliverySec float64
+	err = db.Pool.QueryRow(ctx, `
+		WITH h AS (
+			SELECT sh.order_id, sh.to_status, MIN(sh.created_at) AS at
+			FROM order_status_history sh JOIN orders o ON o.id = sh.order_id
+			WHERE `+reportScope("o.")+`
+			GROUP BY sh.order_id, sh.to_status
+		), t AS (
+			SELECT order_id,
+			       MAX(at) FILTER (WHERE to_status = 'preparing') AS preparing,
+			       MAX(at) FILTER (WHERE to_status = 'ready') AS ready,
+			       COALESCE(MAX(at) FILTER (WHERE to_status = 'picked_up'), MAX(at) FILTER (WHERE to_status = 'assigned')) AS taken,
+			       MAX(at) FILTER (WHERE to_status = 'completed') AS completed
+			FROM h GROUP BY order_id
+		)
+		SELECT COALESCE(EXTRACT(EPOCH FROM AVG(ready - preparing)), 0)::float8,
+		       COUNT(*) FILTER (WHERE ready IS NOT NULL AND preparing IS NOT NULL)::int,
+		       COALESCE(EXTRACT(EPOCH FROM AVG(completed - taken)), 0)::float8,
+		       COUNT(*) FILTER (WHERE completed IS NOT NULL AND taken IS NOT NULL)::int
+		FROM t`, args...,
+	).Scan(&prepSec, &r.PrepSamples, &deliverySec, &r.DeliverySamples)
+	if err != nil {
+		return nil, err
+	}
+	r.AvgPrep = time.Duration(prepSec * float64(time.Second)).Round(time.Second)
+	r.AvgDelivery = time.Duration(deliverySec * float64(time.Second)).Round(time.Second)
+
+	rows, err = db.Pool.Query(ctx, `
+		SELECT i.name, SUM(i.qty)::int, SUM(i.price * i.qty)::bigint
+		FROM order_items i JOIN orders o ON o.id = i.order_id
+		WHERE o.status = 'completed' AND `+reportScope("o.")+`
+		GROUP BY i.name ORDER BY 2 DESC, 3 DESC, i.name LIMIT $4`, locationID, from, to, reportTopItems)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var it ReportItem
+		if err := rows.Scan(&it.Name, &it.Qty, &it.Revenue); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.TopItems = append(r.TopItems, it)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Pool.Query(ctx, `
+		SELECT EXTRACT(DOW FROM created_at AT TIME ZONE 'Asia/Tashkent')::int,
+		       EXTRACT(HOUR FROM created_at AT TIME ZONE 'Asia/Tashkent')::int,
+		       COUNT(*)::int
+		FROM orders WHERE `+scope+` GROUP BY 1, 2`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var dow, hour, n int
+		if err := rows.Scan(&dow, &hour, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.Heatmap[dow][hour] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = db.Pool.QueryRow(ctx, `
+		WITH c AS (
+			SELECT user_id, MIN(created_at) AS first_at
+			FROM orders
+			WHERE ($1::bigint = 0 OR location_id = $1)
+			  AND user_id IN (SELECT user_id FROM orders WHERE `+scope+`)
+			GROUP BY user_id
+		)
+		SELECT COUNT(*) FILTER (WHERE first_at >= $2)::int, COUNT(*) FILTER (WHERE first_at < $2)::int FROM c`,
+		args...,
+	).Scan(&r.NewCustomers, &r.ReturningCustomers)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
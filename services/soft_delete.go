@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RestoreDeadline returns the last moment a row soft-deleted at deletedAt can be restored.
+func RestoreDeadline(deletedAt time.Time, retention time.Duration) time.Time {
+	return deletedAt.Add(retention)
+}
+
+// RestoreWindowOpen reports whether a row soft-deleted at deletedAt can still be restored at now (the deadline itself included).
+func RestoreWindowOpen(deletedAt, now time.Time, retention time.Duration) bool {
+	return !now.After(RestoreDeadline(deletedAt, retention))
+}
+
+// DeletedLocation is a soft-deleted location that has not been purged yet.
+type DeletedLocation struct {
+	ID        int64
+	Name      string
+	DeletedAt time.Time
+	DeletedBy int64 // 0 = unknown / system
+	MenuItems int   // menu items deleted together with the location
+}
+
+// DeletedMenuItem is a menu item deleted on its own (not together with its location).
+type DeletedMenuItem struct {
+	ID           int64
+	Category     string
+	Name         string
+	Price        int64
+	LocationID   int64
+	LocationName string
+	DeletedAt    time.Time
+}
+
+// ListDeletedLocations returns soft-deleted, not yet purged locations, newest first.
+func ListDeletedLocations(ctx context.Context) ([]DeletedLocation, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT l.id, l.name, l.deleted_at, COALESCE(l.deleted_by, 0),
+			(SELECT COUNT(*) FROM menu_items m WHERE m.location_id = l.id AND m.deleted_at = l.deleted_at)
+		FROM locations l
+		WHERE l.deleted_at IS NOT NULL AND l.purged_at IS NULL
+		ORDER BY l.deleted_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list deleted locations: %w", err)
+	}
+	defer rows.Close()
+	var list []DeletedLocation
+	for rows.Next() {
+		var l DeletedLocation
+		if err := rows.Scan(&l.ID, &l.Name, &l.DeletedAt, &l.DeletedBy, &l.MenuItems); err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+// ListDeletedMenuItems returns menu items deleted individually from locations that still exist, newest first.
+// Items deleted together with their location are restored with it and not listed here.
+func ListDeletedMenuItems(ctx context.Context) ([]DeletedMenuItem, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT m.id, m.category, m.name, m.price, COALESCE(m.location_id, 0), COALESCE(l.name, ''), m.deleted_at
+		FROM menu_items m LEFT JOIN locations l ON l.id = m.location_id
+		WHERE m.deleted_at IS NOT NULL AND l.deleted_at IS NULL
+		ORDER BY m.deleted_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list deleted menu items: %w", err)
+	}
+	defer rows.Close()
+	var list []DeletedMenuItem
+	for rows.Next() {
+		var m DeletedMenuItem
+		if err := rows.Scan(&m.ID, &m.Category, &m.Name, &m.Price, &m.LocationID, &m.LocationName, &m.DeletedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// RestoreLocation undoes DeleteLocation within the retention window: the location and the menu items deleted with it
+// come back, and owners get their applicant login and approved application back. Staff log in again themselves.
+func RestoreLocation(ctx context.Context, id int64, retention time.Duration) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var name string
+	var deletedAt *time.Time
+	var purgedAt *time.Time
+	err = tx.QueryRow(ctx, `SELECT name, deleted_at, purged_at FROM locations WHERE id = $1 FOR UPDATE`, id).Scan(&name, &deletedAt, &purgedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("filial topilmadi")
+		}
+		return fmt.Errorf("restore location: %w", err)
+	}
+	if deletedAt == nil {
+		return fmt.Errorf("filial o'chirilmagan")
+	}
+	if purgedAt != nil || !RestoreWindowOpen(*deletedAt, time.Now(), retention) {
+		return fmt.Errorf("tiklash muddati o'tgan")
+	}
+	if _, err := tx.Exec(ctx, `UPDATE locations SET deleted_at = NULL, deleted_by = NULL WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("restore location: %w", err)
+	}
+	res, err := tx.Exec(ctx, `UPDATE menu_items SET deleted_at = NULL WHERE location_id = $1 AND deleted_at = $2`, id, *deletedAt)
+	if err != nil {
+		return fmt.Errorf("restore location menu: %w", err)
+	}
+	menuItems := res.RowsAffected()
+
+	ownerIDs, err := NewPgStore(tx).Locations().OwnerIDs(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, tgUserID := range ownerIDs {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO user_credentials (tg_user_id, role, password_hash)
+			SELECT c.tg_user_id, $2, c.password_hash FROM branch_admin_credentials c
+			WHERE c.tg_user_id = $1 AND EXISTS (SELECT 1 FROM subscriptions s WHERE s.tg_user_id = $1 AND s.role = $2)
+			ON CONFLICT (tg_user_id) DO NOTHING`,
+			tgUserID, UserRoleRestaurantAdmin,
+		); err != nil {
+			return fmt.Errorf("restore owner credential: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE applications SET status = 'approved', reject_reason = NULL, updated_at = now()
+			WHERE tg_user_id = $1 AND type = 'restaurant_admin' AND status = 'rejected' AND reject_reason = $2 AND updated_at >= $3`,
+			tgUserID, locationDeletedReason, *deletedAt,
+		); err != nil {
+			return fmt.Errorf("restore owner application: %w", err)
+		}
+	}
+
+	before := map[string]interface{}{"deleted_at": *deletedAt}
+	after := map[string]interface{}{"name": name, "menu_items_restored": menuItems, "owner_user_ids": ownerIDs}
+	if err := recordAudit(ctx, tx, AuditLocationRestore, AuditTargetLocation, strconv.FormatInt(id, 10), before, after); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	for _, tgUserID := range ownerIDs {
+		_ = syncCredentialActiveFromSubscription(ctx, tgUserID, UserRoleRestaurantAdmin)
+	}
+	return nil
+}
+
+// RestoreMenuItem undoes DeleteMenuItem within the retention window. Items of a deleted location come back with RestoreLocation.
+func RestoreMenuItem(ctx context.Context, id int64, retention time.Duration) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var category, name string
+	var price int64
+	var deletedAt *time.Time
+	var locDeleted bool
+	err = tx.QueryRow(ctx, `
+		SELECT m.category, m.name, m.price, m.deleted_at, l.deleted_at IS NOT NULL
+		FROM menu_items m LEFT JOIN locations l ON l.id = m.location_id
+		WHERE m.id = $1 FOR UPDATE OF m`,
+		id,
+	).Scan(&category, &name, &price, &deletedAt, &locDeleted)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("mahsulot topilmadi")
+		}
+		return fmt.Errorf("restore menu item: %w", err)
+	}
+	if deletedAt == nil {
+		return fmt.Errorf("mahsulot o'chirilmagan")
+	}
+	if locDeleted {
+		return fmt.Errorf("filial o'chirilgan, avval filialni tiklang")
+	}
+	if !RestoreWindowOpen(*deletedAt, time.Now(), retention) {
+		return fmt.Errorf("tiklash muddati o'tgan")
+	}
+	if _, err := tx.Exec(ctx, `UPDATE menu_items SET deleted_at = NULL WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("restore menu item: %w", err)
+	}
+	before := map[string]interface{}{"deleted_at": *deletedAt}
+	after := map[string]interface{}{"category": category, "name": name, "price": price}
+	if err := recordAudit(ctx, tx, AuditMenuItemRestore, AuditTargetMenuItem, strconv.FormatInt(id, 10), before, after); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// PurgeResult counts what PurgeDeletedData removed.
+type PurgeResult struct {
+	Locations int
+	MenuItems int
+}
+
+// PurgeDeletedData permanently removes what was soft-deleted longer than retention ago. For a location: its menu,
+// customer bindings and staff, plus credentials and subscriptions of staff who are not staff of another live location.
+// The location row stays (marked purged_at) so orders keep their location_id.
+func PurgeDeletedData(ctx context.Context, retention time.Duration) (PurgeResult, error) {
+	var res PurgeResult
+	cutoff := time.Now().Add(-retention)
+	rows, err := db.Pool.Query(ctx, `SELECT id FROM locations WHERE deleted_at < $1 AND purged_at IS NULL ORDER BY id`, cutoff)
+	if err != nil {
+		return res, fmt.Errorf("list locations to purge: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return res, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+	for _, id := range ids {
+		if err := purgeLocation(ctx, id, cutoff); err != nil {
+			return res, err
+		}
+		res.Locations++
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	rows, err = tx.Query(ctx, `DELETE FROM menu_items WHERE deleted_at < $1 RETURNING id, name`, cutoff)
+	if err != nil {
+		return res, fmt.Errorf("purge menu items: %w", err)
+	}
+	var purged []DeletedMenuItem
+	for rows.Next() {
+		var m DeletedMenuItem
+		if err := rows.Scan(&m.ID, &m.Name); err != nil {
+			rows.Close()
+			return res, err
+		}
+		purged = append(purged, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+	for _, m := range purged {
+		if err := recordAudit(ctx, tx, AuditMenuItemPurge, AuditTargetMenuItem, strconv.FormatInt(m.ID, 10), map[string]interface{}{"name": m.Name}, nil); err != nil {
+			return res, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return res, err
+	}
+	res.MenuItems = len(purged)
+	return res, nil
+}
+
+func purgeLocation(ctx context.Context, id int64, cutoff time.Time) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var name string
+	err = tx.QueryRow(ctx, `
+		UPDATE locations SET purged_at = now() WHERE id = $1 AND deleted_at < $2 AND purged_at IS NULL RETURNING name`,
+		id, cutoff,
+	).Scan(&name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil // restored or purged meanwhile
+		}
+		return fmt.Errorf("purge location %d: %w", id, err)
+	}
+	rows, err := tx.Query(ctx, `DELETE FROM branch_admins WHERE branch_location_id = $1 RETURNING admin_user_id`, id)
+	if err != nil {
+		return fmt.Errorf("purge location staff: %w", err)
+	}
+	var staffIDs []int64
+	for rows.Next() {
+		var uid int64
+		if err := rows.Scan(&uid); err != nil {
+			rows.Close()
+			return err
+		}
+		staffIDs = append(staffIDs, uid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, uid := range staffIDs {
+		var elsewhere bool
+		if err := tx.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM branch_admins ba JOIN locations l ON l.id = ba.branch_location_id
+				WHERE ba.admin_user_id = $1 AND l.purged_at IS NULL
+			)`,
+			uid,
+		).Scan(&elsewhere); err != nil {
+			return fmt.Errorf("purge staff %d: %w", uid, err)
+		}
+		if elsewhere {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM branch_admin_credentials WHERE tg_user_id = $1`, uid); err != nil {
+			return fmt.Errorf("purge staff credential: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM user_credentials WHERE tg_user_id = $1 AND role = $2`, uid, UserRoleRestaurantAdmin); err != nil {
+			return fmt.Errorf("purge owner credential: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM subscriptions WHERE tg_user_id = $1 AND role = $2`, uid, UserRoleRestaurantAdmin); err != nil {
+			return fmt.Errorf("purge owner subscription: %w", err)
+		}
+	}
+	menu, err := tx.Exec(ctx, `DELETE FROM menu_items WHERE location_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("purge location menu: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_locations WHERE location_id = $1`, id); err != nil {
+		return fmt.Errorf("purge location user bindings: %w", err)
+	}
+	before := map[string]interface{}{"name": name, "staff_user_ids": staffIDs, "menu_items": menu.RowsAffected()}
+	if err := recordAudit(ctx, tx, AuditLocationPurge, AuditTargetLocation, strconv.FormatInt(id, 10), before, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"food-telegram/models"
+)
+
+func TestRestoreWindowOpen(t *testing.T) {
+	deleted := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	retention := 30 * 24 * time.Hour
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"just deleted", deleted.Add(time.Minute), true},
+		{"day before deadline", deleted.Add(29 * 24 * time.Hour), true},
+		{"at deadline", deleted.Add(retention), true},
+		{"after deadline", deleted.Add(retention + time.Second), false},
+		{"long after", deleted.Add(90 * 24 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RestoreWindowOpen(deleted, tt.now, retention); got != tt.want {
+				t.Errorf("RestoreWindowOpen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func seedDeletableLocation(t *testing.T, st *MemStore) (locationID int64, appID string) {
+	t.Helper()
+	ctx := context.Background()
+	appID = restaurantApplication(st, 77)
+	if _, err := approveApplication(ctx, st, appID, 1); err != nil {
+		t.Fatal(err)
+	}
+	locs, _ := st.Locations().List(ctx)
+	id := locs[0].ID
+	if _, err := addMenuItem(ctx, st, models.CategoryFood, "Lavash", 25000, &id); err != nil {
+		t.Fatal(err)
+	}
+	st.PutBranchSession(id, 77)
+	return id, appID
+}
+
+func TestDeleteLocation(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemStore()
+	id, appID := seedDeletableLocation(t, st)
+
+	if err := deleteLocation(ctx, st, id); err != nil {
+		t.Fatal(err)
+	}
+	if locs, _ := st.Locations().List(ctx); len(locs) != 0 {
+		t.Errorf("locations = %+v", locs)
+	}
+	if l, _ := st.Locations().GetLive(ctx, id); l != nil {
+		t.Errorf("GetLive returned the deleted location %+v", l)
+	}
+	if l, _ := st.Locations().Get(ctx, id); l == nil {
+		t.Error("Get should still find the deleted location for restore and audit")
+	}
+	if items, _ := st.Menu().List(ctx, models.CategoryFood, id); len(items) != 0 {
+		t.Errorf("menu = %+v", items)
+	}
+	if _, ok := st.Login(77); ok {
+		t.Error("owner login kept")
+	}
+	if s := st.BranchSessions(id); len(s) != 0 {
+		t.Errorf("sessions = %v", s)
+	}
+	if app, _, _, _ := st.Applications().Get(ctx, appID); app.Status != ApplicationStatusRejected {
+		t.Errorf("owner application = %s, want rejected so they can apply again", app.Status)
+	}
+
+	// Retrying after success is a no-op
+	events := len(st.AuditEvents())
+	if err := deleteLocation(ctx, st, id); err != nil {
+		t.Errorf("second delete: %v", err)
+	}
+	if got := len(st.AuditEvents()); got != events {
+		t.Errorf("second delete audited: %d events, want %d", got, events)
+	}
+	if err := deleteLocation(ctx, st, 9999); err == nil {
+		t.Error("missing location deleted")
+	}
+}
+
+func TestDeleteLocationPartialFailure(t *testing.T) {
+	steps := []string{
+		"locations.softdelete",
+		"menu.deleteforlocation",
+		"credentials.removerestaurantlogin",
+		"applications.rejectapproved",
+		"locations.endaccess",
+		"audit",
+	}
+	for _, op := range steps {
+		t.Run(op, func(t *testing.T) {
+			ctx := context.Background()
+			st := NewMemStore()
+			id, _ := seedDeletableLocation(t, st)
+			events := len(st.AuditEvents())
+			boom := errors.New("boom")
+			st.FailOn(op, boom)
+
+			if err := deleteLocation(ctx, st, id); !errors.Is(err, boom) {
+				t.Fatalf("err = %v, want boom", err)
+			}
+			if locs, _ := st.Locations().List(ctx); len(locs) != 1 {
+				t.Errorf("location gone after failure: %+v", locs)
+			}
+			if items, _ := st.Menu().List(ctx, models.CategoryFood, id); len(items) != 1 {
+				t.Errorf("menu after failure = %+v", items)
+			}
+			if _, ok := st.Login(77); !ok {
+				t.Error("owner login removed after failure")
+			}
+			if s := st.BranchSessions(id); len(s) != 1 {
+				t.Errorf("sessions after failure = %v", s)
+			}
+			if got := len(st.AuditEvents()); got != events {
+				t.Errorf("audit events = %d, want %d", got, events)
+			}
+
+			if err := deleteLocation(ctx, st, id); err != nil {
+				t.Fatalf("retry: %v", err)
+			}
+			if locs, _ := st.Locations().List(ctx); len(locs) != 0 {
+				t.Errorf("locations after retry = %+v", locs)
+			}
+		})
+	}
+}
@@ -0,0 +1,604 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"food-telegram/db"
+	"food-telegram/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DBTX is what the pgx repositories run on: *pgxpool.Pool or a pgx.Tx (Begin on a Tx opens a savepoint).
+type DBTX interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// OrderRepo reads and updates orders. Get returns nil if the order does not exist.
+type OrderRepo interface {
+	Get(ctx context.Context, id int64) (*models.Order, error)
+	Coordinates(ctx context.Context, id int64) (lat, lon float64, err error)
+	ListByUser(ctx context.Context, userID int64, limit int) ([]CustomerOrderRow, error)
+	ActiveByDriver(ctx context.Context, driverID string) ([]models.Order, error)
+	SetDeliveryType(ctx context.Context, id int64, deliveryType string) error
+}
+
+// MenuRepo reads and adds menu items (not deleted ones). locationID 0 lists every location; Get returns nil if missing.
+type MenuRepo interface {
+	List(ctx context.Context, category string, locationID int64) ([]models.MenuItem, error)
+	ListAll(ctx context.Context) ([]models.MenuItem, error)
+	Get(ctx context.Context, id int64) (*models.MenuItem, error)
+	Add(ctx context.Context, category, name string, price int64, locationID *int64) (int64, error)
+	DeleteForLocation(ctx context.Context, locationID int64, at time.Time) (int64, error)
+}
+
+// LocationRepo reads, adds and soft-deletes branches. List skips deleted ones; Get returns nil if the branch does not exist
+// (deleted ones included, for restore and audit), GetLive also if it is deleted.
+// SoftDelete returns nil if there is no live branch with the ID (missing or already deleted). EndAccess drops the
+// branch's staff access and sessions, logging each ended session as revoked by actorID.
+type LocationRepo interface {
+	Get(ctx context.Context, id int64) (*models.Location, error)
+	GetLive(ctx context.Context, id int64) (*models.Location, error)
+	List(ctx context.Context) ([]models.Location, error)
+	Add(ctx context.Context, name string, lat, lon float64) (int64, error)
+	AddOwner(ctx context.Context, id, tgUserID, promotedBy int64, orderLang string) error
+	OwnerIDs(ctx context.Context, id int64) ([]int64, error)
+	SoftDelete(ctx context.Context, id int64, deletedBy *int64) (deletedAt *time.Time, err error)
+	EndAccess(ctx context.Context, id int64, actorID int64) error
+}
+
+// DriverRepo reads and registers drivers. The getters return nil if the driver does not exist.
+type DriverRepo interface {
+	GetByID(ctx context.Context, id string) (*Driver, error)
+	GetByTgUserID(ctx context.Context, tgUserID int64) (*Driver, error)
+	Register(ctx context.Context, tgUserID, chatID int64) (*Driver, error)
+	SetCarPlate(ctx context.Context, id, carPlate string) error
+}
+
+// SubscriptionRepo reads and (re)starts subscriptions. Get returns nil if the user has none for the role.
+type SubscriptionRepo interface {
+	Get(ctx context.Context, tgUserID int64, role string) (*Subscription, error)
+	Start(ctx context.Context, tgUserID int64, role string, days int) error
+}
+
+// ApplicationRepo reads and reviews applications. Get returns nil if missing; Review only moves a pending
+// application and reports whether it did.
+type ApplicationRepo interface {
+	Get(ctx context.Context, id string) (*Application, *ApplicationRestaurantDetails, *ApplicationDriverDetails, error)
+	ListPending(ctx context.Context, limit int) ([]Application, error)
+	Review(ctx context.Context, id, status string, reviewedBy int64, rejectReason *string) (bool, error)
+	RejectApproved(ctx context.Context, tgUserID int64, appType, reason string) error
+}
+
+// CredentialRepo manages logins: the user_credentials row of a role and the branch admin's own password.
+// RemoveRestaurantLogin drops a restaurant_admin login unless the user still owns a live branch.
+type CredentialRepo interface {
+	Upsert(ctx context.Context, tgUserID int64, role, passwordHash string) error
+	SetBranchPassword(ctx context.Context, tgUserID int64, passwordHash string) error
+	RemoveRestaurantLogin(ctx context.Context, tgUserID int64) error
+}
+
+// Store bundles the repositories over one connection or transaction. InTx runs fn on a store whose changes
+// (audit events included) are committed together when fn returns nil and rolled back otherwise.
+type Store interface {
+	Orders() OrderRepo
+	Menu() MenuRepo
+	Locations() LocationRepo
+	Drivers() DriverRepo
+	Subscriptions() SubscriptionRepo
+	Applications() ApplicationRepo
+	Credentials() CredentialRepo
+	Audit(ctx context.Context, action, targetType, targetID string, before, after map[string]interface{}) error
+	InTx(ctx context.Context, fn func(Store) error) error
+}
+
+// NewPgStore returns the Postgres store running on q (the pool, or a transaction the caller owns).
+func NewPgStore(q DBTX) Store {
+	return pgStore{q: q}
+}
+
+// defaultStore is the store the package-level service functions use.
+func defaultStore() Store {
+	return NewPgStore(db.Pool)
+}
+
+type pgStore struct{ q DBTX }
+
+func (s pgStore) Orders() OrderRepo               { return pgOrders(s) }
+func (s pgStore) Menu() MenuRepo                  { return pgMenu(s) }
+func (s pgStore) Locations() LocationRepo         { return pgLocations(s) }
+func (s pgStore) Drivers() DriverRepo             { return pgDrivers(s) }
+func (s pgStore) Subscriptions() SubscriptionRepo { return pgSubscriptions(s) }
+func (s pgStore) Applications() ApplicationRepo   { return pgApplications(s) }
+func (s pgStore) Credentials() CredentialRepo     { return pgCredentials(s) }
+
+func (s pgStore) Audit(ctx context.Context, action, targetType, targetID string, before, after map[string]interface{}) error {
+	return recordAudit(ctx, s.q, action, targetType, targetID, before, after)
+}
+
+func (s pgStore) InTx(ctx context.Context, fn func(Store) error) error {
+	tx, err := s.q.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err := fn(pgStore{q: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+type pgOrders pgStore
+
+func (r pgOrders) Get(ctx context.Context, id int64) (*models.Order, error) {
+	var o models.Order
+	err := r.q.QueryRow(ctx, `
+		SELECT id, COALESCE(location_id, 0), status, chat_id, items_total, grand_total,
+		       COALESCE(delivery_fee, 0), COALESCE(distance_km, 0), delivery_type, driver_id
+		FROM orders WHERE id = $1`,
+		id,
+	).Scan(&o.ID, &o.LocationID, &o.Status, &o.ChatID, &o.ItemsTotal, &o.GrandTotal, &o.DeliveryFee, &o.DistanceKm, &o.DeliveryType, &o.DriverID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r pgOrders) Coordinates(ctx context.Context, id int64) (lat, lon float64, err error) {
+	err = r.q.QueryRow(ctx, `SELECT lat, lon FROM orders WHERE id = $1`, id).Scan(&lat, &lon)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	return lat, lon, nil
+}
+
+func (r pgOrders) ListByUser(ctx context.Context, userID int64, limit int) ([]CustomerOrderRow, error) {
+	rows, err := r.q.Query(ctx, `
+		SELECT id, status, grand_total, created_at::text
+		FROM orders WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []CustomerOrderRow
+	for rows.Next() {
+		var o CustomerOrderRow
+		if err := rows.Scan(&o.ID, &o.Status, &o.GrandTotal, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, o)
+	}
+	return list, rows.Err()
+}
+
+func (r pgOrders) ActiveByDriver(ctx context.Context, driverID string) ([]models.Order, error) {
+	return queryDriverActiveOrders(ctx, r.q, driverID)
+}
+
+func (r pgOrders) SetDeliveryType(ctx context.Context, id int64, deliveryType string) error {
+	_, err := r.q.Exec(ctx, `UPDATE orders SET delivery_type = $1 WHERE id = $2`, deliveryType, id)
+	return err
+}
+
+type pgMenu pgStore
+
+func (r pgMenu) List(ctx context.Context, category string, locationID int64) ([]models.MenuItem, error) {
+	return r.query(ctx, `
+		SELECT id, category, name, price FROM menu_items
+		WHERE category = $1 AND ($2::bigint = 0 OR location_id = $2) AND deleted_at IS NULL
+		ORDER BY id`,
+		category, locationID,
+	)
+}
+
+func (r pgMenu) ListAll(ctx context.Context) ([]models.MenuItem, error) {
+	return r.query(ctx, `
+		SELECT id, category, name, price FROM menu_items
+		WHERE deleted_at IS NULL
+		ORDER BY category, id`,
+	)
+}
+
+func (r pgMenu) query(ctx context.Context, sql string, args ...any) ([]models.MenuItem, error) {
+	rows, err := r.q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []models.MenuItem
+	for rows.Next() {
+		var id int64
+		var it models.MenuItem
+		if err := rows.Scan(&id, &it.Category, &it.Name, &it.Price); err != nil {
+			return nil, err
+		}
+		it.ID = strconv.FormatInt(id, 10)
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+func (r pgMenu) Get(ctx context.Context, id int64) (*models.MenuItem, error) {
+	it := models.MenuItem{ID: strconv.FormatInt(id, 10)}
+	err := r.q.QueryRow(ctx, `SELECT category, name, price FROM menu_items WHERE id = $1 AND deleted_at IS NULL`, id).
+		Scan(&it.Category, &it.Name, &it.Price)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &it, nil
+}
+
+func (r pgMenu) Add(ctx context.Context, category, name string, price int64, locationID *int64) (int64, error) {
+	var id int64
+	err := r.q.QueryRow(ctx, `
+		INSERT INTO menu_items (category, name, price, location_id) VALUES ($1, $2, $3, $4)
+		RETURNING id`,
+		category, name, price, locationID,
+	).Scan(&id)
+	return id, err
+}
+
+func (r pgMenu) DeleteForLocation(ctx context.Context, locationID int64, at time.Time) (int64, error) {
+	res, err := r.q.Exec(ctx, `UPDATE menu_items SET deleted_at = $2 WHERE location_id = $1 AND deleted_at IS NULL`, locationID, at)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+type pgLocations pgStore
+
+func (r pgLocations) Get(ctx context.Context, id int64) (*models.Location, error) {
+	var l models.Location
+	err := r.q.QueryRow(ctx, `SELECT id, name, lat, lon FROM locations WHERE id = $1`, id).Scan(&l.ID, &l.Name, &l.Lat, &l.Lon)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r pgLocations) GetLive(ctx context.Context, id int64) (*models.Location, error) {
+	var l models.Location
+	err := r.q.QueryRow(ctx, `SELECT id, name, lat, lon FROM locations WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&l.ID, &l.Name, &l.Lat, &l.Lon)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r pgLocations) List(ctx context.Context) ([]models.Location, error) {
+	rows, err := r.q.Query(ctx, `
+		SELECT id, name, lat, lon
+		FROM locations
+		WHERE deleted_at IS NULL
+		ORDER BY id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Location
+	for rows.Next() {
+		var l models.Location
+		if err := rows.Scan(&l.ID, &l.Name, &l.Lat, &l.Lon); err != nil {
+			return nil, err
+		}
+		res = append(res, l)
+	}
+	return res, rows.Err()
+}
+
+func (r pgLocations) Add(ctx context.Context, name string, lat, lon float64) (int64, error) {
+	var id int64
+	err := r.q.QueryRow(ctx, `
+		INSERT INTO locations (name, lat, lon)
+		VALUES ($1, $2, $3)
+		RETURNING id`,
+		name, lat, lon,
+	).Scan(&id)
+	return id, err
+}
+
+func (r pgLocations) OwnerIDs(ctx context.Context, id int64) ([]int64, error) {
+	rows, err := r.q.Query(ctx, `SELECT admin_user_id FROM branch_admins WHERE branch_location_id = $1 AND role = 'owner'`, id)
+	if err != nil {
+		return nil, fmt.Errorf("location owners: %w", err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var uid int64
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		ids = append(ids, uid)
+	}
+	return ids, rows.Err()
+}
+
+func (r pgLocations) AddOwner(ctx context.Context, id, tgUserID, promotedBy int64, orderLang string) error {
+	res, err := r.q.Exec(ctx, `
+		INSERT INTO branch_admins (branch_location_id, branch_name, admin_user_id, promoted_by, order_lang, role)
+		SELECT id, name, $2, $3, $4, $5 FROM locations WHERE id = $1 AND deleted_at IS NULL
+		ON CONFLICT (branch_location_id, admin_user_id) DO UPDATE SET
+			branch_name = EXCLUDED.branch_name,
+			promoted_by = EXCLUDED.promoted_by,
+			order_lang = EXCLUDED.order_lang,
+			role = EXCLUDED.role,
+			promoted_at = now()`,
+		id, tgUserID, promotedBy, orderLang, BranchRoleOwner,
+	)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("branch location with ID %d does not exist", id)
+	}
+	return nil
+}
+
+func (r pgLocations) SoftDelete(ctx context.Context, id int64, deletedBy *int64) (*time.Time, error) {
+	var deletedAt time.Time
+	err := r.q.QueryRow(ctx, `
+		UPDATE locations SET deleted_at = now(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL
+		RETURNING deleted_at`,
+		id, deletedBy,
+	).Scan(&deletedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &deletedAt, nil
+}
+
+func (r pgLocations) EndAccess(ctx context.Context, id int64, actorID int64) error {
+	if _, err := r.q.Exec(ctx, `
+		DELETE FROM web_sessions WHERE tg_user_id IN (
+			SELECT tg_user_id FROM branch_admin_access WHERE branch_location_id = $1
+			UNION SELECT admin_user_id FROM branch_admins WHERE branch_location_id = $1)`,
+		id,
+	); err != nil {
+		return fmt.Errorf("end branch web sessions: %w", err)
+	}
+	if _, err := r.q.Exec(ctx, `DELETE FROM branch_admin_access WHERE branch_location_id = $1`, id); err != nil {
+		return fmt.Errorf("delete branch access: %w", err)
+	}
+	rows, err := r.q.Query(ctx, `DELETE FROM admin_sessions WHERE branch_location_id = $1 RETURNING tg_user_id`, id)
+	if err != nil {
+		return fmt.Errorf("end branch sessions: %w", err)
+	}
+	var sessionUsers []int64
+	for rows.Next() {
+		var uid int64
+		if err := rows.Scan(&uid); err != nil {
+			rows.Close()
+			return err
+		}
+		sessionUsers = append(sessionUsers, uid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, uid := range sessionUsers {
+		if err := logAdminSessionEvent(ctx, r.q, uid, &id, AdminSessionEventRevoked, actorID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type pgDrivers pgStore
+
+const driverColumns = `id, tg_user_id, chat_id,
+		       COALESCE(full_name, ''), COALESCE(phone, ''), COALESCE(car_plate, ''),
+		       COALESCE(car_model, ''), COALESCE(car_color, ''),
+		       status, COALESCE(is_online, false), verification_status, COALESCE(verification_reject_reason, '')`
+
+func (r pgDrivers) GetByID(ctx context.Context, id string) (*Driver, error) {
+	return r.get(ctx, `SELECT `+driverColumns+` FROM drivers WHERE id = $1`, id)
+}
+
+func (r pgDrivers) GetByTgUserID(ctx context.Context, tgUserID int64) (*Driver, error) {
+	return r.get(ctx, `SELECT `+driverColumns+` FROM drivers WHERE tg_user_id = $1`, tgUserID)
+}
+
+func (r pgDrivers) get(ctx context.Context, sql string, arg any) (*Driver, error) {
+	var d Driver
+	err := r.q.QueryRow(ctx, sql, arg).Scan(&d.ID, &d.TgUserID, &d.ChatID, &d.FullName, &d.Phone, &d.CarPlate, &d.CarModel, &d.CarColor,
+		&d.Status, &d.IsOnline, &d.VerificationStatus, &d.VerificationRejectReason)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r pgDrivers) Register(ctx context.Context, tgUserID, chatID int64) (*Driver, error) {
+	var id string
+	err := r.q.QueryRow(ctx, `
+		INSERT INTO drivers (tg_user_id, chat_id, status, is_online)
+		VALUES ($1, $2, $3, false)
+		ON CONFLICT (tg_user_id) DO UPDATE SET chat_id = EXCLUDED.chat_id, updated_at = now()
+		RETURNING id`,
+		tgUserID, chatID, DriverStatusOffline,
+	).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("register driver: %w", err)
+	}
+	return &Driver{ID: id, TgUserID: tgUserID, ChatID: chatID, Status: DriverStatusOffline, VerificationStatus: DriverVerificationPendingDocuments}, nil
+}
+
+func (r pgDrivers) SetCarPlate(ctx context.Context, id, carPlate string) error {
+	_, err := r.q.Exec(ctx, `UPDATE drivers SET car_plate = $1, updated_at = now() WHERE id = $2`, carPlate, id)
+	return err
+}
+
+type pgSubscriptions pgStore
+
+func (r pgSubscriptions) Get(ctx context.Context, tgUserID int64, role string) (*Subscription, error) {
+	var s Subscription
+	err := r.q.QueryRow(ctx, `
+		SELECT id::text, tg_user_id, role, status, start_at, expires_at, last_payment_at, created_at, updated_at
+		FROM subscriptions WHERE tg_user_id = $1 AND role = $2`,
+		tgUserID, role,
+	).Scan(&s.ID, &s.TgUserID, &s.Role, &s.Status, &s.StartAt, &s.ExpiresAt, &s.LastPaymentAt, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r pgSubscriptions) Start(ctx context.Context, tgUserID int64, role string, days int) error {
+	_, err := r.q.Exec(ctx, `
+		INSERT INTO subscriptions (tg_user_id, role, status, start_at, expires_at, updated_at)
+		VALUES ($1, $2, 'active', now(), now() + ($3 * interval '1 day'), now())
+		ON CONFLICT (tg_user_id, role) DO UPDATE SET
+			status = 'active',
+			expires_at = now() + ($3 * interval '1 day'),
+			last_payment_at = now(),
+			updated_at = now()`,
+		tgUserID, role, days,
+	)
+	return err
+}
+
+type pgApplications pgStore
+
+const applicationColumns = `id::text, type, tg_user_id, chat_id, COALESCE(full_name,''), COALESCE(phone,''), language, status,
+		       reviewed_by, reviewed_at::text, reject_reason`
+
+func scanApplication(row pgx.Row, a *Application) error {
+	return row.Scan(&a.ID, &a.Type, &a.TgUserID, &a.ChatID, &a.FullName, &a.Phone, &a.Language, &a.Status,
+		&a.ReviewedBy, &a.ReviewedAt, &a.RejectReason)
+}
+
+func (r pgApplications) Get(ctx context.Context, id string) (*Application, *ApplicationRestaurantDetails, *ApplicationDriverDetails, error) {
+	var app Application
+	if err := scanApplication(r.q.QueryRow(ctx, `SELECT `+applicationColumns+` FROM applications WHERE id = $1`, id), &app); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, nil, nil
+		}
+		return nil, nil, nil, err
+	}
+	var rest *ApplicationRestaurantDetails
+	var drv *ApplicationDriverDetails
+	if app.Type == ApplicationTypeRestaurantAdmin {
+		d := ApplicationRestaurantDetails{ApplicationID: id}
+		err := r.q.QueryRow(ctx, `SELECT restaurant_name, lat, lon, address FROM application_restaurant_details WHERE application_id = $1`, id).
+			Scan(&d.RestaurantName, &d.Lat, &d.Lon, &d.Address)
+		if err == nil {
+			rest = &d
+		}
+	} else {
+		d := ApplicationDriverDetails{ApplicationID: id}
+		err := r.q.QueryRow(ctx, `SELECT car_plate, car_model FROM application_driver_details WHERE application_id = $1`, id).
+			Scan(&d.CarPlate, &d.CarModel)
+		if err == nil {
+			drv = &d
+		}
+	}
+	return &app, rest, drv, nil
+}
+
+func (r pgApplications) ListPending(ctx context.Context, limit int) ([]Application, error) {
+	rows, err := r.q.Query(ctx, `
+		SELECT `+applicationColumns+`
+		FROM applications WHERE status = $1 ORDER BY created_at DESC LIMIT $2`,
+		ApplicationStatusPending, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []Application
+	for rows.Next() {
+		var a Application
+		if err := scanApplication(rows, &a); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+func (r pgApplications) Review(ctx context.Context, id, status string, reviewedBy int64, rejectReason *string) (bool, error) {
+	res, err := r.q.Exec(ctx, `
+		UPDATE applications SET status = $1, reviewed_by = $2, reviewed_at = now(), reject_reason = $3, reject_in_progress_by = NULL, updated_at = now()
+		WHERE id = $4 AND status = $5`,
+		status, reviewedBy, rejectReason, id, ApplicationStatusPending,
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected() == 1, nil
+}
+
+func (r pgApplications) RejectApproved(ctx context.Context, tgUserID int64, appType, reason string) error {
+	_, err := r.q.Exec(ctx, `
+		UPDATE applications SET status = $1, reject_reason = $2, updated_at = now()
+		WHERE tg_user_id = $3 AND type = $4 AND status = $5`,
+		ApplicationStatusRejected, reason, tgUserID, appType, ApplicationStatusApproved,
+	)
+	return err
+}
+
+type pgCredentials pgStore
+
+func (r pgCredentials) Upsert(ctx context.Context, tgUserID int64, role, passwordHash string) error {
+	_, err := r.q.Exec(ctx, `
+		INSERT INTO user_credentials (tg_user_id, role, password_hash, is_active, updated_at)
+		VALUES ($1, $2, $3, true, now())
+		ON CONFLICT (tg_user_id) DO UPDATE SET password_hash = EXCLUDED.password_hash, role = EXCLUDED.role, is_active = true, updated_at = now()`,
+		tgUserID, role, passwordHash,
+	)
+	return err
+}
+
+func (r pgCredentials) SetBranchPassword(ctx context.Context, tgUserID int64, passwordHash string) error {
+	return setBranchAdminPasswordTx(ctx, r.q, tgUserID, passwordHash)
+}
+
+func (r pgCredentials) RemoveRestaurantLogin(ctx context.Context, tgUserID int64) error {
+	_, err := r.q.Exec(ctx, `
+		DELETE FROM user_credentials WHERE tg_user_id = $1 AND role = 'restaurant_admin'
+		AND NOT EXISTS (
+			SELECT 1 FROM branch_admins ba JOIN locations l ON l.id = ba.branch_location_id
+			WHERE ba.admin_user_id = $1 AND ba.role = 'owner' AND l.deleted_at IS NULL
+		)`,
+		tgUserID,
+	)
+	return err
+}
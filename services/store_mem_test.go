@@ -0,0 +1,702 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"food-telegram/models"
+)
+
+// MemStore is an in-memory Store for tests of service logic. InTx restores the state from before fn when fn
+// fails; transactions are not isolated from concurrent callers. FailOn makes a write fail to test partial failures.
+type MemStore struct {
+	mu   sync.Mutex
+	data memData
+	fail map[string]error
+}
+
+type memData struct {
+	seq          int64
+	orders       map[int64]memOrder
+	menu         map[int64]memMenuItem
+	locations    map[int64]memLocation
+	drivers      map[string]Driver
+	subs         map[string]Subscription
+	applications map[string]memApplication
+	logins       map[int64]string // tg_user_id -> user_credentials role
+	branchPass   map[int64]string // tg_user_id -> branch admin password hash
+	sessions     map[int64][]int64
+	audit        []AuditEvent
+}
+
+type memOrder struct {
+	order    models.Order
+	userID   int64
+	lat, lon float64
+}
+
+type memMenuItem struct {
+	item       models.MenuItem
+	locationID *int64
+	deletedAt  *time.Time
+}
+
+type memLocation struct {
+	loc       models.Location
+	owners    []int64
+	deletedAt *time.Time
+}
+
+type memApplication struct {
+	app  Application
+	rest *ApplicationRestaurantDetails
+	drv  *ApplicationDriverDetails
+	seq  int64
+}
+
+var _ Store = (*MemStore)(nil)
+
+// NewMemStore returns an empty in-memory store.
+func NewMemStore() *MemStore {
+	return &MemStore{data: memData{
+		orders:       make(map[int64]memOrder),
+		menu:         make(map[int64]memMenuItem),
+		locations:    make(map[int64]memLocation),
+		drivers:      make(map[string]Driver),
+		subs:         make(map[string]Subscription),
+		applications: make(map[string]memApplication),
+		logins:       make(map[int64]string),
+		branchPass:   make(map[int64]string),
+		sessions:     make(map[int64][]int64),
+	}}
+}
+
+func (d memData) clone() memData {
+	c := d
+	c.orders = make(map[int64]memOrder, len(d.orders))
+	for k, v := range d.orders {
+		c.orders[k] = v
+	}
+	c.menu = make(map[int64]memMenuItem, len(d.menu))
+	for k, v := range d.menu {
+		c.menu[k] = v
+	}
+	c.locations = make(map[int64]memLocation, len(d.locations))
+	for k, v := range d.locations {
+		v.owners = append([]int64(nil), v.owners...)
+		c.locations[k] = v
+	}
+	c.drivers = make(map[string]Driver, len(d.drivers))
+	for k, v := range d.drivers {
+		c.drivers[k] = v
+	}
+	c.subs = make(map[string]Subscription, len(d.subs))
+	for k, v := range d.subs {
+		c.subs[k] = v
+	}
+	c.applications = make(map[string]memApplication, len(d.applications))
+	for k, v := range d.applications {
+		c.applications[k] = v
+	}
+	c.logins = make(map[int64]string, len(d.logins))
+	for k, v := range d.logins {
+		c.logins[k] = v
+	}
+	c.branchPass = make(map[int64]string, len(d.branchPass))
+	for k, v := range d.branchPass {
+		c.branchPass[k] = v
+	}
+	c.sessions = make(map[int64][]int64, len(d.sessions))
+	for k, v := range d.sessions {
+		c.sessions[k] = append([]int64(nil), v...)
+	}
+	c.audit = append([]AuditEvent(nil), d.audit...)
+	return c
+}
+
+// FailOn makes the next call of op return err. Ops are named "<repo>.<method>" in lower case
+// (e.g. "locations.addowner", "credentials.upsert") and "audit".
+func (m *MemStore) FailOn(op string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail == nil {
+		m.fail = make(map[string]error)
+	}
+	m.fail[op] = err
+}
+
+// failure returns (and forgets) the error queued for op; m.mu must be held.
+func (m *MemStore) failure(op string) error {
+	err := m.fail[op]
+	delete(m.fail, op)
+	return err
+}
+
+func (m *MemStore) next() int64 {
+	m.data.seq++
+	return m.data.seq
+}
+
+// PutOrder stores o (a new ID is given when o.ID is 0) placed by userID for delivery at lat, lon, and returns its ID.
+func (m *MemStore) PutOrder(o models.Order, userID int64, lat, lon float64) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if o.ID == 0 {
+		o.ID = m.next()
+	}
+	m.data.orders[o.ID] = memOrder{order: o, userID: userID, lat: lat, lon: lon}
+	return o.ID
+}
+
+// PutLocationOwner makes tgUserID an owner of the location.
+func (m *MemStore) PutLocationOwner(locationID, tgUserID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := m.data.locations[locationID]
+	l.owners = append(l.owners, tgUserID)
+	m.data.locations[locationID] = l
+}
+
+// PutApplication stores a pending application (a new ID is given when app.ID is "") and returns its ID.
+func (m *MemStore) PutApplication(app Application, rest *ApplicationRestaurantDetails, drv *ApplicationDriverDetails) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seq := m.next()
+	if app.ID == "" {
+		app.ID = strconv.FormatInt(seq, 10)
+	}
+	if app.Status == "" {
+		app.Status = ApplicationStatusPending
+	}
+	m.data.applications[app.ID] = memApplication{app: app, rest: rest, drv: drv, seq: seq}
+	return app.ID
+}
+
+// PutBranchSession records an open staff session of tgUserID on the location.
+func (m *MemStore) PutBranchSession(locationID, tgUserID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data.sessions[locationID] = append(m.data.sessions[locationID], tgUserID)
+}
+
+// BranchSessions returns the users with an open staff session on the location.
+func (m *MemStore) BranchSessions(locationID int64) []int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]int64(nil), m.data.sessions[locationID]...)
+}
+
+// Login returns the role of the user's login and whether there is one.
+func (m *MemStore) Login(tgUserID int64) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	role, ok := m.data.logins[tgUserID]
+	return role, ok
+}
+
+// AuditEvents returns the audit events recorded so far, oldest first.
+func (m *MemStore) AuditEvents() []AuditEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]AuditEvent(nil), m.data.audit...)
+}
+
+func (m *MemStore) Orders() OrderRepo               { return memOrders{m} }
+func (m *MemStore) Menu() MenuRepo                  { return memMenu{m} }
+func (m *MemStore) Locations() LocationRepo         { return memLocations{m} }
+func (m *MemStore) Drivers() DriverRepo             { return memDrivers{m} }
+func (m *MemStore) Subscriptions() SubscriptionRepo { return memSubscriptions{m} }
+func (m *MemStore) Applications() ApplicationRepo   { return memApplications{m} }
+func (m *MemStore) Credentials() CredentialRepo     { return memCredentials{m} }
+
+func (m *MemStore) Audit(ctx context.Context, action, targetType, targetID string, before, after map[string]interface{}) error {
+	beforeJSON, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+	actorID, role := auditActorFrom(ctx)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.failure("audit"); err != nil {
+		return err
+	}
+	e := AuditEvent{ID: m.next(), ActorRole: role, Action: action, TargetType: targetType, TargetID: targetID, CreatedAt: time.Now()}
+	if actorID != nil {
+		e.ActorID = *actorID
+	}
+	if beforeJSON != nil {
+		e.Before = *beforeJSON
+	}
+	if afterJSON != nil {
+		e.After = *afterJSON
+	}
+	m.data.audit = append(m.data.audit, e)
+	return nil
+}
+
+func (m *MemStore) InTx(ctx context.Context, fn func(Store) error) error {
+	m.mu.Lock()
+	snapshot := m.data.clone()
+	m.mu.Unlock()
+	if err := fn(m); err != nil {
+		m.mu.Lock()
+		m.data = snapshot
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+type memOrders struct{ m *MemStore }
+
+func (r memOrders) Get(ctx context.Context, id int64) (*models.Order, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	o, ok := r.m.data.orders[id]
+	if !ok {
+		return nil, nil
+	}
+	return &o.order, nil
+}
+
+func (r memOrders) Coordinates(ctx context.Context, id int64) (lat, lon float64, err error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	o := r.m.data.orders[id]
+	return o.lat, o.lon, nil
+}
+
+func (r memOrders) ListByUser(ctx context.Context, userID int64, limit int) ([]CustomerOrderRow, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var list []CustomerOrderRow
+	for _, o := range r.m.data.orders {
+		if o.userID == userID {
+			list = append(list, CustomerOrderRow{ID: o.order.ID, Status: o.order.Status, GrandTotal: o.order.GrandTotal})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func (r memOrders) ActiveByDriver(ctx context.Context, driverID string) ([]models.Order, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var list []models.Order
+	for _, o := range r.m.data.orders {
+		if o.order.DriverID == nil || *o.order.DriverID != driverID {
+			continue
+		}
+		switch o.order.Status {
+		case OrderStatusAssigned, OrderStatusPickedUp, OrderStatusDelivering:
+			list = append(list, o.order)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (r memOrders) SetDeliveryType(ctx context.Context, id int64, deliveryType string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	o, ok := r.m.data.orders[id]
+	if !ok {
+		return nil
+	}
+	o.order.DeliveryType = &deliveryType
+	r.m.data.orders[id] = o
+	return nil
+}
+
+type memMenu struct{ m *MemStore }
+
+func (r memMenu) List(ctx context.Context, category string, locationID int64) ([]models.MenuItem, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var ids []int64
+	for id, it := range r.m.data.menu {
+		if it.deletedAt != nil || it.item.Category != category || (locationID != 0 && (it.locationID == nil || *it.locationID != locationID)) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	items := make([]models.MenuItem, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, r.m.data.menu[id].item)
+	}
+	return items, nil
+}
+
+func (r memMenu) ListAll(ctx context.Context) ([]models.MenuItem, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var ids []int64
+	for id, it := range r.m.data.menu {
+		if it.deletedAt == nil {
+			ids = append(ids, id)
+		}
+	}
+	menu := r.m.data.menu
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := menu[ids[i]].item, menu[ids[j]].item
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return ids[i] < ids[j]
+	})
+	items := make([]models.MenuItem, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, menu[id].item)
+	}
+	return items, nil
+}
+
+func (r memMenu) Get(ctx context.Context, id int64) (*models.MenuItem, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	it, ok := r.m.data.menu[id]
+	if !ok || it.deletedAt != nil {
+		return nil, nil
+	}
+	return &it.item, nil
+}
+
+func (r memMenu) Add(ctx context.Context, category, name string, price int64, locationID *int64) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("menu.add"); err != nil {
+		return 0, err
+	}
+	id := r.m.next()
+	r.m.data.menu[id] = memMenuItem{
+		item:       models.MenuItem{ID: strconv.FormatInt(id, 10), Category: category, Name: name, Price: price},
+		locationID: locationID,
+	}
+	return id, nil
+}
+
+func (r memMenu) DeleteForLocation(ctx context.Context, locationID int64, at time.Time) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("menu.deleteforlocation"); err != nil {
+		return 0, err
+	}
+	var n int64
+	for id, it := range r.m.data.menu {
+		if it.deletedAt == nil && it.locationID != nil && *it.locationID == locationID {
+			it.deletedAt = &at
+			r.m.data.menu[id] = it
+			n++
+		}
+	}
+	return n, nil
+}
+
+type memLocations struct{ m *MemStore }
+
+func (r memLocations) Get(ctx context.Context, id int64) (*models.Location, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	l, ok := r.m.data.locations[id]
+	if !ok {
+		return nil, nil
+	}
+	return &l.loc, nil
+}
+
+func (r memLocations) GetLive(ctx context.Context, id int64) (*models.Location, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	l, ok := r.m.data.locations[id]
+	if !ok || l.deletedAt != nil {
+		return nil, nil
+	}
+	return &l.loc, nil
+}
+
+func (r memLocations) List(ctx context.Context) ([]models.Location, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var res []models.Location
+	for _, l := range r.m.data.locations {
+		if l.deletedAt == nil {
+			res = append(res, l.loc)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+func (r memLocations) Add(ctx context.Context, name string, lat, lon float64) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("locations.add"); err != nil {
+		return 0, err
+	}
+	id := r.m.next()
+	r.m.data.locations[id] = memLocation{loc: models.Location{ID: id, Name: name, Lat: lat, Lon: lon}}
+	return id, nil
+}
+
+func (r memLocations) OwnerIDs(ctx context.Context, id int64) ([]int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	return append([]int64(nil), r.m.data.locations[id].owners...), nil
+}
+
+func (r memLocations) AddOwner(ctx context.Context, id, tgUserID, promotedBy int64, orderLang string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("locations.addowner"); err != nil {
+		return err
+	}
+	l, ok := r.m.data.locations[id]
+	if !ok || l.deletedAt != nil {
+		return fmt.Errorf("branch location with ID %d does not exist", id)
+	}
+	for _, uid := range l.owners {
+		if uid == tgUserID {
+			return nil
+		}
+	}
+	l.owners = append(l.owners, tgUserID)
+	r.m.data.locations[id] = l
+	return nil
+}
+
+func (r memLocations) SoftDelete(ctx context.Context, id int64, deletedBy *int64) (*time.Time, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("locations.softdelete"); err != nil {
+		return nil, err
+	}
+	l, ok := r.m.data.locations[id]
+	if !ok || l.deletedAt != nil {
+		return nil, nil
+	}
+	now := time.Now()
+	l.deletedAt = &now
+	r.m.data.locations[id] = l
+	return &now, nil
+}
+
+func (r memLocations) EndAccess(ctx context.Context, id int64, actorID int64) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("locations.endaccess"); err != nil {
+		return err
+	}
+	delete(r.m.data.sessions, id)
+	return nil
+}
+
+type memDrivers struct{ m *MemStore }
+
+func (r memDrivers) GetByID(ctx context.Context, id string) (*Driver, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	d, ok := r.m.data.drivers[id]
+	if !ok {
+		return nil, nil
+	}
+	return &d, nil
+}
+
+func (r memDrivers) GetByTgUserID(ctx context.Context, tgUserID int64) (*Driver, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	for _, d := range r.m.data.drivers {
+		if d.TgUserID == tgUserID {
+			return &d, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r memDrivers) Register(ctx context.Context, tgUserID, chatID int64) (*Driver, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("drivers.register"); err != nil {
+		return nil, err
+	}
+	for id, d := range r.m.data.drivers {
+		if d.TgUserID == tgUserID {
+			d.ChatID = chatID
+			r.m.data.drivers[id] = d
+			return &d, nil
+		}
+	}
+	d := Driver{
+		ID: fmt.Sprintf("driver-%d", r.m.next()), TgUserID: tgUserID, ChatID: chatID,
+		Status: DriverStatusOffline, VerificationStatus: DriverVerificationPendingDocuments,
+	}
+	r.m.data.drivers[d.ID] = d
+	return &d, nil
+}
+
+func (r memDrivers) SetCarPlate(ctx context.Context, id, carPlate string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("drivers.setcarplate"); err != nil {
+		return err
+	}
+	if d, ok := r.m.data.drivers[id]; ok {
+		d.CarPlate = carPlate
+		r.m.data.drivers[id] = d
+	}
+	return nil
+}
+
+type memSubscriptions struct{ m *MemStore }
+
+func (r memSubscriptions) Get(ctx context.Context, tgUserID int64, role string) (*Subscription, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	s, ok := r.m.data.subs[subscriptionAuditTarget(tgUserID, role)]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (r memSubscriptions) Start(ctx context.Context, tgUserID int64, role string, days int) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("subscriptions.start"); err != nil {
+		return err
+	}
+	key := subscriptionAuditTarget(tgUserID, role)
+	now := time.Now()
+	s, ok := r.m.data.subs[key]
+	if ok {
+		s.LastPaymentAt = &now
+	} else {
+		s = Subscription{ID: strconv.FormatInt(r.m.next(), 10), TgUserID: tgUserID, Role: role, StartAt: now, CreatedAt: now}
+	}
+	s.Status = SubscriptionStatusActive
+	s.ExpiresAt = now.AddDate(0, 0, days)
+	s.UpdatedAt = now
+	r.m.data.subs[key] = s
+	return nil
+}
+
+type memApplications struct{ m *MemStore }
+
+func (r memApplications) Get(ctx context.Context, id string) (*Application, *ApplicationRestaurantDetails, *ApplicationDriverDetails, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	a, ok := r.m.data.applications[id]
+	if !ok {
+		return nil, nil, nil, nil
+	}
+	return &a.app, a.rest, a.drv, nil
+}
+
+func (r memApplications) ListPending(ctx context.Context, limit int) ([]Application, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var pending []memApplication
+	for _, a := range r.m.data.applications {
+		if a.app.Status == ApplicationStatusPending {
+			pending = append(pending, a)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].seq > pending[j].seq })
+	var list []Application
+	for i := 0; i < len(pending) && i < limit; i++ {
+		list = append(list, pending[i].app)
+	}
+	return list, nil
+}
+
+func (r memApplications) Review(ctx context.Context, id, status string, reviewedBy int64, rejectReason *string) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("applications.review"); err != nil {
+		return false, err
+	}
+	a, ok := r.m.data.applications[id]
+	if !ok || a.app.Status != ApplicationStatusPending {
+		return false, nil
+	}
+	reviewedAt := time.Now().Format(time.RFC3339)
+	a.app.Status = status
+	a.app.ReviewedBy = &reviewedBy
+	a.app.ReviewedAt = &reviewedAt
+	a.app.RejectReason = rejectReason
+	r.m.data.applications[id] = a
+	return true, nil
+}
+
+func (r memApplications) RejectApproved(ctx context.Context, tgUserID int64, appType, reason string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("applications.rejectapproved"); err != nil {
+		return err
+	}
+	for id, a := range r.m.data.applications {
+		if a.app.TgUserID == tgUserID && a.app.Type == appType && a.app.Status == ApplicationStatusApproved {
+			a.app.Status = ApplicationStatusRejected
+			a.app.RejectReason = &reason
+			r.m.data.applications[id] = a
+		}
+	}
+	return nil
+}
+
+type memCredentials struct{ m *MemStore }
+
+func (r memCredentials) Upsert(ctx context.Context, tgUserID int64, role, passwordHash string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("credentials.upsert"); err != nil {
+		return err
+	}
+	r.m.data.logins[tgUserID] = role
+	return nil
+}
+
+func (r memCredentials) SetBranchPassword(ctx context.Context, tgUserID int64, passwordHash string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("credentials.setbranchpassword"); err != nil {
+		return err
+	}
+	r.m.data.branchPass[tgUserID] = passwordHash
+	return nil
+}
+
+func (r memCredentials) RemoveRestaurantLogin(ctx context.Context, tgUserID int64) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if err := r.m.failure("credentials.removerestaurantlogin"); err != nil {
+		return err
+	}
+	if r.m.data.logins[tgUserID] != ApplicationTypeRestaurantAdmin {
+		return nil
+	}
+	for _, l := range r.m.data.locations {
+		if l.deletedAt != nil {
+			continue
+		}
+		for _, uid := range l.owners {
+			if uid == tgUserID {
+				return nil
+			}
+		}
+	}
+	delete(r.m.data.logins, tgUserID)
+	return nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"food-telegram/models"
+)
+
+func TestSetDeliveryType(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemStore()
+	ready := st.PutOrder(models.Order{LocationID: 1, Status: OrderStatusReady}, 7, 41.3, 69.2)
+	noCoords := st.PutOrder(models.Order{LocationID: 1, Status: OrderStatusReady}, 7, 0, 0)
+	preparing := st.PutOrder(models.Order{LocationID: 1, Status: OrderStatusPreparing}, 7, 41.3, 69.2)
+
+	tests := []struct {
+		name         string
+		orderID      int64
+		deliveryType string
+		locationID   int64
+		wantErr      bool
+	}{
+		{"delivery", ready, "delivery", 1, false},
+		{"pickup without coordinates", noCoords, "pickup", 1, false},
+		{"delivery without coordinates", noCoords, "delivery", 1, true},
+		{"other branch", ready, "pickup", 2, true},
+		{"not ready", preparing, "pickup", 1, true},
+		{"missing order", 999, "pickup", 1, true},
+		{"bad type", ready, "drone", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setDeliveryType(ctx, st, tt.orderID, tt.deliveryType, tt.locationID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			o, _ := st.Orders().Get(ctx, tt.orderID)
+			if o.DeliveryType == nil || *o.DeliveryType != tt.deliveryType {
+				t.Errorf("delivery_type = %v, want %s", o.DeliveryType, tt.deliveryType)
+			}
+		})
+	}
+}
+
+func TestAddMenuItemAuditsInSameTx(t *testing.T) {
+	ctx := WithAuditActor(context.Background(), 42, AuditRoleSuperadmin)
+	st := NewMemStore()
+	loc := int64(3)
+	id, err := addMenuItem(ctx, st, models.CategoryFood, "Lavash", 25000, &loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, _ := st.Menu().List(ctx, models.CategoryFood, loc)
+	if len(items) != 1 || items[0].Name != "Lavash" {
+		t.Fatalf("menu = %+v", items)
+	}
+	events := st.AuditEvents()
+	if len(events) != 1 || events[0].Action != AuditMenuItemCreate || events[0].ActorID != 42 {
+		t.Fatalf("audit = %+v", events)
+	}
+	var after map[string]interface{}
+	if err := json.Unmarshal([]byte(events[0].After), &after); err != nil {
+		t.Fatal(err)
+	}
+	if after["location_id"] != float64(loc) {
+		t.Errorf("after = %v", after)
+	}
+	if item, _ := st.Menu().Get(ctx, id); item == nil || item.Price != 25000 {
+		t.Errorf("Get(%d) = %+v", id, item)
+	}
+
+	if _, err := addMenuItem(ctx, st, "pizza", "Margherita", 1, nil); err == nil {
+		t.Error("invalid category accepted")
+	}
+	if got := len(st.AuditEvents()); got != 1 {
+		t.Errorf("rejected item audited: %d events", got)
+	}
+}
+
+func TestMemStoreInTxRollsBack(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemStore()
+	boom := errors.New("boom")
+	err := st.InTx(ctx, func(tx Store) error {
+		id, err := tx.Locations().Add(ctx, "Chilonzor", 41.28, 69.2)
+		if err != nil {
+			return err
+		}
+		if err := tx.Audit(ctx, AuditLocationCreate, AuditTargetLocation, "x", nil, nil); err != nil {
+			return err
+		}
+		if l, _ := tx.Locations().Get(ctx, id); l == nil {
+			t.Error("location not visible inside the transaction")
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v", err)
+	}
+	if locs, _ := st.Locations().List(ctx); len(locs) != 0 {
+		t.Errorf("locations after rollback = %+v", locs)
+	}
+	if events := st.AuditEvents(); len(events) != 0 {
+		t.Errorf("audit after rollback = %+v", events)
+	}
+
+	id, err := addLocation(ctx, st, "Yunusobod", 41.36, 69.28)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l, _ := st.Locations().Get(ctx, id); l == nil || l.Name != "Yunusobod" {
+		t.Errorf("Get(%d) = %+v", id, l)
+	}
+}
+
+func TestRejectApplicationOnlyOnce(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemStore()
+	id := st.PutApplication(Application{Type: ApplicationTypeDriver, TgUserID: 5}, nil, &ApplicationDriverDetails{})
+	if err := rejectApplication(ctx, st, id, 1, "hujjatlar yo'q"); err != nil {
+		t.Fatal(err)
+	}
+	app, _, _, _ := st.Applications().Get(ctx, id)
+	if app.Status != ApplicationStatusRejected || app.RejectReason == nil || *app.RejectReason != "hujjatlar yo'q" {
+		t.Fatalf("application = %+v", app)
+	}
+	if err := rejectApplication(ctx, st, id, 1, "again"); err == nil {
+		t.Error("rejected twice")
+	}
+	if pending, _ := st.Applications().ListPending(ctx, 10); len(pending) != 0 {
+		t.Errorf("pending = %+v", pending)
+	}
+	if got := len(st.AuditEvents()); got != 1 {
+		t.Errorf("audit events = %d, want 1", got)
+	}
+}
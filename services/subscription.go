@@ -3,9 +3,11 @@ package services
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"food-telegram/db"
+	"github.com/jackc/pgx/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -35,34 +37,22 @@ func CreateSubscription(ctx context.Context, tgUserID int64, role string, days i
 	if days <= 0 {
 		days = 1
 	}
-	_, err := db.Pool.Exec(ctx, `
-		INSERT INTO subscriptions (tg_user_id, role, status, start_at, expires_at, updated_at)
-		VALUES ($1, $2, 'active', now(), now() + ($3 * interval '1 day'), now())
-		ON CONFLICT (tg_user_id, role) DO UPDATE SET
-			status = 'active',
-			expires_at = now() + ($3 * interval '1 day'),
-			last_payment_at = now(),
-			updated_at = now()`,
-		tgUserID, role, days,
-	)
-	if err != nil {
+	if err := defaultStore().Subscriptions().Start(ctx, tgUserID, role, days); err != nil {
 		return err
 	}
 	return syncCredentialActiveFromSubscription(ctx, tgUserID, role)
 }
 
-// GetSubscription returns the subscription for the user and role, if any.
+// GetSubscription returns the subscription for the user and role, or pgx.ErrNoRows if there is none.
 func GetSubscription(ctx context.Context, tgUserID int64, role string) (*Subscription, error) {
-	var s Subscription
-	err := db.Pool.QueryRow(ctx, `
-		SELECT id::text, tg_user_id, role, status, start_at, expires_at, last_payment_at, created_at, updated_at
-		FROM subscriptions WHERE tg_user_id = $1 AND role = $2`,
-		tgUserID, role,
-	).Scan(&s.ID, &s.TgUserID, &s.Role, &s.Status, &s.StartAt, &s.ExpiresAt, &s.LastPaymentAt, &s.CreatedAt, &s.UpdatedAt)
+	sub, err := defaultStore().Subscriptions().Get(ctx, tgUserID, role)
 	if err != nil {
 		return nil, err
 	}
-	return &s, nil
+	if sub == nil {
+		return nil, pgx.ErrNoRows
+	}
+	return sub, nil
 }
 
 // IsEffectiveExpired returns true if the subscription should be treated as expired (past expires_at or status expired/paused).
@@ -82,6 +72,19 @@ func (s *Subscription) ExpiresWithinDays(days int) bool {
 	return s.ExpiresAt.Before(deadline) || s.ExpiresAt.Equal(deadline)
 }
 
+// subscriptionAuditState snapshots the subscription for audit_events (nil if the user has none).
+func subscriptionAuditState(ctx context.Context, tgUserID int64, role string) map[string]interface{} {
+	sub, err := GetSubscription(ctx, tgUserID, role)
+	if err != nil || sub == nil {
+		return nil
+	}
+	return map[string]interface{}{"status": sub.Status, "expires_at": sub.ExpiresAt}
+}
+
+func subscriptionAuditTarget(tgUserID int64, role string) string {
+	return fmt.Sprintf("%d:%s", tgUserID, role)
+}
+
 // syncCredentialActiveFromSubscription sets user_credentials.is_active from subscription (active and not expired).
 func syncCredentialActiveFromSubscription(ctx context.Context, tgUserID int64, role string) error {
 	sub, err := GetSubscription(ctx, tgUserID, role)
@@ -170,6 +173,7 @@ func RenewSubscription(ctx context.Context, tgUserID int64, role string, days in
 	if days <= 0 {
 		days = 1
 	}
+	before := subscriptionAuditState(ctx, tgUserID, role)
 	var hash string
 	if role != UserRoleRestaurantAdmin {
 		newPlainPassword, err = GenerateSecurePassword()
@@ -199,7 +203,7 @@ func RenewSubscription(ctx context.Context, tgUserID int64, role string, days in
 	}
 
 	if role == "restaurant_admin" {
-		// Reactivate only; do not change password (lives in branch_admins).
+		// Reactivate only; do not change password (lives in branch_admin_credentials).
 		_, err = db.Pool.Exec(ctx, `UPDATE user_credentials SET is_active = true, updated_at = now() WHERE tg_user_id = $1 AND role = $2`, tgUserID, role)
 	} else {
 		_, err = db.Pool.Exec(ctx, `UPDATE user_credentials SET password_hash = $1, is_active = true, updated_at = now() WHERE tg_user_id = $2 AND role = $3`, hash, tgUserID, role)
@@ -216,6 +220,14 @@ func RenewSubscription(ctx context.Context, tgUserID int64, role string, days in
 		_ = RecordPaymentReceipt(ctx, tgUserID, role, amt, approvedBy, note)
 	}
 
+	after := subscriptionAuditState(ctx, tgUserID, role)
+	if after != nil {
+		after["days"] = days
+		if amount != nil {
+			after["amount"] = *amount
+		}
+	}
+	_ = recordAudit(ctx, db.Pool, AuditSubscriptionRenew, AuditTargetSubscription, subscriptionAuditTarget(tgUserID, role), before, after)
 	return newPlainPassword, nil
 }
 
@@ -231,16 +243,21 @@ func RecordPaymentReceipt(ctx context.Context, tgUserID int64, role string, amou
 
 // PauseSubscription sets status=paused and credential is_active=false.
 func PauseSubscription(ctx context.Context, tgUserID int64, role string) error {
+	before := subscriptionAuditState(ctx, tgUserID, role)
 	_, err := db.Pool.Exec(ctx, `UPDATE subscriptions SET status = 'paused', updated_at = now() WHERE tg_user_id = $1 AND role = $2`, tgUserID, role)
 	if err != nil {
 		return err
 	}
 	_, err = db.Pool.Exec(ctx, `UPDATE user_credentials SET is_active = false, updated_at = now() WHERE tg_user_id = $1 AND role = $2`, tgUserID, role)
-	return err
+	if err != nil {
+		return err
+	}
+	return recordAudit(ctx, db.Pool, AuditSubscriptionPause, AuditTargetSubscription, subscriptionAuditTarget(tgUserID, role), before, subscriptionAuditState(ctx, tgUserID, role))
 }
 
 // UnpauseSubscription sets status=active and is_active=true only if expires_at > now().
 func UnpauseSubscription(ctx context.Context, tgUserID int64, role string) error {
+	before := subscriptionAuditState(ctx, tgUserID, role)
 	res, err := db.Pool.Exec(ctx, `
 		UPDATE subscriptions SET status = 'active', updated_at = now() WHERE tg_user_id = $1 AND role = $2 AND expires_at > now()`,
 		tgUserID, role,
@@ -252,7 +269,10 @@ func UnpauseSubscription(ctx context.Context, tgUserID int64, role string) error
 		return fmt.Errorf("abonement allaqachon tugagan; yangilash uchun /renew ishlating")
 	}
 	_, err = db.Pool.Exec(ctx, `UPDATE user_credentials SET is_active = true, updated_at = now() WHERE tg_user_id = $1 AND role = $2`, tgUserID, role)
-	return err
+	if err != nil {
+		return err
+	}
+	return recordAudit(ctx, db.Pool, AuditSubscriptionUnpause, AuditTargetSubscription, subscriptionAuditTarget(tgUserID, role), before, subscriptionAuditState(ctx, tgUserID, role))
 }
 
 // GetChatIDForSubscriber returns the most recent application chat_id for the user/role (for sending renewal password).
@@ -261,9 +281,9 @@ func GetChatIDForSubscriber(ctx context.Context, tgUserID int64, role string) (c
 	return chatID, err
 }
 
-// ResetBranchAdminPassword generates a new 8-char password, updates branch_admins.password_hash for the branch, and returns the plain password and the primary admin's tg_user_id (for sending via Zayafka).
+// ResetBranchAdminPassword generates a new 8-char password, updates the branch owner's credential (branch_admin_credentials and user_credentials), and returns the plain password and the primary admin's tg_user_id (for sending via Zayafka).
 func ResetBranchAdminPassword(ctx context.Context, branchLocationID int64) (newPlainPassword string, primaryTgUserID int64, err error) {
-	err = db.Pool.QueryRow(ctx, `SELECT admin_user_id FROM branch_admins WHERE branch_location_id = $1`, branchLocationID).Scan(&primaryTgUserID)
+	err = db.Pool.QueryRow(ctx, `SELECT admin_user_id FROM branch_admins WHERE branch_location_id = $1 AND role = 'owner' ORDER BY promoted_at LIMIT 1`, branchLocationID).Scan(&primaryTgUserID)
 	if err != nil {
 		return "", 0, fmt.Errorf("branch not found: %w", err)
 	}
@@ -275,9 +295,10 @@ func ResetBranchAdminPassword(ctx context.Context, branchLocationID int64) (newP
 	if err != nil {
 		return "", 0, err
 	}
-	_, err = db.Pool.Exec(ctx, `UPDATE branch_admins SET password_hash = $1 WHERE branch_location_id = $2`, string(hash), branchLocationID)
-	if err != nil {
+	if err = SetBranchAdminPassword(ctx, primaryTgUserID, string(hash)); err != nil {
 		return "", 0, err
 	}
+	after := map[string]interface{}{"location_id": branchLocationID}
+	_ = recordAudit(ctx, db.Pool, AuditCredentialPasswordReset, AuditTargetCredential, strconv.FormatInt(primaryTgUserID, 10), nil, after)
 	return newPlainPassword, primaryTgUserID, nil
 }
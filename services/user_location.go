@@ -8,17 +8,23 @@ import (
 	"food-telegram/models"
 )
 
-// SetUserLocation stores the selected location for a user.
+// SetUserLocation stores the selected location for a user. Deleted locations cannot be selected.
 func SetUserLocation(ctx context.Context, userID int64, locationID int64) error {
-	_, err := db.Pool.Exec(ctx, `
+	res, err := db.Pool.Exec(ctx, `
 		INSERT INTO user_locations (user_id, location_id, updated_at)
-		VALUES ($1, $2, now())
+		SELECT $1, id, now() FROM locations WHERE id = $2 AND deleted_at IS NULL
 		ON CONFLICT (user_id) DO UPDATE SET
 			location_id = EXCLUDED.location_id,
 			updated_at = now()`,
 		userID, locationID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("location %d is not available", locationID)
+	}
+	return nil
 }
 
 // GetUserLocation returns the selected location for a user (if any).
@@ -36,7 +42,7 @@ func GetUserLocation(ctx context.Context, userID int64) (*models.Location, error
 	err = db.Pool.QueryRow(ctx, `
 		SELECT id, name, lat, lon
 		FROM locations
-		WHERE id = $1`,
+		WHERE id = $1 AND deleted_at IS NULL`,
 		locationID,
 	).Scan(&l.ID, &l.Name, &l.Lat, &l.Lon)
 	if err != nil {
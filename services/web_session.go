@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// WebSession is a web dashboard login. Access (branch, role, superadmin) is resolved per request, not stored.
+type WebSession struct {
+	TgUserID    int64
+	CSRFToken   string
+	ExpiresAt   time.Time
+	PendingTOTP bool // signed in with Telegram, authenticator code not entered yet
+}
+
+func randomToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// CreateWebSession starts a dashboard session for ttl and returns the cookie token. A pendingTOTP session gives no
+// access until CompleteWebSessionTOTP. Expired sessions are cleaned up.
+func CreateWebSession(ctx context.Context, tgUserID int64, ttl time.Duration, pendingTOTP bool) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	csrf, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	if _, err := db.Pool.Exec(ctx, `DELETE FROM web_sessions WHERE expires_at < now()`); err != nil {
+		return "", err
+	}
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO web_sessions (token_hash, tg_user_id, csrf_token, expires_at, pending_totp)
+		VALUES ($1, $2, $3, now() + $4 * interval '1 second', $5)`,
+		hashAPIKey(token), tgUserID, csrf, ttl.Seconds(), pendingTOTP,
+	)
+	if err != nil {
+		return "", fmt.Errorf("create web session: %w", err)
+	}
+	return token, nil
+}
+
+// GetWebSession returns the live session for the cookie token, or nil: past its expiry, or unused for idle
+// (0 = no idle limit). It does not count as activity.
+func GetWebSession(ctx context.Context, token string, idle time.Duration) (*WebSession, error) {
+	var s WebSession
+	err := db.Pool.QueryRow(ctx, `
+		SELECT tg_user_id, csrf_token, expires_at, pending_totp FROM web_sessions
+		WHERE token_hash = $1 AND expires_at > now() AND ($2::float8 = 0 OR last_seen_at > now() - $2 * interval '1 second')`,
+		hashAPIKey(token), idle.Seconds(),
+	).Scan(&s.TgUserID, &s.CSRFToken, &s.ExpiresAt, &s.PendingTOTP)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// TouchWebSession is GetWebSession for a request by the user: the live session is marked as seen now.
+func TouchWebSession(ctx context.Context, token string, idle time.Duration) (*WebSession, error) {
+	var s WebSession
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE web_sessions SET last_seen_at = now()
+		WHERE token_hash = $1 AND expires_at > now() AND ($2::float8 = 0 OR last_seen_at > now() - $2 * interval '1 second')
+		RETURNING tg_user_id, csrf_token, expires_at, pending_totp`,
+		hashAPIKey(token), idle.Seconds(),
+	).Scan(&s.TgUserID, &s.CSRFToken, &s.ExpiresAt, &s.PendingTOTP)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("touch web session: %w", err)
+	}
+	return &s, nil
+}
+
+// CompleteWebSessionTOTP gives the session access once the user entered their authenticator code.
+func CompleteWebSessionTOTP(ctx context.Context, token string) error {
+	if _, err := db.Pool.Exec(ctx, `
+		UPDATE web_sessions SET pending_totp = false, last_seen_at = now() WHERE token_hash = $1`,
+		hashAPIKey(token),
+	); err != nil {
+		return fmt.Errorf("complete web session: %w", err)
+	}
+	return nil
+}
+
+// LogWebSessionEvent records a dashboard login or logout in admin_logins, with the user's branch (none for the
+// superadmin).
+func LogWebSessionEvent(ctx context.Context, tgUserID int64, event string) error {
+	locID, _, err := GetBranchRole(ctx, tgUserID)
+	if err != nil {
+		return err
+	}
+	var loc *int64
+	if locID != 0 {
+		loc = &locID
+	}
+	return logAdminSessionEvent(ctx, db.Pool, tgUserID, loc, event, 0)
+}
+
+// endWebSessions ends every dashboard session of the user (access revoked).
+func endWebSessions(ctx context.Context, q auditExecer, tgUserID int64) error {
+	if _, err := q.Exec(ctx, `DELETE FROM web_sessions WHERE tg_user_id = $1`, tgUserID); err != nil {
+		return fmt.Errorf("end web sessions: %w", err)
+	}
+	return nil
+}
+
+// DeleteWebSession ends the session (logout).
+func DeleteWebSession(ctx context.Context, token string) error {
+	_, err := db.Pool.Exec(ctx, `DELETE FROM web_sessions WHERE token_hash = $1`, hashAPIKey(token))
+	return err
+}
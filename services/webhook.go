@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"food-telegram/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Webhook events. Each one is derived from an order_status_history row (see webhookEventsForTransition).
+const (
+	WebhookEventOrderCreated       = "order.created"        // order placed (history row "" -> new)
+	WebhookEventOrderStatusChanged = "order.status_changed" // every later status change
+	WebhookEventDriverAssigned     = "order.driver_assigned"
+	WebhookEventOrderCompleted     = "order.completed"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{WebhookEventOrderCreated, WebhookEventOrderStatusChanged, WebhookEventDriverAssigned, WebhookEventOrderCompleted}
+
+// Webhook is a branch's subscription. Events empty = every event.
+type Webhook struct {
+	ID         int64
+	LocationID int64
+	URL        string
+	Secret     string
+	Events     []string
+	CreatedBy  int64
+	CreatedAt  time.Time
+	DisabledAt *time.Time
+	Failed     int // failed deliveries waiting for replay (ListWebhooks only)
+}
+
+// Wants reports whether the webhook subscribes to event.
+func (w Webhook) Wants(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPayload is the JSON body POSTed to the webhook URL.
+type WebhookPayload struct {
+	Event      string       `json:"event"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Order      WebhookOrder `json:"order"`
+}
+
+// WebhookOrder is the order as it was right after the change.
+type WebhookOrder struct {
+	ID             int64          `json:"id"`
+	LocationID     int64          `json:"location_id"`
+	Status         string         `json:"status"`
+	PreviousStatus string         `json:"previous_status,omitempty"`
+	DeliveryType   *string        `json:"delivery_type"`
+	ItemsTotal     int64          `json:"items_total"`
+	DeliveryFee    int64          `json:"delivery_fee"`
+	GrandTotal     int64          `json:"grand_total"`
+	CreatedAt      time.Time      `json:"created_at"`
+	Driver         *WebhookDriver `json:"driver,omitempty"`
+}
+
+// WebhookDriver is the assigned driver, if any.
+type WebhookDriver struct {
+	ID       string `json:"id"`
+	FullName string `json:"full_name"`
+	Phone    string `json:"phone"`
+	CarPlate string `json:"car_plate"`
+}
+
+// webhookEventsForTransition maps one order_status_history row to its webhook events.
+func webhookEventsForTransition(from, to string) []string {
+	if from == "" {
+		return []string{WebhookEventOrderCreated}
+	}
+	events := []string{WebhookEventOrderStatusChanged}
+	switch to {
+	case OrderStatusAssigned:
+		events = append(events, WebhookEventDriverAssigned)
+	case OrderStatusCompleted:
+		events = append(events, WebhookEventOrderCompleted)
+	}
+	return events
+}
+
+// ParseWebhookEvents reads a comma-separated event list; "" or "all" = every event.
+func ParseWebhookEvents(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "all" {
+		return nil, nil
+	}
+	var events []string
+	for _, e := range strings.Split(s, ",") {
+		e = strings.TrimSpace(e)
+		known := false
+		for _, k := range WebhookEvents {
+			known = known || k == e
+		}
+		if !known {
+			return nil, fmt.Errorf("noma'lum hodisa %q (mavjud: %s)", e, strings.Join(WebhookEvents, ", "))
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// enqueueWebhookEventsTx queues a delivery for every live webhook of the order's branch that wants an event of
+// the history row from -> to. It runs in the transaction that writes the row, so no event is lost or sent twice.
+func enqueueWebhookEventsTx(ctx context.Context, tx pgx.Tx, orderID int64, from, to string) error {
+	rows, err := tx.Query(ctx, `
+		SELECT w.id, w.events FROM webhooks w
+		JOIN orders o ON o.location_id = w.location_id
+		WHERE o.id = $1 AND w.disabled_at IS NULL`,
+		orderID,
+	)
+	if err != nil {
+		return fmt.Errorf("webhooks for order %d: %w", orderID, err)
+	}
+	var hooks []Webhook
+	for rows.Next() {
+		var w Webhook
+		if err := rows.Scan(&w.ID, &w.Events); err != nil {
+			rows.Close()
+			return err
+		}
+		hooks = append(hooks, w)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	p := WebhookPayload{Order: WebhookOrder{PreviousStatus: from}}
+	var driverID, driverName, driverPhone, driverPlate *string
+	err = tx.QueryRow(ctx, `
+		SELECT now(), o.id, COALESCE(o.location_id, 0), o.status, o.delivery_type, o.items_total, COALESCE(o.delivery_fee, 0),
+		       o.grand_total, o.created_at, o.driver_id, d.full_name, d.phone, d.car_plate
+		FROM orders o LEFT JOIN drivers d ON d.id = o.driver_id
+		WHERE o.id = $1`,
+		orderID,
+	).Scan(&p.OccurredAt, &p.Order.ID, &p.Order.LocationID, &p.Order.Status, &p.Order.DeliveryType, &p.Order.ItemsTotal,
+		&p.Order.DeliveryFee, &p.Order.GrandTotal, &p.Order.CreatedAt, &driverID, &driverName, &driverPhone, &driverPlate)
+	if err != nil {
+		return fmt.Errorf("webhook payload for order %d: %w", orderID, err)
+	}
+	if driverID != nil {
+		p.Order.Driver = &WebhookDriver{ID: *driverID, FullName: deref(driverName), Phone: deref(driverPhone), CarPlate: deref(driverPlate)}
+	}
+	for _, event := range webhookEventsForTransition(from, to) {
+		p.Event = event
+		body, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		for _, w := range hooks {
+			if !w.Wants(event) {
+				continue
+			}
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO webhook_deliveries (webhook_id, event, order_id, payload) VALUES ($1, $2, $3, $4::jsonb)`,
+				w.ID, event, orderID, string(body),
+			); err != nil {
+				return fmt.Errorf("enqueue webhook %d: %w", w.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// CreateWebhook subscribes a branch to events (nil = every event) at rawURL and returns the webhook with its secret.
+func CreateWebhook(ctx context.Context, locationID int64, rawURL string, events []string, createdBy int64) (*Webhook, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("URL http:// yoki https:// bilan boshlanishi kerak")
+	}
+	l, err := GetLiveLocation(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return nil, fmt.Errorf("filial #%d topilmadi", locationID)
+	}
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("generate webhook secret: %w", err)
+	}
+	if events == nil {
+		events = []string{}
+	}
+	w := Webhook{LocationID: locationID, URL: u.String(), Secret: "whsec_" + hex.EncodeToString(raw), Events: events, CreatedBy: createdBy}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	err = tx.QueryRow(ctx, `
+		INSERT INTO webhooks (location_id, url, secret, events, created_by) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		w.LocationID, w.URL, w.Secret, w.Events, createdBy,
+	).Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("create webhook: %w", err)
+	}
+	if err := recordAudit(ctx, tx, AuditWebhookCreate, AuditTargetWebhook, strconv.FormatInt(w.ID, 10), nil,
+		map[string]interface{}{"location_id": locationID, "url": w.URL, "events": w.Events}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// ListWebhooks returns the webhooks of a branch (0 = every branch), live ones first, with their failed delivery count.
+func ListWebhooks(ctx context.Context, locationID int64) ([]Webhook, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT w.id, w.location_id, w.url, w.secret, w.events, COALESCE(w.created_by, 0), w.created_at, w.disabled_at,
+		       (SELECT COUNT(*) FROM webhook_deliveries d WHERE d.webhook_id = w.id AND d.status = 'failed')
+		FROM webhooks w
+		WHERE $1::bigint = 0 OR w.location_id = $1
+		ORDER BY w.disabled_at IS NOT NULL, w.location_id, w.id`,
+		locationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []Webhook
+	for rows.Next() {
+		var w Webhook
+		if err := rows.Scan(&w.ID, &w.LocationID, &w.URL, &w.Secret, &w.Events, &w.CreatedBy, &w.CreatedAt, &w.DisabledAt, &w.Failed); err != nil {
+			return nil, err
+		}
+		list = append(list, w)
+	}
+	return list, rows.Err()
+}
+
+// DisableWebhook stops a webhook for good; its queued deliveries are marked failed.
+func DisableWebhook(ctx context.Context, id int64) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	var locationID int64
+	var u string
+	err = tx.QueryRow(ctx, `UPDATE webhooks SET disabled_at = now() WHERE id = $1 AND disabled_at IS NULL RETURNING location_id, url`, id).
+		Scan(&locationID, &u)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("webhook #%d topilmadi yoki allaqachon o'chirilgan", id)
+		}
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE webhook_deliveries SET status = 'failed', failed_at = now(), last_error = 'webhook disabled'
+		WHERE webhook_id = $1 AND status IN ('pending', 'sending')`,
+		id,
+	); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, AuditWebhookDisable, AuditTargetWebhook, strconv.FormatInt(id, 10),
+		map[string]interface{}{"location_id": locationID, "url": u}, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
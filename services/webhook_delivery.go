@@ -0,0 +1,299 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"food-telegram/db"
+)
+
+// Webhook delivery statuses.
+const (
+	WebhookStatusPending   = "pending"
+	WebhookStatusSending   = "sending"
+	WebhookStatusDelivered = "delivered"
+	WebhookStatusFailed    = "failed"
+)
+
+// Request headers of a webhook delivery.
+const (
+	WebhookHeaderSignature = "X-Webhook-Signature" // t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderDelivery  = "X-Webhook-Delivery" // delivery ID; the same on every retry and replay
+)
+
+const (
+	// WebhookMaxAttempts is how many times a delivery is tried before it is marked failed.
+	WebhookMaxAttempts = 10
+	webhookBatchSize   = 20
+	// webhookLease is how long a claimed delivery is left alone before another pass may try it again.
+	webhookLease = 2 * time.Minute
+	// WebhookDeliveredRetention is how long delivered rows are kept before PurgeWebhookDeliveries deletes them.
+	WebhookDeliveredRetention = 7 * 24 * time.Hour
+	// WebhookFailedRetention is how long failed rows stay for /webhook_failed and /webhook_replay.
+	WebhookFailedRetention = 30 * 24 * time.Hour
+	// webhookPurgeBatch caps one DELETE so a large backlog does not hold a long lock.
+	webhookPurgeBatch = 5000
+)
+
+// WebhookDelivery is one webhook_deliveries row, with the target of its webhook.
+type WebhookDelivery struct {
+	ID           int64
+	WebhookID    int64
+	Event        string
+	OrderID      int64
+	Payload      []byte
+	Attempts     int
+	ResponseCode *int
+	LastError    string
+	CreatedAt    time.Time
+	URL          string
+	Secret       string
+}
+
+// SignWebhook returns the X-Webhook-Signature value for body sent at ts. Receivers recompute the HMAC with
+// their secret over "<t>.<body>" and compare; checking t guards against replayed requests.
+func SignWebhook(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookErrorPermanent reports whether a response code means retrying cannot help (4xx other than 408 and 429).
+// 0 is a network error; 5xx are transient.
+func webhookErrorPermanent(code int) bool {
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
+// postWebhook sends one delivery. It returns the response code (0 on a network error), the Retry-After the
+// receiver asked for, and an error unless the receiver answered 2xx.
+func postWebhook(ctx context.Context, client *http.Client, d WebhookDelivery, now time.Time) (int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "food-telegram-webhooks/1")
+	req.Header.Set(WebhookHeaderSignature, SignWebhook(d.Secret, now, d.Payload))
+	req.Header.Set(WebhookHeaderEvent, d.Event)
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatInt(d.ID, 10))
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, 0, nil
+	}
+	var retryAfter time.Duration
+	if sec, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && sec > 0 {
+		retryAfter = time.Duration(sec) * time.Second
+	}
+	return resp.StatusCode, retryAfter, fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+}
+
+// DeliverWebhooks sends the due deliveries until none is left. A delivery waits while an older one for the same
+// webhook and order is still queued, so each receiver sees an order's events in order. Failures are retried with
+// the outbox backoff; permanent ones and those out of attempts are marked failed for /webhook_replay.
+func DeliverWebhooks(ctx context.Context, client *http.Client) (delivered, failed int, err error) {
+	for {
+		list, err := claimWebhookDeliveries(ctx, webhookBatchSize, webhookLease)
+		if err != nil {
+			return delivered, failed, err
+		}
+		if len(list) == 0 {
+			return delivered, failed, nil
+		}
+		var markErr error
+		sendWebhookBatch(ctx, client, list, func(d WebhookDelivery, code int, retryAfter time.Duration, sendErr error) {
+			var err error
+			switch {
+			case sendErr == nil:
+				err = markWebhookDelivery(ctx, d.ID, WebhookStatusDelivered, code, 0, "")
+				delivered++
+			case webhookErrorPermanent(code) || d.Attempts >= WebhookMaxAttempts:
+				err = markWebhookDelivery(ctx, d.ID, WebhookStatusFailed, code, 0, sendErr.Error())
+				failed++
+			default:
+				err = markWebhookDelivery(ctx, d.ID, WebhookStatusPending, code, OutboxRetryDelay(d.Attempts, retryAfter), sendErr.Error())
+			}
+			if err != nil && markErr == nil {
+				markErr = fmt.Errorf("webhook delivery %d: %w", d.ID, err)
+			}
+		})
+		if markErr != nil {
+			return delivered, failed, markErr
+		}
+	}
+}
+
+// sendWebhookBatch posts the claimed deliveries with one goroutine per webhook, so a slow receiver only holds up
+// its own deliveries; each webhook's deliveries go out one after another in claim order. done is called for every
+// delivery, one call at a time.
+func sendWebhookBatch(ctx context.Context, client *http.Client, list []WebhookDelivery,
+	done func(d WebhookDelivery, code int, retryAfter time.Duration, err error)) {
+	byWebhook := make(map[int64][]WebhookDelivery)
+	var order []int64
+	for _, d := range list {
+		if _, ok := byWebhook[d.WebhookID]; !ok {
+			order = append(order, d.WebhookID)
+		}
+		byWebhook[d.WebhookID] = append(byWebhook[d.WebhookID], d)
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, id := range order {
+		wg.Add(1)
+		go func(list []WebhookDelivery) {
+			defer wg.Done()
+			for _, d := range list {
+				code, retryAfter, err := postWebhook(ctx, client, d, time.Now())
+				mu.Lock()
+				done(d, code, retryAfter, err)
+				mu.Unlock()
+			}
+		}(byWebhook[id])
+	}
+	wg.Wait()
+}
+
+// claimWebhookDeliveries marks up to limit due deliveries of live webhooks as sending (for lease) and returns them.
+func claimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	rows, err := db.Pool.Query(ctx, `
+		UPDATE webhook_deliveries d
+		SET status = 'sending', attempts = d.attempts + 1, next_attempt_at = now() + $2 * interval '1 second'
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT q.id FROM webhook_deliveries q
+			WHERE q.status IN ('pending', 'sending') AND q.next_attempt_at <= now()
+			  AND NOT EXISTS (
+			      SELECT 1 FROM webhook_deliveries p
+			      WHERE p.webhook_id = q.webhook_id AND p.order_id = q.order_id AND p.id < q.id
+			        AND p.status IN ('pending', 'sending'))
+			ORDER BY q.next_attempt_at, q.id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.webhook_id, d.event, COALESCE(d.order_id, 0), d.payload::text, d.attempts, d.created_at, w.url, w.secret`,
+		limit, lease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	var list []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload string
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.OrderID, &payload, &d.Attempts, &d.CreatedAt, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+func markWebhookDelivery(ctx context.Context, id int64, status string, code int, delay time.Duration, lastError string) error {
+	var respCode *int
+	if code != 0 {
+		respCode = &code
+	}
+	var errText *string
+	if lastError != "" {
+		errText = &lastError
+	}
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE webhook_deliveries SET
+			status = $2, response_code = $3, last_error = $4,
+			next_attempt_at = now() + $5 * interval '1 second',
+			delivered_at = CASE WHEN $2 = 'delivered' THEN now() END,
+			failed_at = CASE WHEN $2 = 'failed' THEN now() END
+		WHERE id = $1`,
+		id, status, respCode, errText, delay.Seconds(),
+	)
+	return err
+}
+
+// ListFailedWebhookDeliveries returns the most recently failed deliveries of a webhook (0 = every webhook).
+func ListFailedWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]WebhookDelivery, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT d.id, d.webhook_id, d.event, COALESCE(d.order_id, 0), d.attempts, d.response_code, COALESCE(d.last_error, ''), d.created_at, w.url
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = 'failed' AND ($1::bigint = 0 OR d.webhook_id = $1)
+		ORDER BY d.failed_at DESC LIMIT $2`,
+		webhookID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list failed webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	var list []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.OrderID, &d.Attempts, &d.ResponseCode, &d.LastError, &d.CreatedAt, &d.URL); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// ReplayWebhookDeliveries queues failed deliveries again with a fresh attempt budget: the one with deliveryID, or
+// (deliveryID 0) every failed delivery of webhookID. Only deliveries of live webhooks are replayed; the payload is
+// the original one. Returns how many were queued.
+func ReplayWebhookDeliveries(ctx context.Context, deliveryID, webhookID int64) (int64, error) {
+	res, err := db.Pool.Exec(ctx, `
+		UPDATE webhook_deliveries d
+		SET status = 'pending', attempts = 0, next_attempt_at = now(), failed_at = NULL
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND w.disabled_at IS NULL AND d.status = 'failed'
+		  AND (($1::bigint <> 0 AND d.id = $1) OR ($1::bigint = 0 AND d.webhook_id = $2))`,
+		deliveryID, webhookID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("replay webhook deliveries: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+// WebhookPurgeCutoffs returns the times before which delivered and failed deliveries are purged at now.
+func WebhookPurgeCutoffs(now time.Time) (deliveredBefore, failedBefore time.Time) {
+	return now.Add(-WebhookDeliveredRetention), now.Add(-WebhookFailedRetention)
+}
+
+// PurgeWebhookDeliveries deletes deliveries delivered before WebhookDeliveredRetention and failed before
+// WebhookFailedRetention, in batches, and returns how many it deleted. Queued deliveries are never touched.
+func PurgeWebhookDeliveries(ctx context.Context, now time.Time) (int64, error) {
+	deliveredBefore, failedBefore := WebhookPurgeCutoffs(now)
+	var total int64
+	for {
+		res, err := db.Pool.Exec(ctx, `
+			DELETE FROM webhook_deliveries WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE (status = 'delivered' AND delivered_at < $1) OR (status = 'failed' AND failed_at < $2)
+				LIMIT $3)`,
+			deliveredBefore, failedBefore, webhookPurgeBatch,
+		)
+		if err != nil {
+			return total, fmt.Errorf("purge webhook deliveries: %w", err)
+		}
+		total += res.RowsAffected()
+		if res.RowsAffected() < webhookPurgeBatch {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWebhookEventsForTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     []string
+	}{
+		{"", OrderStatusNew, []string{WebhookEventOrderCreated}},
+		{OrderStatusNew, OrderStatusPreparing, []string{WebhookEventOrderStatusChanged}},
+		{OrderStatusReady, OrderStatusAssigned, []string{WebhookEventOrderStatusChanged, WebhookEventDriverAssigned}},
+		{OrderStatusReady, OrderStatusCompleted, []string{WebhookEventOrderStatusChanged, WebhookEventOrderCompleted}},
+		{OrderStatusDelivering, OrderStatusCompleted, []string{WebhookEventOrderStatusChanged, WebhookEventOrderCompleted}},
+	}
+	for _, tt := range tests {
+		if got := webhookEventsForTransition(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q -> %q = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestParseWebhookEvents(t *testing.T) {
+	if events, err := ParseWebhookEvents("all"); err != nil || events != nil {
+		t.Errorf("all = %v, %v", events, err)
+	}
+	events, err := ParseWebhookEvents("order.created, order.completed")
+	if err != nil || !reflect.DeepEqual(events, []string{WebhookEventOrderCreated, WebhookEventOrderCompleted}) {
+		t.Fatalf("list = %v, %v", events, err)
+	}
+	w := Webhook{Events: events}
+	if !w.Wants(WebhookEventOrderCompleted) || w.Wants(WebhookEventOrderStatusChanged) {
+		t.Errorf("Wants does not follow %v", events)
+	}
+	if !(Webhook{}).Wants(WebhookEventDriverAssigned) {
+		t.Error("webhook without events should want every event")
+	}
+	if _, err := ParseWebhookEvents("order.created,order.paid"); err == nil {
+		t.Error("unknown event accepted")
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	body := []byte(`{"event":"order.created"}`)
+	now := time.Unix(1714550400, 0)
+	var got *http.Request
+	var gotBody []byte
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		if status == http.StatusServiceUnavailable {
+			w.Header().Set("Retry-After", "120")
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("busy"))
+	}))
+	defer srv.Close()
+	d := WebhookDelivery{ID: 7, Event: WebhookEventOrderCreated, Payload: body, URL: srv.URL, Secret: "whsec_test"}
+
+	code, _, err := postWebhook(context.Background(), srv.Client(), d, now)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("post = %d, %v", code, err)
+	}
+	if string(gotBody) != string(body) || got.Header.Get(WebhookHeaderEvent) != WebhookEventOrderCreated || got.Header.Get(WebhookHeaderDelivery) != "7" {
+		t.Errorf("request = %v %q", got.Header, gotBody)
+	}
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1714550400." + string(body)))
+	if want := "t=1714550400,v1=" + hex.EncodeToString(mac.Sum(nil)); got.Header.Get(WebhookHeaderSignature) != want {
+		t.Errorf("signature = %q, want %q", got.Header.Get(WebhookHeaderSignature), want)
+	}
+
+	status = http.StatusServiceUnavailable
+	code, retryAfter, err := postWebhook(context.Background(), srv.Client(), d, now)
+	if err == nil || code != http.StatusServiceUnavailable || retryAfter != 2*time.Minute {
+		t.Errorf("503 = %d, %s, %v", code, retryAfter, err)
+	}
+	if webhookErrorPermanent(code) {
+		t.Error("503 treated as permanent")
+	}
+
+	status = http.StatusGone
+	if code, _, err = postWebhook(context.Background(), srv.Client(), d, now); err == nil || !webhookErrorPermanent(code) {
+		t.Errorf("410 = %d, %v, want a permanent error", code, err)
+	}
+	for _, code := range []int{0, http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway} {
+		if webhookErrorPermanent(code) {
+			t.Errorf("%d treated as permanent", code)
+		}
+	}
+}
+
+func TestSendWebhookBatchIsolatesSlowReceivers(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fast.Close()
+
+	list := []WebhookDelivery{
+		{ID: 1, WebhookID: 10, URL: slow.URL},
+		{ID: 2, WebhookID: 20, URL: fast.URL},
+		{ID: 3, WebhookID: 10, URL: slow.URL},
+		{ID: 4, WebhookID: 20, URL: fast.URL},
+	}
+	var got []int64
+	fastDone := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		sendWebhookBatch(context.Background(), http.DefaultClient, list, func(d WebhookDelivery, code int, _ time.Duration, err error) {
+			if err != nil || code != http.StatusOK {
+				t.Errorf("delivery %d = %d, %v", d.ID, code, err)
+			}
+			got = append(got, d.ID)
+			if d.ID == 4 {
+				close(fastDone)
+			}
+		})
+		close(finished)
+	}()
+
+	// The fast receiver gets both of its deliveries while the slow one has not answered the first.
+	select {
+	case <-fastDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fast webhook waited for the slow one")
+	}
+	close(release)
+	<-finished
+	if want := []int64{2, 4, 1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("done order = %v, want %v (each webhook in claim order)", got, want)
+	}
+}
+
+func TestWebhookPurgeCutoffs(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	delivered, failed := WebhookPurgeCutoffs(now)
+	if want := time.Date(2024, 3, 24, 12, 0, 0, 0, time.UTC); !delivered.Equal(want) {
+		t.Errorf("delivered cutoff = %v, want %v", delivered, want)
+	}
+	if want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC); !failed.Equal(want) {
+		t.Errorf("failed cutoff = %v, want %v", failed, want)
+	}
+}
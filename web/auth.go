@@ -0,0 +1,133 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"food-telegram/services"
+)
+
+type loginData struct {
+	BotUsername string
+}
+
+// totpData is the authenticator code form; the session is not usable yet, so the page has no header with CSRF.
+type totpData struct {
+	CSRF string
+}
+
+// GET /login shows the Telegram Login Widget; it sends the user back to /auth/telegram.
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = s.pages["login"].ExecuteTemplate(w, "layout.html", pageData{Title: "Kirish", Page: "login",
+		Error: r.URL.Query().Get("err"), Data: loginData{BotUsername: s.cfg.BotUsername}})
+}
+
+// GET /auth/telegram is the Login Widget redirect: verify the hash and start a session. Staff with 2FA get a session
+// that waits for their authenticator code on /login/2fa.
+func (s *Server) authTelegram(w http.ResponseWriter, r *http.Request) {
+	u, err := verifyTelegramLogin(s.cfg.BotToken, r.URL.Query(), time.Now())
+	if err != nil {
+		s.denied(w, err.Error())
+		return
+	}
+	pendingTOTP, err := services.BranchAdminTOTPEnabled(r.Context(), u.ID)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	token, err := services.CreateWebSession(r.Context(), u.ID, s.cfg.SessionTTL, pendingTOTP)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(s.cfg.SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	if pendingTOTP {
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+		return
+	}
+	s.logSessionEvent(r, u.ID, services.AdminSessionEventWebLogin)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// GET, POST /login/2fa asks staff with 2FA for their authenticator code, with the adder bot's login throttle.
+func (s *Server) loginTOTP(w http.ResponseWriter, r *http.Request) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil || c.Value == "" {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	ctx := r.Context()
+	sess, err := services.GetWebSession(ctx, c.Value, s.cfg.SessionIdle)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if sess == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if !sess.PendingTOTP {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	var errMsg string
+	if r.Method == http.MethodPost {
+		if !s.validCSRF(r, sess) {
+			http.Error(w, "CSRF token noto'g'ri, sahifani yangilang", http.StatusForbidden)
+			return
+		}
+		if wait, _ := services.LoginThrottleWaitSeconds(ctx, sess.TgUserID, services.ThrottleRoleRestaurantAdmin); wait > 0 {
+			errMsg = fmt.Sprintf("Iltimos %d soniya kutib qayta urinib ko'ring.", wait)
+		} else {
+			ok, err := services.VerifyBranchAdminTOTP(ctx, sess.TgUserID, r.PostFormValue("code"))
+			if err != nil {
+				s.internalError(w, r, err)
+				return
+			}
+			if ok {
+				_ = services.RecordLoginSuccess(ctx, sess.TgUserID, services.ThrottleRoleRestaurantAdmin)
+				if err := services.CompleteWebSessionTOTP(ctx, c.Value); err != nil {
+					s.internalError(w, r, err)
+					return
+				}
+				s.logSessionEvent(r, sess.TgUserID, services.AdminSessionEventWebLogin)
+				http.Redirect(w, r, "/", http.StatusSeeOther)
+				return
+			}
+			_ = services.RecordLoginFailed(ctx, sess.TgUserID, services.ThrottleRoleRestaurantAdmin)
+			errMsg = "Kod noto'g'ri yoki ishlatilgan."
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = s.pages["totp"].ExecuteTemplate(w, "layout.html", pageData{Title: "Tasdiqlash", Page: "totp", Error: errMsg,
+		Data: totpData{CSRF: sess.CSRFToken}})
+}
+
+// POST /logout
+func (s *Server) logout(w http.ResponseWriter, r *http.Request, a access) {
+	if err := services.DeleteWebSession(r.Context(), a.token); err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	s.logSessionEvent(r, a.userID, services.AdminSessionEventWebLogout)
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// logSessionEvent records a dashboard sign-in or sign-out in admin_logins next to the adder bot's.
+func (s *Server) logSessionEvent(r *http.Request, userID int64, event string) {
+	if err := services.LogWebSessionEvent(r.Context(), userID, event); err != nil {
+		fmt.Fprintf(os.Stderr, "web: log %s user=%d: %v\n", event, userID, err)
+	}
+}
@@ -0,0 +1,96 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"food-telegram/services"
+)
+
+// boardOrder is one card on the live order board.
+type boardOrder struct {
+	ID           int64        `json:"id"`
+	Status       string       `json:"status"`
+	StatusLabel  string       `json:"status_label"`
+	Phone        string       `json:"phone"`
+	DeliveryType string       `json:"delivery_type"`
+	Driver       bool         `json:"driver"`
+	GrandTotal   string       `json:"grand_total"`
+	AgeMin       int          `json:"age_min"`
+	Next         []boardLabel `json:"next"`
+}
+
+type boardLabel struct {
+	Status string `json:"status"`
+	Label  string `json:"label"`
+}
+
+// GET /board
+func (s *Server) board(w http.ResponseWriter, r *http.Request, a access) {
+	if s.needLocation(w, r, a) {
+		return
+	}
+	s.render(w, r, a, "board", "Buyurtmalar", nil)
+}
+
+// GET /board/orders.json returns the branch's active orders, oldest first, with the buttons staff may press.
+func (s *Server) boardOrders(w http.ResponseWriter, r *http.Request, a access) {
+	if a.locationID == 0 {
+		http.Error(w, "location_id kerak", http.StatusBadRequest)
+		return
+	}
+	orders, err := services.ListOrders(r.Context(), services.OrderFilter{LocationID: a.locationID, Active: true, Limit: 200})
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	canHandle := a.can(services.BranchPermHandleOrders)
+	now := time.Now()
+	out := make([]boardOrder, 0, len(orders))
+	for i := len(orders) - 1; i >= 0; i-- {
+		o := orders[i]
+		b := boardOrder{ID: o.ID, Status: o.Status, StatusLabel: statusLabel(o.Status), Phone: o.Phone,
+			Driver: o.DriverID != nil, GrandTotal: formatMoney(o.GrandTotal), AgeMin: int(now.Sub(o.CreatedAt).Minutes())}
+		if o.DeliveryType != nil {
+			b.DeliveryType = *o.DeliveryType
+		}
+		b.Next = []boardLabel{}
+		if canHandle {
+			facts := services.OrderFacts{DeliveryType: b.DeliveryType, DriverAssigned: b.Driver}
+			for _, st := range services.NextOrderStatuses(o.Status, services.OrderActorBranch, facts) {
+				b.Next = append(b.Next, boardLabel{Status: st, Label: statusLabel(st)})
+			}
+		}
+		out = append(out, b)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// POST /board/status (order_id, status) moves an order as the branch, like the order card buttons. The board and the
+// kitchen display both use it.
+func (s *Server) boardStatus(w http.ResponseWriter, r *http.Request, a access) {
+	if !a.can(services.BranchPermHandleOrders) {
+		http.Error(w, "Buyurtmalarni boshqarishga ruxsatingiz yo'q", http.StatusForbidden)
+		return
+	}
+	orderID, err := strconv.ParseInt(r.PostFormValue("order_id"), 10, 64)
+	if err != nil || a.locationID == 0 {
+		http.Error(w, "noto'g'ri so'rov", http.StatusBadRequest)
+		return
+	}
+	status := r.PostFormValue("status")
+	if _, err := services.UpdateOrderStatus(r.Context(), orderID, status, a.locationID, a.userID); err != nil {
+		http.Error(w, fmt.Sprintf("#%d: %v", orderID, err), http.StatusConflict)
+		return
+	}
+	s.changed.notify()
+	if s.onOrderUpdated != nil {
+		s.onOrderUpdated(orderID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
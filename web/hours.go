@@ -0,0 +1,59 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"food-telegram/services"
+)
+
+type hoursData struct {
+	Week    [7]services.LocationHours
+	CanEdit bool
+}
+
+// GET /hours shows the weekly opening hours; POST /hours saves them (fields set_N, closed_N, opens_N, closes_N
+// for weekday N, Sunday = 0).
+func (s *Server) hours(w http.ResponseWriter, r *http.Request, a access) {
+	if s.needLocation(w, r, a) {
+		return
+	}
+	canEdit := a.can(services.BranchPermManageMenu)
+	if r.Method == http.MethodPost {
+		if !canEdit {
+			redirect(w, r, a, "/hours", "Ish vaqtini o'zgartirishga ruxsatingiz yo'q", true)
+			return
+		}
+		var week [7]services.LocationHours
+		for i := range week {
+			n := strconv.Itoa(i)
+			h := &week[i]
+			h.Set = r.PostFormValue("set_"+n) != ""
+			h.Closed = r.PostFormValue("closed_"+n) != ""
+			if !h.Set || h.Closed {
+				continue
+			}
+			var err error
+			if h.OpensMin, err = services.ParseClock(r.PostFormValue("opens_" + n)); err != nil {
+				redirect(w, r, a, "/hours", err.Error(), true)
+				return
+			}
+			if h.ClosesMin, err = services.ParseClock(r.PostFormValue("closes_" + n)); err != nil {
+				redirect(w, r, a, "/hours", err.Error(), true)
+				return
+			}
+		}
+		if err := services.SetLocationHours(r.Context(), a.locationID, week); err != nil {
+			redirect(w, r, a, "/hours", err.Error(), true)
+			return
+		}
+		redirect(w, r, a, "/hours", "Ish vaqti saqlandi", false)
+		return
+	}
+	week, err := services.GetLocationHours(r.Context(), a.locationID)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	s.render(w, r, a, "hours", "Ish vaqti", hoursData{Week: week, CanEdit: canEdit})
+}
@@ -0,0 +1,207 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"food-telegram/models"
+	"food-telegram/services"
+)
+
+const (
+	kitchenPollInterval = 3 * time.Second  // orders changed from Telegram or the driver bot are picked up this often
+	kitchenKeepAlive    = 20 * time.Second // comment line so proxies do not close an idle stream
+	kitchenTouch        = time.Minute      // an open screen counts as session activity this often, like the board's polling
+)
+
+// changeSignal wakes the kitchen streams when an order is moved from the dashboard, so the tapping screen and the
+// other screens of the branch update at once instead of on the next poll.
+type changeSignal struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func (c *changeSignal) wait() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ch == nil {
+		c.ch = make(chan struct{})
+	}
+	return c.ch
+}
+
+func (c *changeSignal) notify() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ch != nil {
+		close(c.ch)
+		c.ch = nil
+	}
+}
+
+type kitchenItem struct {
+	Name string `json:"name"`
+	Qty  int    `json:"qty"`
+}
+
+// kitchenOrder is one ticket on the kitchen display. Times are Unix milliseconds so the screen can run the age
+// timers itself.
+type kitchenOrder struct {
+	ID           int64         `json:"id"`
+	DeliveryType string        `json:"delivery_type"`
+	Driver       bool          `json:"driver"`
+	CreatedAt    int64         `json:"created_at"`
+	UpdatedAt    int64         `json:"updated_at"`
+	Items        []kitchenItem `json:"items"`
+	Next         []boardLabel  `json:"next"`
+}
+
+type kitchenGroup struct {
+	Status string         `json:"status"`
+	Label  string         `json:"label"`
+	Orders []kitchenOrder `json:"orders"`
+}
+
+// kitchenGroups groups active orders by status in flow order, oldest ticket first. Every status gets a group, so
+// the screen keeps its columns when one empties.
+func kitchenGroups(orders []services.OrderRecord, items map[int64][]models.OrderItem, canHandle bool) []kitchenGroup {
+	groups := make([]kitchenGroup, 0, len(services.ActiveOrderStatuses))
+	byStatus := make(map[string]int)
+	for _, st := range services.ActiveOrderStatuses {
+		byStatus[st] = len(groups)
+		groups = append(groups, kitchenGroup{Status: st, Label: statusLabel(st), Orders: []kitchenOrder{}})
+	}
+	for i := len(orders) - 1; i >= 0; i-- { // ListOrders is newest first
+		o := orders[i]
+		g, ok := byStatus[o.Status]
+		if !ok {
+			continue
+		}
+		k := kitchenOrder{ID: o.ID, Driver: o.DriverID != nil, CreatedAt: o.CreatedAt.UnixMilli(), UpdatedAt: o.UpdatedAt.UnixMilli(),
+			Items: []kitchenItem{}, Next: []boardLabel{}}
+		if o.DeliveryType != nil {
+			k.DeliveryType = *o.DeliveryType
+		}
+		for _, it := range items[o.ID] {
+			k.Items = append(k.Items, kitchenItem{Name: it.Name, Qty: it.Qty})
+		}
+		if canHandle {
+			facts := services.OrderFacts{DeliveryType: k.DeliveryType, DriverAssigned: k.Driver}
+			for _, st := range services.NextOrderStatuses(o.Status, services.OrderActorBranch, facts) {
+				k.Next = append(k.Next, boardLabel{Status: st, Label: statusLabel(st)})
+			}
+		}
+		groups[g].Orders = append(groups[g].Orders, k)
+	}
+	return groups
+}
+
+// GET /kitchen
+func (s *Server) kitchen(w http.ResponseWriter, r *http.Request, a access) {
+	if s.needLocation(w, r, a) {
+		return
+	}
+	s.render(w, r, a, "kitchen", "Oshxona", nil)
+}
+
+func (s *Server) kitchenSnapshot(r *http.Request, a access) ([]byte, error) {
+	orders, err := services.ListOrders(r.Context(), services.OrderFilter{LocationID: a.locationID, Active: true, Limit: 200})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(orders))
+	for _, o := range orders {
+		ids = append(ids, o.ID)
+	}
+	items, err := services.ListOrderItems(r.Context(), ids)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(kitchenGroups(orders, items, a.can(services.BranchPermHandleOrders)))
+}
+
+// GET /kitchen/events streams the branch's active orders as Server-Sent Events: an "orders" event with every group
+// whenever something changed. Access is checked again before every update, like withAccess does per request; once
+// the session ends or the user loses the branch, a "logout" event ends the stream. An open stream keeps the session
+// from idling out, as the board's polling does; the absolute lifetime still ends it.
+func (s *Server) kitchenEvents(w http.ResponseWriter, r *http.Request, a access) {
+	if a.locationID == 0 {
+		http.Error(w, "location_id kerak", http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	// The server's WriteTimeout would cut the stream
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	poll := time.NewTicker(kitchenPollInterval)
+	defer poll.Stop()
+	var last []byte
+	lastWrite, lastTouch := time.Now(), time.Now()
+	for {
+		touch := time.Since(lastTouch) >= kitchenTouch
+		if touch {
+			lastTouch = time.Now()
+		}
+		if !s.kitchenAccessValid(r, &a, touch) {
+			fmt.Fprint(w, "event: logout\ndata: {}\n\n")
+			flusher.Flush()
+			return
+		}
+		wake := s.changed.wait()
+		snap, err := s.kitchenSnapshot(r, a)
+		if err != nil {
+			if r.Context().Err() != nil {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "web kitchen %d: %v\n", a.locationID, err)
+		} else if !bytes.Equal(snap, last) {
+			if _, err := fmt.Fprintf(w, "event: orders\ndata: %s\n\n", snap); err != nil {
+				return
+			}
+			flusher.Flush()
+			last, lastWrite = snap, time.Now()
+		} else if time.Since(lastWrite) >= kitchenKeepAlive {
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			lastWrite = time.Now()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-poll.C:
+		case <-wake:
+		}
+	}
+}
+
+// kitchenAccessValid re-resolves the stream's access (touch: counting as session activity) and reports whether it
+// still covers the same branch; a's role is updated so the buttons follow it. A database error keeps the stream (it
+// is retried on the next update).
+func (s *Server) kitchenAccessValid(r *http.Request, a *access, touch bool) bool {
+	cur, deny, err := s.resolveAccess(r, a.token, touch)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "web kitchen %d: access: %v\n", a.locationID, err)
+		return true
+	}
+	if cur.session == nil || cur.session.PendingTOTP || deny != "" || cur.locationID != a.locationID {
+		return false
+	}
+	a.role = cur.role
+	return true
+}
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"testing"
+	"time"
+
+	"food-telegram/models"
+	"food-telegram/services"
+)
+
+func TestKitchenGroups(t *testing.T) {
+	pickup, delivery := "pickup", "delivery"
+	now := time.Now()
+	orders := []services.OrderRecord{ // newest first, as ListOrders returns them
+		{ID: 3, Status: services.OrderStatusNew, DeliveryType: &delivery, CreatedAt: now},
+		{ID: 2, Status: services.OrderStatusReady, DeliveryType: &pickup, CreatedAt: now.Add(-10 * time.Minute)},
+		{ID: 1, Status: services.OrderStatusNew, DeliveryType: &delivery, CreatedAt: now.Add(-20 * time.Minute)},
+	}
+	items := map[int64][]models.OrderItem{1: {{Name: "Osh", Qty: 2}, {Name: "Choy", Qty: 1}}}
+
+	groups := kitchenGroups(orders, items, true)
+	if len(groups) != len(services.ActiveOrderStatuses) {
+		t.Fatalf("groups = %d, want one per active status", len(groups))
+	}
+	newGroup := groups[0]
+	if newGroup.Status != services.OrderStatusNew || len(newGroup.Orders) != 2 {
+		t.Fatalf("new group = %+v", newGroup)
+	}
+	if newGroup.Orders[0].ID != 1 || newGroup.Orders[1].ID != 3 {
+		t.Errorf("new tickets not oldest first: %d, %d", newGroup.Orders[0].ID, newGroup.Orders[1].ID)
+	}
+	if got := newGroup.Orders[0].Items; len(got) != 2 || got[0].Name != "Osh" || got[0].Qty != 2 {
+		t.Errorf("items = %+v", got)
+	}
+	if len(newGroup.Orders[1].Items) != 0 || newGroup.Orders[1].Items == nil {
+		t.Errorf("order without lines should have an empty list, got %#v", newGroup.Orders[1].Items)
+	}
+	if next := newGroup.Orders[0].Next; len(next) != 2 || next[0].Status != services.OrderStatusPreparing {
+		t.Errorf("next = %+v", next)
+	}
+	ready := groups[2]
+	if ready.Status != services.OrderStatusReady || len(ready.Orders) != 1 {
+		t.Fatalf("ready group = %+v", ready)
+	}
+	if next := ready.Orders[0].Next; len(next) != 1 || next[0].Status != services.OrderStatusCompleted {
+		t.Errorf("pickup ready order next = %+v, want completed", next)
+	}
+
+	for _, g := range kitchenGroups(orders, items, false) {
+		for _, o := range g.Orders {
+			if len(o.Next) != 0 {
+				t.Errorf("order %d has buttons without handle_orders", o.ID)
+			}
+		}
+	}
+}
+
+func TestChangeSignal(t *testing.T) {
+	var c changeSignal
+	w := c.wait()
+	c.notify()
+	select {
+	case <-w:
+	default:
+		t.Fatal("waiter not woken")
+	}
+	select {
+	case <-c.wait():
+		t.Fatal("new waiter woken by an old change")
+	default:
+	}
+}
@@ -0,0 +1,124 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"food-telegram/models"
+	"food-telegram/services"
+)
+
+type menuData struct {
+	Items      []services.BranchMenuItem
+	Categories []string
+	CanEdit    bool
+}
+
+var menuCategories = []string{models.CategoryFood, models.CategoryDrink, models.CategoryDessert}
+
+// GET /menu
+func (s *Server) menu(w http.ResponseWriter, r *http.Request, a access) {
+	if s.needLocation(w, r, a) {
+		return
+	}
+	items, err := services.ListBranchMenu(r.Context(), a.locationID, "")
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	s.render(w, r, a, "menu", "Menyu", menuData{Items: items, Categories: menuCategories, CanEdit: a.can(services.BranchPermManageMenu)})
+}
+
+// menuForm reads category, name and price from the posted form.
+func menuForm(r *http.Request) (category, name string, price int64, ok bool) {
+	category = r.PostFormValue("category")
+	name = strings.TrimSpace(r.PostFormValue("name"))
+	price, err := strconv.ParseInt(strings.TrimSpace(r.PostFormValue("price")), 10, 64)
+	return category, name, price, err == nil && price > 0 && name != ""
+}
+
+// ownMenuItem loads the posted item and checks it belongs to the branch; it answers the request when not.
+func (s *Server) ownMenuItem(w http.ResponseWriter, r *http.Request, a access) (int64, bool) {
+	id, err := strconv.ParseInt(r.PostFormValue("id"), 10, 64)
+	if err != nil {
+		redirect(w, r, a, "/menu", "Noto'g'ri mahsulot ID", true)
+		return 0, false
+	}
+	it, err := services.GetBranchMenuItem(r.Context(), id)
+	if err != nil {
+		s.internalError(w, r, err)
+		return 0, false
+	}
+	if it == nil || it.LocationID == nil || *it.LocationID != a.locationID {
+		redirect(w, r, a, "/menu", "Mahsulot topilmadi", true)
+		return 0, false
+	}
+	return id, true
+}
+
+func (s *Server) canEditMenu(w http.ResponseWriter, r *http.Request, a access) bool {
+	if a.locationID == 0 {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return false
+	}
+	if !a.can(services.BranchPermManageMenu) {
+		redirect(w, r, a, "/menu", "Menyuni o'zgartirishga ruxsatingiz yo'q", true)
+		return false
+	}
+	return true
+}
+
+// POST /menu/add
+func (s *Server) menuAdd(w http.ResponseWriter, r *http.Request, a access) {
+	if !s.canEditMenu(w, r, a) {
+		return
+	}
+	category, name, price, ok := menuForm(r)
+	if !ok {
+		redirect(w, r, a, "/menu", "Nom va narxni to'g'ri kiriting", true)
+		return
+	}
+	if _, err := services.AddMenuItemForLocation(r.Context(), category, name, price, a.locationID); err != nil {
+		redirect(w, r, a, "/menu", err.Error(), true)
+		return
+	}
+	redirect(w, r, a, "/menu", "Qo'shildi: "+name, false)
+}
+
+// POST /menu/update
+func (s *Server) menuUpdate(w http.ResponseWriter, r *http.Request, a access) {
+	if !s.canEditMenu(w, r, a) {
+		return
+	}
+	id, ok := s.ownMenuItem(w, r, a)
+	if !ok {
+		return
+	}
+	category, name, price, ok := menuForm(r)
+	if !ok {
+		redirect(w, r, a, "/menu", "Nom va narxni to'g'ri kiriting", true)
+		return
+	}
+	if err := services.UpdateMenuItem(r.Context(), id, category, name, price); err != nil {
+		redirect(w, r, a, "/menu", err.Error(), true)
+		return
+	}
+	redirect(w, r, a, "/menu", "Saqlandi: "+name, false)
+}
+
+// POST /menu/delete
+func (s *Server) menuDelete(w http.ResponseWriter, r *http.Request, a access) {
+	if !s.canEditMenu(w, r, a) {
+		return
+	}
+	id, ok := s.ownMenuItem(w, r, a)
+	if !ok {
+		return
+	}
+	if err := services.DeleteMenuItem(r.Context(), id); err != nil {
+		redirect(w, r, a, "/menu", err.Error(), true)
+		return
+	}
+	redirect(w, r, a, "/menu", "O'chirildi", false)
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"food-telegram/services"
+)
+
+// overviewRow is one branch on the superadmin overview, with today's numbers.
+type overviewRow struct {
+	ID     int64
+	Name   string
+	Active int
+	Today  services.BranchDayReport
+}
+
+// GET / is the superadmin's branch overview; branch staff go to their order board.
+func (s *Server) home(w http.ResponseWriter, r *http.Request, a access) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if !a.super || a.locationID != 0 {
+		redirect(w, r, a, "/board", "", false)
+		return
+	}
+	locs, err := services.ListLocations(r.Context())
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	from := startOfDay(time.Now())
+	rows := make([]overviewRow, 0, len(locs))
+	for _, l := range locs {
+		row := overviewRow{ID: l.ID, Name: l.Name}
+		days, err := services.ListBranchDailyReport(r.Context(), l.ID, from, from.AddDate(0, 0, 1))
+		if err != nil {
+			s.internalError(w, r, err)
+			return
+		}
+		if len(days) > 0 {
+			row.Today = days[0]
+		}
+		active, err := services.ListOrders(r.Context(), services.OrderFilter{LocationID: l.ID, Active: true, Limit: 200})
+		if err != nil {
+			s.internalError(w, r, err)
+			return
+		}
+		row.Active = len(active)
+		rows = append(rows, row)
+	}
+	s.render(w, r, a, "overview", "Filiallar", rows)
+}
+
+type reportsData struct {
+	From  string
+	To    string
+	Days  []services.BranchDayReport
+	Total services.BranchDayReport
+}
+
+// GET /reports?from=YYYY-MM-DD&to=YYYY-MM-DD (both inclusive, default the last 7 days). The superadmin without a
+// branch picked sees every branch together.
+func (s *Server) reports(w http.ResponseWriter, r *http.Request, a access) {
+	if !a.super && a.locationID == 0 {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	today := startOfDay(time.Now())
+	from, to := today.AddDate(0, 0, -6), today
+	q := r.URL.Query()
+	if t, err := time.ParseInLocation("2006-01-02", q.Get("from"), services.BranchTimezone); err == nil {
+		from = t
+	}
+	if t, err := time.ParseInLocation("2006-01-02", q.Get("to"), services.BranchTimezone); err == nil {
+		to = t
+	}
+	if to.Before(from) {
+		from, to = to, from
+	}
+	days, err := services.ListBranchDailyReport(r.Context(), a.locationID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	data := reportsData{From: from.Format("2006-01-02"), To: to.Format("2006-01-02"), Days: days}
+	for _, d := range days {
+		data.Total.Orders += d.Orders
+		data.Total.Completed += d.Completed
+		data.Total.Rejected += d.Rejected
+		data.Total.ItemsTotal += d.ItemsTotal
+		data.Total.DeliveryFee += d.DeliveryFee
+		data.Total.Revenue += d.Revenue
+	}
+	s.render(w, r, a, "reports", "Hisobotlar", data)
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.In(services.BranchTimezone)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, services.BranchTimezone)
+}
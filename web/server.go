@@ -0,0 +1,334 @@
+// Package web serves the admin dashboard: an order board, a kitchen display, menu and opening hours editing and
+// reports for branch staff (their own branch) and the superadmin (every branch). Users sign in with the Telegram
+// Login Widget.
+package web
+
+import (
+	"crypto/subtle"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"food-telegram/lang"
+	"food-telegram/services"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+const sessionCookie = "dash_session"
+
+// Config is what the dashboard needs from the bot setup.
+type Config struct {
+	BotToken     string        // token of the bot the Login Widget is linked to (its hash is checked with it)
+	BotUsername  string        // that bot's username, for the widget
+	SuperadminID int64         // Telegram user who sees every branch
+	SessionTTL   time.Duration // how long a login lasts
+	SessionIdle  time.Duration // a login unused this long ends (0 = no idle limit)
+}
+
+// Server is the dashboard's HTTP handler.
+type Server struct {
+	cfg            Config
+	pages          map[string]*template.Template
+	onOrderUpdated func(orderID int64)
+	changed        changeSignal
+}
+
+// New parses the embedded templates.
+func New(cfg Config) (*Server, error) {
+	s := &Server{cfg: cfg, pages: map[string]*template.Template{}}
+	funcs := template.FuncMap{
+		"money":       formatMoney,
+		"statusLabel": statusLabel,
+		"clock":       services.FormatClock,
+		"weekday":     weekdayName,
+	}
+	for _, page := range []string{"login", "totp", "overview", "board", "kitchen", "menu", "hours", "reports"} {
+		t, err := template.New("layout.html").Funcs(funcs).ParseFS(templateFS, "templates/layout.html", "templates/"+page+".html")
+		if err != nil {
+			return nil, fmt.Errorf("web template %s: %w", page, err)
+		}
+		s.pages[page] = t
+	}
+	return s, nil
+}
+
+// SetOnOrderUpdated is called after a status change from the board so queued notifications go out at once.
+func (s *Server) SetOnOrderUpdated(fn func(orderID int64)) {
+	s.onOrderUpdated = fn
+}
+
+// ListenAndServe serves the dashboard on addr until the listener fails.
+func (s *Server) ListenAndServe(addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
+
+// Handler returns the dashboard routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", s.login)
+	mux.HandleFunc("/auth/telegram", s.authTelegram)
+	mux.HandleFunc("/login/2fa", s.loginTOTP)
+	mux.HandleFunc("/logout", s.requirePOST(s.withAccess(s.logout)))
+	mux.HandleFunc("/", s.withAccess(s.home))
+	mux.HandleFunc("/board", s.withAccess(s.board))
+	mux.HandleFunc("/board/orders.json", s.withAccess(s.boardOrders))
+	mux.HandleFunc("/board/status", s.requirePOST(s.withAccess(s.boardStatus)))
+	mux.HandleFunc("/kitchen", s.withAccess(s.kitchen))
+	mux.HandleFunc("/kitchen/events", s.withAccess(s.kitchenEvents))
+	mux.HandleFunc("/menu", s.withAccess(s.menu))
+	mux.HandleFunc("/menu/add", s.requirePOST(s.withAccess(s.menuAdd)))
+	mux.HandleFunc("/menu/update", s.requirePOST(s.withAccess(s.menuUpdate)))
+	mux.HandleFunc("/menu/delete", s.requirePOST(s.withAccess(s.menuDelete)))
+	mux.HandleFunc("/hours", s.withAccess(s.hours))
+	mux.HandleFunc("/reports", s.withAccess(s.reports))
+	return securityHeaders(mux)
+}
+
+func securityHeaders(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "same-origin")
+		h.ServeHTTP(w, r)
+	})
+}
+
+// access is who is signed in and which branch the request acts on. Branch staff always act on their own branch;
+// the superadmin picks one with ?location_id= (0 = overview of every branch).
+type access struct {
+	session    *services.WebSession
+	token      string
+	userID     int64
+	super      bool
+	locationID int64
+	role       string
+}
+
+func (a access) can(perm string) bool {
+	return a.super || services.BranchRoleCan(a.role, perm)
+}
+
+type handler func(w http.ResponseWriter, r *http.Request, a access)
+
+// withAccess loads the session and resolves access on every request, so removed staff and expired subscriptions
+// lose the dashboard at once. A session waiting for its 2FA code is sent to /login/2fa.
+func (s *Server) withAccess(h handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie(sessionCookie)
+		if err != nil || c.Value == "" {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		a, deny, err := s.resolveAccess(r, c.Value, true)
+		if err != nil {
+			s.internalError(w, r, err)
+			return
+		}
+		switch {
+		case a.session == nil:
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		case a.session.PendingTOTP:
+			http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+			return
+		case deny != "":
+			s.denied(w, deny)
+			return
+		}
+		if r.Method == http.MethodPost && !s.validCSRF(r, a.session) {
+			http.Error(w, "CSRF token noto'g'ri, sahifani yangilang", http.StatusForbidden)
+			return
+		}
+		ctx := services.WithAuditActor(r.Context(), a.userID, auditRole(a))
+		h(w, r.WithContext(ctx), a)
+	}
+}
+
+// resolveAccess loads the session for token (touch: the request counts as activity) and what it may act on. No
+// session: a.session is nil. A user without access gets deny, the reason to show.
+func (s *Server) resolveAccess(r *http.Request, token string, touch bool) (a access, deny string, err error) {
+	var sess *services.WebSession
+	if touch {
+		sess, err = services.TouchWebSession(r.Context(), token, s.cfg.SessionIdle)
+	} else {
+		sess, err = services.GetWebSession(r.Context(), token, s.cfg.SessionIdle)
+	}
+	if err != nil || sess == nil || sess.PendingTOTP {
+		return access{session: sess}, "", err
+	}
+	a = access{session: sess, token: token, userID: sess.TgUserID}
+	if s.cfg.SuperadminID != 0 && sess.TgUserID == s.cfg.SuperadminID {
+		a.super = true
+		a.locationID, _ = strconv.ParseInt(r.FormValue("location_id"), 10, 64)
+		return a, "", nil
+	}
+	locID, role, err := services.GetBranchRole(r.Context(), sess.TgUserID)
+	if err != nil {
+		return a, "", err
+	}
+	if locID == 0 {
+		return a, "Siz hech qaysi filialning xodimi emassiz.", nil
+	}
+	active, err := services.LocationHasActiveSubscription(r.Context(), locID)
+	if err != nil {
+		return a, "", err
+	}
+	if !active {
+		return a, "Filial obunasi tugagan. Obunani yangilang.", nil
+	}
+	a.locationID, a.role = locID, role
+	return a, "", nil
+}
+
+func auditRole(a access) string {
+	if a.super {
+		return services.AuditRoleSuperadmin
+	}
+	return a.role
+}
+
+func (s *Server) validCSRF(r *http.Request, sess *services.WebSession) bool {
+	token := r.Header.Get("X-CSRF-Token")
+	if token == "" {
+		token = r.PostFormValue("csrf")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(sess.CSRFToken)) == 1
+}
+
+func (s *Server) requirePOST(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// pageData is what every page template gets; Data is the page's own content.
+type pageData struct {
+	Title      string
+	Page       string
+	Super      bool
+	LocationID int64
+	Location   string
+	Role       string
+	CSRF       string
+	Flash      string
+	Error      string
+	Locations  []locationOption
+	Data       interface{}
+}
+
+type locationOption struct {
+	ID   int64
+	Name string
+}
+
+func (s *Server) render(w http.ResponseWriter, r *http.Request, a access, page, title string, data interface{}) {
+	pd := pageData{Title: title, Page: page, Super: a.super, LocationID: a.locationID, Role: a.role,
+		Flash: r.URL.Query().Get("msg"), Error: r.URL.Query().Get("err"), Data: data}
+	if a.session != nil {
+		pd.CSRF = a.session.CSRFToken
+	}
+	if a.locationID != 0 {
+		pd.Location, _ = services.GetLocationName(r.Context(), a.locationID)
+	}
+	if a.super {
+		locs, err := services.ListLocations(r.Context())
+		if err != nil {
+			s.internalError(w, r, err)
+			return
+		}
+		for _, l := range locs {
+			pd.Locations = append(pd.Locations, locationOption{ID: l.ID, Name: l.Name})
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.pages[page].ExecuteTemplate(w, "layout.html", pd); err != nil {
+		fmt.Fprintf(os.Stderr, "web: render %s: %v\n", page, err)
+	}
+}
+
+func (s *Server) denied(w http.ResponseWriter, msg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+	_ = s.pages["login"].ExecuteTemplate(w, "layout.html", pageData{Title: "Kirish", Page: "login", Error: msg,
+		Data: loginData{BotUsername: s.cfg.BotUsername}})
+}
+
+// internalError logs err and answers 500 without details.
+func (s *Server) internalError(w http.ResponseWriter, r *http.Request, err error) {
+	fmt.Fprintf(os.Stderr, "web %s %s: %v\n", r.Method, r.URL.Path, err)
+	http.Error(w, "Ichki xato", http.StatusInternalServerError)
+}
+
+// redirect goes back to path keeping the superadmin's branch, with a flash message (or error).
+func redirect(w http.ResponseWriter, r *http.Request, a access, path, msg string, failed bool) {
+	q := url.Values{}
+	if a.super && a.locationID != 0 {
+		q.Set("location_id", strconv.FormatInt(a.locationID, 10))
+	}
+	if msg != "" {
+		key := "msg"
+		if failed {
+			key = "err"
+		}
+		q.Set(key, msg)
+	}
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	http.Redirect(w, r, path, http.StatusSeeOther)
+}
+
+// needLocation answers pages that work on one branch when the superadmin has not picked one.
+func (s *Server) needLocation(w http.ResponseWriter, r *http.Request, a access) bool {
+	if a.locationID != 0 {
+		return false
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return true
+}
+
+func statusLabel(status string) string {
+	return lang.T(lang.Uz, "adm_status_"+status)
+}
+
+var weekdayNames = [7]string{"Yakshanba", "Dushanba", "Seshanba", "Chorshanba", "Payshanba", "Juma", "Shanba"}
+
+func weekdayName(d time.Weekday) string {
+	return weekdayNames[d]
+}
+
+// formatMoney writes 1234567 as "1 234 567".
+func formatMoney(v int64) string {
+	s := strconv.FormatInt(v, 10)
+	neg := ""
+	if v < 0 {
+		neg, s = "-", s[1:]
+	}
+	var out []byte
+	for i := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			out = append(out, ' ')
+		}
+		out = append(out, s[i])
+	}
+	return neg + string(out)
+}
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"food-telegram/services"
+)
+
+func TestTemplatesRender(t *testing.T) {
+	s, err := New(Config{BotUsername: "food_admin_bot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var week [7]services.LocationHours
+	for i := range week {
+		week[i].Weekday = time.Weekday(i)
+	}
+	week[1] = services.LocationHours{Weekday: time.Monday, Set: true, OpensMin: 9 * 60, ClosesMin: 2 * 60}
+	data := map[string]interface{}{
+		"login":    loginData{BotUsername: "food_admin_bot"},
+		"totp":     totpData{CSRF: "tok"},
+		"overview": []overviewRow{{ID: 1, Name: "Chilonzor", Active: 2}},
+		"board":    nil,
+		"kitchen":  nil,
+		"menu":     menuData{Items: []services.BranchMenuItem{{ID: 5, Category: "food", Name: "Osh", Price: 45000}}, Categories: menuCategories, CanEdit: true},
+		"hours":    hoursData{Week: week, CanEdit: true},
+		"reports":  reportsData{From: "2024-01-01", To: "2024-01-07", Days: []services.BranchDayReport{{Day: time.Now(), Orders: 3, Revenue: 120000}}},
+	}
+	for page, d := range data {
+		var buf bytes.Buffer
+		pd := pageData{Title: page, Page: page, Super: true, LocationID: 1, CSRF: "tok", Data: d}
+		if err := s.pages[page].ExecuteTemplate(&buf, "layout.html", pd); err != nil {
+			t.Errorf("%s: %v", page, err)
+			continue
+		}
+		if page != "login" && !strings.Contains(buf.String(), `name="csrf" value="tok"`) {
+			t.Errorf("%s: no CSRF field", page)
+		}
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	for v, want := range map[int64]string{0: "0", 999: "999", 1000: "1 000", 1234567: "1 234 567", -45000: "-45 000"} {
+		if got := formatMoney(v); got != want {
+			t.Errorf("formatMoney(%d) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestLoginTOTPWithoutSession(t *testing.T) {
+	s, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/login/2fa", strings.NewReader("code=123456")))
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/login" {
+		t.Errorf("2FA form without a session = %d %q, want redirect to /login", rec.Code, rec.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// telegramLoginMaxAge is how old a Login Widget authorization may be when it reaches us; the widget redirects
+	// at once, so a short window keeps a leaked login link from being replayed later.
+	telegramLoginMaxAge = 5 * time.Minute
+	// telegramLoginClockSkew is how far auth_date may lie in the future (Telegram's clock ahead of ours).
+	telegramLoginClockSkew = 30 * time.Second
+)
+
+// telegramUser is the user the Login Widget vouches for.
+type telegramUser struct {
+	ID        int64
+	FirstName string
+	Username  string
+}
+
+var errTelegramLogin = errors.New("Telegram orqali kirish tasdiqlanmadi")
+
+// verifyTelegramLogin checks the Login Widget redirect parameters (https://core.telegram.org/widgets/login):
+// hash must be the hex HMAC-SHA256 of the sorted "key=value" lines with SHA256(bot token) as the key, and auth_date
+// must be recent and not in the future.
+func verifyTelegramLogin(botToken string, q url.Values, now time.Time) (telegramUser, error) {
+	hash := q.Get("hash")
+	if hash == "" || botToken == "" {
+		return telegramUser{}, errTelegramLogin
+	}
+	var lines []string
+	for k := range q {
+		if k == "hash" {
+			continue
+		}
+		lines = append(lines, k+"="+q.Get(k))
+	}
+	sort.Strings(lines)
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	want := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(strings.ToLower(hash)), []byte(want)) {
+		return telegramUser{}, errTelegramLogin
+	}
+	authDate, err := strconv.ParseInt(q.Get("auth_date"), 10, 64)
+	if err != nil {
+		return telegramUser{}, errTelegramLogin
+	}
+	if age := now.Sub(time.Unix(authDate, 0)); age > telegramLoginMaxAge || age < -telegramLoginClockSkew {
+		return telegramUser{}, errors.New("kirish havolasi eskirgan, qaytadan kiring")
+	}
+	id, err := strconv.ParseInt(q.Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return telegramUser{}, errTelegramLogin
+	}
+	return telegramUser{ID: id, FirstName: q.Get("first_name"), Username: q.Get("username")}, nil
+}
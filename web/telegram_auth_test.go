@@ -0,0 +1,70 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signLogin(token string, q url.Values) url.Values {
+	var lines []string
+	for k := range q {
+		lines = append(lines, k+"="+q.Get(k))
+	}
+	sort.Strings(lines)
+	secret := sha256.Sum256([]byte(token))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	q.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return q
+}
+
+func TestVerifyTelegramLogin(t *testing.T) {
+	const token = "123:abc"
+	now := time.Unix(1_700_000_000, 0)
+	login := func(authDate time.Time) url.Values {
+		return signLogin(token, url.Values{
+			"id":         {"42"},
+			"first_name": {"Ali"},
+			"username":   {"ali"},
+			"auth_date":  {strconv.FormatInt(authDate.Unix(), 10)},
+		})
+	}
+
+	u, err := verifyTelegramLogin(token, login(now.Add(-time.Minute)), now)
+	if err != nil {
+		t.Fatalf("valid login: %v", err)
+	}
+	if u.ID != 42 || u.FirstName != "Ali" || u.Username != "ali" {
+		t.Errorf("user = %+v", u)
+	}
+
+	tampered := login(now)
+	tampered.Set("id", "43")
+	if _, err := verifyTelegramLogin(token, tampered, now); err == nil {
+		t.Error("tampered id accepted")
+	}
+	if _, err := verifyTelegramLogin("999:other", login(now), now); err == nil {
+		t.Error("login signed for another bot accepted")
+	}
+	if _, err := verifyTelegramLogin(token, login(now.Add(-telegramLoginMaxAge-time.Second)), now); err == nil {
+		t.Error("expired login accepted")
+	}
+	if _, err := verifyTelegramLogin(token, login(now.Add(telegramLoginClockSkew)), now); err != nil {
+		t.Errorf("login within the clock skew: %v", err)
+	}
+	if _, err := verifyTelegramLogin(token, login(now.Add(telegramLoginClockSkew+time.Second)), now); err == nil {
+		t.Error("login from the future accepted")
+	}
+	noHash := login(now)
+	noHash.Del("hash")
+	if _, err := verifyTelegramLogin(token, noHash, now); err == nil {
+		t.Error("login without hash accepted")
+	}
+}